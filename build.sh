@@ -16,8 +16,9 @@ if [ $# -eq 0 ]; then
     exit 1
 fi
 
-# code will be compiled in this container
-BUILD_CONTAINER=golang:1.7.0-alpine
+# code will be compiled in this container.  Go 1.17 or later is needed (go:embed, csv.Reader.FieldPos, time.UnixMicro).
+# The code is built from GOPATH with the vendor dir so modules are off.
+BUILD_CONTAINER=golang:1.17-alpine
 
 DOCKER_TMP=docker-build-tmp
 
@@ -44,7 +45,7 @@ echo "nobody:x:65534:65534:Nobody:/:" > ${DOCKER_TMP}/etc/passwd
 
 for i in "$@"
 do
-	docker run -e "GOBIN=/usr/src/go/src/github.com/GeoNet/${CWD}/${DOCKER_TMP}" -e "GOPATH=/usr/src/go" -e "CGO_ENABLED=0" -e "GOOS=linux" -e "BUILD=$BUILD" --rm \
+	docker run -e "GOBIN=/usr/src/go/src/github.com/GeoNet/${CWD}/${DOCKER_TMP}" -e "GOPATH=/usr/src/go" -e "CGO_ENABLED=0" -e "GOOS=linux" -e "GO111MODULE=off" -e "BUILD=$BUILD" --rm \
 		-v "$PWD":/usr/src/go/src/github.com/GeoNet/${CWD} \
 		-w /usr/src/go/src/github.com/GeoNet/${CWD} ${BUILD_CONTAINER} \
 		go install -a -ldflags "-X main.Prefix=${i}/${VERSION}" -installsuffix cgo ./${i}
@@ -26,8 +26,15 @@ var (
 	DBSSLMode        = os.Getenv("DB_SSLMODE")
 )
 
+/*
+InitPG returns a DB configured from the DB_* env vars.  If DB_SCHEMA_CHECK is true InitPG waits
+for the DB (see Check) and returns an error if the schema is older than the latest migration.
+*/
 func InitPG() (DB, error) {
 	db, err := sql.Open("postgres", dbOpenString())
+	if err != nil {
+		return DB{db}, err
+	}
 
 	if s := os.Getenv("DB_MAX_IDLE_CONNS"); s != "" {
 		if i, err := strconv.Atoi(s); err == nil {
@@ -47,7 +54,17 @@ func InitPG() (DB, error) {
 	}
 	db.SetMaxOpenConns(maxOpenConns)
 
-	return DB{db}, err
+	d := DB{db}
+
+	if os.Getenv("DB_SCHEMA_CHECK") == "true" {
+		d.Check()
+
+		if err = d.CheckSchema(); err != nil {
+			return d, err
+		}
+	}
+
+	return d, nil
 }
 
 func (db *DB) Check() {
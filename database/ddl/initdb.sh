@@ -12,4 +12,5 @@ psql  -d hazard -c 'create extension postgis;'
 psql  --quiet hazard < /docker-entrypoint-initdb.d/drop-create.ddl
 psql  --quiet hazard < /docker-entrypoint-initdb.d/impact-create.ddl
 psql  --quiet hazard < /docker-entrypoint-initdb.d/impact-functions.ddl
+psql  --quiet hazard < /docker-entrypoint-initdb.d/push-create.ddl
 psql  --quiet hazard < /docker-entrypoint-initdb.d/user-permissions.ddl
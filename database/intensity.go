@@ -0,0 +1,153 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"strings"
+)
+
+/*
+SaveIntensity saves i to impact.intensity_measured or impact.intensity_reported depending on
+i.Quality.  A measured intensity only replaces the stored value for the source if it is higher.
+A reported intensity is saved with its questionnaire, which needs migration 5.  Without a
+questionnaire the original function is used so reports can be saved before the DB is migrated.
+*/
+func (db *DB) SaveIntensity(i msg.Intensity) error {
+	var err error
+
+	switch i.Quality {
+	case "measured":
+		_, err = db.Exec(`SELECT impact.add_intensity_measured($1, $2, $3, $4, $5)`,
+			i.Source, i.Longitude, i.Latitude, i.Time, i.MMI)
+	case "reported":
+		if i.Questionnaire == nil {
+			_, err = db.Exec(`SELECT impact.add_intensity_reported($1, $2, $3, $4, $5, $6)`,
+				i.Source, i.Longitude, i.Latitude, i.Time, i.MMI, i.Comment)
+			break
+		}
+
+		var b []byte
+		if b, err = json.Marshal(i.Questionnaire); err != nil {
+			return err
+		}
+
+		_, err = db.Exec(`SELECT impact.add_intensity_reported($1, $2, $3, $4, $5, $6, $7::jsonb)`,
+			i.Source, i.Longitude, i.Latitude, i.Time, i.MMI, i.Comment, string(b))
+	default:
+		err = fmt.Errorf("no method to save intensity with quality: %s", i.Quality)
+	}
+
+	return err
+}
+
+/*
+Intensities returns the intensities matching q.  Reported intensities include their questionnaire
+once the DB has migration 5.
+*/
+func (db *DB) Intensities(q IntensityQuery) ([]msg.Intensity, error) {
+	var s string
+
+	switch q.Quality {
+	case "measured":
+		s = `SELECT source, '', mmi, ST_Y(location::geometry), ST_X(location::geometry), time, NULL
+			FROM impact.intensity_measured`
+	case "reported":
+		ok, err := db.hasQuestionnaire()
+		if err != nil {
+			return nil, err
+		}
+
+		qn := `NULL`
+		if ok {
+			qn = `questionnaire`
+		}
+
+		s = `SELECT source, comment, mmi, ST_Y(location::geometry), ST_X(location::geometry), time, ` + qn + `
+			FROM impact.intensity_reported`
+	default:
+		return nil, fmt.Errorf("unknown intensity quality: %s", q.Quality)
+	}
+
+	w, a := q.where()
+
+	rows, err := db.Query(s+w+` ORDER BY time, source`, a...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var in []msg.Intensity
+
+	for rows.Next() {
+		i := msg.Intensity{Quality: q.Quality}
+		var qn []byte
+		if err = rows.Scan(&i.Source, &i.Comment, &i.MMI, &i.Latitude, &i.Longitude, &i.Time, &qn); err != nil {
+			return nil, err
+		}
+		if qn != nil {
+			i.Questionnaire = &msg.Questionnaire{}
+			if err = json.Unmarshal(qn, i.Questionnaire); err != nil {
+				return nil, err
+			}
+		}
+		i.Time = i.Time.UTC()
+		in = append(in, i)
+	}
+
+	return in, rows.Err()
+}
+
+// hasQuestionnaire returns true if impact.intensity_reported has the questionnaire column (migration 5).
+func (db *DB) hasQuestionnaire() (bool, error) {
+	var ok bool
+
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_attribute
+		WHERE attrelid = 'impact.intensity_reported'::regclass AND attname = 'questionnaire' AND NOT attisdropped)`).Scan(&ok)
+
+	return ok, err
+}
+
+// CountIntensities returns the number of intensities matching q.
+func (db *DB) CountIntensities(q IntensityQuery) (int, error) {
+	var s string
+
+	switch q.Quality {
+	case "measured":
+		s = `SELECT count(*) FROM impact.intensity_measured`
+	case "reported":
+		s = `SELECT count(*) FROM impact.intensity_reported`
+	default:
+		return 0, fmt.Errorf("unknown intensity quality: %s", q.Quality)
+	}
+
+	w, a := q.where()
+
+	var n int
+	err := db.QueryRow(s+w, a...).Scan(&n)
+
+	return n, err
+}
+
+// where returns the where clause and arguments for q.
+func (q IntensityQuery) where() (string, []interface{}) {
+	var w []string
+	var a []interface{}
+
+	if q.Quality == "reported" {
+		if !q.Start.IsZero() {
+			a = append(a, q.Start)
+			w = append(w, fmt.Sprintf(`time >= $%d`, len(a)))
+		}
+		if !q.End.IsZero() {
+			a = append(a, q.End)
+			w = append(w, fmt.Sprintf(`time < $%d`, len(a)))
+		}
+	}
+
+	if len(w) == 0 {
+		return "", nil
+	}
+
+	return ` WHERE ` + strings.Join(w, ` AND `), a
+}
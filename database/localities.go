@@ -0,0 +1,65 @@
+package database
+
+import (
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"log"
+	"os"
+)
+
+/*
+Localities reads localities and their region membership from qrt.locality and qrt.region.
+A locality is in every region that contains it.
+*/
+func (db *DB) Localities() (map[msg.RegionID][]msg.Locality, error) {
+	rows, err := db.Query(`SELECT r.regionname, l.name, ST_X(l.locality_geom), ST_Y(l.locality_geom), l.size
+		FROM qrt.locality l JOIN qrt.region r ON ST_Contains(r.geom, l.locality_geom)
+		WHERE l.size IN (0, 1, 2)
+		ORDER BY r.regionname, l.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	l := make(map[msg.RegionID][]msg.Locality)
+
+	for rows.Next() {
+		var r msg.RegionID
+		var v msg.Locality
+
+		if err = rows.Scan(&r, &v.Name, &v.Longitude, &v.Latitude, &v.Size); err != nil {
+			return nil, err
+		}
+
+		l[r] = append(l[r], v)
+	}
+
+	return l, rows.Err()
+}
+
+/*
+InitLocalities replaces the compiled in localities in msg with those from the DB if the LOCALITIES_DB
+env var is true, otherwise it calls msg.InitLocalities for LOCALITIES_FILE.  Only one of them can be set.
+*/
+func (db *DB) InitLocalities() error {
+	if os.Getenv("LOCALITIES_DB") != "true" {
+		return msg.InitLocalities()
+	}
+
+	if os.Getenv("LOCALITIES_FILE") != "" {
+		return fmt.Errorf("set only one of LOCALITIES_DB and LOCALITIES_FILE")
+	}
+
+	l, err := db.Localities()
+	if err != nil {
+		return fmt.Errorf("reading localities from the DB: %s", err)
+	}
+
+	if err = msg.SetLocalities(l); err != nil {
+		return fmt.Errorf("localities from the DB: %s", err)
+	}
+
+	log.Printf("loaded localities for %d regions from the DB", len(l))
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"sort"
+	"sync"
+)
+
+/*
+MemStore is an in memory QuakeStore for testing consumers and handlers without a DB.
+It follows the same rules as DB for out of order and repeated messages.  Use NewMemStore.
+*/
+type MemStore struct {
+	mu         sync.RWMutex
+	quakes     map[string]msg.Quake
+	history    map[string]map[int64]msg.Quake // keyed by publicID and unixMicro(ModificationTime).
+	heartBeats map[string]msg.HeartBeat
+	measured   map[string]msg.Intensity
+	reported   map[reportedKey]msg.Intensity
+}
+
+type reportedKey struct {
+	source string
+	time   int64
+}
+
+var _ QuakeStore = (*MemStore)(nil)
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		quakes:     make(map[string]msg.Quake),
+		history:    make(map[string]map[int64]msg.Quake),
+		heartBeats: make(map[string]msg.HeartBeat),
+		measured:   make(map[string]msg.Intensity),
+		reported:   make(map[reportedKey]msg.Intensity),
+	}
+}
+
+func (m *MemStore) SaveQuake(q msg.Quake) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.history[q.PublicID]
+	if !ok {
+		h = make(map[int64]msg.Quake)
+		m.history[q.PublicID] = h
+	}
+
+	if _, ok := h[unixMicro(q.ModificationTime)]; !ok {
+		h[unixMicro(q.ModificationTime)] = q
+	}
+
+	if c, ok := m.quakes[q.PublicID]; !ok || q.ModificationTime.After(c.ModificationTime) {
+		m.quakes[q.PublicID] = q
+	}
+
+	return nil
+}
+
+func (m *MemStore) SaveHeartBeat(h msg.HeartBeat) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.heartBeats[h.ServiceID] = h
+
+	return nil
+}
+
+func (m *MemStore) SaveIntensity(i msg.Intensity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch i.Quality {
+	case "measured":
+		// the highest MMI for the source is kept.
+		if c, ok := m.measured[i.Source]; !ok || i.MMI > c.MMI {
+			i.Comment = ""
+			m.measured[i.Source] = i
+		}
+	case "reported":
+		m.reported[reportedKey{source: i.Source, time: unixMicro(i.Time)}] = i
+	default:
+		return fmt.Errorf("no method to save intensity with quality: %s", i.Quality)
+	}
+
+	return nil
+}
+
+func (m *MemStore) Quake(publicID string) (msg.Quake, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	q, ok := m.quakes[publicID]
+	if !ok {
+		return q, ErrNotFound
+	}
+
+	return q, nil
+}
+
+func (m *MemStore) QuakeHistory(publicID string) ([]msg.Quake, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	h, ok := m.history[publicID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	var q []msg.Quake
+	for _, v := range h {
+		q = append(q, v)
+	}
+
+	sort.Slice(q, func(i, j int) bool { return q[i].ModificationTime.After(q[j].ModificationTime) })
+
+	return q, nil
+}
+
+func (m *MemStore) Quakes(qq QuakeQuery) ([]msg.Quake, error) {
+	if err := qq.validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var q []msg.Quake
+	for _, v := range m.quakes {
+		if qq.match(v) {
+			q = append(q, v)
+		}
+	}
+
+	sort.Slice(q, func(i, j int) bool { return less(qq.OrderBy, q[i], q[j]) })
+
+	if qq.Offset >= len(q) {
+		return nil, nil
+	}
+	q = q[qq.Offset:]
+
+	if qq.Limit > 0 && len(q) > qq.Limit {
+		q = q[:qq.Limit]
+	}
+
+	return q, nil
+}
+
+func (m *MemStore) HeartBeats() ([]msg.HeartBeat, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var h []msg.HeartBeat
+	for _, v := range m.heartBeats {
+		h = append(h, v)
+	}
+
+	sort.Slice(h, func(i, j int) bool { return h[i].ServiceID < h[j].ServiceID })
+
+	return h, nil
+}
+
+func (m *MemStore) Intensities(q IntensityQuery) ([]msg.Intensity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var in []msg.Intensity
+
+	switch q.Quality {
+	case "measured":
+		for _, v := range m.measured {
+			in = append(in, v)
+		}
+	case "reported":
+		for _, v := range m.reported {
+			if (!q.Start.IsZero() && v.Time.Before(q.Start)) || (!q.End.IsZero() && !v.Time.Before(q.End)) {
+				continue
+			}
+			in = append(in, v)
+		}
+	default:
+		return nil, fmt.Errorf("unknown intensity quality: %s", q.Quality)
+	}
+
+	sort.Slice(in, func(i, j int) bool {
+		if in[i].Time.Equal(in[j].Time) {
+			return in[i].Source < in[j].Source
+		}
+		return in[i].Time.Before(in[j].Time)
+	})
+
+	return in, nil
+}
+
+func (m *MemStore) CountIntensities(q IntensityQuery) (int, error) {
+	in, err := m.Intensities(q)
+	return len(in), err
+}
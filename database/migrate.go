@@ -0,0 +1,390 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+/*
+Migrations are SQL files in database/migrations named
+
+	<version>_<name>.up.sql
+	<version>_<name>.down.sql
+
+version is a positive integer, usually zero padded e.g., 0002_quake_upsert.up.sql.  Versions are applied
+in order and each migration is run in its own transaction along with the update to schema_migrations.
+The down file is optional; a migration without one can't be rolled back.
+
+Once released a migration must not be edited, add a new one.  Services run against the schema while
+it is migrated so migrations should be backwards compatible with the previous version of the code.
+*/
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string // empty if the migration can't be rolled back.
+}
+
+// AppliedMigration is a row from schema_migrations.
+type AppliedMigration struct {
+	Version int
+	Name    string
+	Applied string
+}
+
+// migrateLock is the key for the advisory lock held while migrating.
+const migrateLock = 7423001
+
+var migrationRe = regexp.MustCompile(`^([0-9]+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migrations returns the migrations compiled into the package in version order.
+func Migrations() ([]Migration, error) {
+	return ParseMigrations(migrationFiles, "migrations")
+}
+
+// ParseMigrations reads the migration files from dir in f.  See Migrations.
+func ParseMigrations(f fs.FS, dir string) ([]Migration, error) {
+	e, err := fs.ReadDir(f, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[int]*Migration)
+
+	for _, v := range e {
+		if v.IsDir() {
+			continue
+		}
+
+		p := migrationRe.FindStringSubmatch(v.Name())
+		if p == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", v.Name())
+		}
+
+		n, err := strconv.Atoi(p[1])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid migration version %s", v.Name())
+		}
+
+		b, err := fs.ReadFile(f, path.Join(dir, v.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := m[n]
+		if !ok {
+			mg = &Migration{Version: n, Name: p[2]}
+			m[n] = mg
+		}
+
+		if mg.Name != p[2] {
+			return nil, fmt.Errorf("migration %d has files for %s and %s", n, mg.Name, p[2])
+		}
+
+		switch p[3] {
+		case "up":
+			mg.Up = string(b)
+		case "down":
+			mg.Down = string(b)
+		}
+	}
+
+	var ms []Migration
+
+	for _, v := range m {
+		if v.Up == "" {
+			return nil, fmt.Errorf("migration %d %s has no up file", v.Version, v.Name)
+		}
+		ms = append(ms, *v)
+	}
+
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+
+	return ms, nil
+}
+
+// Latest returns the highest version in ms or 0 for no migrations.
+func Latest(ms []Migration) int {
+	if len(ms) == 0 {
+		return 0
+	}
+
+	return ms[len(ms)-1].Version
+}
+
+// step is one migration to apply (up) or roll back.
+type step struct {
+	m  Migration
+	up bool
+}
+
+/*
+plan returns the steps to move the schema from version current to target.
+It is an error to roll back past a migration without a down file or for
+current to be a version not in ms.
+*/
+func plan(ms []Migration, current, target int) ([]step, error) {
+	if current != 0 {
+		var found bool
+		for _, v := range ms {
+			if v.Version == current {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("schema version %d is unknown to this code (latest %d)", current, Latest(ms))
+		}
+	}
+
+	if target != 0 {
+		var found bool
+		for _, v := range ms {
+			if v.Version == target {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no migration for version %d", target)
+		}
+	}
+
+	var s []step
+
+	switch {
+	case target > current:
+		for _, v := range ms {
+			if v.Version > current && v.Version <= target {
+				s = append(s, step{m: v, up: true})
+			}
+		}
+	case target < current:
+		for i := len(ms) - 1; i >= 0; i-- {
+			v := ms[i]
+			if v.Version <= current && v.Version > target {
+				if v.Down == "" {
+					return nil, fmt.Errorf("migration %d %s can't be rolled back", v.Version, v.Name)
+				}
+				s = append(s, step{m: v, up: false})
+			}
+		}
+	}
+
+	return s, nil
+}
+
+const createMigrations = `CREATE TABLE IF NOT EXISTS public.schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT now()
+)`
+
+/*
+SchemaVersion returns the highest applied migration version.  Returns 0 if no migrations
+have been applied (including when the schema_migrations table doesn't exist).
+*/
+func (db *DB) SchemaVersion() (int, error) {
+	var t sql.NullString
+
+	if err := db.QueryRow(`SELECT to_regclass('public.schema_migrations')::text`).Scan(&t); err != nil {
+		return 0, err
+	}
+
+	if !t.Valid {
+		return 0, nil
+	}
+
+	var v int
+
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM public.schema_migrations`).Scan(&v)
+
+	return v, err
+}
+
+// AppliedMigrations returns the rows from schema_migrations in version order.
+func (db *DB) AppliedMigrations() ([]AppliedMigration, error) {
+	var t sql.NullString
+
+	if err := db.QueryRow(`SELECT to_regclass('public.schema_migrations')::text`).Scan(&t); err != nil {
+		return nil, err
+	}
+
+	if !t.Valid {
+		return nil, nil
+	}
+
+	rows, err := db.Query(`SELECT version, name, applied::text FROM public.schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var a []AppliedMigration
+
+	for rows.Next() {
+		var m AppliedMigration
+		if err = rows.Scan(&m.Version, &m.Name, &m.Applied); err != nil {
+			return nil, err
+		}
+		a = append(a, m)
+	}
+
+	return a, rows.Err()
+}
+
+/*
+Migrate applies or rolls back the migrations ms to move the schema to version target.
+Use Latest(ms) to migrate to the newest version and 0 to roll back everything.  An advisory lock
+is held while migrating so only one process can migrate at a time; others wait for the lock and then
+find the schema already migrated.  log, if not nil, is called before each step.
+*/
+func (db *DB) Migrate(ms []Migration, target int, log func(string)) error {
+	return db.migrate(ms, func(int) int { return target }, log)
+}
+
+/*
+MigrateDown rolls back the latest applied migration in ms.  The version to roll back to is found
+while the migration lock is held so concurrent migrations can't change it.  See Migrate.
+*/
+func (db *DB) MigrateDown(ms []Migration, log func(string)) error {
+	return db.migrate(ms, func(current int) int { return previous(ms, current) }, log)
+}
+
+// migrate moves the schema to the version returned by target for the current version.
+func (db *DB) migrate(ms []Migration, target func(current int) int, log func(string)) error {
+	return db.withMigrateLock(func(c *sql.Conn) error {
+		var current int
+		if err := c.QueryRowContext(context.Background(),
+			`SELECT COALESCE(MAX(version), 0) FROM public.schema_migrations`).Scan(&current); err != nil {
+			return err
+		}
+
+		t := target(current)
+
+		if log != nil && t != current {
+			log(fmt.Sprintf("schema version %d, migrating to %d", current, t))
+		}
+
+		s, err := plan(ms, current, t)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range s {
+			if log != nil {
+				if v.up {
+					log(fmt.Sprintf("applying %d %s", v.m.Version, v.m.Name))
+				} else {
+					log(fmt.Sprintf("rolling back %d %s", v.m.Version, v.m.Name))
+				}
+			}
+
+			if err = applyStep(c, v); err != nil {
+				return fmt.Errorf("migration %d %s: %s", v.m.Version, v.m.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// previous returns the version before v in ms.
+func previous(ms []Migration, v int) int {
+	var p int
+	for _, m := range ms {
+		if m.Version >= v {
+			break
+		}
+		p = m.Version
+	}
+
+	return p
+}
+
+// withMigrateLock runs f on a single connection holding the migration lock.
+func (db *DB) withMigrateLock(f func(*sql.Conn) error) error {
+	ctx := context.Background()
+
+	c, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// advisory locks belong to the session so take and release it on c.
+	if _, err = c.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrateLock); err != nil {
+		return err
+	}
+	defer c.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrateLock)
+
+	if _, err = c.ExecContext(ctx, createMigrations); err != nil {
+		return err
+	}
+
+	return f(c)
+}
+
+func applyStep(c *sql.Conn, s step) error {
+	ctx := context.Background()
+
+	txn, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	q := s.m.Down
+	if s.up {
+		q = s.m.Up
+	}
+
+	if _, err = txn.Exec(q); err != nil {
+		txn.Rollback()
+		return err
+	}
+
+	if s.up {
+		_, err = txn.Exec(`INSERT INTO public.schema_migrations(version, name) VALUES($1, $2)`, s.m.Version, s.m.Name)
+	} else {
+		_, err = txn.Exec(`DELETE FROM public.schema_migrations WHERE version = $1`, s.m.Version)
+	}
+	if err != nil {
+		txn.Rollback()
+		return err
+	}
+
+	return txn.Commit()
+}
+
+/*
+CheckSchema returns an error if the schema is older than the latest migration compiled into
+the package.  A newer schema is allowed; migrations are backwards compatible with the previous code.
+*/
+func (db *DB) CheckSchema() error {
+	ms, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	v, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if l := Latest(ms); v < l {
+		return fmt.Errorf("schema version %d is older than %d required by this code, run haz-db-migrate", v, l)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrations(t *testing.T) {
+	ms, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ms) == 0 || ms[0].Version != 1 || ms[0].Name != "baseline" {
+		t.Errorf("expected the baseline migration first got %+v", ms)
+	}
+
+	for i, v := range ms {
+		if i > 0 && v.Version <= ms[i-1].Version {
+			t.Errorf("migrations out of order at %d", v.Version)
+		}
+	}
+}
+
+func TestParseMigrations(t *testing.T) {
+	f := fstest.MapFS{
+		"m/0002_two.up.sql":   {Data: []byte("two up")},
+		"m/0002_two.down.sql": {Data: []byte("two down")},
+		"m/0001_one.up.sql":   {Data: []byte("one up")},
+		"m/0010_ten.up.sql":   {Data: []byte("ten up")},
+	}
+
+	ms, err := ParseMigrations(f, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ms) != 3 {
+		t.Fatalf("expected 3 migrations got %d", len(ms))
+	}
+
+	if ms[0].Version != 1 || ms[1].Version != 2 || ms[2].Version != 10 {
+		t.Errorf("unexpected order %+v", ms)
+	}
+
+	if ms[1].Up != "two up" || ms[1].Down != "two down" || ms[0].Down != "" {
+		t.Errorf("unexpected sql %+v", ms)
+	}
+
+	if Latest(ms) != 10 {
+		t.Errorf("expected latest 10 got %d", Latest(ms))
+	}
+
+	bad := []fstest.MapFS{
+		{"m/two.up.sql": {Data: []byte("x")}},
+		{"m/0002_two.sql": {Data: []byte("x")}},
+		{"m/0000_zero.up.sql": {Data: []byte("x")}},
+		{"m/0002_two.down.sql": {Data: []byte("x")}},
+		{"m/0002_two.up.sql": {Data: []byte("x")}, "m/0002_other.down.sql": {Data: []byte("x")}},
+	}
+
+	for i, v := range bad {
+		if _, err := ParseMigrations(v, "m"); err == nil {
+			t.Errorf("%d: expected error", i)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	ms := []Migration{
+		{Version: 1, Name: "one", Up: "1"},
+		{Version: 2, Name: "two", Up: "2", Down: "-2"},
+		{Version: 5, Name: "five", Up: "5", Down: "-5"},
+	}
+
+	in := []struct {
+		id              string
+		current, target int
+		steps           []int // negative for down.
+		err             bool
+	}{
+		{id: "all up", current: 0, target: 5, steps: []int{1, 2, 5}},
+		{id: "some up", current: 1, target: 5, steps: []int{2, 5}},
+		{id: "up to", current: 0, target: 2, steps: []int{1, 2}},
+		{id: "none", current: 5, target: 5},
+		{id: "down", current: 5, target: 2, steps: []int{-5}},
+		{id: "down two", current: 5, target: 1, steps: []int{-5, -2}},
+		{id: "no down", current: 5, target: 0, err: true},
+		{id: "unknown target", current: 0, target: 3, err: true},
+		{id: "unknown current", current: 4, target: 5, err: true},
+	}
+
+	for _, v := range in {
+		s, err := plan(ms, v.current, v.target)
+		if v.err {
+			if err == nil {
+				t.Errorf("%s: expected error", v.id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", v.id, err)
+			continue
+		}
+
+		if len(s) != len(v.steps) {
+			t.Errorf("%s: expected %d steps got %d", v.id, len(v.steps), len(s))
+			continue
+		}
+
+		for i, st := range s {
+			n := st.m.Version
+			if !st.up {
+				n = -n
+			}
+			if n != v.steps[i] {
+				t.Errorf("%s: step %d expected %d got %d", v.id, i, v.steps[i], n)
+			}
+		}
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	ms := []Migration{{Version: 1}, {Version: 2}, {Version: 5}}
+
+	in := []struct {
+		v, expected int
+	}{
+		{v: 5, expected: 2},
+		{v: 2, expected: 1},
+		{v: 1, expected: 0},
+		{v: 0, expected: 0},
+		{v: 4, expected: 2},
+	}
+
+	for _, v := range in {
+		if p := previous(ms, v.v); p != v.expected {
+			t.Errorf("%d: expected previous %d got %d", v.v, v.expected, p)
+		}
+	}
+}
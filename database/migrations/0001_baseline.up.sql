@@ -0,0 +1,6 @@
+-- The baseline is the haz, impact, and push schemas created by the files in database/ddl.
+-- Existing databases are at this version once the migration table can be read by the services.
+-- There is no down migration for the baseline; use database/ddl to recreate the db.
+GRANT SELECT ON public.schema_migrations TO hazard_r;
+GRANT SELECT ON public.schema_migrations TO hazard_w;
+GRANT SELECT ON public.schema_migrations TO impact_w;
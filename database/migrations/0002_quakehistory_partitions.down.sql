@@ -0,0 +1,22 @@
+-- Returns haz.quakehistory to a single table.  Archived and dropped partitions are not restored.
+
+ALTER TABLE haz.quakehistory RENAME TO quakehistory_partitioned;
+ALTER TABLE haz.quakehistory_partitioned RENAME CONSTRAINT quakehistory_publicid_modificationtime_key TO quakehistory_partitioned_key;
+ALTER INDEX haz.quakehistory_publicid_idx RENAME TO quakehistory_partitioned_publicid_idx;
+
+CREATE TABLE haz.quakehistory (LIKE haz.quakehistory_partitioned INCLUDING DEFAULTS);
+
+ALTER TABLE haz.quakehistory ADD CONSTRAINT quakehistory_publicid_modificationtime_key UNIQUE (PublicID, ModificationTimeUnixMicro);
+CREATE INDEX quakehistory_publicid_idx ON haz.quakehistory (PublicID);
+
+CREATE TRIGGER quakehistory_geom_trigger BEFORE INSERT OR UPDATE ON haz.quakehistory
+  FOR EACH ROW EXECUTE PROCEDURE haz.quake_geom();
+
+INSERT INTO haz.quakehistory SELECT * FROM haz.quakehistory_partitioned ON CONFLICT DO NOTHING;
+
+DROP TABLE haz.quakehistory_partitioned;
+DROP FUNCTION haz.create_quakehistory_partition(TIMESTAMP WITH TIME ZONE);
+DROP FUNCTION haz.drop_quakehistory_partition(TEXT);
+
+GRANT ALL ON haz.quakehistory TO hazard_w;
+GRANT SELECT ON haz.quakehistory TO hazard_r;
@@ -0,0 +1,87 @@
+-- Partitions haz.quakehistory by month (on quake time) so old history can be archived and dropped
+-- a partition at a time.  Needs Postgres 11 or later.
+--
+-- Partitions are created and dropped by the retention in haz-db-consumer using the functions below.
+-- They are security definer so the write user doesn't need to own the schema.  Rows for months
+-- without a partition go to quakehistory_default until the partition is created.
+
+ALTER TABLE haz.quakehistory RENAME TO quakehistory_old;
+ALTER TABLE haz.quakehistory_old RENAME CONSTRAINT quakehistory_publicid_modificationtime_key TO quakehistory_old_key;
+ALTER INDEX haz.quakehistory_publicid_idx RENAME TO quakehistory_old_publicid_idx;
+
+CREATE TABLE haz.quakehistory (LIKE haz.quakehistory_old INCLUDING DEFAULTS) PARTITION BY RANGE (Time);
+
+-- a unique constraint on a partitioned table must include the partition key.
+ALTER TABLE haz.quakehistory ADD CONSTRAINT quakehistory_publicid_modificationtime_key UNIQUE (PublicID, ModificationTimeUnixMicro, Time);
+CREATE INDEX quakehistory_publicid_idx ON haz.quakehistory (PublicID);
+
+-- row triggers are added to each partition, Postgres 11 doesn't allow them on the partitioned table.
+CREATE TABLE haz.quakehistory_default PARTITION OF haz.quakehistory DEFAULT;
+CREATE TRIGGER quakehistory_default_geom_trigger BEFORE INSERT OR UPDATE ON haz.quakehistory_default
+  FOR EACH ROW EXECUTE PROCEDURE haz.quake_geom();
+
+-- create_quakehistory_partition creates the partition for the month (UTC) containing m if it doesn't exist.
+-- Rows for the month already in quakehistory_default (e.g., from loading old quakes) are moved to the
+-- new partition, Postgres won't add a partition for rows that are in the default partition.
+-- Returns the partition name.
+CREATE FUNCTION haz.create_quakehistory_partition(m TIMESTAMP WITH TIME ZONE)
+RETURNS TEXT AS
+$$
+DECLARE
+  s TIMESTAMP := date_trunc('month', m AT TIME ZONE 'UTC');
+  n TEXT := 'quakehistory_' || to_char(s, 'YYYY_MM');
+  lo TEXT := s::text || '+00';
+  hi TEXT := (s + interval '1 month')::text || '+00';
+BEGIN
+  IF to_regclass('haz.' || n) IS NOT NULL THEN
+    RETURN n;
+  END IF;
+
+  -- writes wait until the rows are moved and the partition is attached.
+  LOCK TABLE haz.quakehistory IN SHARE ROW EXCLUSIVE MODE;
+
+  IF to_regclass('haz.' || n) IS NOT NULL THEN
+    RETURN n;
+  END IF;
+
+  EXECUTE format('CREATE TABLE haz.%I (LIKE haz.quakehistory INCLUDING DEFAULTS)', n);
+  EXECUTE format('WITH moved AS (DELETE FROM haz.quakehistory_default WHERE Time >= %L AND Time < %L RETURNING *) ' ||
+    'INSERT INTO haz.%I SELECT * FROM moved', lo, hi, n);
+  EXECUTE format('ALTER TABLE haz.quakehistory ATTACH PARTITION haz.%I FOR VALUES FROM (%L) TO (%L)', n, lo, hi);
+  EXECUTE format('CREATE TRIGGER %I BEFORE INSERT OR UPDATE ON haz.%I FOR EACH ROW EXECUTE PROCEDURE haz.quake_geom()',
+    n || '_geom_trigger', n);
+
+  RETURN n;
+END;
+$$
+LANGUAGE plpgsql SECURITY DEFINER SET search_path = haz, public, pg_temp;
+
+-- drop_quakehistory_partition drops the monthly partition n e.g., quakehistory_2016_01.
+CREATE FUNCTION haz.drop_quakehistory_partition(n TEXT)
+RETURNS VOID AS
+$$
+BEGIN
+  IF n !~ '^quakehistory_[0-9]{4}_[0-9]{2}$' THEN
+    RAISE EXCEPTION 'invalid quakehistory partition %', n;
+  END IF;
+  EXECUTE format('DROP TABLE IF EXISTS haz.%I', n);
+END;
+$$
+LANGUAGE plpgsql SECURITY DEFINER SET search_path = haz, public, pg_temp;
+
+REVOKE ALL ON FUNCTION haz.create_quakehistory_partition(TIMESTAMP WITH TIME ZONE) FROM PUBLIC;
+REVOKE ALL ON FUNCTION haz.drop_quakehistory_partition(TEXT) FROM PUBLIC;
+GRANT EXECUTE ON FUNCTION haz.create_quakehistory_partition(TIMESTAMP WITH TIME ZONE) TO hazard_w;
+GRANT EXECUTE ON FUNCTION haz.drop_quakehistory_partition(TEXT) TO hazard_w;
+
+SELECT haz.create_quakehistory_partition(m) FROM
+  (SELECT DISTINCT date_trunc('month', Time AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS m FROM haz.quakehistory_old) AS months;
+SELECT haz.create_quakehistory_partition(now());
+SELECT haz.create_quakehistory_partition(now() + interval '1 month');
+
+INSERT INTO haz.quakehistory SELECT * FROM haz.quakehistory_old;
+
+DROP TABLE haz.quakehistory_old;
+
+GRANT ALL ON haz.quakehistory TO hazard_w;
+GRANT SELECT ON haz.quakehistory TO hazard_r;
@@ -0,0 +1,2 @@
+DROP TRIGGER quake_notify_trigger ON haz.quake;
+DROP FUNCTION haz.quake_notify();
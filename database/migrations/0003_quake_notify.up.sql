@@ -0,0 +1,16 @@
+-- Notifies the haz_quake channel with the PublicID when the information for a quake in haz.quake
+-- changes.  Used for the quake stream in geonet-rest.  Ordered upserts only update haz.quake for
+-- newer information so there is a notification for each new version of a quake.
+
+CREATE FUNCTION haz.quake_notify()
+RETURNS TRIGGER AS
+$$
+BEGIN
+PERFORM pg_notify('haz_quake', NEW.PublicID);
+RETURN NULL;
+END;
+$$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER quake_notify_trigger AFTER INSERT OR UPDATE ON haz.quake
+  FOR EACH ROW EXECUTE PROCEDURE haz.quake_notify();
@@ -0,0 +1,3 @@
+DROP TRIGGER volcano_modified_trigger ON haz.volcano;
+DROP FUNCTION haz.volcano_modified();
+ALTER TABLE haz.volcano DROP COLUMN modificationtime;
@@ -0,0 +1,17 @@
+-- Adds haz.volcano.modificationtime.  It is set when a volcano (e.g., the alert level) is updated
+-- and is used for Last-Modified on the volcanic alert level in geonet-rest.
+
+ALTER TABLE haz.volcano ADD COLUMN modificationtime TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT now();
+
+CREATE FUNCTION haz.volcano_modified()
+RETURNS TRIGGER AS
+$$
+BEGIN
+NEW.modificationtime = now();
+RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER volcano_modified_trigger BEFORE UPDATE ON haz.volcano
+  FOR EACH ROW EXECUTE PROCEDURE haz.volcano_modified();
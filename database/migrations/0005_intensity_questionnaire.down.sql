@@ -0,0 +1,3 @@
+DROP FUNCTION impact.add_intensity_reported(TEXT, NUMERIC, NUMERIC, TIMESTAMP(6) WITH TIME ZONE, INTEGER, VARCHAR(140), JSONB);
+
+ALTER TABLE impact.intensity_reported DROP COLUMN questionnaire;
@@ -0,0 +1,36 @@
+-- Adds impact.intensity_reported.questionnaire for the structured part of felt reports
+-- sent to the geonet-rest felt report API.  The questionnaire is saved with an overload of
+-- impact.add_intensity_reported.  The original function is kept for the previous version of the code.
+
+ALTER TABLE impact.intensity_reported ADD COLUMN questionnaire JSONB;
+
+CREATE FUNCTION impact.add_intensity_reported(source_n TEXT, longitude_n NUMERIC, latitude_n NUMERIC, time_n TIMESTAMP(6) WITH TIME ZONE, mmi_n INTEGER, comment_n VARCHAR(140), questionnaire_n JSONB) RETURNS VOID AS
+$$
+DECLARE
+loc GEOGRAPHY = ST_GeogFromWKB(st_AsEWKB(st_setsrid(st_makepoint(longitude_n, latitude_n), 4326)));
+tries INTEGER = 0;
+BEGIN
+LOOP
+UPDATE impact.intensity_reported 
+SET mmi = mmi_n, comment = comment_n, questionnaire = questionnaire_n
+WHERE source = source_n
+AND intensity_reported.time = time_n;
+IF found THEN
+RETURN;
+END IF;
+
+BEGIN
+INSERT INTO impact.intensity_reported(source, time, mmi, comment, questionnaire, geohash5, geohash6, location) 
+VALUES (source_n, time_n, mmi_n, comment_n, questionnaire_n, st_geohash(loc, 5), st_geohash(loc, 6), loc);
+RETURN;
+EXCEPTION WHEN unique_violation THEN
+--  Loop once more to see if a different insert happened after the update but before our insert.
+tries = tries + 1;
+if tries > 1 THEN
+RETURN;
+END IF;
+END;
+END LOOP;
+END;
+$$
+LANGUAGE plpgsql;
@@ -0,0 +1 @@
+DROP INDEX haz.quakeapi_modificationtime_idx;
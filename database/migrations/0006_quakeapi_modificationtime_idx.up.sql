@@ -0,0 +1,4 @@
+-- Adds an index on haz.quakeapi.modificationtime for the version of the quake stats
+-- used for conditional GET in geonet-rest.
+
+CREATE INDEX quakeapi_modificationtime_idx ON haz.quakeapi (modificationtime);
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// QuakeChannel is notified with the PublicID when the information in haz.quake changes (migration 3).
+const QuakeChannel = "haz_quake"
+
+/*
+ListenQuakes returns a listener for QuakeChannel configured from the DB_* env vars.  It blocks until
+the DB can be reached.  The listener reconnects after connection problems, these are passed to problem
+(which may be nil).  Notifications can be missed while the listener is reconnecting; a nil notification
+is sent on Notify after each reconnection.
+*/
+func ListenQuakes(problem func(error)) (*pq.Listener, error) {
+	l := pq.NewListener(dbOpenString(), time.Duration(10)*time.Second, time.Minute,
+		func(e pq.ListenerEventType, err error) {
+			if err != nil && problem != nil {
+				problem(err)
+			}
+		})
+
+	if err := l.Listen(QuakeChannel); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
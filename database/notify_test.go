@@ -0,0 +1,57 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListenQuakes(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	clean := func() {
+		for _, v := range []string{`haz.quakehistory`, `haz.quake`, `haz.quakeapi`} {
+			if _, err := db.Exec(`DELETE FROM ` + v + ` WHERE publicid = '2099p200001'`); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	clean()
+	defer clean()
+
+	l, err := ListenQuakes(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	t0 := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := testQuake("2099p200001", t0)
+
+	if err = db.SaveQuake(q); err != nil {
+		t.Fatal(err)
+	}
+
+	// older information doesn't update haz.quake so there is no notification.
+	old := q
+	old.ModificationTime = t0.Add(-time.Minute)
+	if err = db.SaveQuake(old); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case n := <-l.Notify:
+		if n == nil || n.Extra != "2099p200001" {
+			t.Errorf("unexpected notification %+v", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification")
+	}
+
+	select {
+	case n := <-l.Notify:
+		t.Errorf("unexpected notification %+v", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
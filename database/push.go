@@ -0,0 +1,78 @@
+package database
+
+import (
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/push"
+	"strconv"
+	"strings"
+)
+
+// SaveSubscription adds or updates the push subscription s.
+func (db *DB) SaveSubscription(s push.Subscription) error {
+	if err := s.Valid(); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`INSERT INTO push.subscription(provider, platform, token, location, radius_km, min_mmi, min_magnitude, modified)
+		VALUES($1, $2, $3, ST_GeogFromWKB(st_AsEWKB(st_setsrid(st_makepoint($4, $5), 4326))), $6, $7, $8, now())
+		ON CONFLICT (provider, token) DO UPDATE SET platform = EXCLUDED.platform, location = EXCLUDED.location,
+		radius_km = EXCLUDED.radius_km, min_mmi = EXCLUDED.min_mmi, min_magnitude = EXCLUDED.min_magnitude, modified = EXCLUDED.modified`,
+		s.Provider, s.Platform, s.Token, s.Longitude, s.Latitude, s.RadiusKm, s.MinMMI, s.MinMagnitude)
+
+	return err
+}
+
+// DeleteSubscription removes the push subscription for the device token.
+func (db *DB) DeleteSubscription(provider, token string) error {
+	_, err := db.Exec(`DELETE FROM push.subscription WHERE provider = $1 AND token = $2`, provider, token)
+	return err
+}
+
+// subscriptionSlack (m) allows for differences between the PostGIS and msg distance calculations.
+const subscriptionSlack = 1000
+
+/*
+SubscriptionsForQuake returns the push subscriptions that could be targeted by q.  Subscriptions
+are filtered on magnitude and on the distance from q where the MMI reaches each subscriber's
+min_mmi or the subscriber's radius.  Use push.Target for the exact recipients.
+*/
+func (db *DB) SubscriptionsForQuake(q *msg.Quake) ([]push.Subscription, error) {
+	// radii[k-1] is the distance (m) from q where the MMI drops below k.  min_mmi is >= 1 so the
+	// radius for MMI 1 bounds all the MMI matches.
+	radii := make([]string, 12)
+	for k := range radii {
+		r := q.MMIRadius(float64(k + 1))
+		if r >= 0 {
+			r = r*1000 + subscriptionSlack
+		}
+		radii[k] = strconv.FormatFloat(r, 'f', 0, 64)
+	}
+
+	rows, err := db.Query(`WITH e AS (SELECT ST_GeogFromWKB(st_AsEWKB(st_setsrid(st_makepoint($2, $3), 4326))) AS epicentre)
+		SELECT provider, platform, token, ST_Y(location::geometry), ST_X(location::geometry),
+		radius_km, min_mmi, min_magnitude, modified
+		FROM push.subscription, e
+		WHERE min_magnitude <= $1
+		AND ((ST_DWithin(location, epicentre, $4) AND ST_Distance(location, epicentre) <= ($5::float8[])[floor(min_mmi)::int])
+		OR (radius_km > 0 AND ST_DWithin(location, epicentre, radius_km * 1000 + $6)))`,
+		q.Magnitude, q.Longitude, q.Latitude, radii[0], "{"+strings.Join(radii, ",")+"}", subscriptionSlack)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []push.Subscription
+
+	for rows.Next() {
+		var s push.Subscription
+
+		if err = rows.Scan(&s.Provider, &s.Platform, &s.Token, &s.Latitude, &s.Longitude,
+			&s.RadiusKm, &s.MinMMI, &s.MinMagnitude, &s.Modified); err != nil {
+			return nil, err
+		}
+
+		subs = append(subs, s)
+	}
+
+	return subs, rows.Err()
+}
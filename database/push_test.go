@@ -0,0 +1,91 @@
+package database
+
+import (
+	"github.com/GeoNet/haz/push"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestSubscriptionsForQuake checks the SQL filter returns the subscribers push.Target notifies and
+// that saving a subscription again updates it.
+func TestSubscriptionsForQuake(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	const provider = "test"
+
+	clean := func() {
+		if _, err := db.Exec(`DELETE FROM push.subscription WHERE provider = $1`, provider); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clean()
+	defer clean()
+
+	// the quake is near Wellington.
+	q := testQuake("2099p000003", time.Now().UTC())
+	q.Magnitude = 5.5
+
+	in := []push.Subscription{
+		{Token: "wellington", Latitude: -41.29, Longitude: 174.78, MinMMI: 3},
+		{Token: "auckland", Latitude: -36.85, Longitude: 174.76, MinMMI: 3},
+		{Token: "auckland-radius", Latitude: -36.85, Longitude: 174.76, MinMMI: 6, RadiusKm: 600},
+		{Token: "wellington-magnitude", Latitude: -41.29, Longitude: 174.78, MinMMI: 3, MinMagnitude: 6},
+		{Token: "wellington-strong", Latitude: -41.29, Longitude: 174.78, MinMMI: 12},
+	}
+
+	for _, v := range in {
+		v.Provider = provider
+		v.Platform = "android"
+
+		if err := db.SaveSubscription(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// saving again replaces the subscription.
+	if err := db.SaveSubscription(push.Subscription{Provider: provider, Platform: "ios", Token: "auckland", Latitude: -41.29, Longitude: 174.78, MinMMI: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := db.SubscriptionsForQuake(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subs []push.Subscription
+	var got []string
+	for _, v := range s {
+		if v.Provider == provider {
+			subs = append(subs, v)
+			got = append(got, v.Token)
+		}
+	}
+	sort.Strings(got)
+
+	expected := []string{"auckland", "auckland-radius", "wellington"}
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, got)
+	}
+
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected %v got %v", expected, got)
+		}
+	}
+
+	r := push.Target(&q, subs)
+
+	if len(r) != len(subs) {
+		t.Errorf("expected push.Target to notify every subscriber returned got %d of %d", len(r), len(subs))
+	}
+
+	for _, v := range r {
+		if v.Token == "auckland" && v.Platform != "ios" {
+			t.Errorf("expected the updated subscription got %+v", v.Subscription)
+		}
+	}
+}
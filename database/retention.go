@@ -0,0 +1,247 @@
+package database
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/GeoNet/mtr/mtrapp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Retention is the config for removing old quake information.
+type Retention struct {
+	HistoryDays  int // haz.quakehistory is kept for quakes in the last HistoryDays.
+	QuakeAPIDays int // haz.quakeapi is kept for quakes in the last QuakeAPIDays.
+	FutureMonths int // quakehistory partitions are created this many months ahead.
+	// ArchiveDir - if not empty quakehistory partitions are written to a gzipped JSON lines
+	// file in ArchiveDir before they are dropped.
+	ArchiveDir string
+}
+
+// RetentionDefault keeps a year of quakes.
+var RetentionDefault = Retention{HistoryDays: 365, QuakeAPIDays: 365, FutureMonths: 2}
+
+// RetentionResult is what Retain did.
+type RetentionResult struct {
+	Created         []string // partitions created.
+	Archived        []string // archive files written.
+	Dropped         []string // partitions dropped.
+	HistoryDeleted  int64    // rows deleted from quakehistory (outside dropped partitions).
+	QuakeAPIDeleted int64    // rows deleted from quakeapi.
+	Skipped         bool     // true if retention is running elsewhere.
+}
+
+// retainLock is the key for the advisory lock held while applying retention.
+const retainLock = 7423002
+
+// mtrPartitionDropped times dropping a partition.  Rows deleted are in the RetentionResult.
+const mtrPartitionDropped = "retention.partition.dropped"
+
+var partitionRe = regexp.MustCompile(`^quakehistory_([0-9]{4})_([0-9]{2})$`)
+
+/*
+Retain removes quake information older than the windows in r.  now is the current time.
+
+If haz.quakehistory is partitioned (migration 2) partitions are created for the months up to
+r.FutureMonths ahead and partitions that are entirely older than r.HistoryDays are archived
+(if r.ArchiveDir is set) and dropped.  Any remaining history older than r.HistoryDays is deleted
+row by row.  A partition is never dropped if archiving it fails.
+
+Only one Retain runs at a time across all processes, others return with Skipped = true.
+*/
+func (db *DB) Retain(r Retention, now time.Time) (res RetentionResult, err error) {
+	t := mtrapp.Start()
+	defer t.Track("retention")
+
+	ctx := context.Background()
+
+	c, err := db.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+
+	var ok bool
+	if err = c.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, retainLock).Scan(&ok); err != nil {
+		return
+	}
+	if !ok {
+		res.Skipped = true
+		return
+	}
+	defer c.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, retainLock)
+
+	historyCutoff := now.AddDate(0, 0, -r.HistoryDays)
+
+	var kind string
+	if err = c.QueryRowContext(ctx, `SELECT relkind::text FROM pg_class WHERE oid = 'haz.quakehistory'::regclass`).Scan(&kind); err != nil {
+		return
+	}
+
+	if kind == "p" {
+		if err = retainPartitions(ctx, c, r, now, historyCutoff, &res); err != nil {
+			return
+		}
+	}
+
+	var x sql.Result
+
+	if x, err = c.ExecContext(ctx, `DELETE FROM haz.quakehistory WHERE time < $1`, historyCutoff); err != nil {
+		return
+	}
+	res.HistoryDeleted, _ = x.RowsAffected()
+
+	if x, err = c.ExecContext(ctx, `DELETE FROM haz.quakeapi WHERE time < $1`, now.AddDate(0, 0, -r.QuakeAPIDays)); err != nil {
+		return
+	}
+	res.QuakeAPIDeleted, _ = x.RowsAffected()
+
+	return
+}
+
+func retainPartitions(ctx context.Context, c *sql.Conn, r Retention, now, cutoff time.Time, res *RetentionResult) error {
+	for i := 0; i <= r.FutureMonths; i++ {
+		var n string
+		if err := c.QueryRowContext(ctx, `SELECT haz.create_quakehistory_partition($1)`,
+			monthStart(now).AddDate(0, i, 0)).Scan(&n); err != nil {
+			return err
+		}
+		res.Created = append(res.Created, n)
+	}
+
+	p, err := partitions(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range p {
+		start, ok := partitionMonth(n)
+		if !ok || start.AddDate(0, 1, 0).After(cutoff) {
+			continue
+		}
+
+		if r.ArchiveDir != "" {
+			a := mtrapp.Start()
+			f, err := archivePartition(ctx, c, r.ArchiveDir, n, start)
+			a.Track("retention.archive")
+			if err != nil {
+				return fmt.Errorf("archiving %s: %s", n, err)
+			}
+			res.Archived = append(res.Archived, f)
+		}
+
+		d := mtrapp.Start()
+		if _, err = c.ExecContext(ctx, `SELECT haz.drop_quakehistory_partition($1)`, n); err != nil {
+			return err
+		}
+		d.Track(mtrPartitionDropped)
+		res.Dropped = append(res.Dropped, n)
+	}
+
+	return nil
+}
+
+// partitions returns the names of the monthly quakehistory partitions in order.
+func partitions(ctx context.Context, c *sql.Conn) ([]string, error) {
+	rows, err := c.QueryContext(ctx, `SELECT p.relname FROM pg_inherits i
+		JOIN pg_class p ON p.oid = i.inhrelid
+		WHERE i.inhparent = 'haz.quakehistory'::regclass`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var p []string
+
+	for rows.Next() {
+		var n string
+		if err = rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		if _, ok := partitionMonth(n); ok {
+			p = append(p, n)
+		}
+	}
+
+	sort.Strings(p)
+
+	return p, rows.Err()
+}
+
+// partitionMonth returns the start of the month for partition name n.
+func partitionMonth(n string) (time.Time, bool) {
+	m := partitionRe.FindStringSubmatch(n)
+	if m == nil {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse("2006_01", m[1]+"_"+m[2])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+/*
+archivePartition writes the rows for the partition n (the month from start) as JSON lines to
+dir/n.jsonl.gz.  The file is written to a temporary name and renamed once complete.
+Returns the file name.
+*/
+func archivePartition(ctx context.Context, c *sql.Conn, dir, n string, start time.Time) (string, error) {
+	file := filepath.Join(dir, n+".jsonl.gz")
+
+	f, err := os.CreateTemp(dir, n+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := gzip.NewWriter(f)
+
+	rows, err := c.QueryContext(ctx, `SELECT row_to_json(q)::text FROM haz.quakehistory q
+		WHERE time >= $1 AND time < $2 ORDER BY time, publicid, modificationtimeunixmicro`,
+		start, start.AddDate(0, 1, 0))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var j string
+		if err = rows.Scan(&j); err != nil {
+			return "", err
+		}
+		if _, err = w.Write([]byte(j + "\n")); err != nil {
+			return "", err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return "", err
+	}
+
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+
+	if err = os.Rename(f.Name(), file); err != nil {
+		return "", err
+	}
+
+	return file, nil
+}
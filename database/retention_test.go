@@ -0,0 +1,134 @@
+package database
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPartitionMonth(t *testing.T) {
+	m, ok := partitionMonth("quakehistory_2016_11")
+	if !ok || !m.Equal(time.Date(2016, time.November, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected month %v %t", m, ok)
+	}
+
+	for _, v := range []string{"quakehistory_default", "quakehistory_2016_1", "quakehistory_2016_13", "quake_2016_11"} {
+		if _, ok := partitionMonth(v); ok {
+			t.Errorf("%s: expected not a monthly partition", v)
+		}
+	}
+
+	s := monthStart(time.Date(2016, time.November, 14, 11, 2, 56, 0, time.FixedZone("NZDT", 13*3600)))
+	if !s.Equal(time.Date(2016, time.November, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected month start %v", s)
+	}
+}
+
+// TestRetain needs the DB migrated to the latest version.
+func TestRetain(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	if err := db.CheckSchema(); err != nil {
+		t.Skipf("DB not migrated: %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "retain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const publicID = "2099p000002"
+
+	clean := func() {
+		for _, v := range []string{`haz.quakehistory`, `haz.quake`, `haz.quakeapi`} {
+			if _, err := db.Exec(`DELETE FROM `+v+` WHERE publicid = $1`, publicID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	clean()
+	defer clean()
+
+	now := time.Now().UTC()
+
+	// a quake old enough for its whole month to be outside the window.
+	q := testQuake(publicID, now.AddDate(-2, 0, 0))
+
+	// saved before its partition exists so the history goes to the default partition and is
+	// moved when the partition is created.
+	if err = db.SaveQuake(q); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.Exec(`SELECT haz.create_quakehistory_partition($1)`, q.Time); err != nil {
+		t.Fatal(err)
+	}
+
+	var d int
+	if err = db.QueryRow(`SELECT count(*) FROM haz.quakehistory_default WHERE publicid = $1`, publicID).Scan(&d); err != nil {
+		t.Fatal(err)
+	}
+	if d != 0 {
+		t.Errorf("expected the history moved out of the default partition got %d rows", d)
+	}
+
+	r, err := db.Retain(Retention{HistoryDays: 365, QuakeAPIDays: 365, FutureMonths: 1, ArchiveDir: dir}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Skipped {
+		t.Skip("retention running elsewhere")
+	}
+
+	if len(r.Created) != 2 {
+		t.Errorf("expected 2 partitions created got %v", r.Created)
+	}
+
+	p := "quakehistory_" + monthStart(q.Time).Format("2006_01")
+
+	var found bool
+	for _, v := range r.Dropped {
+		if v == p {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected %s to be dropped got %v", p, r.Dropped)
+	}
+
+	f, err := os.Open(filepath.Join(dir, p+".jsonl.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	z, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadAll(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(b), publicID) {
+		t.Errorf("archive missing quake %s", publicID)
+	}
+
+	var n int
+	if err = db.QueryRow(`SELECT count(*) FROM haz.quakeapi WHERE publicid = $1`, publicID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("expected quake pruned from quakeapi")
+	}
+}
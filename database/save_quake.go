@@ -3,8 +3,9 @@ package database
 import (
 	"fmt"
 	"github.com/GeoNet/haz/msg"
-	"github.com/lib/pq"
 	"log"
+	"strings"
+	"time"
 )
 
 // These regions must exist in the DB.
@@ -12,186 +13,172 @@ var regionIDs = []msg.RegionID{
 	msg.NewZealand,
 }
 
-func (db *DB) SaveQuake(q msg.Quake) error {
-
-	//  could build the map from the struct using https://github.com/fatih/structs (reflection)
-	//
-	// Geom is added with a DB trigger for each new row
-	var qv = map[string]interface{}{
-		`PublicID`:              q.PublicID,
-		`Type`:                  q.Type,
-		`AgencyID`:              q.AgencyID,
-		`ModificationTime`:      q.ModificationTime,
-		`Time`:                  q.Time,
-		`Longitude`:             q.Longitude,
-		`Latitude`:              q.Latitude,
-		`Depth`:                 q.Depth,
-		`DepthType`:             q.DepthType,
-		`MethodID`:              q.MethodID,
-		`EarthModelID`:          q.EarthModelID,
-		`EvaluationMode`:        q.EvaluationMode,
-		`EvaluationStatus`:      q.EvaluationStatus,
-		`UsedPhaseCount`:        q.UsedPhaseCount,
-		`UsedStationCount`:      q.UsedStationCount,
-		`StandardError`:         q.StandardError,
-		`AzimuthalGap`:          q.AzimuthalGap,
-		`MinimumDistance`:       q.MinimumDistance,
-		`Magnitude`:             q.Magnitude,
-		`MagnitudeUncertainty`:  q.MagnitudeUncertainty,
-		`MagnitudeType`:         q.MagnitudeType,
-		`MagnitudeStationCount`: q.MagnitudeStationCount,
-		`Site`:                  q.Site,
-		`Status`:                q.Status(),
-		`Quality`:               q.Quality(),
-		`Deleted`:               q.Status() == `deleted`,
-		`BackupSite`:            q.Site == `backup`,
-		`MMI`:                   q.MMI(),
+// quakeColumns are the columns saved for a quake in haz.quakehistory, haz.quake, and haz.quakeapi
+// in the order of the values from quakeValues.  MMID_ and Intensity_ columns for regionIDs follow.
+// Geom and In_ columns are set with a DB trigger for each new row.
+var quakeColumns = append([]string{
+	`PublicID`,
+	`Type`,
+	`AgencyID`,
+	`ModificationTime`,
+	`Time`,
+	`Longitude`,
+	`Latitude`,
+	`Depth`,
+	`DepthType`,
+	`MethodID`,
+	`EarthModelID`,
+	`EvaluationMode`,
+	`EvaluationStatus`,
+	`UsedPhaseCount`,
+	`UsedStationCount`,
+	`StandardError`,
+	`AzimuthalGap`,
+	`MinimumDistance`,
+	`Magnitude`,
+	`MagnitudeUncertainty`,
+	`MagnitudeType`,
+	`MagnitudeStationCount`,
+	`Site`,
+	`Status`,
+	`Quality`,
+	`Deleted`,
+	`BackupSite`,
+	`MMI`,
+	`Intensity`,
+	`ModificationTimeUnixMicro`,
+	`Locality`,
+}, regionColumns()...)
+
+func regionColumns() []string {
+	var c []string
+	for _, v := range regionIDs {
+		c = append(c, `MMID_`+string(v), `Intensity_`+string(v))
 	}
 
+	return c
+}
+
+// quakeValues returns the values for quakeColumns for q.
+func quakeValues(q msg.Quake) []interface{} {
 	mmi := q.MMI()
-	qv[`Intensity`] = msg.MMIIntensity(mmi)
-	qv[`MMI`] = int(mmi)
 
-	// don't use time.UnixNano() for modificationTimeMicro, the zero time overflows int64.
-	mtUnixMicro := q.ModificationTime.Unix()*1000000 + int64(q.ModificationTime.Nanosecond()/1000)
-	qv[`ModificationTimeUnixMicro`] = mtUnixMicro
+	locality := "unknown"
+	if c, err := q.ClosestInRegion(msg.NewZealand); err == nil {
+		locality = c.Location()
+	}
+
+	v := []interface{}{
+		q.PublicID,
+		q.Type,
+		q.AgencyID,
+		q.ModificationTime,
+		q.Time,
+		q.Longitude,
+		q.Latitude,
+		q.Depth,
+		q.DepthType,
+		q.MethodID,
+		q.EarthModelID,
+		q.EvaluationMode,
+		q.EvaluationStatus,
+		q.UsedPhaseCount,
+		q.UsedStationCount,
+		q.StandardError,
+		q.AzimuthalGap,
+		q.MinimumDistance,
+		q.Magnitude,
+		q.MagnitudeUncertainty,
+		q.MagnitudeType,
+		q.MagnitudeStationCount,
+		q.Site,
+		q.Status(),
+		q.Quality(),
+		q.Status() == `deleted`,
+		q.Site == `backup`,
+		int(mmi),
+		msg.MMIIntensity(mmi),
+		unixMicro(q.ModificationTime),
+		locality,
+	}
 
 	// Add the region MMID and intensity for all regions in the DB.
-	for _, v := range regionIDs {
-		l, err := q.ClosestInRegion(v)
+	for _, r := range regionIDs {
+		l, err := q.ClosestInRegion(r)
 		if err != nil {
-			log.Println("error finding closest locality in " + string(v))
+			log.Println("error finding closest locality in " + string(r))
 			log.Println("setting MMID and intensity unknown.")
-			qv[`MMID_`+string(v)] = 0.0
-			qv[`Intensity_`+string(v)] = msg.MMIIntensity(0.0)
+			v = append(v, 0, msg.MMIIntensity(0.0))
 			continue
 		}
-		qv[`MMID_`+string(v)] = int(l.MMIDistance)
-		qv[`Intensity_`+string(v)] = msg.MMIIntensity(l.MMIDistance)
+		v = append(v, int(l.MMIDistance), msg.MMIIntensity(l.MMIDistance))
 	}
 
-	var insert string
-	var params string
-	var values []interface{}
-	var i int = 1
-
-	for k, v := range qv {
-		insert = insert + k + `, `
-		params = params + fmt.Sprintf("$%d, ", i)
-		values = append(values, v)
-		i = i + 1
-	}
+	return v
+}
 
-	locality := "'unknown'"
-	c, err := q.ClosestInRegion(msg.NewZealand)
-	if err == nil {
-		locality = fmt.Sprintf("$$%s$$", c.Location())
-	}
-	insert = insert + `Locality`
-	params = params + locality
+// unixMicro is t as micro seconds since the epoch.  Don't use time.UnixNano() for this,
+// the zero time overflows int64.
+func unixMicro(t time.Time) int64 {
+	return t.Unix()*1000000 + int64(t.Nanosecond()/1000)
+}
 
-	// Quake History
-	txn, err := db.Begin()
-	if err != nil {
-		return err
-	}
+var (
+	// no conflict target; the history unique constraint includes Time when the table is partitioned.
+	quakeHistoryInsert = quakeInsert(`haz.quakehistory`) + ` ON CONFLICT DO NOTHING`
+	quakeUpsert        = quakeInsert(`haz.quake`) + quakeConflict(`haz.quake`)
+	quakeAPIUpsert     = quakeInsert(`haz.quakeapi`) + quakeConflict(`haz.quakeapi`)
+)
 
-	_, err = txn.Exec(`DELETE FROM haz.quakehistory WHERE PublicID = $1 AND ModificationTimeUnixMicro = $2`, q.PublicID, mtUnixMicro)
-	if err != nil {
-		txn.Rollback()
-		return err
+func quakeInsert(table string) string {
+	p := make([]string, len(quakeColumns))
+	for i := range quakeColumns {
+		p[i] = fmt.Sprintf("$%d", i+1)
 	}
 
-	_, err = txn.Exec(`INSERT INTO haz.quakehistory(`+insert+`) VALUES( `+params+` )`, values...)
-	if err != nil {
-		txn.Rollback()
-		return err
-	}
+	return `INSERT INTO ` + table + `(` + strings.Join(quakeColumns, `, `) + `) VALUES(` + strings.Join(p, `, `) + `)`
+}
 
-	err = txn.Commit()
-	if err != nil {
-		return err
+// quakeConflict updates the existing row for the quake only if the new information is more recent.
+func quakeConflict(table string) string {
+	var s []string
+	for _, c := range quakeColumns[1:] {
+		s = append(s, c+` = excluded.`+c)
 	}
 
-	// Clean out old quake history
-	_, err = db.Exec(`DELETE FROM haz.quakehistory WHERE time < now() - interval '365 days'`)
+	return ` ON CONFLICT (PublicID) DO UPDATE SET ` + strings.Join(s, `, `) +
+		` WHERE excluded.ModificationTime > ` + table + `.ModificationTime`
+}
 
-	// Quake
-	txn, err = db.Begin()
-	if err != nil {
-		return err
-	}
+/*
+SaveQuake saves q to haz.quakehistory and, if it is more recent than the information already
+stored for the quake, haz.quake and haz.quakeapi.  Messages can be saved in any order and more
+than once; an older ModificationTime never replaces a newer one.  Duplicate quakes and quakes older
+than RetentionDefault.QuakeAPIDays are removed from haz.quakeapi.
+*/
+func (db *DB) SaveQuake(q msg.Quake) error {
+	values := quakeValues(q)
 
-	_, err = txn.Exec(`DELETE FROM haz.quake WHERE PublicID = $1 AND ModificationTime <= $2`, q.PublicID, q.ModificationTime)
+	txn, err := db.Begin()
 	if err != nil {
-		txn.Rollback()
 		return err
 	}
 
-	_, err = txn.Exec(`INSERT INTO haz.quake(`+insert+`) VALUES( `+params+` )`, values...)
-	if err != nil {
-		if err, ok := err.(*pq.Error); ok {
-			// a unique_violation means the new quake info is older than in the table already.
-			// this is not an error for this application - we want the latest information only in
-			// the quake table.
-			// http://www.postgresql.org/docs/9.3/static/errcodes-appendix.html
-			if err.Code == `23505` {
-				txn.Rollback()
-				err = nil
-			} else {
-				txn.Rollback()
-				return err
-			}
-		} else {
+	for _, v := range []string{quakeHistoryInsert, quakeUpsert, quakeAPIUpsert} {
+		if _, err = txn.Exec(v, values...); err != nil {
 			txn.Rollback()
 			return err
 		}
-	} else {
-		err = txn.Commit()
-		if err != nil {
-			return err
-		}
-	}
-
-	// Quake api
-	txn, err = db.Begin()
-	if err != nil {
-		return err
 	}
 
-	_, err = txn.Exec(`DELETE FROM haz.quakeapi WHERE PublicID = $1 AND ModificationTime <= $2`, q.PublicID, q.ModificationTime)
-	if err != nil {
-		txn.Rollback()
+	if err = txn.Commit(); err != nil {
 		return err
 	}
 
-	_, err = txn.Exec(`INSERT INTO haz.quakeapi(`+insert+`) VALUES( `+params+` )`, values...)
-	if err != nil {
-		if err, ok := err.(*pq.Error); ok {
-			// a unique_violation means the new quake info is older than in the table already.
-			// this is not an error for this application - we want the latest information only in
-			// the quake table.
-			// http://www.postgresql.org/docs/9.3/static/errcodes-appendix.html
-			if err.Code == `23505` {
-				txn.Rollback()
-				err = nil
-			} else {
-				txn.Rollback()
-				return err
-			}
-		} else {
-			txn.Rollback()
-			return err
-		}
-	} else {
-		err = txn.Commit()
-		if err != nil {
-			return err
-		}
-	}
-
-	// Clean out old quakes from quakeapi
-	_, err = db.Exec(`DELETE FROM haz.quakeapi WHERE time < now() - interval '365 days' OR status = 'duplicate'`)
+	// Clean out old quakes from quakeapi.  This runs wherever quakes are saved, not just where
+	// Retain runs, so haz.quakeapi is never more than a year.  Old history is removed by Retain.
+	_, err = db.Exec(`DELETE FROM haz.quakeapi WHERE time < now() - $1 * interval '1 day' OR status = 'duplicate'`,
+		RetentionDefault.QuakeAPIDays)
 
 	return err
 }
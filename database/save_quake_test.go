@@ -0,0 +1,185 @@
+package database
+
+import (
+	"github.com/GeoNet/haz/msg"
+	_ "github.com/lib/pq"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDB returns a connection to the test DB.  Tests that need the DB are skipped if it is not available.
+func testDB(t *testing.T) DB {
+	db, err := InitPG()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		t.Skipf("no test DB: %s", err)
+	}
+
+	return db
+}
+
+func testQuake(publicID string, t time.Time) msg.Quake {
+	return msg.Quake{
+		PublicID:              publicID,
+		Type:                  "earthquake",
+		AgencyID:              "WEL(GNS_Primary)",
+		ModificationTime:      t,
+		Time:                  t,
+		Latitude:              -41.5,
+		Longitude:             174.0,
+		Depth:                 10.0,
+		EvaluationMode:        "automatic",
+		EvaluationStatus:      "preliminary",
+		UsedPhaseCount:        25,
+		UsedStationCount:      20,
+		MagnitudeType:         "M",
+		MagnitudeStationCount: 12,
+		Magnitude:             4.0,
+		Site:                  "primary",
+	}
+}
+
+// TestSaveQuakeReplay saves shuffled (and repeated) histories for a quake and checks that
+// the latest information is always the most recent modification.
+func TestSaveQuakeReplay(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	const publicID = "2099p000001"
+
+	clean := func() {
+		for _, v := range []string{`haz.quakehistory`, `haz.quake`, `haz.quakeapi`} {
+			if _, err := db.Exec(`DELETE FROM `+v+` WHERE publicid = $1`, publicID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	clean()
+	defer clean()
+
+	mt := time.Now().UTC().Truncate(time.Microsecond)
+
+	var h []msg.Quake
+
+	for i := 0; i < 10; i++ {
+		q := testQuake(publicID, mt.Add(time.Duration(-1)*time.Hour))
+		q.ModificationTime = mt.Add(time.Duration(i) * time.Minute)
+		q.Depth = 10.0 + float64(i)
+		q.Magnitude = 4.0 + float64(i)/10.0
+		h = append(h, q)
+	}
+
+	latest := h[len(h)-1]
+
+	r := rand.New(rand.NewSource(1))
+
+	for run := 0; run < 5; run++ {
+		clean()
+
+		for _, i := range r.Perm(len(h)) {
+			if err := db.SaveQuake(h[i]); err != nil {
+				t.Fatal(err)
+			}
+
+			// saving again is a no-op.
+			if r.Intn(3) == 0 {
+				if err := db.SaveQuake(h[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		for _, v := range []string{`haz.quake`, `haz.quakeapi`} {
+			var m, d float64
+			var u int64
+
+			if err := db.QueryRow(`SELECT magnitude, depth, modificationtimeunixmicro FROM `+v+` WHERE publicid = $1`, publicID).Scan(&m, &d, &u); err != nil {
+				t.Fatalf("run %d %s: %s", run, v, err)
+			}
+
+			if m != latest.Magnitude || d != latest.Depth || u != unixMicro(latest.ModificationTime) {
+				t.Errorf("run %d %s: expected the latest modification got magnitude %.1f depth %.1f", run, v, m, d)
+			}
+		}
+
+		var n int
+
+		if err := db.QueryRow(`SELECT count(*) FROM haz.quakehistory WHERE publicid = $1`, publicID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+
+		if n != len(h) {
+			t.Errorf("run %d: expected %d history rows got %d", run, len(h), n)
+		}
+	}
+}
+
+// TestSaveQuakeOld checks a quake older than the quakeapi window is saved to haz.quake but not
+// kept in haz.quakeapi e.g., when quakes are back loaded.
+func TestSaveQuakeOld(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	const publicID = "2099p000004"
+
+	clean := func() {
+		for _, v := range []string{`haz.quakehistory`, `haz.quake`, `haz.quakeapi`} {
+			if _, err := db.Exec(`DELETE FROM `+v+` WHERE publicid = $1`, publicID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	clean()
+	defer clean()
+
+	if err := db.SaveQuake(testQuake(publicID, time.Now().UTC().AddDate(-2, 0, 0))); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []struct {
+		table    string
+		expected int
+	}{
+		{`haz.quake`, 1},
+		{`haz.quakeapi`, 0},
+	} {
+		var n int
+		if err := db.QueryRow(`SELECT count(*) FROM `+v.table+` WHERE publicid = $1`, publicID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != v.expected {
+			t.Errorf("%s: expected %d rows got %d", v.table, v.expected, n)
+		}
+	}
+}
+
+func TestQuakeColumns(t *testing.T) {
+	q := msg.Quake{PublicID: "2015p012816", Latitude: -41.5, Longitude: 174.0, Magnitude: 4.0}
+
+	if v := quakeValues(q); len(v) != len(quakeColumns) {
+		t.Errorf("expected %d values got %d", len(quakeColumns), len(v))
+	}
+
+	if quakeColumns[len(quakeColumns)-1] != `Intensity_newzealand` {
+		t.Errorf("expected region columns last got %v", quakeColumns)
+	}
+
+	// the SQL is the same every time, it doesn't depend on map iteration order.
+	for _, v := range []string{quakeHistoryInsert, quakeUpsert, quakeAPIUpsert} {
+		if !strings.Contains(v, `Intensity_newzealand) VALUES(`) {
+			t.Errorf("missing region columns: %s", v)
+		}
+	}
+
+	if quakeUpsert != quakeInsert(`haz.quake`)+quakeConflict(`haz.quake`) {
+		t.Error("upsert SQL differs from a fresh build")
+	}
+}
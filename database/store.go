@@ -0,0 +1,393 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"github.com/lib/pq"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when there is no information for a quake.
+var ErrNotFound = errors.New("not found")
+
+/*
+QuakeStore stores and queries quake, heartbeat, and intensity information.  DB implements
+it for Postgres and MemStore in memory for tests.
+*/
+type QuakeStore interface {
+	SaveQuake(msg.Quake) error
+	SaveHeartBeat(msg.HeartBeat) error
+	SaveIntensity(msg.Intensity) error
+
+	// Quake returns the latest information for the quake or ErrNotFound.
+	Quake(publicID string) (msg.Quake, error)
+	// QuakeHistory returns all versions of the quake newest first or ErrNotFound.
+	QuakeHistory(publicID string) ([]msg.Quake, error)
+	// Quakes returns the quakes matching q ordered by q.OrderBy.
+	Quakes(q QuakeQuery) ([]msg.Quake, error)
+	// HeartBeats returns the latest heart beat for each service.
+	HeartBeats() ([]msg.HeartBeat, error)
+	// Intensities returns the intensities matching q.
+	Intensities(q IntensityQuery) ([]msg.Intensity, error)
+	// CountIntensities returns the number of intensities matching q.
+	CountIntensities(q IntensityQuery) (int, error)
+}
+
+var _ QuakeStore = (*DB)(nil)
+
+/*
+QuakeQuery selects quakes.  Zero values and nil pointers don't restrict the query.
+*/
+type QuakeQuery struct {
+	PublicID      string
+	Start, End    time.Time // Start <= Time < End
+	UpdatedAfter  time.Time // ModificationTime > UpdatedAfter
+	MinMagnitude  *float64
+	MaxMagnitude  *float64
+	MagnitudeType string // case insensitive.
+	MinDepth      *float64
+	MaxDepth      *float64
+	MinMMI        int       // the MMI at the quake, 0 for all.
+	BBox          []float64 // minLon, minLat, maxLon, maxLat.  minLon > maxLon crosses the anti-meridian.
+	Radius        *Radius
+	Statuses      []string // see msg.Quake.Status.
+	OrderBy       string   // see OrderBy values.
+	Limit         int
+	Offset        int
+}
+
+// Radius selects quakes between Min and Max degrees (great circle) from Latitude, Longitude.
+type Radius struct {
+	Latitude, Longitude float64
+	Min, Max            float64
+}
+
+// OrderBy values for QuakeQuery.  Ties are ordered by PublicID.
+const (
+	OrderTime         = "time" // newest first, the default.
+	OrderTimeAsc      = "time-asc"
+	OrderMagnitude    = "magnitude" // largest first.
+	OrderMagnitudeAsc = "magnitude-asc"
+)
+
+/*
+IntensityQuery selects intensities.  Quality is 'measured' or 'reported'.  For reported
+intensities Start <= Time < End, zero values don't restrict the query.
+*/
+type IntensityQuery struct {
+	Quality    string
+	Start, End time.Time
+}
+
+func (q QuakeQuery) validate() error {
+	if q.BBox != nil && len(q.BBox) != 4 {
+		return fmt.Errorf("bbox needs 4 values got %d", len(q.BBox))
+	}
+
+	if _, ok := orderBy[q.OrderBy]; !ok {
+		return fmt.Errorf("invalid order by: %s", q.OrderBy)
+	}
+
+	if q.Limit < 0 || q.Offset < 0 {
+		return fmt.Errorf("limit and offset must be positive")
+	}
+
+	return nil
+}
+
+var orderBy = map[string]string{
+	"":                `Time DESC, PublicID`,
+	OrderTime:         `Time DESC, PublicID`,
+	OrderTimeAsc:      `Time ASC, PublicID`,
+	OrderMagnitude:    `Magnitude DESC, PublicID`,
+	OrderMagnitudeAsc: `Magnitude ASC, PublicID`,
+}
+
+// less returns true if a is before b in the order o.
+func less(o string, a, b msg.Quake) bool {
+	switch o {
+	case OrderTimeAsc:
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+	case OrderMagnitude:
+		if a.Magnitude != b.Magnitude {
+			return a.Magnitude > b.Magnitude
+		}
+	case OrderMagnitudeAsc:
+		if a.Magnitude != b.Magnitude {
+			return a.Magnitude < b.Magnitude
+		}
+	default:
+		if !a.Time.Equal(b.Time) {
+			return a.Time.After(b.Time)
+		}
+	}
+
+	return a.PublicID < b.PublicID
+}
+
+// Degrees returns the great circle distance in degrees between two points.
+func Degrees(lat1, lon1, lat2, lon2 float64) float64 {
+	r := math.Pi / 180.0
+
+	c := math.Sin(lat1*r)*math.Sin(lat2*r) + math.Cos(lat1*r)*math.Cos(lat2*r)*math.Cos((lon1-lon2)*r)
+
+	return math.Acos(math.Max(-1.0, math.Min(1.0, c))) / r
+}
+
+// degreesSQL is Degrees from the quake location to $%[1]d, $%[2]d.
+const degreesSQL = `degrees(acos(GREATEST(-1.0, LEAST(1.0, sin(radians(Latitude)) * sin(radians($%[1]d))
+	+ cos(radians(Latitude)) * cos(radians($%[1]d)) * cos(radians(Longitude - $%[2]d))))))`
+
+// match returns true if k matches q.
+func (q QuakeQuery) match(k msg.Quake) bool {
+	switch {
+	case q.PublicID != "" && k.PublicID != q.PublicID:
+		return false
+	case !q.Start.IsZero() && k.Time.Before(q.Start):
+		return false
+	case !q.End.IsZero() && !k.Time.Before(q.End):
+		return false
+	case !q.UpdatedAfter.IsZero() && !k.ModificationTime.After(q.UpdatedAfter):
+		return false
+	case q.MagnitudeType != "" && !strings.EqualFold(q.MagnitudeType, k.MagnitudeType):
+		return false
+	case q.MinMagnitude != nil && k.Magnitude < *q.MinMagnitude:
+		return false
+	case q.MaxMagnitude != nil && k.Magnitude > *q.MaxMagnitude:
+		return false
+	case q.MinDepth != nil && k.Depth < *q.MinDepth:
+		return false
+	case q.MaxDepth != nil && k.Depth > *q.MaxDepth:
+		return false
+	case q.MinMMI > 0 && int(k.MMI()) < q.MinMMI:
+		return false
+	case q.BBox != nil && (k.Latitude < q.BBox[1] || k.Latitude > q.BBox[3]):
+		return false
+	case q.BBox != nil && q.BBox[0] <= q.BBox[2] && (k.Longitude < q.BBox[0] || k.Longitude > q.BBox[2]):
+		return false
+	case q.BBox != nil && q.BBox[0] > q.BBox[2] && (k.Longitude < q.BBox[0] && k.Longitude > q.BBox[2]):
+		return false
+	case q.Radius != nil:
+		d := Degrees(q.Radius.Latitude, q.Radius.Longitude, k.Latitude, k.Longitude)
+		if d < q.Radius.Min || d > q.Radius.Max {
+			return false
+		}
+	}
+
+	if q.Statuses != nil {
+		s := k.Status()
+		for _, v := range q.Statuses {
+			if v == s {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// where returns the SQL where clause and args for q.
+func (q QuakeQuery) where() (string, []interface{}) {
+	var w []string
+	var a []interface{}
+
+	add := func(c string, v interface{}) {
+		a = append(a, v)
+		w = append(w, fmt.Sprintf(c, len(a)))
+	}
+
+	if q.PublicID != "" {
+		add(`PublicID = $%d`, q.PublicID)
+	}
+	if !q.Start.IsZero() {
+		add(`Time >= $%d`, q.Start)
+	}
+	if !q.End.IsZero() {
+		add(`Time < $%d`, q.End)
+	}
+	if !q.UpdatedAfter.IsZero() {
+		add(`ModificationTime > $%d`, q.UpdatedAfter)
+	}
+	if q.MagnitudeType != "" {
+		add(`lower(MagnitudeType) = lower($%d)`, q.MagnitudeType)
+	}
+	if q.MinMagnitude != nil {
+		add(`Magnitude >= $%d`, *q.MinMagnitude)
+	}
+	if q.MaxMagnitude != nil {
+		add(`Magnitude <= $%d`, *q.MaxMagnitude)
+	}
+	if q.MinDepth != nil {
+		add(`Depth >= $%d`, *q.MinDepth)
+	}
+	if q.MaxDepth != nil {
+		add(`Depth <= $%d`, *q.MaxDepth)
+	}
+	if q.MinMMI > 0 {
+		add(`MMI >= $%d`, q.MinMMI)
+	}
+	if q.BBox != nil {
+		add(`Latitude >= $%d`, q.BBox[1])
+		add(`Latitude <= $%d`, q.BBox[3])
+
+		a = append(a, q.BBox[0], q.BBox[2])
+		op := `AND`
+		if q.BBox[0] > q.BBox[2] {
+			op = `OR`
+		}
+		w = append(w, fmt.Sprintf(`(Longitude >= $%d %s Longitude <= $%d)`, len(a)-1, op, len(a)))
+	}
+	if q.Radius != nil {
+		a = append(a, q.Radius.Latitude, q.Radius.Longitude)
+		d := fmt.Sprintf(degreesSQL, len(a)-1, len(a))
+		add(d+` >= $%d`, q.Radius.Min)
+		add(d+` <= $%d`, q.Radius.Max)
+	}
+	if q.Statuses != nil {
+		add(`Status = ANY($%d)`, pq.Array(q.Statuses))
+	}
+
+	if len(w) == 0 {
+		return "", nil
+	}
+
+	return ` WHERE ` + strings.Join(w, ` AND `), a
+}
+
+// QuakeColumns are the haz.quake columns, in order, read by ScanQuake.
+var QuakeColumns = strings.Join(quakeColumns[:23], `, `)
+
+// quakeSelect is the columns for ScanQuake.
+var quakeSelect = `SELECT ` + QuakeColumns
+
+// Scanner is a *sql.Row or *sql.Rows.
+type Scanner interface {
+	Scan(...interface{}) error
+}
+
+// ScanQuake reads a quake from a row of QuakeColumns.
+func ScanQuake(s Scanner) (msg.Quake, error) {
+	var q msg.Quake
+
+	err := s.Scan(
+		&q.PublicID,
+		&q.Type,
+		&q.AgencyID,
+		&q.ModificationTime,
+		&q.Time,
+		&q.Longitude,
+		&q.Latitude,
+		&q.Depth,
+		&q.DepthType,
+		&q.MethodID,
+		&q.EarthModelID,
+		&q.EvaluationMode,
+		&q.EvaluationStatus,
+		&q.UsedPhaseCount,
+		&q.UsedStationCount,
+		&q.StandardError,
+		&q.AzimuthalGap,
+		&q.MinimumDistance,
+		&q.Magnitude,
+		&q.MagnitudeUncertainty,
+		&q.MagnitudeType,
+		&q.MagnitudeStationCount,
+		&q.Site,
+	)
+
+	q.ModificationTime = q.ModificationTime.UTC()
+	q.Time = q.Time.UTC()
+
+	return q, err
+}
+
+func scanQuakes(rows *sql.Rows) ([]msg.Quake, error) {
+	defer rows.Close()
+
+	var q []msg.Quake
+
+	for rows.Next() {
+		k, err := ScanQuake(rows)
+		if err != nil {
+			return nil, err
+		}
+		q = append(q, k)
+	}
+
+	return q, rows.Err()
+}
+
+func (db *DB) Quake(publicID string) (msg.Quake, error) {
+	q, err := ScanQuake(db.QueryRow(quakeSelect+` FROM haz.quake WHERE PublicID = $1`, publicID))
+	if err == sql.ErrNoRows {
+		return q, ErrNotFound
+	}
+
+	return q, err
+}
+
+func (db *DB) QuakeHistory(publicID string) ([]msg.Quake, error) {
+	rows, err := db.Query(quakeSelect+` FROM haz.quakehistory WHERE PublicID = $1
+		ORDER BY ModificationTimeUnixMicro DESC`, publicID)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := scanQuakes(rows)
+	if err == nil && len(q) == 0 {
+		err = ErrNotFound
+	}
+
+	return q, err
+}
+
+func (db *DB) Quakes(q QuakeQuery) ([]msg.Quake, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+
+	w, a := q.where()
+
+	s := quakeSelect + ` FROM haz.quake` + w + ` ORDER BY ` + orderBy[q.OrderBy]
+	if q.Limit > 0 {
+		s += fmt.Sprintf(` LIMIT %d`, q.Limit)
+	}
+	if q.Offset > 0 {
+		s += fmt.Sprintf(` OFFSET %d`, q.Offset)
+	}
+
+	rows, err := db.Query(s, a...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanQuakes(rows)
+}
+
+func (db *DB) HeartBeats() ([]msg.HeartBeat, error) {
+	rows, err := db.Query(`SELECT serverID, timeReceived FROM haz.soh ORDER BY serverID`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var h []msg.HeartBeat
+
+	for rows.Next() {
+		var b msg.HeartBeat
+		if err = rows.Scan(&b.ServiceID, &b.SentTime); err != nil {
+			return nil, err
+		}
+		b.SentTime = b.SentTime.UTC()
+		h = append(h, b)
+	}
+
+	return h, rows.Err()
+}
@@ -0,0 +1,234 @@
+package database
+
+import (
+	"github.com/GeoNet/haz/msg"
+	"testing"
+	"time"
+)
+
+func TestMemStore(t *testing.T) {
+	testStore(t, NewMemStore())
+}
+
+func TestDBStore(t *testing.T) {
+	db := testDB(t)
+	defer db.Close()
+
+	clean := func() {
+		for _, v := range []string{`haz.quakehistory`, `haz.quake`, `haz.quakeapi`} {
+			if _, err := db.Exec(`DELETE FROM ` + v + ` WHERE publicid LIKE '2099p1%'`); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := db.Exec(`DELETE FROM haz.soh WHERE serverid LIKE 'test.store%'`); err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range []string{`impact.intensity_measured`, `impact.intensity_reported`} {
+			if _, err := db.Exec(`DELETE FROM ` + v + ` WHERE source LIKE 'test.store%'`); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	clean()
+	defer clean()
+
+	testStore(t, &db)
+}
+
+// testStore checks the behaviour of a QuakeStore.  Quakes are in 2099 to avoid other data in the DB.
+func testStore(t *testing.T, s QuakeStore) {
+	t0 := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	q := testQuake("2099p100001", t0)
+	q1 := q
+	q1.ModificationTime = t0.Add(time.Minute)
+	q1.Magnitude = 5.0
+
+	// out of order and repeated.
+	for _, v := range []msg.Quake{q1, q, q1} {
+		if err := s.SaveQuake(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := testQuake("2099p100002", t0.Add(time.Hour))
+	r.Depth = 100
+	r.Magnitude = 3.0
+	r.Longitude = 178.0
+	if err := s.SaveQuake(r); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := s.Quake("2099p100001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.ModificationTime.Equal(q1.ModificationTime) || k.Magnitude != 5.0 || k.PublicID != q.PublicID {
+		t.Errorf("expected the latest information got %+v", k)
+	}
+
+	if _, err = s.Quake("2099p100099"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound got %v", err)
+	}
+
+	h, err := s.QuakeHistory("2099p100001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 || !h[0].ModificationTime.Equal(q1.ModificationTime) || !h[1].ModificationTime.Equal(q.ModificationTime) {
+		t.Errorf("expected 2 history versions newest first got %+v", h)
+	}
+
+	if _, err = s.QuakeHistory("2099p100099"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound got %v", err)
+	}
+
+	f := func(v float64) *float64 { return &v }
+
+	in := []struct {
+		id  string
+		q   QuakeQuery
+		ids []string
+	}{
+		{id: "all", q: QuakeQuery{Start: t0}, ids: []string{"2099p100002", "2099p100001"}},
+		{id: "limit", q: QuakeQuery{Start: t0, Limit: 1}, ids: []string{"2099p100002"}},
+		{id: "end", q: QuakeQuery{Start: t0, End: t0.Add(time.Hour)}, ids: []string{"2099p100001"}},
+		{id: "mag", q: QuakeQuery{Start: t0, MinMagnitude: f(4.5)}, ids: []string{"2099p100001"}},
+		{id: "max mag", q: QuakeQuery{Start: t0, MaxMagnitude: f(3.5)}, ids: []string{"2099p100002"}},
+		{id: "depth", q: QuakeQuery{Start: t0, MinDepth: f(50)}, ids: []string{"2099p100002"}},
+		{id: "max depth", q: QuakeQuery{Start: t0, MaxDepth: f(50)}, ids: []string{"2099p100001"}},
+		{id: "bbox", q: QuakeQuery{Start: t0, BBox: []float64{177, -42, 179, -41}}, ids: []string{"2099p100002"}},
+		{id: "none", q: QuakeQuery{Start: t0, MinMagnitude: f(9)}},
+		{id: "public id", q: QuakeQuery{PublicID: "2099p100002"}, ids: []string{"2099p100002"}},
+		{id: "updated after", q: QuakeQuery{Start: t0, UpdatedAfter: t0.Add(30 * time.Second)}, ids: []string{"2099p100002", "2099p100001"}},
+		{id: "updated after none", q: QuakeQuery{Start: t0, UpdatedAfter: t0.Add(2 * time.Hour)}},
+		{id: "mag type", q: QuakeQuery{Start: t0, MagnitudeType: "m"}, ids: []string{"2099p100002", "2099p100001"}},
+		{id: "mag type none", q: QuakeQuery{Start: t0, MagnitudeType: "ML"}},
+		{id: "time asc", q: QuakeQuery{Start: t0, OrderBy: OrderTimeAsc}, ids: []string{"2099p100001", "2099p100002"}},
+		{id: "magnitude", q: QuakeQuery{Start: t0, OrderBy: OrderMagnitude}, ids: []string{"2099p100001", "2099p100002"}},
+		{id: "magnitude asc", q: QuakeQuery{Start: t0, OrderBy: OrderMagnitudeAsc}, ids: []string{"2099p100002", "2099p100001"}},
+		{id: "offset", q: QuakeQuery{Start: t0, Offset: 1}, ids: []string{"2099p100001"}},
+		{id: "offset past", q: QuakeQuery{Start: t0, Offset: 2}},
+		{id: "bbox anti-meridian", q: QuakeQuery{Start: t0, BBox: []float64{175, -42, -175, -41}}, ids: []string{"2099p100002"}},
+		{id: "radius", q: QuakeQuery{Start: t0, Radius: &Radius{Latitude: -41.5, Longitude: 174.0, Max: 1}}, ids: []string{"2099p100001"}},
+		{id: "radius ring", q: QuakeQuery{Start: t0, Radius: &Radius{Latitude: -41.5, Longitude: 174.0, Min: 1, Max: 5}}, ids: []string{"2099p100002"}},
+		{id: "statuses", q: QuakeQuery{Start: t0, Statuses: []string{"automatic"}}, ids: []string{"2099p100002", "2099p100001"}},
+		{id: "statuses none", q: QuakeQuery{Start: t0, Statuses: []string{"reviewed"}}},
+	}
+
+	for _, v := range in {
+		k, err := s.Quakes(v.q)
+		if err != nil {
+			t.Errorf("%s: %s", v.id, err)
+			continue
+		}
+
+		if len(k) != len(v.ids) {
+			t.Errorf("%s: expected %d quakes got %d", v.id, len(v.ids), len(k))
+			continue
+		}
+
+		for i := range k {
+			if k[i].PublicID != v.ids[i] {
+				t.Errorf("%s: expected %s got %s", v.id, v.ids[i], k[i].PublicID)
+			}
+		}
+	}
+
+	if _, err = s.Quakes(QuakeQuery{BBox: []float64{1}}); err == nil {
+		t.Error("expected error for short bbox")
+	}
+
+	if _, err = s.Quakes(QuakeQuery{OrderBy: "depth"}); err == nil {
+		t.Error("expected error for unknown order")
+	}
+
+	for _, v := range []msg.HeartBeat{
+		{ServiceID: "test.store.b", SentTime: t0},
+		{ServiceID: "test.store.a", SentTime: t0},
+		{ServiceID: "test.store.a", SentTime: t0.Add(time.Minute)},
+	} {
+		if err = s.SaveHeartBeat(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hb, err := s.HeartBeats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var a msg.HeartBeat
+	var n int
+	for _, v := range hb {
+		if v.ServiceID == "test.store.a" {
+			a = v
+		}
+		if v.ServiceID == "test.store.a" || v.ServiceID == "test.store.b" {
+			n++
+		}
+	}
+	if n != 2 || !a.SentTime.Equal(t0.Add(time.Minute)) {
+		t.Errorf("expected the latest heart beat for 2 services got %+v", hb)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, v := range []msg.Intensity{
+		{Source: "test.store.m", Quality: "measured", MMI: 4, Latitude: -41, Longitude: 174, Time: now},
+		{Source: "test.store.m", Quality: "measured", MMI: 3, Latitude: -41, Longitude: 174, Time: now.Add(time.Second)},
+		{Source: "test.store.r", Quality: "reported", MMI: 5, Latitude: -41, Longitude: 174, Time: now, Comment: "shaky",
+			Questionnaire: &msg.Questionnaire{Situation: "inside", Shaking: "moderate"}},
+		{Source: "test.store.r", Quality: "reported", MMI: 6, Latitude: -41, Longitude: 174, Time: now.Add(time.Hour)},
+	} {
+		if err = s.SaveIntensity(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = s.SaveIntensity(msg.Intensity{Quality: "guessed"}); err == nil {
+		t.Error("expected error for unknown quality")
+	}
+
+	m, err := s.Intensities(IntensityQuery{Quality: "measured"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, v := range m {
+		if v.Source == "test.store.m" {
+			found = true
+			if v.MMI != 4 || !v.Time.Equal(now) {
+				t.Errorf("expected the highest measured MMI got %+v", v)
+			}
+		}
+	}
+	if !found {
+		t.Error("no measured intensity")
+	}
+
+	if n, err := s.CountIntensities(IntensityQuery{Quality: "measured"}); err != nil || n != len(m) {
+		t.Errorf("expected %d measured intensities got %d %v", len(m), n, err)
+	}
+
+	rep, err := s.Intensities(IntensityQuery{Quality: "reported", Start: now.Add(-time.Minute), End: now.Add(time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found = false
+	for _, v := range rep {
+		if v.Source == "test.store.r" {
+			if found || v.MMI != 5 || v.Comment != "shaky" || v.Latitude != -41 || v.Longitude != 174 ||
+				v.Questionnaire == nil || *v.Questionnaire != (msg.Questionnaire{Situation: "inside", Shaking: "moderate"}) {
+				t.Errorf("unexpected reported intensity %+v", v)
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Error("no reported intensity in the window")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"github.com/GeoNet/weft"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Version negotiation.  The version of a query is selected by the media type and its version parameter
+in the Accept header e.g., application/vnd.geo+json;version=2.  The Accept header is parsed (RFC 7231)
+and the most preferred version that has a route for the request path is served.  Media types without
+a version, and wildcards, get the latest version.  If the path only has routes for versions that are not
+acceptable the response is 406.
+
+The quake formats, QuakeML (application/xml) and CSV (text/csv), are negotiated the same way for
+the routes that serve them.  For other routes they are the latest version e.g., application/xml for the
+CAP feed.
+
+The format query parameter overrides Accept for clients that can't set headers e.g., a browser.
+*/
+
+// mediaTypes are the versioned media types keyed by type and version.
+var mediaTypes = map[string]map[string]string{
+	"application/vnd.geo+json": {"1": V1GeoJSON, "2": V2GeoJSON},
+	"application/json":         {"1": V1JSON, "2": V2JSON},
+}
+
+// formats are the values for the format query parameter that select a version.  Other values
+// of format are left for the handlers e.g., format=ascii for shaking.
+var formats = map[string]string{
+	"geojson.v1": V1GeoJSON,
+	"geojson.v2": V2GeoJSON,
+	"json.v1":    V1JSON,
+	"json.v2":    V2JSON,
+	"protobuf":   protobuf,
+	"quakeml":    QuakeML,
+	"csv":        CSV,
+}
+
+// quakeFormats are the media types for quakes that are also served by unversioned routes.
+var quakeFormats = map[string]bool{
+	QuakeML: true,
+	CSV:     true,
+}
+
+var statusNotAcceptable = weft.Result{
+	Code: http.StatusNotAcceptable,
+	Msg:  "Not Acceptable.  Supported versions are: " + strings.Join([]string{V1GeoJSON, V2GeoJSON, V1JSON, V2JSON, protobuf}, ", "),
+}
+
+type acceptRange struct {
+	accept   string  // the versioned media type or quake format, "" for the latest version.
+	q        float64 // the quality value.
+	specific int     // 2 for a versioned media type, 1 for a media type, 0 for a wildcard.
+}
+
+/*
+parseAccept returns the acceptable ranges in the Accept header s, most preferred first.  Badly
+formed ranges are ignored.  none is true if s has ranges but none of them are acceptable
+e.g., a version that isn't served.
+*/
+func parseAccept(s string) (ranges []acceptRange, none bool) {
+	for _, v := range strings.Split(s, ",") {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+
+		t, params, err := mime.ParseMediaType(v)
+		if err != nil {
+			continue
+		}
+
+		none = true
+
+		a := acceptRange{q: 1.0, specific: 1}
+
+		if p, ok := params["q"]; ok {
+			if a.q, err = strconv.ParseFloat(p, 64); err != nil || a.q < 0 || a.q > 1 {
+				continue
+			}
+		}
+
+		if a.q == 0 {
+			continue
+		}
+
+		switch {
+		case t == protobuf, quakeFormats[t]:
+			a.accept = t
+			a.specific = 2
+		case mediaTypes[t] != nil && params["version"] != "":
+			if a.accept = mediaTypes[t][params["version"]]; a.accept == "" {
+				continue
+			}
+			a.specific = 2
+		case strings.Contains(t, "*"):
+			a.specific = 0
+		}
+
+		ranges = append(ranges, a)
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specific > ranges[j].specific
+	})
+
+	return ranges, none && len(ranges) == 0
+}
+
+/*
+negotiate returns the versioned media type to serve r with ("" for the latest version) and the mux
+for it.  ok is false if the path has routes but not for an acceptable version.
+*/
+func negotiate(r *http.Request) (accept string, mux *http.ServeMux, ok bool) {
+	var ranges []acceptRange
+	var none bool
+
+	if f, found := formats[r.URL.Query().Get("format")]; found {
+		ranges = []acceptRange{{accept: f}}
+	} else {
+		ranges, none = parseAccept(strings.Join(r.Header.Values("Accept"), ","))
+	}
+
+	// no Accept header is the same as */*.
+	if len(ranges) == 0 && !none {
+		ranges = []acceptRange{{}}
+	}
+
+	for _, a := range ranges {
+		m := muxFor(a.accept)
+
+		// a quake format for another route is the latest version e.g., application/xml for the CAP feed.
+		if quakeFormats[a.accept] && !quakeRoute(r) {
+			a.accept, m = "", muxDefault
+		}
+
+		if routed(m, r) {
+			return a.accept, m, true
+		}
+	}
+
+	if none {
+		return "", nil, false
+	}
+
+	for _, m := range []*http.ServeMux{muxV1GeoJSON, muxV1JSON, muxV2GeoJSON, muxV2JSON, muxProto, muxQuakeML, muxCSV, muxDefault} {
+		if routed(m, r) {
+			return "", nil, false
+		}
+	}
+
+	// not routed for any version.  The docs handler serves the error.
+	return ranges[0].accept, muxFor(ranges[0].accept), true
+}
+
+/*
+quakeRoute returns true if r is for /quake or /quake/{publicID}, the routes served by the quake formats.
+Other routes under /quake/ e.g., /quake/stats, /quake/technical/{publicID}, and /quake/{publicID}/shaking,
+have their own formats.
+*/
+func quakeRoute(r *http.Request) bool {
+	_, p := muxDefault.Handler(r)
+	return p == "/quake" || (p == "/quake/" && !strings.HasSuffix(r.URL.Path, shakingSuffix) &&
+		!strings.HasPrefix(r.URL.Path, technicalPrefix))
+}
+
+// routed returns true if m has a route for r other than the docs.
+func routed(m *http.ServeMux, r *http.Request) bool {
+	_, p := m.Handler(r)
+	return p != "/" || r.URL.Path == "/"
+}
+
+/*
+withVersion returns r to be served as accept.  The Accept header is replaced so the handlers, the
+response cache, and ETags see the version being served.  A format parameter used for the version is removed.
+*/
+func withVersion(r *http.Request, accept string) *http.Request {
+	_, override := formats[r.URL.Query().Get("format")]
+
+	if accept == "" && !override {
+		return r
+	}
+
+	r = r.Clone(r.Context())
+
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+
+	if override {
+		q := r.URL.Query()
+		q.Del("format")
+		r.URL.RawQuery = q.Encode()
+	}
+
+	return r
+}
+
+func notAcceptable(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	return &statusNotAcceptable
+}
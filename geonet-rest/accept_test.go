@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseAccept(t *testing.T) {
+	in := []struct {
+		id, accept string
+		best       string
+		n          int
+		none       bool
+	}{
+		{id: "empty"},
+		{id: "exact", accept: V2GeoJSON, best: V2GeoJSON, n: 1},
+		{id: "space", accept: "application/vnd.geo+json; version=1", best: V1GeoJSON, n: 1},
+		{id: "case", accept: "Application/JSON;Version=2", best: V2JSON, n: 1},
+		{id: "wildcard", accept: "application/vnd.geo+json;version=2, */*;q=0.1", best: V2GeoJSON, n: 2},
+		{id: "q order", accept: "*/*;q=0.1, application/json;version=1;q=0.5, application/x-protobuf", best: protobuf, n: 3},
+		{id: "specific first", accept: "*/*, application/vnd.geo+json;version=1", best: V1GeoJSON, n: 2},
+		{id: "no version", accept: "application/vnd.geo+json", n: 1},
+		{id: "browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", n: 4},
+		{id: "unsupported", accept: "application/vnd.geo+json;version=3", none: true},
+		{id: "unsupported fallback", accept: "application/vnd.geo+json;version=3, */*;q=0.1", n: 1},
+		{id: "q zero", accept: "application/x-protobuf;q=0", none: true},
+		{id: "bad q", accept: "application/x-protobuf;q=2, " + V2JSON, best: V2JSON, n: 1},
+		{id: "malformed", accept: "application/x-protobuf;;;=, " + V2JSON, best: V2JSON, n: 1},
+		{id: "quakeml", accept: "application/xml", best: QuakeML, n: 1},
+		{id: "csv", accept: "text/csv;charset=utf-8", best: CSV, n: 1},
+	}
+
+	for _, v := range in {
+		ranges, none := parseAccept(v.accept)
+
+		if len(ranges) != v.n || none != v.none {
+			t.Errorf("%s: expected %d ranges (none %t) got %d (none %t)", v.id, v.n, v.none, len(ranges), none)
+			continue
+		}
+
+		if v.n > 0 && ranges[0].accept != v.best {
+			t.Errorf("%s: expected %q first got %q", v.id, v.best, ranges[0].accept)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	in := []struct {
+		id, url, accept string
+		served          string
+		mux             *http.ServeMux
+		ok              bool
+	}{
+		{id: "latest", url: "/quake/2013p407387", mux: muxDefault, ok: true},
+		{id: "versioned", url: "/quake/2013p407387", accept: V1GeoJSON, served: V1GeoJSON, mux: muxV1GeoJSON, ok: true},
+		{id: "q", url: "/quake/2013p407387", accept: "application/vnd.geo+json; version=1, */*;q=0.1", served: V1GeoJSON, mux: muxV1GeoJSON, ok: true},
+		{id: "fallback", url: "/volcano/val", accept: "application/vnd.geo+json;version=1, */*;q=0.1", mux: muxDefault, ok: true},
+		{id: "only v1", url: "/felt/report?publicID=2013p407387", accept: "application/vnd.geo+json;version=2;q=0.9, application/vnd.geo+json;version=1;q=0.5", served: V1GeoJSON, mux: muxV1GeoJSON, ok: true},
+		{id: "unsupported", url: "/quake/2013p407387", accept: "application/vnd.geo+json;version=3"},
+		{id: "no route for version", url: "/volcano/val", accept: V1GeoJSON},
+		{id: "no route", url: "/fred", accept: V1GeoJSON, served: V1GeoJSON, mux: muxV1GeoJSON, ok: true},
+		{id: "docs", url: "/", accept: protobuf, served: protobuf, mux: muxProto, ok: true},
+		{id: "format", url: "/quake/2013p407387?format=geojson.v1", accept: protobuf, served: V1GeoJSON, mux: muxV1GeoJSON, ok: true},
+		{id: "format protobuf", url: "/quake/stream?MMI=3&format=protobuf", served: protobuf, mux: muxProto, ok: true},
+		{id: "format no route", url: "/volcano/val?format=json.v1"},
+		{id: "handler format", url: "/quake/2013p407387/shaking?format=ascii", mux: muxDefault, ok: true},
+		{id: "quakeml", url: "/quake/2013p407387", accept: "application/xml", served: QuakeML, mux: muxQuakeML, ok: true},
+		{id: "csv", url: "/quake?MMI=3", accept: "text/csv", served: CSV, mux: muxCSV, ok: true},
+		{id: "csv preferred", url: "/quake?MMI=3", accept: V2GeoJSON + ";q=0.5, text/csv", served: CSV, mux: muxCSV, ok: true},
+		{id: "format csv", url: "/quake/2013p407387?format=csv", accept: V2GeoJSON, served: CSV, mux: muxCSV, ok: true},
+		{id: "format quakeml", url: "/quake?MMI=3&format=quakeml", served: QuakeML, mux: muxQuakeML, ok: true},
+		{id: "browser quake", url: "/quake/2013p407387", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", mux: muxDefault, ok: true},
+		{id: "xml cap feed", url: "/cap/1.2/GPA1.0/feed/atom1.0/quake", accept: "application/xml", mux: muxDefault, ok: true},
+		{id: "xml stats", url: "/quake/stats", accept: "application/xml", mux: muxDefault, ok: true},
+		{id: "xml history", url: "/quake/history/2013p407387", accept: "application/xml", mux: muxDefault, ok: true},
+		{id: "xml shaking", url: "/quake/2013p407387/shaking", accept: "application/xml", mux: muxDefault, ok: true},
+		{id: "xml technical", url: "/quake/technical/2013p407387", accept: "application/xml", mux: muxDefault, ok: true},
+		{id: "csv technical", url: "/quake/technical/2013p407387", accept: "text/csv", mux: muxDefault, ok: true},
+		{id: "technical", url: "/quake/technical/2013p407387", accept: protobuf, served: protobuf, mux: muxProto, ok: true},
+		{id: "csv no route", url: "/volcano/val", accept: "text/csv", mux: muxDefault, ok: true},
+	}
+
+	for _, v := range in {
+		r := httptest.NewRequest("GET", v.url, nil)
+		if v.accept != "" {
+			r.Header.Set("Accept", v.accept)
+		}
+
+		served, mux, ok := negotiate(r)
+
+		if served != v.served || mux != v.mux || ok != v.ok {
+			t.Errorf("%s: expected %q %t got %q %t", v.id, v.served, v.ok, served, ok)
+		}
+	}
+}
+
+func TestWithVersion(t *testing.T) {
+	r := httptest.NewRequest("GET", "/quake?MMI=3&format=geojson.v2&limit=10", nil)
+	r.Header.Set("Accept", "text/html")
+
+	v := withVersion(r, V2GeoJSON)
+
+	if v.Header.Get("Accept") != V2GeoJSON || v.URL.Query().Get("format") != "" || v.URL.Query().Get("limit") != "10" {
+		t.Errorf("unexpected request %s %s", v.Header.Get("Accept"), v.URL)
+	}
+
+	if r.Header.Get("Accept") != "text/html" || r.URL.Query().Get("format") != "geojson.v2" {
+		t.Error("the original request was changed")
+	}
+
+	r = httptest.NewRequest("GET", "/quake/2013p407387/shaking?format=ascii", nil)
+
+	if v = withVersion(r, ""); v != r {
+		t.Error("expected the request unchanged")
+	}
+}
+
+func TestNotAcceptable(t *testing.T) {
+	s := httptest.NewServer(handler())
+	defer s.Close()
+
+	for _, v := range []struct{ url, accept string }{
+		{url: "/quake/2013p407387", accept: "application/vnd.geo+json;version=3"},
+		{url: "/volcano/val", accept: V1GeoJSON},
+		{url: "/volcano/val?format=json.v1"},
+	} {
+		req, err := http.NewRequest("GET", s.URL+v.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", v.accept)
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.StatusCode != http.StatusNotAcceptable || res.Header.Get("Content-Type") != ErrContent || !strings.Contains(string(b), V2GeoJSON) {
+			t.Errorf("%s %s: unexpected response %d %s %s", v.accept, v.url, res.StatusCode, res.Header.Get("Content-Type"), b)
+		}
+	}
+}
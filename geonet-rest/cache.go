@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"github.com/GeoNet/weft"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+An in-process cache of responses for the hot endpoints that run expensive queries.
+
+Responses are cached by route, Accept, and query for the max-age in their Surrogate-Control header
+(weft's default of 10s if it isn't set).  The cache is bounded in size and the least recently used
+responses are evicted first.  Concurrent misses for the same key are coalesced so only one of them
+runs the handler.  All responses are invalidated when haz.quake changes, see listenQuakes.
+
+Only 200 responses are cached.  Conditional GET (conditional.go) is evaluated against the cached
+ETag and Last-Modified.
+*/
+
+// cacheMaxBytes is the default size of the response cache.
+const cacheMaxBytes = 64 << 20
+
+// responses is the cache shared by the handlers wrapped with cached.
+var responses = newResponseCache(cacheMaxBytes)
+
+// cacheHeaders are the response headers that are cached.
+var cacheHeaders = []string{"Content-Type", "Surrogate-Control", "ETag", "Last-Modified", "Link"}
+
+type responseCache struct {
+	sync.Mutex
+	maxBytes int
+	size     int
+	gen      uint64 // incremented by invalidate.
+	lru      *list.List
+	entries  map[string]*list.Element
+	inflight map[string]*cacheCall
+}
+
+type cacheEntry struct {
+	key     string
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// cacheCall is a handler call that concurrent misses for the same key wait for.
+type cacheCall struct {
+	done   chan struct{}
+	header http.Header
+	body   []byte
+	res    *weft.Result
+}
+
+func newResponseCache(maxBytes int) *responseCache {
+	return &responseCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		inflight: make(map[string]*cacheCall),
+	}
+}
+
+// cached wraps f with the response cache.
+func cached(f weft.RequestHandler) weft.RequestHandler {
+	return responses.handler(f)
+}
+
+func (c *responseCache) handler(f weft.RequestHandler) weft.RequestHandler {
+	return func(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+		header, body, res := c.get(cacheKey(r), r, f)
+
+		for k, v := range header {
+			h[k] = v
+		}
+
+		if !res.Ok || res.Code != http.StatusOK {
+			return res
+		}
+
+		if e := header.Get("ETag"); e != "" {
+			mt, _ := http.ParseTime(header.Get("Last-Modified"))
+			if notModified(r, h, version{etag: e, modified: mt}) {
+				return &statusNotModified
+			}
+		}
+
+		b.Write(body)
+		return res
+	}
+}
+
+/*
+cacheKey returns the key for r.  The query is encoded in a sorted order.  Whether or not the
+client accepts gzip is part of the key because it changes the ETag.
+*/
+func cacheKey(r *http.Request) string {
+	return r.URL.Path + "?" + r.URL.Query().Encode() + "\x00" + r.Header.Get("Accept") + "\x00" +
+		strconv.FormatBool(strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"))
+}
+
+/*
+get returns the cached response for key or calls f to make it.  The returned header and body
+must not be modified.  If f panics the panic is recovered and every caller waiting for the
+response gets an error.
+*/
+func (c *responseCache) get(key string, r *http.Request, f weft.RequestHandler) (header http.Header, body []byte, res *weft.Result) {
+	c.Lock()
+
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		if time.Now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.Unlock()
+			return e.header, e.body, &weft.StatusOK
+		}
+		c.remove(el)
+	}
+
+	if call, ok := c.inflight[key]; ok {
+		c.Unlock()
+		<-call.done
+		return call.header, call.body, call.res
+	}
+
+	call := &cacheCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	gen := c.gen
+	c.Unlock()
+
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("ERROR: problem making the response for %s: panic: %v\n%s", r.URL.Path, p, debug.Stack())
+			call.header = make(http.Header)
+			call.body = nil
+			call.res = weft.InternalServerError(fmt.Errorf("panic making the response for %s: %v", r.URL.Path, p))
+			header, body, res = call.header, call.body, call.res
+		}
+
+		c.Lock()
+		delete(c.inflight, key)
+		c.Unlock()
+		close(call.done)
+	}()
+
+	// the cached response is unconditional.
+	u := r.Clone(r.Context())
+	u.Header.Del("If-None-Match")
+	u.Header.Del("If-Modified-Since")
+
+	h := make(http.Header)
+	b := new(bytes.Buffer)
+
+	call.res = f(u, h, b)
+	call.header = make(http.Header)
+	call.body = b.Bytes()
+
+	for _, k := range cacheHeaders {
+		if v := h.Values(k); len(v) > 0 {
+			call.header[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+
+	if call.res.Ok && call.res.Code == http.StatusOK {
+		c.add(gen, &cacheEntry{key: key, header: call.header, body: call.body, expires: time.Now().Add(maxAge(h))})
+	}
+
+	return call.header, call.body, call.res
+}
+
+// add adds e unless the cache has been invalidated since gen or e is too big.
+func (c *responseCache) add(gen uint64, e *cacheEntry) {
+	n := len(e.key) + len(e.body)
+
+	c.Lock()
+	defer c.Unlock()
+
+	if gen != c.gen || n > c.maxBytes {
+		return
+	}
+
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size += n
+
+	for c.size > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove removes el from the cache.  c must be locked.
+func (c *responseCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, e.key)
+	c.size -= len(e.key) + len(e.body)
+}
+
+// invalidate removes all the cached responses.  Calls in flight won't be cached.
+func (c *responseCache) invalidate() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.gen++
+	c.size = 0
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+func (c *responseCache) len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.lru.Len()
+}
+
+// maxAge returns the max-age from the Surrogate-Control header in h.  weft uses 10s if it isn't set.
+func maxAge(h http.Header) time.Duration {
+	for _, s := range strings.Split(h.Get("Surrogate-Control"), ",") {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "max-age=") {
+			if n, err := strconv.Atoi(strings.TrimPrefix(s, "max-age=")); err == nil {
+				return time.Duration(n) * time.Second
+			}
+		}
+	}
+
+	return time.Duration(10) * time.Second
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/GeoNet/weft"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countHandler returns a handler that counts calls and serves s with Surrogate-Control sc.
+func countHandler(n *int32, s, sc string) weft.RequestHandler {
+	return func(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+		atomic.AddInt32(n, 1)
+		b.WriteString(s)
+		h.Set("Content-Type", V2JSON)
+		h.Set("Surrogate-Control", sc)
+		return contentResult(r, h, b, time.Time{})
+	}
+}
+
+func cacheGet(f weft.RequestHandler, u, accept string, header ...string) (*weft.Result, http.Header, string) {
+	r := httptest.NewRequest("GET", u, nil)
+	r.Header.Set("Accept", accept)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+
+	h := make(http.Header)
+	b := new(bytes.Buffer)
+
+	res := f(r, h, b)
+
+	return res, h, b.String()
+}
+
+func TestCache(t *testing.T) {
+	var n int32
+	c := newResponseCache(1 << 20)
+	f := c.handler(countHandler(&n, `{"count":1}`, maxAge300))
+
+	res, h, b := cacheGet(f, "/quake/stats?a=1&b=2", V2JSON)
+	if res.Code != http.StatusOK || b != `{"count":1}` || h.Get("Content-Type") != V2JSON || h.Get("ETag") == "" {
+		t.Fatalf("unexpected response %d %v %s", res.Code, h, b)
+	}
+
+	if _, _, b = cacheGet(f, "/quake/stats?b=2&a=1", V2JSON); b != `{"count":1}` || n != 1 {
+		t.Errorf("expected a cached response got %s after %d calls", b, n)
+	}
+
+	if cacheGet(f, "/quake/stats?a=1&b=2", protobuf); n != 2 {
+		t.Errorf("expected a miss for a different Accept got %d calls", n)
+	}
+
+	if cacheGet(f, "/quake/stats?a=1", V2JSON); n != 3 {
+		t.Errorf("expected a miss for a different query got %d calls", n)
+	}
+
+	if res, _, b = cacheGet(f, "/quake/stats?a=1&b=2", V2JSON, "If-None-Match", h.Get("ETag")); res.Code != http.StatusNotModified || b != "" || n != 3 {
+		t.Errorf("expected a cached 304 got %d %s after %d calls", res.Code, b, n)
+	}
+
+	c.invalidate()
+
+	if cacheGet(f, "/quake/stats?a=1&b=2", V2JSON); n != 4 || c.len() != 1 {
+		t.Errorf("expected a miss after invalidate got %d calls and %d entries", n, c.len())
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	var n int32
+	c := newResponseCache(1 << 20)
+	f := c.handler(countHandler(&n, `{"count":1}`, "max-age=0"))
+
+	cacheGet(f, "/quake/stats", V2JSON)
+	cacheGet(f, "/quake/stats", V2JSON)
+
+	if n != 2 {
+		t.Errorf("expected expired responses to be refreshed got %d calls", n)
+	}
+
+	for _, v := range []struct {
+		sc string
+		d  time.Duration
+	}{
+		{sc: maxAge300, d: 300 * time.Second},
+		{sc: "public, max-age=86400", d: 86400 * time.Second},
+		{sc: "", d: 10 * time.Second},
+	} {
+		h := make(http.Header)
+		h.Set("Surrogate-Control", v.sc)
+
+		if maxAge(h) != v.d {
+			t.Errorf("%s: expected %s got %s", v.sc, v.d, maxAge(h))
+		}
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	var n int32
+	c := newResponseCache(1 << 20)
+	f := c.handler(func(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+		atomic.AddInt32(&n, 1)
+		return weft.ServiceUnavailableError(fmt.Errorf("no db"))
+	})
+
+	for i := 0; i < 2; i++ {
+		if res, _, _ := cacheGet(f, "/quake/stats", V2JSON); res.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503 got %d", res.Code)
+		}
+	}
+
+	if n != 2 || c.len() != 0 {
+		t.Errorf("expected errors not to be cached got %d calls and %d entries", n, c.len())
+	}
+}
+
+func TestCacheEvict(t *testing.T) {
+	var n int32
+	body := string(make([]byte, 100))
+	c := newResponseCache(300)
+	f := c.handler(countHandler(&n, body, maxAge300))
+
+	cacheGet(f, "/a", V2JSON)
+	cacheGet(f, "/b", V2JSON)
+	cacheGet(f, "/a", V2JSON) // a is now the most recently used.
+	cacheGet(f, "/c", V2JSON)
+
+	if c.len() != 2 || c.size > 300 {
+		t.Fatalf("expected 2 entries got %d (%d bytes)", c.len(), c.size)
+	}
+
+	if cacheGet(f, "/a", V2JSON); n != 3 {
+		t.Errorf("expected a to be cached got %d calls", n)
+	}
+
+	if cacheGet(f, "/b", V2JSON); n != 4 {
+		t.Errorf("expected b to be evicted got %d calls", n)
+	}
+}
+
+func TestCacheCoalesce(t *testing.T) {
+	var n int32
+	c := newResponseCache(1 << 20)
+	release := make(chan struct{})
+
+	f := c.handler(func(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+		atomic.AddInt32(&n, 1)
+		<-release
+		b.WriteString(`{"count":1}`)
+		return &weft.StatusOK
+	})
+
+	var wg sync.WaitGroup
+	var ok int32
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, b := cacheGet(f, "/quake/stats", V2JSON); b == `{"count":1}` {
+				atomic.AddInt32(&ok, 1)
+			}
+		}()
+	}
+
+	// wait for the first call to start then give the others time to arrive.
+	for atomic.LoadInt32(&n) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// a change while the query is running.  The result is still returned but isn't cached.
+	c.invalidate()
+	close(release)
+	wg.Wait()
+
+	if n != 1 || ok != 10 {
+		t.Errorf("expected 1 call and 10 responses got %d and %d", n, ok)
+	}
+
+	if c.len() != 0 {
+		t.Errorf("expected a result from before invalidate not to be cached got %d entries", c.len())
+	}
+}
+
+func TestCachePanic(t *testing.T) {
+	var n int32
+	c := newResponseCache(1 << 20)
+	release := make(chan struct{})
+
+	f := c.handler(func(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+		atomic.AddInt32(&n, 1)
+		<-release
+		panic("test")
+	})
+
+	var wg sync.WaitGroup
+	var errs int32
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if res, _, _ := cacheGet(f, "/quake/stats", V2JSON); res.Code == http.StatusInternalServerError {
+				atomic.AddInt32(&errs, 1)
+			}
+		}()
+	}
+
+	for atomic.LoadInt32(&n) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	if n != 1 || errs != 10 {
+		t.Errorf("expected 1 call and 10 errors got %d and %d", n, errs)
+	}
+
+	if c.len() != 0 {
+		t.Errorf("expected the error not to be cached got %d entries", c.len())
+	}
+
+	// the next request calls the handler again.
+	cacheGet(f, "/quake/stats", V2JSON)
+
+	if n != 2 {
+		t.Errorf("expected 2 calls got %d", n)
+	}
+}
+
+func TestCacheHandler(t *testing.T) {
+	var n int32
+	c := responses
+	responses = newResponseCache(cacheMaxBytes)
+	defer func() { responses = c }()
+
+	s := httptest.NewServer(inbound(weft.MakeHandlerAPI(cached(countHandler(&n, `{"count":1}`, maxAge300)))))
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		res, err := http.Get(s.URL + "/quake/stats")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK || res.Header.Get("Surrogate-Control") != maxAge300 {
+			t.Errorf("unexpected response %d %v", res.StatusCode, res.Header)
+		}
+	}
+
+	if n != 1 {
+		t.Errorf("expected 1 call got %d", n)
+	}
+}
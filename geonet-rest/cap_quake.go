@@ -33,7 +33,7 @@ func capQuake(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 		return weft.BadRequest("invalid ID: " + id)
 	}
 
-	c := capQuakeT{ID: id}
+	c := capQuakeT{ID: id, Langs: capLangs}
 	c.Quake.PublicID = p[0]
 
 	rows, err := db.Query(`select modificationTimeUnixMicro, modificationtime from haz.quakehistory
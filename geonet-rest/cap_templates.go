@@ -5,6 +5,7 @@ import (
 	"github.com/GeoNet/haz/msg"
 	"log"
 	"math"
+	"os"
 	"sort"
 	"strings"
 	"text/template"
@@ -18,7 +19,8 @@ type capQuakeT struct {
 	Closest    msg.LocalityQuake
 	Status     string // quake status
 	Localities []msg.LocalityQuake
-	ID         string // CAP message ID
+	ID         string     // CAP message ID
+	Langs      []msg.Lang // languages for info blocks in addition to English.
 }
 
 type capAtomEntry struct {
@@ -46,6 +48,7 @@ var (
 	capTemplates = template.Must(template.New("").Funcs(funcMap).ParseGlob("assets/tmpl/cap*.tmpl"))
 	nz           *time.Location
 	expire       = time.Duration(48) * time.Hour
+	capLangs     []msg.Lang // from CAP_LANGUAGES e.g., en,mi
 )
 
 func init() {
@@ -55,6 +58,18 @@ func init() {
 		log.Println("Error loading TZNZ carrying on with UTC")
 		nz = time.UTC
 	}
+
+	l, err := msg.ParseLangs(os.Getenv("CAP_LANGUAGES"))
+	if err != nil {
+		log.Fatalf("ERROR: CAP_LANGUAGES: %s", err)
+	}
+
+	// English is always the first info block.
+	for _, v := range l {
+		if v != msg.English {
+			capLangs = append(capLangs, v)
+		}
+	}
 }
 
 var funcMap = template.FuncMap{
@@ -64,6 +79,10 @@ var funcMap = template.FuncMap{
 	"atomTime": func(t time.Time) string {
 		return t.Format(time.RFC3339)
 	},
+	"nzLocal": func(t time.Time) time.Time {
+		return t.In(nz)
+	},
+	"intensityMMI": msg.IntensityMMI,
 	"nzTime": func(t time.Time) string {
 		return t.In(nz).Format(displayTime)
 	},
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"github.com/GeoNet/weft"
+	"github.com/golang/protobuf/proto"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*
+Conditional GET (RFC 7232).  Handlers find the version of a resource and return &statusNotModified
+if the client already has it.  Where possible the version is found from the max modificationtime
+before the response is built (keyVersion), otherwise from a hash of the response (contentVersion).
+
+ETags are strong.  The same URL has different representations depending on Accept and weft gzips
+some of them so the ETag includes the content type and the content coding.
+*/
+
+// statusNotModified is returned by a handler when the client has the current version.
+// weft writes it without a body.
+var statusNotModified = weft.Result{Ok: true, Code: http.StatusNotModified}
+
+// weft only gzips responses longer than this.
+const gzipMin = 20
+
+type version struct {
+	etag     string    // a strong entity tag including the quotes.
+	modified time.Time // for Last-Modified, the zero value if it isn't known.
+}
+
+/*
+keyVersion returns the version of the representation with contentType of the data identified by key
+e.g., a publicID and a modification time.  modified is used for Last-Modified and may be zero.
+*/
+func keyVersion(r *http.Request, contentType string, modified time.Time, key ...interface{}) version {
+	return version{
+		etag:     etag(contentType, []byte(fmt.Sprintln(key...)), gzipped(r, contentType)),
+		modified: modified,
+	}
+}
+
+/*
+contentVersion returns the version of the response b with contentType.  modified is used for
+Last-Modified and may be zero.
+*/
+func contentVersion(r *http.Request, contentType string, b []byte, modified time.Time) version {
+	return version{
+		etag:     etag(contentType, b, gzipped(r, contentType) && len(b) > gzipMin),
+		modified: modified,
+	}
+}
+
+/*
+protoVersion returns the version of the protobuf m.  Marshal doesn't order map keys so the version
+is found from the text format, which does.
+*/
+func protoVersion(r *http.Request, m proto.Message, modified time.Time) version {
+	return keyVersion(r, protobuf, modified, proto.CompactTextString(m))
+}
+
+func etag(contentType string, b []byte, gz bool) string {
+	h := sha256.New()
+	h.Write([]byte(contentType))
+	h.Write([]byte{0})
+	h.Write(b)
+
+	e := hex.EncodeToString(h.Sum(nil)[:16])
+	if gz {
+		e += "-gzip"
+	}
+
+	return `"` + e + `"`
+}
+
+// gzipped returns true if weft would gzip a (long enough) response for r with contentType.
+func gzipped(r *http.Request, contentType string) bool {
+	return strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") && contentType != protobuf
+}
+
+/*
+notModified sets the ETag and Last-Modified headers for v and returns true if the preconditions
+for r show the client already has v.  If-None-Match takes precedence over If-Modified-Since.
+*/
+func notModified(r *http.Request, h http.Header, v version) bool {
+	h.Set("ETag", v.etag)
+	if !v.modified.IsZero() {
+		h.Set("Last-Modified", v.modified.UTC().Format(http.TimeFormat))
+	}
+
+	if m := r.Header["If-None-Match"]; len(m) > 0 {
+		return etagMatch(strings.Join(m, ","), v.etag)
+	}
+
+	if v.modified.IsZero() {
+		return false
+	}
+
+	t, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// Last-Modified has a resolution of one second.
+	return !v.modified.Truncate(time.Second).After(t)
+}
+
+// etagMatch returns true if e is in the If-None-Match list s.  If-None-Match uses the weak comparison.
+func etagMatch(s, e string) bool {
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == e {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+contentResult sets the validators for the response in b (with the Content-Type already set in h).
+It returns &statusNotModified if the client has the response, otherwise &weft.StatusOK.
+*/
+func contentResult(r *http.Request, h http.Header, b *bytes.Buffer, modified time.Time) *weft.Result {
+	if notModified(r, h, contentVersion(r, h.Get("Content-Type"), b.Bytes(), modified)) {
+		b.Reset()
+		return &statusNotModified
+	}
+
+	return &weft.StatusOK
+}
+
+// quakeVersion returns the version of the quake publicID as contentType.
+func quakeVersion(r *http.Request, contentType, publicID string) (version, error) {
+	var t time.Time
+	if err := db.QueryRow(quakeModifiedSQL, publicID).Scan(&t); err != nil {
+		return version{}, err
+	}
+
+	return keyVersion(r, contentType, t, publicID, t.UnixMicro()), nil
+}
+
+/*
+quakeHistoryVersion returns the version of the history for the quake publicID as contentType.
+The count changes the version if history is added out of order or removed.
+*/
+func quakeHistoryVersion(r *http.Request, contentType, publicID string) (version, error) {
+	var t sql.NullTime
+	var n int
+	if err := db.QueryRow(quakeHistoryModifiedSQL, publicID).Scan(&t, &n); err != nil {
+		return version{}, err
+	}
+
+	return keyVersion(r, contentType, t.Time, publicID, t.Time.UnixMicro(), n), nil
+}
+
+// queryModified returns the time from the query s.  It is the zero time if there is no data.
+func queryModified(s string) (time.Time, error) {
+	var t sql.NullTime
+	err := db.QueryRow(s).Scan(&t)
+	return t.Time, err
+}
+
+/*
+quakeStatsVersion returns the version of the quake stats as contentType.  The count changes the
+version when quakes are removed.
+*/
+func quakeStatsVersion(r *http.Request, contentType string) (version, error) {
+	var t sql.NullTime
+	var n int
+	if err := db.QueryRow(quakeStatsModifiedSQL, quakeStatsDays).Scan(&t, &n); err != nil {
+		return version{}, err
+	}
+
+	return keyVersion(r, contentType, t.Time, t.Time.UnixMicro(), n), nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"github.com/GeoNet/haz"
+	"github.com/GeoNet/weft"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	mt := time.Date(2016, 11, 13, 11, 2, 56, 346000000, time.UTC)
+	v := version{etag: `"abc"`, modified: mt}
+
+	in := []struct {
+		id           string
+		header       map[string]string
+		v            version
+		notModified  bool
+		lastModified string
+	}{
+		{id: "unconditional", v: v, lastModified: "Sun, 13 Nov 2016 11:02:56 GMT"},
+		{id: "etag", header: map[string]string{"If-None-Match": `"abc"`}, v: v, notModified: true},
+		{id: "etag list", header: map[string]string{"If-None-Match": `"xyz", W/"abc"`}, v: v, notModified: true},
+		{id: "etag any", header: map[string]string{"If-None-Match": `*`}, v: v, notModified: true},
+		{id: "etag changed", header: map[string]string{"If-None-Match": `"xyz"`}, v: v},
+		{id: "etag before date", header: map[string]string{"If-None-Match": `"xyz"`, "If-Modified-Since": "Sun, 13 Nov 2016 11:02:56 GMT"}, v: v},
+		{id: "same second", header: map[string]string{"If-Modified-Since": "Sun, 13 Nov 2016 11:02:56 GMT"}, v: v, notModified: true},
+		{id: "later", header: map[string]string{"If-Modified-Since": "Sun, 13 Nov 2016 12:00:00 GMT"}, v: v, notModified: true},
+		{id: "modified", header: map[string]string{"If-Modified-Since": "Sun, 13 Nov 2016 11:02:55 GMT"}, v: v},
+		{id: "bad date", header: map[string]string{"If-Modified-Since": "yesterday"}, v: v},
+		{id: "unknown modified", header: map[string]string{"If-Modified-Since": "Sun, 13 Nov 2016 12:00:00 GMT"}, v: version{etag: `"abc"`}},
+	}
+
+	for _, x := range in {
+		r := httptest.NewRequest("GET", "/quake/2016p858000", nil)
+		for k, s := range x.header {
+			r.Header.Set(k, s)
+		}
+
+		h := make(http.Header)
+
+		if notModified(r, h, x.v) != x.notModified {
+			t.Errorf("%s: expected not modified %t", x.id, x.notModified)
+		}
+
+		if h.Get("ETag") != x.v.etag {
+			t.Errorf("%s: expected ETag %s got %s", x.id, x.v.etag, h.Get("ETag"))
+		}
+
+		if x.lastModified != "" && h.Get("Last-Modified") != x.lastModified {
+			t.Errorf("%s: expected Last-Modified %s got %s", x.id, x.lastModified, h.Get("Last-Modified"))
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	r := httptest.NewRequest("GET", "/quake/2016p858000", nil)
+	gz := httptest.NewRequest("GET", "/quake/2016p858000", nil)
+	gz.Header.Set("Accept-Encoding", "gzip")
+
+	b := []byte(`{"type":"FeatureCollection","features":[]}`)
+
+	v := contentVersion(r, V2GeoJSON, b, time.Time{})
+
+	if v == contentVersion(r, V1GeoJSON, b, time.Time{}) {
+		t.Error("expected different versions for different content types")
+	}
+
+	if v == contentVersion(gz, V2GeoJSON, b, time.Time{}) {
+		t.Error("expected different versions for gzipped content")
+	}
+
+	if contentVersion(r, protobuf, b, time.Time{}) != contentVersion(gz, protobuf, b, time.Time{}) {
+		t.Error("protobufs aren't gzipped, expected the same version")
+	}
+
+	if v == contentVersion(r, V2GeoJSON, b[1:], time.Time{}) {
+		t.Error("expected different versions for different content")
+	}
+
+	if keyVersion(r, V2GeoJSON, time.Time{}, "2016p858000", 1) == keyVersion(r, V2GeoJSON, time.Time{}, "2016p858000", 2) {
+		t.Error("expected different versions for different keys")
+	}
+
+	// Marshal doesn't order maps.
+	s := haz.Shaking{MmiSummary: map[int32]int32{1: 1, 2: 2, 3: 3, 4: 4, 5: 5, 6: 6, 7: 7, 8: 8}}
+	p := protoVersion(r, &s, time.Time{})
+
+	for i := 0; i < 10; i++ {
+		if protoVersion(r, &s, time.Time{}) != p {
+			t.Fatal("expected the same version for the same protobuf")
+		}
+	}
+}
+
+func TestContentResult(t *testing.T) {
+	f := func(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+		b.WriteString(`{"type":"FeatureCollection","features":[]}`)
+		h.Set("Content-Type", V2GeoJSON)
+		return contentResult(r, h, b, time.Date(2016, 11, 13, 11, 2, 56, 0, time.UTC))
+	}
+
+	s := httptest.NewServer(inbound(weft.MakeHandlerAPI(f)))
+	defer s.Close()
+
+	res, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	e := res.Header.Get("ETag")
+
+	if res.StatusCode != http.StatusOK || e == "" || res.Header.Get("Last-Modified") != "Sun, 13 Nov 2016 11:02:56 GMT" {
+		t.Fatalf("unexpected response %d %v", res.StatusCode, res.Header)
+	}
+
+	req, err := http.NewRequest("GET", s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("If-None-Match", e)
+
+	if res, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != http.StatusNotModified || len(b) != 0 || res.Header.Get("ETag") != e {
+		t.Errorf("unexpected response %d %v %s", res.StatusCode, res.Header, b)
+	}
+}
+
+// TestConditionalGET needs the DB.
+func TestConditionalGET(t *testing.T) {
+	setup()
+	defer teardown()
+
+	in := []struct {
+		accept, url  string
+		lastModified bool
+	}{
+		{accept: V1GeoJSON, url: "/quake/2013p407387", lastModified: true},
+		{accept: V2GeoJSON, url: "/quake/2013p407387", lastModified: true},
+		{accept: protobuf, url: "/quake/2013p407387", lastModified: true},
+		{accept: V2GeoJSON, url: "/quake/history/2013p407387", lastModified: true},
+		{accept: protobuf, url: "/quake/history/2013p407387", lastModified: true},
+		{accept: V1GeoJSON, url: "/intensity?type=measured", lastModified: true},
+		{accept: V2GeoJSON, url: "/intensity?type=measured", lastModified: true},
+		{accept: protobuf, url: "/intensity?type=measured", lastModified: true},
+		{accept: V2GeoJSON, url: "/intensity?type=reported"},
+		{accept: protobuf, url: "/intensity?type=reported"},
+		{accept: V2GeoJSON, url: "/volcano/val", lastModified: true},
+		{accept: protobuf, url: "/volcano/val", lastModified: true},
+		{accept: V2JSON, url: "/quake/stats", lastModified: true},
+		{accept: protobuf, url: "/quake/stats", lastModified: true},
+	}
+
+	get := func(accept, url string, h map[string]string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL+url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", accept)
+		for k, v := range h {
+			req.Header.Set(k, v)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		return res
+	}
+
+	for _, v := range in {
+		res := get(v.accept, v.url, nil)
+
+		e := res.Header.Get("ETag")
+		lm := res.Header.Get("Last-Modified")
+
+		if res.StatusCode != http.StatusOK || e == "" || (lm != "") != v.lastModified {
+			t.Errorf("%s %s: unexpected response %d ETag %s Last-Modified %s", v.accept, v.url, res.StatusCode, e, lm)
+			continue
+		}
+
+		if res = get(v.accept, v.url, map[string]string{"If-None-Match": e}); res.StatusCode != http.StatusNotModified {
+			t.Errorf("%s %s: If-None-Match expected 304 got %d", v.accept, v.url, res.StatusCode)
+		}
+
+		if res = get(v.accept, v.url, map[string]string{"If-None-Match": `"changed"`}); res.StatusCode != http.StatusOK {
+			t.Errorf("%s %s: If-None-Match expected 200 got %d", v.accept, v.url, res.StatusCode)
+		}
+
+		if !v.lastModified {
+			continue
+		}
+
+		if res = get(v.accept, v.url, map[string]string{"If-Modified-Since": lm}); res.StatusCode != http.StatusNotModified {
+			t.Errorf("%s %s: If-Modified-Since expected 304 got %d", v.accept, v.url, res.StatusCode)
+		}
+
+		if res = get(v.accept, v.url, map[string]string{"If-Modified-Since": "Mon, 01 Jan 2001 00:00:00 GMT"}); res.StatusCode != http.StatusOK {
+			t.Errorf("%s %s: If-Modified-Since expected 200 got %d", v.accept, v.url, res.StatusCode)
+		}
+	}
+
+	// an ETag for one representation doesn't match another.
+	e := get(V2GeoJSON, "/quake/2013p407387", nil).Header.Get("ETag")
+
+	if res := get(protobuf, "/quake/2013p407387", map[string]string{"If-None-Match": e}); res.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 for a different representation got %d", res.StatusCode)
+	}
+}
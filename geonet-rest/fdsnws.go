@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/quakeml"
+	"github.com/GeoNet/weft"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FDSN Event Web Service (fdsnws-event) version 1.2 backed by haz.quake.
+// http://www.fdsn.org/webservices/
+
+const (
+	fdsnVersion   = "1.2.0"
+	fdsnCatalog   = "GeoNet"
+	fdsnMaxEvents = 20000
+	fdsnPath      = "/fdsnws/event/1/"
+	fdsnText      = "text/plain; charset=utf-8"
+	fdsnXML       = "application/xml"
+)
+
+var fdsnWADL []byte
+
+func init() {
+	var err error
+	if fdsnWADL, err = ioutil.ReadFile("assets/fdsnws/application.wadl"); err != nil {
+		log.Printf("ERROR: reading FDSN WADL: %s", err)
+	}
+}
+
+// fdsnAliases maps the short parameter names to the full names.
+var fdsnAliases = map[string]string{
+	"start":   "starttime",
+	"end":     "endtime",
+	"minlat":  "minlatitude",
+	"maxlat":  "maxlatitude",
+	"minlon":  "minlongitude",
+	"maxlon":  "maxlongitude",
+	"lat":     "latitude",
+	"lon":     "longitude",
+	"minmag":  "minmagnitude",
+	"maxmag":  "maxmagnitude",
+	"magtype": "magnitudetype",
+}
+
+var fdsnParams = map[string]bool{
+	"starttime":            true,
+	"endtime":              true,
+	"minlatitude":          true,
+	"maxlatitude":          true,
+	"minlongitude":         true,
+	"maxlongitude":         true,
+	"latitude":             true,
+	"longitude":            true,
+	"minradius":            true,
+	"maxradius":            true,
+	"mindepth":             true,
+	"maxdepth":             true,
+	"minmagnitude":         true,
+	"maxmagnitude":         true,
+	"magnitudetype":        true,
+	"includeallorigins":    true,
+	"includeallmagnitudes": true,
+	"includearrivals":      true,
+	"eventid":              true,
+	"limit":                true,
+	"offset":               true,
+	"orderby":              true,
+	"catalog":              true,
+	"updatedafter":         true,
+	"format":               true,
+	"nodata":               true,
+}
+
+// fdsnTimes are the accepted formats for FDSN times.  A trailing Z is removed before parsing.
+var fdsnTimes = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// fdsnRequest is a parsed fdsnws-event query.
+type fdsnRequest struct {
+	query  database.QuakeQuery
+	format string // xml or text.
+	nodata int    // http status for no data.
+	noData bool   // true if the query can't match any quakes e.g., an unknown catalog.
+}
+
+/*
+parseFDSN parses the fdsnws-event query parameters in v.  Short parameter names are accepted.
+Duplicate quakes are never returned; deleted quakes are returned.
+*/
+func parseFDSN(v url.Values) (f fdsnRequest, err error) {
+	f.format = "xml"
+	f.nodata = http.StatusNoContent
+	f.query.Statuses = []string{"automatic", "reviewed", "deleted"}
+
+	p := make(map[string]string)
+
+	for k, val := range v {
+		n := strings.ToLower(k)
+		if a, ok := fdsnAliases[n]; ok {
+			n = a
+		}
+
+		if !fdsnParams[n] {
+			return f, fmt.Errorf("unsupported parameter: %s", k)
+		}
+
+		if _, ok := p[n]; ok || len(val) != 1 {
+			return f, fmt.Errorf("parameter repeated: %s", n)
+		}
+
+		p[n] = val[0]
+	}
+
+	num := func(k string, min, max float64) (*float64, error) {
+		s, ok := p[k]
+		if !ok {
+			return nil, nil
+		}
+
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || n < min || n > max {
+			return nil, fmt.Errorf("invalid %s: %s", k, s)
+		}
+
+		return &n, nil
+	}
+
+	ts := func(k string) (time.Time, error) {
+		s, ok := p[k]
+		if !ok {
+			return time.Time{}, nil
+		}
+
+		for _, l := range fdsnTimes {
+			if t, err := time.Parse(l, strings.TrimSuffix(s, "Z")); err == nil {
+				return t, nil
+			}
+		}
+
+		return time.Time{}, fmt.Errorf("invalid %s: %s", k, s)
+	}
+
+	integer := func(k string, min int) (int, error) {
+		s, ok := p[k]
+		if !ok {
+			return 0, nil
+		}
+
+		i, err := strconv.Atoi(s)
+		if err != nil || i < min {
+			return 0, fmt.Errorf("invalid %s: %s", k, s)
+		}
+
+		return i, nil
+	}
+
+	q := &f.query
+
+	if q.Start, err = ts("starttime"); err != nil {
+		return
+	}
+	if q.End, err = ts("endtime"); err != nil {
+		return
+	}
+	if !q.Start.IsZero() && !q.End.IsZero() && !q.Start.Before(q.End) {
+		return f, fmt.Errorf("starttime must be before endtime")
+	}
+	if q.UpdatedAfter, err = ts("updatedafter"); err != nil {
+		return
+	}
+
+	var n [4]*float64
+
+	for i, k := range []string{"minlongitude", "minlatitude", "maxlongitude", "maxlatitude"} {
+		max := 180.0
+		if strings.HasSuffix(k, "latitude") {
+			max = 90.0
+		}
+		if n[i], err = num(k, -max, max); err != nil {
+			return
+		}
+	}
+
+	if n[0] != nil || n[1] != nil || n[2] != nil || n[3] != nil {
+		q.BBox = []float64{-180, -90, 180, 90}
+		for i := range n {
+			if n[i] != nil {
+				q.BBox[i] = *n[i]
+			}
+		}
+		if q.BBox[1] > q.BBox[3] {
+			return f, fmt.Errorf("minlatitude must not be greater than maxlatitude")
+		}
+	}
+
+	var lat, lon, minR, maxR *float64
+
+	if lat, err = num("latitude", -90, 90); err != nil {
+		return
+	}
+	if lon, err = num("longitude", -180, 180); err != nil {
+		return
+	}
+	if minR, err = num("minradius", 0, 180); err != nil {
+		return
+	}
+	if maxR, err = num("maxradius", 0, 180); err != nil {
+		return
+	}
+
+	if lat != nil || lon != nil || minR != nil || maxR != nil {
+		q.Radius = &database.Radius{Max: 180}
+		if lat != nil {
+			q.Radius.Latitude = *lat
+		}
+		if lon != nil {
+			q.Radius.Longitude = *lon
+		}
+		if minR != nil {
+			q.Radius.Min = *minR
+		}
+		if maxR != nil {
+			q.Radius.Max = *maxR
+		}
+		if q.Radius.Min > q.Radius.Max {
+			return f, fmt.Errorf("minradius must not be greater than maxradius")
+		}
+	}
+
+	if q.MinDepth, err = num("mindepth", -10, 1000); err != nil {
+		return
+	}
+	if q.MaxDepth, err = num("maxdepth", -10, 1000); err != nil {
+		return
+	}
+	if q.MinMagnitude, err = num("minmagnitude", -10, 15); err != nil {
+		return
+	}
+	if q.MaxMagnitude, err = num("maxmagnitude", -10, 15); err != nil {
+		return
+	}
+
+	q.MagnitudeType = p["magnitudetype"]
+	q.PublicID = p["eventid"]
+
+	if q.Limit, err = integer("limit", 1); err != nil {
+		return
+	}
+
+	var offset int
+	if offset, err = integer("offset", 1); err != nil {
+		return
+	}
+	if offset > 0 {
+		q.Offset = offset - 1
+	}
+
+	switch o := p["orderby"]; o {
+	case "", database.OrderTime, database.OrderTimeAsc, database.OrderMagnitude, database.OrderMagnitudeAsc:
+		q.OrderBy = o
+	default:
+		return f, fmt.Errorf("invalid orderby: %s", o)
+	}
+
+	for _, k := range []string{"includeallorigins", "includeallmagnitudes", "includearrivals"} {
+		switch p[k] {
+		case "", "false":
+		case "true":
+			// only the preferred origin and magnitude are available.
+			if k == "includearrivals" {
+				return f, fmt.Errorf("arrivals are not available")
+			}
+		default:
+			return f, fmt.Errorf("invalid %s: %s", k, p[k])
+		}
+	}
+
+	if c, ok := p["catalog"]; ok && c != fdsnCatalog {
+		f.noData = true
+	}
+
+	switch p["format"] {
+	case "", "xml":
+	case "text":
+		f.format = "text"
+	default:
+		return f, fmt.Errorf("invalid format: %s", p["format"])
+	}
+
+	switch p["nodata"] {
+	case "", "204":
+	case "404":
+		f.nodata = http.StatusNotFound
+	default:
+		return f, fmt.Errorf("invalid nodata: %s", p["nodata"])
+	}
+
+	return f, nil
+}
+
+// fdsnError returns a Result with an FDSN error message for the request r.
+func fdsnError(r *http.Request, code int, detail string) *weft.Result {
+	m := fmt.Sprintf("Error %d: %s\n\n%s\n\nUsage details are available from %s\n\nRequest:\n%s\n\nRequest Submitted:\n%s\n\nService version:\n%s\n",
+		code, http.StatusText(code), detail, fdsnPath, r.URL.String(), time.Now().UTC().Format(time.RFC3339), fdsnVersion)
+
+	return &weft.Result{Ok: false, Code: code, Msg: m}
+}
+
+func fdsnwsEventQuery(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	f, err := parseFDSN(r.URL.Query())
+	if err != nil {
+		return fdsnError(r, http.StatusBadRequest, err.Error())
+	}
+
+	if f.query.Limit > fdsnMaxEvents {
+		return fdsnError(r, http.StatusRequestEntityTooLarge, fmt.Sprintf("limit must be no more than %d", fdsnMaxEvents))
+	}
+
+	var q []msg.Quake
+
+	if !f.noData {
+		if f.query.Limit == 0 {
+			f.query.Limit = fdsnMaxEvents + 1
+		}
+
+		if q, err = store.Quakes(f.query); err != nil {
+			return weft.ServiceUnavailableError(err)
+		}
+
+		if len(q) > fdsnMaxEvents {
+			return fdsnError(r, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("the request matches more than %d events, use a smaller query or limit", fdsnMaxEvents))
+		}
+	}
+
+	if len(q) == 0 {
+		if f.nodata == http.StatusNotFound {
+			return fdsnError(r, http.StatusNotFound, "no data matches the request")
+		}
+		return &weft.Result{Ok: true, Code: http.StatusNoContent}
+	}
+
+	switch f.format {
+	case "text":
+		writeFDSNText(b, q)
+		h.Set("Content-Type", fdsnText)
+	default:
+		if err = quakeml.Write(b, q); err != nil {
+			return weft.InternalServerError(err)
+		}
+		h.Set("Content-Type", fdsnXML)
+	}
+
+	return &weft.StatusOK
+}
+
+// writeFDSNText writes q to b in the fdsnws-event text format.
+func writeFDSNText(b *bytes.Buffer, q []msg.Quake) {
+	b.WriteString("#EventID|Time|Latitude|Longitude|Depth/km|Author|Catalog|Contributor|ContributorID|MagType|Magnitude|MagAuthor|EventLocationName|EventType\n")
+
+	for _, v := range q {
+		var loc string
+		if l, err := v.Closest(); err == nil {
+			loc = l.Location()
+		}
+
+		a := quakeml.Agency(v.AgencyID)
+
+		fmt.Fprintf(b, "%s|%s|%.5f|%.5f|%.4f|%s|%s|%s|%s|%s|%.2f|%s|%s|%s\n",
+			v.PublicID, strings.TrimSuffix(quakeml.Time(v.Time), "Z"), v.Latitude, v.Longitude, v.Depth,
+			a, fdsnCatalog, a, v.PublicID, v.MagnitudeType, v.Magnitude, a,
+			strings.Replace(loc, "|", " ", -1), v.Type)
+	}
+}
+
+func fdsnwsEventVersion(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	if res := weft.CheckQuery(r, []string{}, []string{}); !res.Ok {
+		return res
+	}
+
+	b.WriteString(fdsnVersion)
+	h.Set("Content-Type", fdsnText)
+	h.Set("Surrogate-Control", maxAge86400)
+	return &weft.StatusOK
+}
+
+func fdsnwsEventCatalogs(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	if res := weft.CheckQuery(r, []string{}, []string{}); !res.Ok {
+		return res
+	}
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<Catalogs>\n<Catalog>" + fdsnCatalog + "</Catalog>\n</Catalogs>\n")
+	h.Set("Content-Type", fdsnXML)
+	h.Set("Surrogate-Control", maxAge86400)
+	return &weft.StatusOK
+}
+
+func fdsnwsEventWADL(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	if res := weft.CheckQuery(r, []string{}, []string{}); !res.Ok {
+		return res
+	}
+
+	if fdsnWADL == nil {
+		return weft.InternalServerError(fmt.Errorf("no WADL"))
+	}
+
+	b.Write(fdsnWADL)
+	h.Set("Content-Type", fdsnXML)
+	h.Set("Surrogate-Control", maxAge86400)
+	return &weft.StatusOK
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFDSN(t *testing.T) {
+	in := []struct {
+		id    string
+		query string
+		err   bool
+		check func(fdsnRequest) bool
+	}{
+		{id: "defaults", check: func(f fdsnRequest) bool {
+			return f.format == "xml" && f.nodata == http.StatusNoContent && f.query.BBox == nil && f.query.Radius == nil
+		}},
+		{id: "times", query: "starttime=2016-01-01&endtime=2016-02-01T12:00:00.5Z", check: func(f fdsnRequest) bool {
+			return f.query.Start.Equal(time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)) &&
+				f.query.End.Equal(time.Date(2016, 2, 1, 12, 0, 0, 500000000, time.UTC))
+		}},
+		{id: "aliases", query: "start=2016-01-01&minmag=3&lat=-41&lon=174&maxradius=2", check: func(f fdsnRequest) bool {
+			return *f.query.MinMagnitude == 3 && f.query.Radius != nil && f.query.Radius.Latitude == -41 &&
+				f.query.Radius.Longitude == 174 && f.query.Radius.Max == 2
+		}},
+		{id: "rectangle defaults", query: "minlatitude=-45", check: func(f fdsnRequest) bool {
+			return len(f.query.BBox) == 4 && f.query.BBox[0] == -180 && f.query.BBox[1] == -45 && f.query.BBox[3] == 90
+		}},
+		{id: "offset", query: "offset=11&limit=10&orderby=magnitude", check: func(f fdsnRequest) bool {
+			return f.query.Offset == 10 && f.query.Limit == 10 && f.query.OrderBy == database.OrderMagnitude
+		}},
+		{id: "text 404", query: "format=text&nodata=404", check: func(f fdsnRequest) bool {
+			return f.format == "text" && f.nodata == http.StatusNotFound
+		}},
+		{id: "other catalog", query: "catalog=ISC", check: func(f fdsnRequest) bool { return f.noData }},
+		{id: "unknown", query: "region=x", err: true},
+		{id: "alias repeated", query: "start=2016-01-01&starttime=2016-01-01", err: true},
+		{id: "bad time", query: "starttime=yesterday", err: true},
+		{id: "start after end", query: "starttime=2016-02-01&endtime=2016-01-01", err: true},
+		{id: "latitude range", query: "minlatitude=-91", err: true},
+		{id: "latitudes", query: "minlatitude=10&maxlatitude=0", err: true},
+		{id: "radius", query: "minradius=5&maxradius=1", err: true},
+		{id: "limit", query: "limit=0", err: true},
+		{id: "offset zero", query: "offset=0", err: true},
+		{id: "orderby", query: "orderby=depth", err: true},
+		{id: "format", query: "format=json", err: true},
+		{id: "nodata", query: "nodata=500", err: true},
+		{id: "arrivals", query: "includearrivals=true", err: true},
+	}
+
+	for _, v := range in {
+		q, err := url.ParseQuery(v.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := parseFDSN(q)
+		if v.err {
+			if err == nil {
+				t.Errorf("%s: expected error", v.id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", v.id, err)
+			continue
+		}
+
+		if !v.check(f) {
+			t.Errorf("%s: unexpected request %+v", v.id, f)
+		}
+	}
+}
+
+// TestFDSNWS uses an in memory store so doesn't need the DB.
+func TestFDSNWS(t *testing.T) {
+	m := database.NewMemStore()
+	store = m
+	defer func() { store = &db }()
+
+	t0 := time.Date(2016, 6, 1, 4, 31, 27, 0, time.UTC)
+
+	for i, v := range []msg.Quake{
+		{PublicID: "2016p000001", Type: "earthquake", AgencyID: "WEL(GNS_Primary)", Time: t0, ModificationTime: t0,
+			Latitude: -41.5, Longitude: 174.0, Depth: 10, Magnitude: 4.5, MagnitudeType: "M"},
+		{PublicID: "2016p000002", Type: "earthquake", AgencyID: "WEL(GNS_Primary)", Time: t0.Add(time.Hour), ModificationTime: t0,
+			Latitude: -38.5, Longitude: 176.0, Depth: 150, Magnitude: 3.0, MagnitudeType: "ML"},
+		{PublicID: "2016p000003", Type: "duplicate", AgencyID: "WEL(GNS_Primary)", Time: t0, ModificationTime: t0,
+			Latitude: -41.5, Longitude: 174.0, Depth: 10, Magnitude: 4.5, MagnitudeType: "M"},
+	} {
+		if err := m.SaveQuake(v); err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+	}
+
+	h := handler()
+
+	get := func(u string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+		return w
+	}
+
+	w := get("/fdsnws/event/1/query?format=text&orderby=time-asc")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d %s", w.Code, w.Body.String())
+	}
+
+	l := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(l) != 3 || !strings.HasPrefix(l[0], "#EventID|") ||
+		!strings.HasPrefix(l[1], "2016p000001|2016-06-01T04:31:27.000000|-41.50000|174.00000|10.0000|WEL|GeoNet|WEL|2016p000001|M|4.50|WEL|") {
+		t.Errorf("unexpected text %s", w.Body.String())
+	}
+
+	if c := w.Header().Get("Content-Type"); c != fdsnText {
+		t.Errorf("unexpected content type %s", c)
+	}
+
+	w = get("/fdsnws/event/1/query?mindepth=100")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<event publicID="smi:nz.org.geonet/2016p000002">`) ||
+		strings.Contains(w.Body.String(), "2016p000001") {
+		t.Errorf("unexpected QuakeML %d %s", w.Code, w.Body.String())
+	}
+
+	if c := w.Header().Get("Content-Type"); c != fdsnXML {
+		t.Errorf("unexpected content type %s", c)
+	}
+
+	for _, v := range []struct {
+		u    string
+		code int
+	}{
+		{"/fdsnws/event/1/query?eventid=2016p000002", http.StatusOK},
+		{"/fdsnws/event/1/query?eventid=2016p000003", http.StatusNoContent}, // duplicate.
+		{"/fdsnws/event/1/query?minmagnitude=7", http.StatusNoContent},
+		{"/fdsnws/event/1/query?minmagnitude=7&nodata=404", http.StatusNotFound},
+		{"/fdsnws/event/1/query?catalog=ISC", http.StatusNoContent},
+		{"/fdsnws/event/1/query?minmagnitude=big", http.StatusBadRequest},
+		{"/fdsnws/event/1/query?limit=20001", http.StatusRequestEntityTooLarge},
+		{"/fdsnws/event/1/version", http.StatusOK},
+		{"/fdsnws/event/1/catalogs", http.StatusOK},
+		{"/fdsnws/event/1/application.wadl", http.StatusOK},
+	} {
+		if w = get(v.u); w.Code != v.code {
+			t.Errorf("%s: expected %d got %d", v.u, v.code, w.Code)
+		}
+	}
+
+	w = get("/fdsnws/event/1/query?minmagnitude=big")
+	if !strings.HasPrefix(w.Body.String(), "Error 400: Bad Request\n\ninvalid minmagnitude: big") {
+		t.Errorf("unexpected error message %s", w.Body.String())
+	}
+
+	if w = get("/fdsnws/event/1/version"); w.Body.String() != fdsnVersion {
+		t.Errorf("unexpected version %s", w.Body.String())
+	}
+}
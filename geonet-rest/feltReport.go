@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/weft"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Felt reports are POSTed to /felt/report as JSON.  A report is validated as a reported msg.Intensity,
+rate limited by client address and by source, and then published to SNS.  The impact intensity SQS
+queue is subscribed to the topic and impact-intensity-consumer saves the report to the DB.
+
+Sources are set by the client so they are prefixed with feltSourcePrefix.  This keeps them distinct
+from sources sent to the SQS queue by other systems, which can't be overwritten from the API.
+*/
+
+const (
+	feltReportMaxBytes = 4096
+	// feltReportInterval is the minimum time between reports from a client address or a source.
+	// impact-intensity-consumer also drops reports from a source that are within 60s of each other.
+	feltReportInterval = time.Duration(60) * time.Second
+	// feltLimiterMax is the most keys a limiter holds.  Reports are refused when it is full.
+	feltLimiterMax   = 100000
+	feltSourcePrefix = "felt-api."
+)
+
+// publisher sends messages to the transport layer.  It is implemented by sns.SNS.
+type publisher interface {
+	Publish(m msg.Raw, retries int) error
+}
+
+// feltReports publishes felt reports.  It is nil if felt reports are not enabled.
+var feltReports publisher
+
+// feltReport is the body for POST /felt/report.
+type feltReport struct {
+	Source        string
+	Time          time.Time
+	Latitude      float64
+	Longitude     float64
+	MMI           int
+	Comment       string
+	Questionnaire *msg.Questionnaire
+}
+
+// limiter allows one report per key in feltReportInterval.
+type limiter struct {
+	sync.Mutex
+	seen map[string]time.Time
+}
+
+// feltClients and feltSources limit the rate at which each client address and each source can send reports.
+var (
+	feltClients = &limiter{seen: make(map[string]time.Time)}
+	feltSources = &limiter{seen: make(map[string]time.Time)}
+)
+
+func init() {
+	// once per minute remove any keys that can report again.
+	go func() {
+		ticker := time.NewTicker(time.Minute).C
+		for {
+			select {
+			case <-ticker:
+				feltClients.expire()
+				feltSources.expire()
+			}
+		}
+	}()
+}
+
+/*
+feltReportPost publishes the felt report in the request body.  Reports are accepted (202) before they
+are saved to the DB.
+*/
+func feltReportPost(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	if res := weft.CheckQuery(r, []string{}, []string{}); !res.Ok {
+		return res
+	}
+
+	h.Set("Content-Type", ErrContent)
+
+	if feltReports == nil {
+		return weft.ServiceUnavailableError(errors.New("felt reports are not enabled"))
+	}
+
+	if t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || t != JSON {
+		return &weft.Result{Code: http.StatusUnsupportedMediaType, Msg: "Content-Type must be " + JSON}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, feltReportMaxBytes+1))
+	if err != nil {
+		return weft.BadRequest("error reading the felt report")
+	}
+
+	if len(body) > feltReportMaxBytes {
+		return &weft.Result{Code: http.StatusRequestEntityTooLarge, Msg: fmt.Sprintf("felt reports must be no more than %d bytes", feltReportMaxBytes)}
+	}
+
+	var f feltReport
+
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.DisallowUnknownFields()
+
+	if err = d.Decode(&f); err != nil {
+		return weft.BadRequest("invalid felt report: " + err.Error())
+	}
+
+	i := msg.Intensity{
+		Source:        feltSourcePrefix + f.Source,
+		Quality:       "reported",
+		Comment:       f.Comment,
+		MMI:           f.MMI,
+		Latitude:      f.Latitude,
+		Longitude:     f.Longitude,
+		Time:          f.Time.UTC(),
+		Questionnaire: f.Questionnaire,
+	}
+
+	i.Valid()
+	i.Old()
+	i.Future()
+
+	if i.Err() != nil {
+		return weft.BadRequest(i.Err().Error())
+	}
+
+	c := clientAddr(r)
+
+	if !feltClients.allow(c) {
+		h.Set("Retry-After", strconv.Itoa(int(feltReportInterval.Seconds())))
+		return &weft.Result{Code: http.StatusTooManyRequests, Msg: fmt.Sprintf("felt report from %s already seen within %s", c, feltReportInterval)}
+	}
+
+	if !feltSources.allow(i.Source) {
+		feltClients.forget(c)
+		h.Set("Retry-After", strconv.Itoa(int(feltReportInterval.Seconds())))
+		return &weft.Result{Code: http.StatusTooManyRequests, Msg: fmt.Sprintf("felt report from source %s already seen within %s", f.Source, feltReportInterval)}
+	}
+
+	m, err := i.Encode()
+	if err == nil {
+		err = feltReports.Publish(msg.Raw{Body: string(m)}, 0)
+	}
+
+	if err != nil {
+		feltClients.forget(c)
+		feltSources.forget(i.Source)
+		return weft.ServiceUnavailableError(err)
+	}
+
+	return &weft.Result{Ok: true, Code: http.StatusAccepted, Msg: "felt report accepted"}
+}
+
+// allow returns false if key has reported within feltReportInterval or l is full, otherwise it records the report.
+func (l *limiter) allow(key string) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now().UTC()
+
+	if t, ok := l.seen[key]; ok && now.Sub(t) < feltReportInterval {
+		return false
+	}
+
+	if len(l.seen) >= feltLimiterMax {
+		l.expireLocked(now)
+		if len(l.seen) >= feltLimiterMax {
+			return false
+		}
+	}
+
+	l.seen[key] = now
+
+	return true
+}
+
+// forget removes key so a report that wasn't published can be sent again.
+func (l *limiter) forget(key string) {
+	l.Lock()
+	delete(l.seen, key)
+	l.Unlock()
+}
+
+// expire removes keys that can report again.
+func (l *limiter) expire() {
+	l.Lock()
+	l.expireLocked(time.Now().UTC())
+	l.Unlock()
+}
+
+func (l *limiter) expireLocked(now time.Time) {
+	for k, t := range l.seen {
+		if now.Sub(t) >= feltReportInterval {
+			delete(l.seen, k)
+		}
+	}
+}
+
+/*
+clientAddr returns the address of the client for r.  Requests reach the API through a CDN and load
+balancer that append to X-Forwarded-For so the last address in it is the one they saw.  Addresses
+earlier in the header are set by the client and are not used.
+*/
+func clientAddr(r *http.Request) string {
+	if f := r.Header.Values("X-Forwarded-For"); len(f) > 0 {
+		a := strings.Split(f[len(f)-1], ",")
+		if s := strings.TrimSpace(a[len(a)-1]); s != "" {
+			return s
+		}
+	}
+
+	if h, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return h
+	}
+
+	return r.RemoteAddr
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"github.com/GeoNet/haz/msg"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPublisher keeps published messages instead of sending them.
+type testPublisher struct {
+	raw []msg.Raw
+	err error
+}
+
+func (p *testPublisher) Publish(m msg.Raw, retries int) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	p.raw = append(p.raw, m)
+
+	return nil
+}
+
+func postFelt(url, remote, contentType, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", url, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	r.RemoteAddr = remote + ":1234"
+
+	w := httptest.NewRecorder()
+	handler().ServeHTTP(w, r)
+
+	return w
+}
+
+func TestFeltReport(t *testing.T) {
+	p := &testPublisher{}
+	feltReports = p
+	defer func() { feltReports = nil }()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	report := func(source, extra string) string {
+		return `{"Source":"` + source + `","Time":"` + now + `","Latitude":-41.2,"Longitude":174.7,"MMI":5` + extra + `}`
+	}
+
+	in := []struct {
+		id, url, remote, contentType, body string
+		code                               int
+	}{
+		{id: "accepted", body: report("test.felt.a", `,"Comment":"rolling","Questionnaire":{"Situation":"inside","Shaking":"strong"}`), code: http.StatusAccepted},
+		{id: "client", body: report("test.felt.b", ``), code: http.StatusTooManyRequests},
+		{id: "source", remote: "192.0.2.2", body: report("test.felt.a", ``), code: http.StatusTooManyRequests},
+		{id: "another client and source", remote: "192.0.2.2", contentType: "application/json; charset=utf-8", body: report("test.felt.b", ``), code: http.StatusAccepted},
+		{id: "content type", contentType: "text/plain", body: report("test.felt.c", ``), code: http.StatusUnsupportedMediaType},
+		{id: "too big", body: report("test.felt.c", `,"Comment":"`+strings.Repeat("x", feltReportMaxBytes)+`"`), code: http.StatusRequestEntityTooLarge},
+		{id: "not json", body: `felt it`, code: http.StatusBadRequest},
+		{id: "unknown field", body: report("test.felt.c", `,"Quality":"measured"`), code: http.StatusBadRequest},
+		{id: "invalid source", body: report("test felt", ``), code: http.StatusBadRequest},
+		{id: "MMI", body: strings.Replace(report("test.felt.c", ``), `"MMI":5`, `"MMI":13`, 1), code: http.StatusBadRequest},
+		{id: "latitude", body: strings.Replace(report("test.felt.c", ``), `-41.2`, `-91`, 1), code: http.StatusBadRequest},
+		{id: "old", body: strings.Replace(report("test.felt.c", ``), now, time.Now().UTC().Add(-2*time.Hour).Format(time.RFC3339), 1), code: http.StatusBadRequest},
+		{id: "future", body: strings.Replace(report("test.felt.c", ``), now, time.Now().UTC().Add(time.Hour).Format(time.RFC3339), 1), code: http.StatusBadRequest},
+		{id: "questionnaire", body: report("test.felt.c", `,"Questionnaire":{"Shaking":"wobbly"}`), code: http.StatusBadRequest},
+		{id: "query", url: "/felt/report?publicID=2013p407387", body: report("test.felt.c", ``), code: http.StatusBadRequest},
+		{id: "method", url: "/quake", body: report("test.felt.c", ``), code: http.StatusMethodNotAllowed},
+	}
+
+	for _, v := range in {
+		if v.url == "" {
+			v.url = "/felt/report"
+		}
+		if v.remote == "" {
+			v.remote = "192.0.2.1"
+		}
+		if v.contentType == "" {
+			v.contentType = JSON
+		}
+
+		w := postFelt(v.url, v.remote, v.contentType, v.body)
+
+		if w.Code != v.code {
+			t.Errorf("%s: expected %d got %d %s", v.id, v.code, w.Code, w.Body.String())
+		}
+
+		if v.code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "60" {
+			t.Errorf("%s: expected Retry-After 60 got %s", v.id, w.Header().Get("Retry-After"))
+		}
+	}
+
+	if len(p.raw) != 2 {
+		t.Fatalf("expected 2 published reports got %d", len(p.raw))
+	}
+
+	var i msg.Intensity
+	i.Decode([]byte(p.raw[0].Body))
+
+	if i.Err() != nil {
+		t.Fatal(i.Err())
+	}
+
+	if i.Source != "felt-api.test.felt.a" || i.Quality != "reported" || i.MMI != 5 || i.Comment != "rolling" ||
+		i.Questionnaire == nil || *i.Questionnaire != (msg.Questionnaire{Situation: "inside", Shaking: "strong"}) {
+		t.Errorf("unexpected published report %+v", i)
+	}
+
+	// a report that isn't published doesn't count towards the limit.
+	p.err = errors.New("SNS is down")
+
+	if w := postFelt("/felt/report", "192.0.2.3", JSON, report("test.felt.d", ``)); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 got %d", w.Code)
+	}
+
+	p.err = nil
+
+	if w := postFelt("/felt/report", "192.0.2.3", JSON, report("test.felt.d", ``)); w.Code != http.StatusAccepted {
+		t.Errorf("expected 202 got %d", w.Code)
+	}
+
+	feltReports = nil
+
+	if w := postFelt("/felt/report", "192.0.2.4", JSON, report("test.felt.e", ``)); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 when not enabled got %d", w.Code)
+	}
+}
+
+func TestFeltClientAddr(t *testing.T) {
+	in := []struct {
+		remote, forwarded, addr string
+	}{
+		{remote: "192.0.2.1:1234", addr: "192.0.2.1"},
+		{remote: "192.0.2.1:1234", forwarded: "198.51.100.1", addr: "198.51.100.1"},
+		{remote: "192.0.2.1:1234", forwarded: "203.0.113.9, 198.51.100.1", addr: "198.51.100.1"},
+		{remote: "[2001:db8::1]:1234", addr: "2001:db8::1"},
+	}
+
+	for _, v := range in {
+		r := httptest.NewRequest("POST", "/felt/report", nil)
+		r.RemoteAddr = v.remote
+		if v.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", v.forwarded)
+		}
+
+		if a := clientAddr(r); a != v.addr {
+			t.Errorf("%s %s: expected %s got %s", v.remote, v.forwarded, v.addr, a)
+		}
+	}
+}
+
+func TestFeltLimiterMax(t *testing.T) {
+	l := &limiter{seen: make(map[string]time.Time)}
+
+	for n := 0; n < feltLimiterMax; n++ {
+		l.seen[strconv.Itoa(n)] = time.Now().UTC()
+	}
+
+	if l.allow("one more") {
+		t.Error("expected a full limiter to refuse a new key")
+	}
+
+	for k := range l.seen {
+		l.seen[k] = time.Now().UTC().Add(-feltReportInterval)
+	}
+
+	if !l.allow("one more") || len(l.seen) != 1 {
+		t.Errorf("expected expired keys to be removed from a full limiter got %d", len(l.seen))
+	}
+}
@@ -15,9 +15,14 @@ func intensityMeasuredLatestV1(r *http.Request, h http.Header, b *bytes.Buffer)
 		return weft.BadRequest("type must be measured.")
 	}
 
+	mt, err := queryModified(intensityMeasuredModifiedSQL)
+	if err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
 	var d string
 
-	err := db.QueryRow(
+	err = db.QueryRow(
 		`SELECT row_to_json(fc)
 				FROM ( SELECT 'FeatureCollection' as type, COALESCE(array_to_json(array_agg(f)), '[]') as features
 					FROM (SELECT 'Feature' as type,
@@ -35,5 +40,5 @@ func intensityMeasuredLatestV1(r *http.Request, h http.Header, b *bytes.Buffer)
 
 	b.WriteString(d)
 	h.Set("Content-Type", V1GeoJSON)
-	return &weft.StatusOK
+	return contentResult(r, h, b, mt)
 }
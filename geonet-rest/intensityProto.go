@@ -22,9 +22,13 @@ func intensityProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Resul
 	}
 
 	var shaking *haz.Shaking
+	var mt time.Time // Last-Modified is only known for measured intensity.
 
 	switch ts {
 	case "measured":
+		if mt, err = queryModified(intensityMeasuredModifiedSQL); err != nil {
+			return weft.ServiceUnavailableError(err)
+		}
 		if shaking, err = intensityMeasuredLatest(); err != nil {
 			return weft.ServiceUnavailableError(err)
 		}
@@ -51,6 +55,10 @@ func intensityProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Resul
 		}
 	}
 
+	if notModified(r, h, protoVersion(r, shaking, mt)) {
+		return &statusNotModified
+	}
+
 	var by []byte
 	if by, err = proto.Marshal(shaking); err != nil {
 		return weft.ServiceUnavailableError(err)
@@ -20,9 +20,13 @@ func intensityV2(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 	}
 
 	var d string
+	var mt time.Time // Last-Modified is only known for measured intensity.
 
 	switch ts {
 	case "measured":
+		if mt, err = queryModified(intensityMeasuredModifiedSQL); err != nil {
+			return weft.ServiceUnavailableError(err)
+		}
 		err = db.QueryRow(intensityMeasuredLatestV2SQL).Scan(&d)
 	case "reported":
 		publicID := r.URL.Query().Get("publicID")
@@ -46,5 +50,5 @@ func intensityV2(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 	b.WriteString(d)
 	h.Set("Content-Type", V2GeoJSON)
 
-	return &weft.StatusOK
+	return contentResult(r, h, b, mt)
 }
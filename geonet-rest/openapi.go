@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"github.com/GeoNet/weft"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+/*
+openAPISpec is the OpenAPI 3 description of the API.  It is checked against the routes
+and the responses from the handlers by the tests in openapi_test.go.
+*/
+var openAPISpec []byte
+
+func init() {
+	var err error
+	if openAPISpec, err = ioutil.ReadFile("assets/openapi.json"); err != nil {
+		log.Printf("ERROR: reading OpenAPI spec: %s", err)
+	}
+}
+
+// openapi serves the OpenAPI spec for all Accept headers.
+func openapi(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	if res := weft.CheckQuery(r, []string{}, []string{}); !res.Ok {
+		return res
+	}
+
+	b.Write(openAPISpec)
+	h.Set("Surrogate-Control", maxAge300)
+	h.Set("Content-Type", JSON)
+	return &weft.StatusOK
+}
@@ -0,0 +1,738 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+/*
+The tests in this file check the OpenAPI spec (assets/openapi.json) against the code:
+every route is in the spec, every operation in the spec is routed, the query parameters match
+the handlers' weft.CheckQuery, and (with the DB) the responses match the schemas.
+*/
+
+type openAPI struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]*openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Parameters  []openAPIParameter `json:"parameters"`
+	RequestBody struct {
+		Content map[string]struct {
+			Schema *openAPISchema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Ref     string `json:"$ref"`
+		Content map[string]struct {
+			Schema *openAPISchema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+	Accept []string `json:"x-accept"`
+}
+
+type openAPIParameter struct {
+	Name       string         `json:"name"`
+	In         string         `json:"in"`
+	Required   bool           `json:"required"`
+	Example    interface{}    `json:"example"`
+	Schema     *openAPISchema `json:"schema"`
+	MediaTypes []string       `json:"x-media-types"`
+}
+
+// openAPISchema is the subset of the OpenAPI schema object used in the spec.
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref"`
+	Type                 string                    `json:"type"`
+	Enum                 []interface{}             `json:"enum"`
+	Required             []string                  `json:"required"`
+	Properties           map[string]*openAPISchema `json:"properties"`
+	Items                *openAPISchema            `json:"items"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties"`
+}
+
+// versioned are the Accept headers that select a version of the API.
+var versioned = map[string]bool{V1GeoJSON: true, V1JSON: true, V2GeoJSON: true, V2JSON: true, protobuf: true}
+
+var pathParamRe = regexp.MustCompile(`{([^}]+)}`)
+
+func loadOpenAPI(t *testing.T) *openAPI {
+	var o openAPI
+	if err := json.Unmarshal(openAPISpec, &o); err != nil {
+		t.Fatalf("parsing assets/openapi.json: %s", err)
+	}
+
+	return &o
+}
+
+// sortedPaths returns the paths in the spec in order.
+func (o *openAPI) sortedPaths() []string {
+	var p []string
+	for k := range o.Paths {
+		p = append(p, k)
+	}
+	sort.Strings(p)
+
+	return p
+}
+
+// accepts returns the Accept headers for op; the 200 response media types and x-accept.
+func (op openAPIOperation) accepts() []string {
+	var a []string
+	for k := range op.Responses["200"].Content {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+
+	return append(a, op.Accept...)
+}
+
+// query returns the required and optional query parameters for op with the Accept header a.
+func (op openAPIOperation) query(a string) (required, optional []string) {
+	for _, p := range op.Parameters {
+		if p.In != "query" {
+			continue
+		}
+		if len(p.MediaTypes) > 0 && !contains(p.MediaTypes, a) {
+			continue
+		}
+		if p.Required {
+			required = append(required, p.Name)
+		} else {
+			optional = append(optional, p.Name)
+		}
+	}
+	sort.Strings(required)
+	sort.Strings(optional)
+
+	return
+}
+
+/*
+url returns the URL for path and op with the Accept header a using the examples for the path
+parameters and the required query parameters.
+*/
+func (op openAPIOperation) url(path, a string) string {
+	q := url.Values{}
+
+	for _, p := range op.Parameters {
+		e := fmt.Sprint(p.Example)
+		switch {
+		case p.In == "path":
+			path = strings.Replace(path, "{"+p.Name+"}", e, 1)
+		case p.Required && (len(p.MediaTypes) == 0 || contains(p.MediaTypes, a)):
+			q.Set(p.Name, e)
+		}
+	}
+
+	if len(q) == 0 {
+		return path
+	}
+
+	return path + "?" + q.Encode()
+}
+
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// schema resolves $ref in s.
+func (o *openAPI) schema(s *openAPISchema) (*openAPISchema, error) {
+	for s != nil && s.Ref != "" {
+		r, ok := o.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if !ok {
+			return nil, fmt.Errorf("unknown $ref %s", s.Ref)
+		}
+		s = r
+	}
+
+	return s, nil
+}
+
+// validate returns an error if the JSON value v (from encoding/json) doesn't match s.
+func (o *openAPI) validate(s *openAPISchema, v interface{}, at string) error {
+	s, err := o.schema(s)
+	if err != nil || s == nil {
+		return err
+	}
+
+	if len(s.Enum) > 0 {
+		var ok bool
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, v) {
+				ok = true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%s: %v is not one of %v", at, v, s.Enum)
+		}
+	}
+
+	switch s.Type {
+	case "object":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an object got %T", at, v)
+		}
+		for _, k := range s.Required {
+			if _, ok := m[k]; !ok {
+				return fmt.Errorf("%s: missing %s", at, k)
+			}
+		}
+		for k, x := range m {
+			p, ok := s.Properties[k]
+			if !ok {
+				p = s.AdditionalProperties
+			}
+			if err := o.validate(p, x, at+"."+k); err != nil {
+				return err
+			}
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an array got %T", at, v)
+		}
+		for i, x := range a {
+			if err := o.validate(s.Items, x, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: expected a string got %T", at, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: expected a number got %T", at, v)
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: expected an integer got %v", at, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean got %T", at, v)
+		}
+	}
+
+	return nil
+}
+
+// refs calls f with every $ref in s.
+func refs(s *openAPISchema, f func(string)) {
+	if s == nil {
+		return
+	}
+	if s.Ref != "" {
+		f(s.Ref)
+	}
+	for _, p := range s.Properties {
+		refs(p, f)
+	}
+	refs(s.Items, f)
+	refs(s.AdditionalProperties, f)
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	o := loadOpenAPI(t)
+
+	check := func(at string, s *openAPISchema) {
+		refs(s, func(r string) {
+			if _, err := o.schema(&openAPISchema{Ref: r}); err != nil {
+				t.Errorf("%s: %s", at, err)
+			}
+		})
+	}
+
+	for k, s := range o.Components.Schemas {
+		check(k, s)
+	}
+
+	for _, path := range o.sortedPaths() {
+		for m, op := range o.Paths[path] {
+			// POSTs have a request body and are accepted (202).
+			ok := "200"
+
+			switch m {
+			case "get":
+			case "post":
+				ok = "202"
+
+				if len(op.RequestBody.Content) == 0 {
+					t.Errorf("%s: no request body content for %s", path, m)
+				}
+
+				for k, c := range op.RequestBody.Content {
+					check(path+" "+m+" "+k, c.Schema)
+				}
+			default:
+				t.Errorf("%s: unexpected method %s", path, m)
+			}
+
+			if len(op.Responses[ok].Content) == 0 {
+				t.Errorf("%s: no %s response content for %s", path, ok, m)
+			}
+
+			for k, c := range op.Responses[ok].Content {
+				check(path+" "+m+" "+k, c.Schema)
+			}
+
+			in := map[string]bool{}
+			for _, p := range op.Parameters {
+				if p.In == "path" {
+					in[p.Name] = true
+				}
+				if (p.In == "path" || p.Required) && p.Example == nil {
+					t.Errorf("%s: no example for %s", path, p.Name)
+				}
+				check(path+" "+p.Name, p.Schema)
+			}
+
+			for _, n := range pathParamRe.FindAllStringSubmatch(path, -1) {
+				if !in[n[1]] {
+					t.Errorf("%s: no path parameter for %s", path, n[1])
+				}
+				delete(in, n[1])
+			}
+
+			for n := range in {
+				t.Errorf("%s: path parameter %s is not in the path", path, n)
+			}
+		}
+	}
+}
+
+// handlerName returns the name of the handler func in a route expression e.g., weft.MakeHandlerAPI(cached(quakeV2)).
+func handlerName(e ast.Expr) string {
+	for {
+		switch x := e.(type) {
+		case *ast.Ident:
+			return x.Name
+		case *ast.CallExpr:
+			if len(x.Args) != 1 {
+				return ""
+			}
+			e = x.Args[0]
+		default:
+			return ""
+		}
+	}
+}
+
+/*
+parseRoutes returns the handler func names for the patterns registered with HandleFunc in routes.go
+keyed by mux variable name.  Routes registered for all the muxes in a loop are keyed by "v".
+*/
+func parseRoutes(t *testing.T) map[string]map[string]string {
+	f, err := parser.ParseFile(token.NewFileSet(), "routes.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := make(map[string]map[string]string)
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		c, ok := n.(*ast.CallExpr)
+		if !ok || len(c.Args) != 2 {
+			return true
+		}
+
+		s, ok := c.Fun.(*ast.SelectorExpr)
+		if !ok || s.Sel.Name != "HandleFunc" {
+			return true
+		}
+
+		m, ok := s.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		var pattern string
+		ast.Inspect(c.Args[0], func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.BasicLit:
+				p, _ := strconv.Unquote(x.Value)
+				pattern += p
+			case *ast.Ident:
+				if x.Name == "fdsnPath" {
+					pattern += fdsnPath
+				}
+			}
+			return true
+		})
+
+		if routes[m.Name] == nil {
+			routes[m.Name] = make(map[string]string)
+		}
+		routes[m.Name][pattern] = handlerName(c.Args[1])
+
+		return true
+	})
+
+	return routes
+}
+
+// stringList returns the strings in e; a []string literal or a package level var.
+func stringList(e ast.Expr, vars map[string]ast.Expr) []string {
+	if i, ok := e.(*ast.Ident); ok {
+		e = vars[i.Name]
+	}
+
+	c, ok := e.(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+
+	var s []string
+	for _, x := range c.Elts {
+		if l, ok := x.(*ast.BasicLit); ok {
+			v, _ := strconv.Unquote(l.Value)
+			s = append(s, v)
+		}
+	}
+	sort.Strings(s)
+
+	return s
+}
+
+// parseChecks returns the required and optional query parameters from the weft.CheckQuery in each func.
+func parseChecks(t *testing.T) map[string][2][]string {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := make(map[string]ast.Expr)
+	var funcs []*ast.FuncDecl
+
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			for _, d := range f.Decls {
+				switch x := d.(type) {
+				case *ast.FuncDecl:
+					funcs = append(funcs, x)
+				case *ast.GenDecl:
+					for _, s := range x.Specs {
+						if v, ok := s.(*ast.ValueSpec); ok && len(v.Names) == 1 && len(v.Values) == 1 {
+							vars[v.Names[0].Name] = v.Values[0]
+						}
+					}
+				}
+			}
+		}
+	}
+
+	checks := make(map[string][2][]string)
+
+	for _, f := range funcs {
+		ast.Inspect(f, func(n ast.Node) bool {
+			c, ok := n.(*ast.CallExpr)
+			if !ok || len(c.Args) != 3 {
+				return true
+			}
+
+			if s, ok := c.Fun.(*ast.SelectorExpr); ok && s.Sel.Name == "CheckQuery" {
+				if _, ok := checks[f.Name.Name]; !ok {
+					checks[f.Name.Name] = [2][]string{stringList(c.Args[1], vars), stringList(c.Args[2], vars)}
+				}
+			}
+			return true
+		})
+	}
+
+	// handlers that only call another handler e.g., quakeQuakeML calls quakeFormat.
+	for _, f := range funcs {
+		if _, ok := checks[f.Name.Name]; ok || f.Body == nil || len(f.Body.List) != 1 {
+			continue
+		}
+
+		if r, ok := f.Body.List[0].(*ast.ReturnStmt); ok && len(r.Results) == 1 {
+			if c, ok := r.Results[0].(*ast.CallExpr); ok {
+				if i, ok := c.Fun.(*ast.Ident); ok {
+					if v, ok := checks[i.Name]; ok {
+						checks[f.Name.Name] = v
+					}
+				}
+			}
+		}
+	}
+
+	// the FDSN service checks its own parameters.
+	var fdsn []string
+	for k := range fdsnParams {
+		fdsn = append(fdsn, k)
+	}
+	sort.Strings(fdsn)
+	checks["fdsnwsEventQuery"] = [2][]string{nil, fdsn}
+
+	return checks
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	o := loadOpenAPI(t)
+	routes := parseRoutes(t)
+	checks := parseChecks(t)
+
+	muxes := map[*http.ServeMux]string{
+		muxV1GeoJSON: "muxV1GeoJSON",
+		muxV1JSON:    "muxV1JSON",
+		muxV2GeoJSON: "muxV2GeoJSON",
+		muxV2JSON:    "muxV2JSON",
+		muxDefault:   "muxDefault",
+		muxProto:     "muxProto",
+		muxQuakeML:   "muxQuakeML",
+		muxCSV:       "muxCSV",
+		muxPost:      "muxPost",
+	}
+
+	if len(routes) != len(muxes)+1 {
+		t.Fatalf("expected routes for %d muxes and the loop got %d", len(muxes), len(routes))
+	}
+
+	covered := make(map[string]bool)
+
+	// checkQuery checks the query parameters in the spec for op against the handler h.
+	checkQuery := func(a, path, h string, op openAPIOperation) {
+		c, ok := checks[h]
+		if !ok {
+			t.Errorf("%s %s: no query check found for %s", a, path, h)
+			return
+		}
+
+		required, optional := op.query(a)
+
+		if !reflect.DeepEqual(required, c[0]) {
+			t.Errorf("%s %s: spec required parameters %v %s has %v", a, path, required, h, c[0])
+		}
+
+		if !reflect.DeepEqual(optional, c[1]) {
+			t.Errorf("%s %s: spec optional parameters %v %s has %v", a, path, optional, h, c[1])
+		}
+	}
+
+	for _, path := range o.sortedPaths() {
+		op := o.Paths[path]["get"]
+
+		for _, a := range op.accepts() {
+			r := httptest.NewRequest("GET", op.url(path, a), nil)
+			r.Header.Set("Accept", a)
+
+			_, mux, ok := negotiate(r)
+			if !ok {
+				t.Errorf("%s %s: not acceptable", a, path)
+				continue
+			}
+			m := muxes[mux]
+
+			_, pattern := mux.Handler(r)
+			if pattern == "" || (pattern == "/" && path != "/") {
+				t.Errorf("%s %s: not routed", a, path)
+				continue
+			}
+
+			k := m
+			if _, ok := routes[m][pattern]; !ok {
+				k = "v"
+			}
+			covered[k+" "+pattern] = true
+
+			h := routes[k][pattern]
+			if h == "quakeV2" && strings.HasSuffix(path, shakingSuffix) {
+				h = "quakeShaking"
+			}
+
+			checkQuery(a, path, h, op)
+		}
+
+		// POSTs are routed by muxPost without Accept, see inbound.
+		if op, ok := o.Paths[path]["post"]; ok {
+			_, pattern := muxPost.Handler(httptest.NewRequest("POST", op.url(path, ""), nil))
+			if pattern == "" {
+				t.Errorf("POST %s: not routed", path)
+				continue
+			}
+			covered["muxPost "+pattern] = true
+
+			checkQuery("POST", path, routes["muxPost"][pattern], op)
+		}
+	}
+
+	for m, p := range routes {
+		for pattern, h := range p {
+			if !covered[m+" "+pattern] {
+				t.Errorf("%s %s (%s) is not in the OpenAPI spec", m, pattern, h)
+			}
+		}
+	}
+}
+
+// mediaTypeMatch returns true if the Content-Type c is the media type k.  Parameters in c that aren't in k are ignored.
+func mediaTypeMatch(k, c string) bool {
+	kt, kp, err := mime.ParseMediaType(k)
+	if err != nil {
+		return false
+	}
+
+	ct, cp, err := mime.ParseMediaType(c)
+	if err != nil || kt != ct {
+		return false
+	}
+
+	for n, v := range kp {
+		if cp[n] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TestOpenAPIResponses needs the DB.
+func TestOpenAPIResponses(t *testing.T) {
+	setup()
+	defer teardown()
+
+	o := loadOpenAPI(t)
+
+	// these use external services or don't end.
+	skip := map[string]bool{
+		"/felt/report":                true,
+		"/news/geonet":                true,
+		"/quake/technical/{publicID}": true,
+		"/quake/stream":               true,
+		"/soh/impact":                 true,
+	}
+
+	for _, path := range o.sortedPaths() {
+		if skip[path] {
+			continue
+		}
+
+		op := o.Paths[path]["get"]
+
+		for _, a := range op.accepts() {
+			u := op.url(path, a)
+
+			req, err := http.NewRequest("GET", ts.URL+u, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", a)
+
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("%s %s: expected 200 got %d %s", a, u, res.StatusCode, b)
+				continue
+			}
+
+			c := res.Header.Get("Content-Type")
+
+			if versioned[a] && c != a {
+				t.Errorf("%s %s: expected Content-Type %s got %s", a, u, a, c)
+				continue
+			}
+
+			var s *openAPISchema
+			var k string
+
+			for m, x := range op.Responses["200"].Content {
+				if mediaTypeMatch(m, c) {
+					k, s = m, x.Schema
+				}
+			}
+
+			if k == "" {
+				t.Errorf("%s %s: Content-Type %s is not in the spec", a, u, c)
+				continue
+			}
+
+			if len(b) == 0 {
+				t.Errorf("%s %s: empty response", a, u)
+				continue
+			}
+
+			if !strings.Contains(k, "json") {
+				continue
+			}
+
+			var v interface{}
+			if err = json.Unmarshal(b, &v); err != nil {
+				t.Errorf("%s %s: %s", a, u, err)
+				continue
+			}
+
+			if err = o.validate(s, v, "$"); err != nil {
+				t.Errorf("%s %s: %s", a, u, err)
+			}
+		}
+	}
+}
+
+func TestOpenAPIValidate(t *testing.T) {
+	o := loadOpenAPI(t)
+
+	q := `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[172.94,-43.38]},
+	"properties":{"publicID":"2013p407387","time":"2013-06-01T01:42:29.432Z","depth":20.3,"magnitude":4.3,"locality":"Culverden","mmi":3,"quality":"best"}}]}`
+
+	in := []struct {
+		id, doc string
+		ok      bool
+	}{
+		{id: "quakes", doc: q, ok: true},
+		{id: "empty", doc: `{"type":"FeatureCollection","features":[]}`, ok: true},
+		{id: "missing", doc: strings.Replace(q, `"locality":"Culverden",`, "", 1)},
+		{id: "type", doc: strings.Replace(q, `"depth":20.3`, `"depth":"20.3"`, 1)},
+		{id: "integer", doc: strings.Replace(q, `"mmi":3`, `"mmi":3.5`, 1)},
+		{id: "enum", doc: strings.Replace(q, `"Feature"`, `"Features"`, 1)},
+		{id: "array", doc: `{"type":"FeatureCollection","features":{}}`},
+	}
+
+	for _, v := range in {
+		var d interface{}
+		if err := json.Unmarshal([]byte(v.doc), &d); err != nil {
+			t.Fatalf("%s: %s", v.id, err)
+		}
+
+		if err := o.validate(&openAPISchema{Ref: "#/components/schemas/Quakes"}, d, "$"); (err == nil) != v.ok {
+			t.Errorf("%s: expected valid %t got %v", v.id, v.ok, err)
+		}
+	}
+}
@@ -19,8 +19,19 @@ func quakeV1(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 		return res
 	}
 
+	var v version
+	var err error
+
+	if v, err = quakeVersion(r, V1GeoJSON, publicID); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if notModified(r, h, v) {
+		return &statusNotModified
+	}
+
 	var d string
-	err := db.QueryRow(
+	err = db.QueryRow(
 		`SELECT row_to_json(fc)
                          FROM ( SELECT 'FeatureCollection' as type, array_to_json(array_agg(f)) as features
                          FROM (SELECT 'Feature' as type,
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/quakecsv"
+	"github.com/GeoNet/haz/quakeml"
+	"github.com/GeoNet/weft"
+	"io"
+	"net/http"
+)
+
+/*
+Quakes as QuakeML 1.2 BED and CSV.  These are built from the haz.quake rows with the same
+encoders as quakesearch and the FDSN event service so all the services agree.
+*/
+
+// quakeWriter writes quakes in a format e.g., quakeml.Write.
+type quakeWriter func(io.Writer, []msg.Quake) error
+
+func quakeQuakeML(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	return quakeFormat(r, h, b, QuakeML, quakeml.Write)
+}
+
+func quakesQuakeML(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	return quakesFormat(r, h, b, QuakeML, quakeml.Write)
+}
+
+func quakeCSV(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	return quakeFormat(r, h, b, CSV, quakecsv.Write)
+}
+
+func quakesCSV(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	return quakesFormat(r, h, b, CSV, quakecsv.Write)
+}
+
+// quakeFormat writes the quake for the publicID in the path of r as contentType with w.
+func quakeFormat(r *http.Request, h http.Header, b *bytes.Buffer, contentType string, w quakeWriter) *weft.Result {
+	if res := weft.CheckQuery(r, []string{}, []string{}); !res.Ok {
+		return res
+	}
+
+	if len(r.URL.Query()) != 0 {
+		return weft.BadRequest("incorrect number of query parameters.")
+	}
+
+	var publicID string
+	var res *weft.Result
+
+	if publicID, res = getPublicIDPath(r); !res.Ok {
+		return res
+	}
+
+	var v version
+	var err error
+
+	if v, err = quakeVersion(r, contentType, publicID); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if notModified(r, h, v) {
+		return &statusNotModified
+	}
+
+	q, err := store.Quake(publicID)
+	switch {
+	case err == database.ErrNotFound:
+		return &weft.NotFound
+	case err != nil:
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if err = w(b, []msg.Quake{q}); err != nil {
+		return weft.InternalServerError(err)
+	}
+
+	h.Set("Content-Type", contentType)
+	return &weft.StatusOK
+}
+
+/*
+quakesFormat writes a page of quakes as contentType with w.  The query is the same as for
+quakesV2 and a Link header is set if there is a next page, see quakePage.
+*/
+func quakesFormat(r *http.Request, h http.Header, b *bytes.Buffer, contentType string, w quakeWriter) *weft.Result {
+	if res := weft.CheckQuery(r, []string{"MMI"}, quakesOptional); !res.Ok {
+		return res
+	}
+
+	p, err := getQuakePage(r)
+	if err != nil {
+		return weft.BadRequest(err.Error())
+	}
+
+	s, a := p.sql(database.QuakeColumns)
+
+	rows, err := db.Query(s, a...)
+	if err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+	defer rows.Close()
+
+	var quakes []msg.Quake
+
+	for rows.Next() {
+		q, err := database.ScanQuake(rows)
+		if err != nil {
+			return weft.ServiceUnavailableError(err)
+		}
+
+		if len(quakes) == p.limit {
+			last := quakes[len(quakes)-1]
+			setNext(r, h, quakeCursor{publicID: last.PublicID, time: last.Time})
+			break
+		}
+
+		quakes = append(quakes, q)
+	}
+
+	if err = rows.Err(); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if err = w(b, quakes); err != nil {
+		return weft.InternalServerError(err)
+	}
+
+	h.Set("Content-Type", contentType)
+	return &weft.StatusOK
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"github.com/GeoNet/haz/quakecsv"
+	"github.com/GeoNet/haz/quakeml"
+	wt "github.com/GeoNet/weft/wefttest"
+	"reflect"
+	"testing"
+)
+
+type quakeMLEvents struct {
+	Events []struct {
+		PublicID string `xml:"publicID,attr"`
+	} `xml:"eventParameters>event"`
+}
+
+func TestQuakeQuakeML(t *testing.T) {
+	setup()
+	defer teardown()
+
+	for _, v := range []struct {
+		url   string
+		n     int
+		found bool
+	}{
+		{url: "/quake/2013p407387", n: 1, found: true},
+		{url: "/quake?MMI=3", n: 2, found: true},
+		{url: "/quake?MMI=6", n: 1},
+	} {
+		b, err := wt.Request{Accept: QuakeML, URL: v.url}.Do(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var q quakeMLEvents
+
+		if err = xml.Unmarshal(b, &q); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(q.Events) != v.n {
+			t.Errorf("%s: expected %d events got %d", v.url, v.n, len(q.Events))
+		}
+
+		var found bool
+		for _, e := range q.Events {
+			if e.PublicID == quakeml.Prefix+"2013p407387" {
+				found = true
+			}
+		}
+
+		if found != v.found {
+			t.Errorf("%s: expected quake 2013p407387 %t got %t", v.url, v.found, found)
+		}
+	}
+}
+
+func TestQuakeCSV(t *testing.T) {
+	setup()
+	defer teardown()
+
+	for _, v := range []struct {
+		url string
+		n   int
+	}{
+		{url: "/quake/2013p407387", n: 1},
+		{url: "/quake?MMI=3", n: 2},
+		{url: "/quake?MMI=3&limit=1", n: 1},
+	} {
+		b, err := wt.Request{Accept: CSV, URL: v.url}.Do(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(r) != v.n+1 {
+			t.Errorf("%s: expected %d quakes got %d", v.url, v.n, len(r)-1)
+			continue
+		}
+
+		if !reflect.DeepEqual(r[0], quakecsv.Header) {
+			t.Errorf("%s: unexpected header %v", v.url, r[0])
+		}
+
+		if r[1][0] != "2013p407387" && v.url == "/quake/2013p407387" {
+			t.Errorf("%s: unexpected publicID %s", v.url, r[1][0])
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	quakePageDefault = 100
+	quakePageMax     = 1500
+)
+
+// quakesOptional are the optional query parameters for /quake (V2 and protobuf).
+var quakesOptional = []string{"startTime", "endTime", "minMagnitude", "maxMagnitude", "bbox", "modifiedSince", "limit", "cursor"}
+
+/*
+quakePage selects a page of quakes for /quake.  Quakes are ordered newest first.
+Pages are selected with a cursor (keyset) so they are stable as new quakes arrive.
+*/
+type quakePage struct {
+	mmi           int
+	start, end    time.Time // start <= time < end, zero values don't restrict the query.
+	minMagnitude  *float64
+	maxMagnitude  *float64
+	bbox          []float64 // minLon, minLat, maxLon, maxLat.  minLon > maxLon crosses the anti-meridian.
+	modifiedSince time.Time
+	limit         int
+	after         *quakeCursor // nil for the first page.
+}
+
+// quakeCursor is the last quake on a page.
+type quakeCursor struct {
+	time     time.Time
+	publicID string
+}
+
+/*
+String returns the cursor as an opaque string for use in query parameters.
+*/
+func (c quakeCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.time.UnixMicro(), 10) + "," + c.publicID))
+}
+
+func parseQuakeCursor(s string) (*quakeCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid query parameter cursor")
+	}
+
+	p := strings.SplitN(string(b), ",", 2)
+	if len(p) != 2 || !publicIDRe.MatchString(p[1]) {
+		return nil, fmt.Errorf("Invalid query parameter cursor")
+	}
+
+	u, err := strconv.ParseInt(p[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid query parameter cursor")
+	}
+
+	return &quakeCursor{time: time.UnixMicro(u).UTC(), publicID: p[1]}, nil
+}
+
+// getQuakePage reads a quakePage from the query parameters for r.  MMI is required.
+func getQuakePage(r *http.Request) (quakePage, error) {
+	var q quakePage
+	var err error
+
+	if q.mmi, err = getMMI(r); err != nil {
+		return q, err
+	}
+
+	v := r.URL.Query()
+
+	for _, t := range []struct {
+		k string
+		t *time.Time
+	}{
+		{"startTime", &q.start},
+		{"endTime", &q.end},
+		{"modifiedSince", &q.modifiedSince},
+	} {
+		if s := v.Get(t.k); s != "" {
+			if *t.t, err = time.Parse(time.RFC3339Nano, s); err != nil {
+				return q, fmt.Errorf("Invalid query parameter %s", t.k)
+			}
+		}
+	}
+
+	if !q.start.IsZero() && !q.end.IsZero() && !q.start.Before(q.end) {
+		return q, fmt.Errorf("Invalid query parameters startTime must be before endTime")
+	}
+
+	for _, m := range []struct {
+		k string
+		m **float64
+	}{
+		{"minMagnitude", &q.minMagnitude},
+		{"maxMagnitude", &q.maxMagnitude},
+	} {
+		if s := v.Get(m.k); s != "" {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return q, fmt.Errorf("Invalid query parameter %s", m.k)
+			}
+			*m.m = &f
+		}
+	}
+
+	if q.minMagnitude != nil && q.maxMagnitude != nil && *q.minMagnitude > *q.maxMagnitude {
+		return q, fmt.Errorf("Invalid query parameters minMagnitude must not be greater than maxMagnitude")
+	}
+
+	if s := v.Get("bbox"); s != "" {
+		if q.bbox, err = getBBox(s); err != nil {
+			return q, err
+		}
+	}
+
+	q.limit = quakePageDefault
+
+	if s := v.Get("limit"); s != "" {
+		if q.limit, err = strconv.Atoi(s); err != nil || q.limit < 1 || q.limit > quakePageMax {
+			return q, fmt.Errorf("Invalid query parameter limit")
+		}
+	}
+
+	if s := v.Get("cursor"); s != "" {
+		if q.after, err = parseQuakeCursor(s); err != nil {
+			return q, err
+		}
+	}
+
+	return q, nil
+}
+
+// getBBox parses minLon,minLat,maxLon,maxLat.
+func getBBox(s string) ([]float64, error) {
+	p := strings.Split(s, ",")
+	if len(p) != 4 {
+		return nil, fmt.Errorf("Invalid query parameter bbox")
+	}
+
+	b := make([]float64, 4)
+
+	for i := range p {
+		var err error
+		if b[i], err = strconv.ParseFloat(p[i], 64); err != nil {
+			return nil, fmt.Errorf("Invalid query parameter bbox")
+		}
+	}
+
+	if b[0] < -180 || b[0] > 180 || b[2] < -180 || b[2] > 180 ||
+		b[1] < -90 || b[1] > 90 || b[3] < -90 || b[3] > 90 || b[1] > b[3] {
+		return nil, fmt.Errorf("Invalid query parameter bbox")
+	}
+
+	return b, nil
+}
+
+/*
+where returns the SQL where clause and args for q.  Duplicate quakes are never selected
+so haz.quake gives the same quakes as haz.quakeapi for the last year.  Without a start time
+only quakes from the last year (the haz.quakeapi window) are selected.
+*/
+func (q quakePage) where() (string, []interface{}) {
+	a := []interface{}{q.mmi}
+	w := []string{`mmid_newzealand >= $1`, `In_newzealand = true`, `status != 'duplicate'`}
+
+	add := func(c string, v ...interface{}) {
+		var n []interface{}
+		for _, x := range v {
+			a = append(a, x)
+			n = append(n, len(a))
+		}
+		w = append(w, fmt.Sprintf(c, n...))
+	}
+
+	if !q.start.IsZero() {
+		add(`time >= $%d`, q.start)
+	} else {
+		w = append(w, `time >= now() - interval '365 days'`)
+	}
+	if !q.end.IsZero() {
+		add(`time < $%d`, q.end)
+	}
+	if !q.modifiedSince.IsZero() {
+		add(`modificationtime > $%d`, q.modifiedSince)
+	}
+	if q.minMagnitude != nil {
+		add(`magnitude >= $%d`, *q.minMagnitude)
+	}
+	if q.maxMagnitude != nil {
+		add(`magnitude <= $%d`, *q.maxMagnitude)
+	}
+	if q.bbox != nil {
+		add(`latitude >= $%d AND latitude <= $%d`, q.bbox[1], q.bbox[3])
+		if q.bbox[0] <= q.bbox[2] {
+			add(`longitude >= $%d AND longitude <= $%d`, q.bbox[0], q.bbox[2])
+		} else {
+			add(`(longitude >= $%d OR longitude <= $%d)`, q.bbox[0], q.bbox[2])
+		}
+	}
+	if q.after != nil {
+		add(`(time, publicid) < ($%d, $%d)`, q.after.time, q.after.publicID)
+	}
+
+	return ` WHERE ` + strings.Join(w, ` AND `), a
+}
+
+// sql returns the query for a page of quakes from the columns c.  One more quake than the limit
+// is selected to find if there is a next page.
+func (q quakePage) sql(c string) (string, []interface{}) {
+	w, a := q.where()
+
+	return fmt.Sprintf(`SELECT %s FROM haz.quake as q%s ORDER BY time DESC, publicid DESC LIMIT %d`, c, w, q.limit+1), a
+}
+
+// setNext sets a Link header for the next page of quakes after c.
+func setNext(r *http.Request, h http.Header, c quakeCursor) {
+	v := r.URL.Query()
+	v.Set("cursor", c.String())
+
+	h.Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, v.Encode()))
+}
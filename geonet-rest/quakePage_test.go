@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetQuakePage(t *testing.T) {
+	in := []struct {
+		id    string
+		query string
+		err   bool
+		check func(quakePage) bool
+	}{
+		{id: "defaults", query: "MMI=3", check: func(q quakePage) bool {
+			return q.mmi == 3 && q.limit == quakePageDefault && q.after == nil && q.bbox == nil &&
+				q.start.IsZero() && q.end.IsZero() && q.modifiedSince.IsZero() && q.minMagnitude == nil && q.maxMagnitude == nil
+		}},
+		{id: "low MMI", query: "MMI=-1", check: func(q quakePage) bool { return q.mmi == -9 }},
+		{id: "times", query: "MMI=3&startTime=2016-01-01T00:00:00Z&endTime=2016-02-01T00:00:00.5Z&modifiedSince=2016-03-01T12:00:00%2B13:00",
+			check: func(q quakePage) bool {
+				return q.start.Equal(time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)) &&
+					q.end.Equal(time.Date(2016, 2, 1, 0, 0, 0, 500000000, time.UTC)) &&
+					q.modifiedSince.Equal(time.Date(2016, 2, 29, 23, 0, 0, 0, time.UTC))
+			}},
+		{id: "magnitude", query: "MMI=3&minMagnitude=3.5&maxMagnitude=6", check: func(q quakePage) bool {
+			return *q.minMagnitude == 3.5 && *q.maxMagnitude == 6
+		}},
+		{id: "bbox", query: "MMI=3&bbox=165,-48,-175,-34", check: func(q quakePage) bool {
+			return reflect.DeepEqual(q.bbox, []float64{165, -48, -175, -34})
+		}},
+		{id: "limit", query: "MMI=3&limit=1500", check: func(q quakePage) bool { return q.limit == 1500 }},
+		{id: "cursor", query: "MMI=3&cursor=" + quakeCursor{time: time.Unix(1, 2000), publicID: "2016p000001"}.String(),
+			check: func(q quakePage) bool {
+				return q.after != nil && q.after.publicID == "2016p000001" && q.after.time.Equal(time.Unix(1, 2000))
+			}},
+		{id: "no MMI", query: "limit=10", err: true},
+		{id: "bad start", query: "MMI=3&startTime=2016-01-01", err: true},
+		{id: "start after end", query: "MMI=3&startTime=2016-02-01T00:00:00Z&endTime=2016-01-01T00:00:00Z", err: true},
+		{id: "bad magnitude", query: "MMI=3&minMagnitude=big", err: true},
+		{id: "magnitudes", query: "MMI=3&minMagnitude=6&maxMagnitude=3", err: true},
+		{id: "bbox values", query: "MMI=3&bbox=165,-48,-175", err: true},
+		{id: "bbox latitude", query: "MMI=3&bbox=165,-34,-175,-48", err: true},
+		{id: "bbox range", query: "MMI=3&bbox=165,-48,190,-34", err: true},
+		{id: "limit zero", query: "MMI=3&limit=0", err: true},
+		{id: "limit max", query: "MMI=3&limit=1501", err: true},
+		{id: "bad cursor", query: "MMI=3&cursor=abc", err: true},
+		{id: "bad cursor id", query: "MMI=3&cursor=" + quakeCursor{time: time.Unix(1, 0), publicID: "2016P,1"}.String(), err: true},
+	}
+
+	for _, v := range in {
+		q, err := getQuakePage(httptest.NewRequest("GET", "/quake?"+v.query, nil))
+		if v.err {
+			if err == nil {
+				t.Errorf("%s: expected error", v.id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", v.id, err)
+			continue
+		}
+
+		if !v.check(q) {
+			t.Errorf("%s: unexpected page %+v", v.id, q)
+		}
+	}
+}
+
+func TestQuakePageSQL(t *testing.T) {
+	min := 3.5
+	c := quakeCursor{time: time.Unix(1, 0).UTC(), publicID: "2016p000001"}
+
+	s, a := quakePage{mmi: 3, limit: 10, minMagnitude: &min, bbox: []float64{165, -48, -175, -34}, after: &c}.sql("publicid")
+
+	if !regexp.MustCompile(`magnitude >= \$2 AND .*\(longitude >= \$5 OR longitude <= \$6\) AND \(time, publicid\) < \(\$7, \$8\) ORDER BY time DESC, publicid DESC LIMIT 11$`).MatchString(s) {
+		t.Errorf("unexpected SQL %s", s)
+	}
+
+	if !reflect.DeepEqual(a, []interface{}{3, 3.5, -48.0, -34.0, 165.0, -175.0, c.time, c.publicID}) {
+		t.Errorf("unexpected args %v", a)
+	}
+
+	// the last year unless there is a start time.
+	if !strings.Contains(s, `time >= now() - interval '365 days'`) {
+		t.Errorf("expected the default window in %s", s)
+	}
+
+	s, a = quakePage{mmi: 3, limit: 10, start: c.time}.sql("publicid")
+
+	if strings.Contains(s, `now()`) || !strings.Contains(s, `time >= $2`) || len(a) != 2 {
+		t.Errorf("expected only the start time for the window got %s %v", s, a)
+	}
+}
+
+// getLink gets u with the Accept header a and returns the body and Link header.
+func getLink(t *testing.T, a, u string) ([]byte, string) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", a)
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("%s: expected 200 got %d", u, res.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b, res.Header.Get("Link")
+}
+
+var linkRe = regexp.MustCompile(`^<(/quake\?[^>]+)>; rel="next"$`)
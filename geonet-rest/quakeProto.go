@@ -21,12 +21,22 @@ func quakeStatsProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Resu
 		return res
 	}
 
+	var v version
+	var err error
+
+	if v, err = quakeStatsVersion(r, protobuf); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if notModified(r, h, v) {
+		return &statusNotModified
+	}
+
 	var q haz.QuakeStats
 
 	var rows *sql.Rows
-	var err error
 
-	if rows, err = db.Query(quakesPerDaySQL); err != nil {
+	if rows, err = db.Query(quakesPerDaySQL, quakeStatsDays); err != nil {
 		return weft.ServiceUnavailableError(err)
 	}
 	defer rows.Close()
@@ -47,7 +57,7 @@ func quakeStatsProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Resu
 
 	q.Year = make(map[int32]int32)
 
-	if rows, err = db.Query(fmt.Sprintf(sumMagsSQL, 365)); err != nil {
+	if rows, err = db.Query(fmt.Sprintf(sumMagsSQL, quakeStatsDays)); err != nil {
 		return weft.ServiceUnavailableError(err)
 	}
 	defer rows.Close()
@@ -119,9 +129,19 @@ func quakeProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 		return res
 	}
 
+	var v version
+	var err error
+
+	if v, err = quakeVersion(r, protobuf, q.PublicID); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if notModified(r, h, v) {
+		return &statusNotModified
+	}
+
 	var t time.Time
 	var mt time.Time
-	var err error
 
 	if err = db.QueryRow(quakeProtoSQL, q.PublicID).Scan(&t, &mt,
 		&q.Depth, &q.Magnitude, &q.Locality, &q.Mmi, &q.Quality,
@@ -155,9 +175,19 @@ func quakeHistoryProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Re
 		return res
 	}
 
-	var rows *sql.Rows
+	var v version
 	var err error
 
+	if v, err = quakeHistoryVersion(r, protobuf, publicID); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if notModified(r, h, v) {
+		return &statusNotModified
+	}
+
+	var rows *sql.Rows
+
 	if rows, err = db.Query(quakeHistoryProtoSQL, publicID); err != nil {
 		return weft.ServiceUnavailableError(err)
 	}
@@ -194,23 +224,25 @@ func quakeHistoryProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Re
 	return &weft.StatusOK
 }
 
+// quakesProto serves a page of quakes.  Quakes.NextCursor and a Link header are set if there is a next page.
 func quakesProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
-	if res := weft.CheckQuery(r, []string{"MMI"}, []string{}); !res.Ok {
+	if res := weft.CheckQuery(r, []string{"MMI"}, quakesOptional); !res.Ok {
 		return res
 	}
 
-	var mmi int
-	var err error
-
-	if mmi, err = getMMI(r); err != nil {
+	p, err := getQuakePage(r)
+	if err != nil {
 		return weft.BadRequest(err.Error())
 	}
 
+	s, a := p.sql(quakesProtoColumns)
+
 	var rows *sql.Rows
 
-	if rows, err = db.Query(quakesProtoSQL, mmi); err != nil {
+	if rows, err = db.Query(s, a...); err != nil {
 		return weft.ServiceUnavailableError(err)
 	}
+	defer rows.Close()
 
 	var quakes haz.Quakes
 
@@ -225,12 +257,24 @@ func quakesProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 			return weft.ServiceUnavailableError(err)
 		}
 
+		if len(quakes.Quakes) == p.limit {
+			l := quakes.Quakes[p.limit-1]
+			c := quakeCursor{publicID: l.PublicID, time: time.Unix(l.Time.Sec, l.Time.Nsec)}
+			quakes.NextCursor = c.String()
+			setNext(r, h, c)
+			break
+		}
+
 		q.Time = &haz.Timestamp{Sec: t.Unix(), Nsec: int64(t.Nanosecond())}
 		q.ModificationTime = &haz.Timestamp{Sec: mt.Unix(), Nsec: int64(mt.Nanosecond())}
 
 		quakes.Quakes = append(quakes.Quakes, &q)
 	}
 
+	if err = rows.Err(); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
 	var by []byte
 
 	if by, err = proto.Marshal(&quakes); err != nil {
@@ -242,13 +286,16 @@ func quakesProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 	return &weft.StatusOK
 }
 
+// technicalPrefix is the route for the technical quake information.  It is only served as protobuf.
+const technicalPrefix = "/quake/technical/"
+
 // fetches SC3ML and turns it into a protobuf.
 func quakeTechnicalProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 	if res := weft.CheckQuery(r, []string{}, []string{}); !res.Ok {
 		return res
 	}
 
-	by, res := getBytes(s3+strings.TrimPrefix(r.URL.Path, "/quake/technical/")+".xml", "")
+	by, res := getBytes(s3+strings.TrimPrefix(r.URL.Path, technicalPrefix)+".xml", "")
 	if !res.Ok {
 		return res
 	}
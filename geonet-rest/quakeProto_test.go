@@ -193,3 +193,32 @@ func TestQuakeHistoryProto(t *testing.T) {
 		t.Error("didn't find quake")
 	}
 }
+
+func TestQuakesProtoPage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	b, l := getLink(t, protobuf, ts.URL+"/quake?MMI=3&limit=1")
+
+	var first haz.Quakes
+
+	if err := proto.Unmarshal(b, &first); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first.Quakes) != 1 || first.NextCursor == "" || !linkRe.MatchString(l) {
+		t.Fatalf("expected 1 quake and a next cursor got %+v %s", first, l)
+	}
+
+	b, l = getLink(t, protobuf, ts.URL+"/quake?MMI=3&limit=1&cursor="+first.NextCursor)
+
+	var next haz.Quakes
+
+	if err := proto.Unmarshal(b, &next); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(next.Quakes) != 1 || next.Quakes[0].PublicID == first.Quakes[0].PublicID || next.NextCursor != "" || l != "" {
+		t.Errorf("expected the last page got %+v %s", next, l)
+	}
+}
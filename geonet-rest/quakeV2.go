@@ -4,9 +4,14 @@ import (
 	"bytes"
 	"github.com/GeoNet/weft"
 	"net/http"
+	"strings"
 )
 
 func quakeV2(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	if strings.HasSuffix(r.URL.Path, shakingSuffix) {
+		return quakeShakingCached(r, h, b)
+	}
+
 	if res := weft.CheckQuery(r, []string{}, []string{}); !res.Ok {
 		return res
 	}
@@ -22,8 +27,19 @@ func quakeV2(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 		return res
 	}
 
+	var v version
+	var err error
+
+	if v, err = quakeVersion(r, V2GeoJSON, publicID); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if notModified(r, h, v) {
+		return &statusNotModified
+	}
+
 	var d string
-	err := db.QueryRow(quakeV2SQL, publicID).Scan(&d)
+	err = db.QueryRow(quakeV2SQL, publicID).Scan(&d)
 	if err != nil {
 		return weft.ServiceUnavailableError(err)
 	}
@@ -33,25 +49,56 @@ func quakeV2(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 	return &weft.StatusOK
 }
 
+/*
+quakesV2 serves a page of quakes as GeoJSON.  A Link header is set if there is a next page,
+see quakePage.
+*/
 func quakesV2(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
-	if res := weft.CheckQuery(r, []string{"MMI"}, []string{}); !res.Ok {
+	if res := weft.CheckQuery(r, []string{"MMI"}, quakesOptional); !res.Ok {
 		return res
 	}
 
-	var mmi int
-	var err error
-
-	if mmi, err = getMMI(r); err != nil {
+	q, err := getQuakePage(r)
+	if err != nil {
 		return weft.BadRequest(err.Error())
 	}
 
-	var d string
-	err = db.QueryRow(quakesV2SQL, mmi).Scan(&d)
+	s, a := q.sql(quakesV2Columns)
+
+	rows, err := db.Query(s, a...)
 	if err != nil {
 		return weft.ServiceUnavailableError(err)
 	}
+	defer rows.Close()
 
-	b.WriteString(d)
+	var c, last quakeCursor
+	var f string
+	var n int
+
+	b.WriteString(`{"type":"FeatureCollection","features":[`)
+
+	for rows.Next() {
+		if err = rows.Scan(&c.publicID, &c.time, &f); err != nil {
+			return weft.ServiceUnavailableError(err)
+		}
+
+		if n++; n > q.limit {
+			setNext(r, h, last)
+			break
+		}
+
+		if n > 1 {
+			b.WriteString(",")
+		}
+		b.WriteString(f)
+		last = c
+	}
+
+	if err = rows.Err(); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	b.WriteString(`]}`)
 	h.Set("Content-Type", V2GeoJSON)
 	return &weft.StatusOK
 }
@@ -72,8 +119,19 @@ func quakeHistoryV2(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Resul
 		return res
 	}
 
+	var v version
+	var err error
+
+	if v, err = quakeHistoryVersion(r, V2GeoJSON, publicID); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if notModified(r, h, v) {
+		return &statusNotModified
+	}
+
 	var d string
-	err := db.QueryRow(quakeHistoryV2SQL, publicID).Scan(&d)
+	err = db.QueryRow(quakeHistoryV2SQL, publicID).Scan(&d)
 	if err != nil {
 		return weft.ServiceUnavailableError(err)
 	}
@@ -92,8 +150,17 @@ func quakeStatsV2(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result
 		return weft.BadRequest("incorrect number of query parameters.")
 	}
 
+	v, err := quakeStatsVersion(r, V2JSON)
+	if err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if notModified(r, h, v) {
+		return &statusNotModified
+	}
+
 	var d string
-	err := db.QueryRow(quakeStatsV2SQL).Scan(&d)
+	err = db.QueryRow(quakeStatsV2SQL, quakeStatsDays).Scan(&d)
 	if err != nil {
 		return weft.ServiceUnavailableError(err)
 	}
@@ -298,3 +298,53 @@ func TestQuakeStatsV2(t *testing.T) {
 		}
 	}
 }
+
+func TestQuakesV2Page(t *testing.T) {
+	setup()
+	defer teardown()
+
+	b, l := getLink(t, V2GeoJSON, ts.URL+"/quake?MMI=3&limit=1")
+
+	var first quakeV2Features
+
+	if err := json.Unmarshal(b, &first); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first.Features) != 1 {
+		t.Fatalf("expected 1 quake got %d", len(first.Features))
+	}
+
+	m := linkRe.FindStringSubmatch(l)
+	if m == nil {
+		t.Fatalf("expected a next link got %s", l)
+	}
+
+	b, l = getLink(t, V2GeoJSON, ts.URL+m[1])
+
+	var next quakeV2Features
+
+	if err := json.Unmarshal(b, &next); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(next.Features) != 1 || next.Features[0].Properties.PublicID == first.Features[0].Properties.PublicID {
+		t.Errorf("expected the next quake got %+v", next.Features)
+	}
+
+	if l != "" {
+		t.Errorf("expected no link for the last page got %s", l)
+	}
+
+	b, _ = getLink(t, V2GeoJSON, ts.URL+"/quake?MMI=3&startTime=2000-01-01T00:00:00Z&endTime=2000-01-02T00:00:00Z")
+
+	var none quakeV2Features
+
+	if err := json.Unmarshal(b, &none); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(none.Features) != 0 {
+		t.Errorf("expected no quakes got %d", len(none.Features))
+	}
+}
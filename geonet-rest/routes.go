@@ -12,6 +12,9 @@ var (
 	muxV2JSON    *http.ServeMux
 	muxDefault   *http.ServeMux
 	muxProto     *http.ServeMux
+	muxQuakeML   *http.ServeMux
+	muxCSV       *http.ServeMux
+	muxPost      *http.ServeMux
 )
 
 func init() {
@@ -29,13 +32,14 @@ func init() {
 	muxV2GeoJSON.HandleFunc("/quake", weft.MakeHandlerAPI(quakesV2))
 	muxV2GeoJSON.HandleFunc("/quake/", weft.MakeHandlerAPI(quakeV2))
 	muxV2GeoJSON.HandleFunc("/quake/history/", weft.MakeHandlerAPI(quakeHistoryV2))
+	muxV2GeoJSON.HandleFunc("/quake/stream", http.HandlerFunc(quakeStream))
 	muxV2GeoJSON.HandleFunc("/volcano/val", weft.MakeHandlerAPI(valV2))
 	muxV2GeoJSON.HandleFunc("/volcano/quake/", weft.MakeHandlerAPI(quakesVolcanoRegionV2))
 	muxV2GeoJSON.HandleFunc("/volcano/region/", weft.MakeHandlerAPI(volcanoRegionV2))
 
 	muxV2JSON = http.NewServeMux()
 	muxV2JSON.HandleFunc("/news/geonet", weft.MakeHandlerAPI(newsV2))
-	muxV2JSON.HandleFunc("/quake/stats", weft.MakeHandlerAPI(quakeStatsV2))
+	muxV2JSON.HandleFunc("/quake/stats", weft.MakeHandlerAPI(cached(quakeStatsV2)))
 
 	// protobufs
 	muxProto = http.NewServeMux()
@@ -43,10 +47,23 @@ func init() {
 	muxProto.HandleFunc("/quake/", weft.MakeHandlerAPI(quakeProto))
 	muxProto.HandleFunc("/quake/technical/", weft.MakeHandlerAPI(quakeTechnicalProto))
 	muxProto.HandleFunc("/quake/history/", weft.MakeHandlerAPI(quakeHistoryProto))
+	muxProto.HandleFunc("/quake/stream", http.HandlerFunc(quakeStream))
 	muxProto.HandleFunc("/intensity", weft.MakeHandlerAPI(intensityProto))
 	muxProto.HandleFunc("/volcano/val", weft.MakeHandlerAPI(valProto))
 	muxProto.HandleFunc("/news/geonet", weft.MakeHandlerAPI(newsProto))
-	muxProto.HandleFunc("/quake/stats", weft.MakeHandlerAPI(quakeStatsProto))
+	muxProto.HandleFunc("/quake/stats", weft.MakeHandlerAPI(cached(quakeStatsProto)))
+	// Mapbox Vector Tiles are protobufs.
+	muxProto.HandleFunc("/tiles/quake/", weft.MakeHandlerAPI(cached(quakeTile)))
+	muxProto.HandleFunc("/tiles/intensity/", weft.MakeHandlerAPI(intensityTile))
+
+	// quake formats
+	muxQuakeML = http.NewServeMux()
+	muxQuakeML.HandleFunc("/quake", weft.MakeHandlerAPI(quakesQuakeML))
+	muxQuakeML.HandleFunc("/quake/", weft.MakeHandlerAPI(quakeQuakeML))
+
+	muxCSV = http.NewServeMux()
+	muxCSV.HandleFunc("/quake", weft.MakeHandlerAPI(quakesCSV))
+	muxCSV.HandleFunc("/quake/", weft.MakeHandlerAPI(quakeCSV))
 
 	// muxDefault handles routes with no Accept version.
 	// soh routes
@@ -62,36 +79,66 @@ func init() {
 	muxDefault.HandleFunc("/quake/", weft.MakeHandlerAPI(quakeV2))
 	muxDefault.HandleFunc("/quake", weft.MakeHandlerAPI(quakesV2))
 	muxDefault.HandleFunc("/quake/history/", weft.MakeHandlerAPI(quakeHistoryV2))
-	muxDefault.HandleFunc("/quake/stats", weft.MakeHandlerAPI(quakeStatsV2))
+	muxDefault.HandleFunc("/quake/stream", http.HandlerFunc(quakeStream))
+	muxDefault.HandleFunc("/quake/stats", weft.MakeHandlerAPI(cached(quakeStatsV2)))
 	muxDefault.HandleFunc("/intensity", weft.MakeHandlerAPI(intensityV2))
 	muxDefault.HandleFunc("/news/geonet", weft.MakeHandlerAPI(newsV2))
 	muxDefault.HandleFunc("/volcano/val", weft.MakeHandlerAPI(valV2))
 	muxDefault.HandleFunc("/volcano/quake/", weft.MakeHandlerAPI(quakesVolcanoRegionV2))
 	muxDefault.HandleFunc("/volcano/region/", weft.MakeHandlerAPI(volcanoRegionV2))
-	muxDefault.HandleFunc("/quakes/services/all.json", weft.MakeHandlerAPI(quakesWWWall))
-	muxDefault.HandleFunc("/quakes/services/felt.json", weft.MakeHandlerAPI(quakesWWWfelt))
-	muxDefault.HandleFunc("/quakes/services/quakes/newzealand/", weft.MakeHandlerAPI(quakesWWWnz))
-	muxDefault.HandleFunc("/quake/services/quake/", weft.MakeHandlerAPI(quakeWWW))
+	muxDefault.HandleFunc("/quakes/services/all.json", weft.MakeHandlerAPI(cached(quakesWWWall)))
+	muxDefault.HandleFunc("/quakes/services/felt.json", weft.MakeHandlerAPI(cached(quakesWWWfelt)))
+	muxDefault.HandleFunc("/quakes/services/quakes/newzealand/", weft.MakeHandlerAPI(cached(quakesWWWnz)))
+	muxDefault.HandleFunc("/quake/services/quake/", weft.MakeHandlerAPI(cached(quakeWWW)))
+	// Mapbox Vector Tiles.
+	muxDefault.HandleFunc("/tiles/quake/", weft.MakeHandlerAPI(cached(quakeTile)))
+	muxDefault.HandleFunc("/tiles/intensity/", weft.MakeHandlerAPI(intensityTile))
+	// FDSN event web service.
+	muxDefault.HandleFunc(fdsnPath+"query", weft.MakeHandlerAPI(fdsnwsEventQuery))
+	muxDefault.HandleFunc(fdsnPath+"version", weft.MakeHandlerAPI(fdsnwsEventVersion))
+	muxDefault.HandleFunc(fdsnPath+"catalogs", weft.MakeHandlerAPI(fdsnwsEventCatalogs))
+	muxDefault.HandleFunc(fdsnPath+"application.wadl", weft.MakeHandlerAPI(fdsnwsEventWADL))
 
-	for _, v := range []*http.ServeMux{muxV1JSON, muxV2JSON, muxV1GeoJSON, muxV2GeoJSON, muxDefault, muxProto} {
+	// muxPost handles POST requests, see inbound.
+	muxPost = http.NewServeMux()
+	muxPost.HandleFunc("/felt/report", weft.MakeHandlerAPI(feltReportPost))
+
+	for _, v := range []*http.ServeMux{muxV1JSON, muxV2JSON, muxV1GeoJSON, muxV2GeoJSON, muxDefault, muxProto, muxQuakeML, muxCSV} {
 		v.HandleFunc("/", weft.MakeHandlerPage(docs))
+		v.HandleFunc("/openapi.json", weft.MakeHandlerAPI(openapi))
 	}
 
 }
 
+// router serves r with the mux for the negotiated version, see accept.go.
 func router(w http.ResponseWriter, r *http.Request) {
-	switch r.Header.Get("Accept") {
+	accept, mux, ok := negotiate(r)
+	if !ok {
+		weft.MakeHandlerAPI(notAcceptable)(w, r)
+		return
+	}
+
+	mux.ServeHTTP(w, withVersion(r, accept))
+}
+
+// muxFor returns the mux for the versioned media type or quake format a.  Anything else is the latest version.
+func muxFor(a string) *http.ServeMux {
+	switch a {
 	case protobuf:
-		muxProto.ServeHTTP(w, r)
+		return muxProto
 	case V2GeoJSON:
-		muxV2GeoJSON.ServeHTTP(w, r)
+		return muxV2GeoJSON
 	case V1GeoJSON:
-		muxV1GeoJSON.ServeHTTP(w, r)
+		return muxV1GeoJSON
 	case V1JSON:
-		muxV1JSON.ServeHTTP(w, r)
+		return muxV1JSON
 	case V2JSON:
-		muxV2JSON.ServeHTTP(w, r)
+		return muxV2JSON
+	case QuakeML:
+		return muxQuakeML
+	case CSV:
+		return muxCSV
 	default:
-		muxDefault.ServeHTTP(w, r)
+		return muxDefault
 	}
 }
@@ -27,6 +27,8 @@ var routes = wt.Requests{
 	// GeoJSON V2 routes
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake/2013p407387"},
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake/history/2013p407387"},
+	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake/2013p407387/shaking"},
+	{ID: wt.L(), Accept: V2GeoJSON, Content: ASCIIGrid, Surrogate: maxAge10, URL: "/quake/2013p407387/shaking?format=ascii"},
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake?MMI=-1"},
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake?MMI=0"},
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake?MMI=1"},
@@ -37,6 +39,8 @@ var routes = wt.Requests{
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake?MMI=6"},
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake?MMI=7"},
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake?MMI=8"},
+	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake?MMI=-1&limit=10&startTime=2013-01-01T00:00:00Z&minMagnitude=3&bbox=165,-48,-175,-34"},
+	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake?MMI=3&modifiedSince=2013-01-01T00:00:00Z"},
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/intensity?type=measured"},
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/intensity?type=reported"},
 	{ID: wt.L(), Accept: V2GeoJSON, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/intensity?type=reported&publicID=2013p407387"},
@@ -47,15 +51,50 @@ var routes = wt.Requests{
 	// GeoJSON routes without explicit accept should route to latest version
 	{ID: wt.L(), Accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake/2013p407387"},
 	{ID: wt.L(), Accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake?MMI=3"},
+	{ID: wt.L(), Accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake/2013p407387/shaking"},
 	{ID: wt.L(), Accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", Content: V2GeoJSON, Surrogate: maxAge10, URL: "/intensity?type=measured"},
 	{ID: wt.L(), Accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", Content: V2GeoJSON, Surrogate: maxAge10, URL: "/intensity?type=reported"},
 	{ID: wt.L(), Accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", Content: V2GeoJSON, Surrogate: maxAge10, URL: "/volcano/val"},
 	{ID: wt.L(), Accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", Content: V2GeoJSON, Surrogate: maxAge10, URL: "/volcano/quake/ngauruhoe"},
 	{ID: wt.L(), Accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", Content: V2GeoJSON, Surrogate: maxAge10, URL: "/volcano/region/ngauruhoe"},
 
+	// Accept negotiation
+	{ID: wt.L(), Accept: "application/vnd.geo+json; version=1", Content: V1GeoJSON, Surrogate: maxAge10, URL: "/quake/2013p407387"},
+	{ID: wt.L(), Accept: "application/vnd.geo+json;version=1, */*;q=0.1", Content: V1GeoJSON, Surrogate: maxAge10, URL: "/quake/2013p407387"},
+	{ID: wt.L(), Accept: "*/*;q=0.1, application/x-protobuf", Content: protobuf, Surrogate: maxAge10, URL: "/quake/2013p407387"},
+	{ID: wt.L(), Accept: "application/vnd.geo+json;version=1, */*;q=0.1", Content: V2GeoJSON, Surrogate: maxAge10, URL: "/volcano/val"},
+	{ID: wt.L(), Accept: "application/vnd.geo+json;version=3, */*;q=0.1", Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake/2013p407387"},
+	{ID: wt.L(), Content: V1GeoJSON, Surrogate: maxAge10, URL: "/quake/2013p407387?format=geojson.v1"},
+	{ID: wt.L(), Accept: V1GeoJSON, Content: protobuf, Surrogate: maxAge10, URL: "/quake/2013p407387?format=protobuf"},
+	{ID: wt.L(), Accept: V1JSON, Content: V2JSON, Surrogate: maxAge300, URL: "/quake/stats?format=json.v2"},
+	{ID: wt.L(), Accept: "application/vnd.geo+json;version=3", Content: ErrContent, Status: http.StatusNotAcceptable, Surrogate: maxAge10, URL: "/quake/2013p407387"},
+	{ID: wt.L(), Content: ErrContent, Status: http.StatusNotAcceptable, Surrogate: maxAge10, URL: "/volcano/val?format=geojson.v1"},
+
+	// QuakeML and CSV
+	{ID: wt.L(), Accept: QuakeML, Content: QuakeML, Surrogate: maxAge10, URL: "/quake/2013p407387"},
+	{ID: wt.L(), Accept: QuakeML, Content: QuakeML, Surrogate: maxAge10, URL: "/quake?MMI=3"},
+	{ID: wt.L(), Accept: CSV, Content: CSV, Surrogate: maxAge10, URL: "/quake/2013p407387"},
+	{ID: wt.L(), Accept: CSV, Content: CSV, Surrogate: maxAge10, URL: "/quake?MMI=3"},
+	{ID: wt.L(), Content: CSV, Surrogate: maxAge10, URL: "/quake?MMI=3&format=csv"},
+	{ID: wt.L(), Accept: QuakeML, Content: V2JSON, Surrogate: maxAge300, URL: "/quake/stats"},
+	{ID: wt.L(), Accept: QuakeML, Content: V2GeoJSON, Surrogate: maxAge10, URL: "/quake/2013p407387/shaking"},
+
+	// Mapbox Vector Tiles
+	{ID: wt.L(), Accept: mvt, Content: mvt, Surrogate: maxAge10, URL: "/tiles/quake/5/31/20.mvt?MMI=3"},
+	{ID: wt.L(), Accept: protobuf, Content: mvt, Surrogate: maxAge10, URL: "/tiles/quake/5/31/20.mvt?MMI=3"},
+	{ID: wt.L(), Content: mvt, Surrogate: maxAge300, URL: "/tiles/quake/5/31/20.mvt?MMI=3&endTime=2016-11-14T11:00:00Z"},
+	{ID: wt.L(), Accept: mvt, Content: mvt, Surrogate: maxAge10, URL: "/tiles/intensity/5/31/19.mvt"},
+	{ID: wt.L(), Accept: protobuf, Content: mvt, Surrogate: maxAge10, URL: "/tiles/intensity/5/31/19.mvt"},
+	{ID: wt.L(), Content: mvt, Surrogate: maxAge300, URL: "/tiles/intensity/5/31/19.mvt?startTime=2016-11-13T11:00:00Z&endTime=2016-11-14T11:00:00Z"},
+	{ID: wt.L(), Accept: mvt, Content: ErrContent, Status: http.StatusBadRequest, Surrogate: maxAge86400, URL: "/tiles/quake/19/0/0.mvt?MMI=3"},
+	{ID: wt.L(), Accept: mvt, Content: ErrContent, Status: http.StatusBadRequest, Surrogate: maxAge86400, URL: "/tiles/intensity/5/31/19.mvt?startTime=2016-11-01T11:00:00Z&endTime=2016-11-14T11:00:00Z"},
+
 	// Routes that should 404
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Status: http.StatusNotFound, Surrogate: maxAge10, URL: "/quake/2013p407399"},
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Status: http.StatusNotFound, Surrogate: maxAge10, URL: "/felt/report?publicID=2013p407399"},
+	{ID: wt.L(), Accept: V2GeoJSON, Content: ErrContent, Status: http.StatusNotFound, Surrogate: maxAge10, URL: "/quake/2013p407399/shaking"},
+	{ID: wt.L(), Accept: QuakeML, Content: ErrContent, Status: http.StatusNotFound, Surrogate: maxAge10, URL: "/quake/2013p407399"},
+	{ID: wt.L(), Accept: CSV, Content: ErrContent, Status: http.StatusNotFound, Surrogate: maxAge10, URL: "/quake/2013p407399"},
 
 	// JSON routes
 	{ID: wt.L(), Accept: V1JSON, Content: V1JSON, Surrogate: maxAge300, URL: "/news/geonet"},
@@ -81,6 +120,7 @@ var routes = wt.Requests{
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake?regionID=newzealand&regionIntensity=unnoticeable"},
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake?regionID=newzealand"},
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake"},
+	{ID: wt.L(), Accept: V2GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake/2013p407387/shaking?format=tiff"},
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake?regionID=ruapehu&regionIntensity=unnoticeable&number=3&quality=best,caution,good"},
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake?regionID=bad&regionIntensity=unnoticeable&number=3&quality=best,caution,good"},
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake?regionID=newzealand&intensity=bad&number=30&quality=best,caution,good"},
@@ -97,12 +137,15 @@ var routes = wt.Requests{
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/fred"},
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/felt/report?quakeID=2012p498491"},
 	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/intensity?type=reported"}, // no reported at V1
-	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/volcano/quake/1"},
-	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/volcano/quake?id=ngauruhoe"},
+	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Status: http.StatusNotAcceptable, Surrogate: maxAge10, URL: "/volcano/quake/1"},
+	{ID: wt.L(), Accept: V1GeoJSON, Content: ErrContent, Status: http.StatusNotAcceptable, Surrogate: maxAge10, URL: "/volcano/quake?id=ngauruhoe"},
 
 	// V2 GeoJSON routes that should bad request
 	{ID: wt.L(), Accept: V2GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake?MMI=9"},
 	{ID: wt.L(), Accept: V2GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake?MMI=-2"},
+	{ID: wt.L(), Accept: V2GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake?MMI=3&limit=1501"},
+	{ID: wt.L(), Accept: V2GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake?MMI=3&cursor=bad"},
+	{ID: wt.L(), Accept: V2GeoJSON, Content: ErrContent, Surrogate: maxAge86400, Status: http.StatusBadRequest, URL: "/quake?MMI=3&bbox=165,-48"},
 
 	// soh routes
 	{ID: wt.L(), URL: "/soh"},
@@ -114,6 +157,7 @@ var routes = wt.Requests{
 	{ID: wt.L(), Accept: protobuf, Content: protobuf, Surrogate: maxAge10, URL: "/quake/2013p407387"},
 	{ID: wt.L(), Accept: protobuf, Content: protobuf, Surrogate: maxAge10, URL: "/quake/history/2013p407387"},
 	{ID: wt.L(), Accept: protobuf, Content: protobuf, Surrogate: maxAge10, URL: "/quake/technical/2013p407387"},
+	{ID: wt.L(), Accept: protobuf, Content: protobuf, Surrogate: maxAge10, URL: "/quake?MMI=-1&limit=10&endTime=2030-01-01T00:00:00Z&maxMagnitude=9"},
 	{ID: wt.L(), Accept: protobuf, Content: protobuf, Surrogate: maxAge10, URL: "/quake?MMI=-1"},
 	{ID: wt.L(), Accept: protobuf, Content: protobuf, Surrogate: maxAge10, URL: "/quake?MMI=0"},
 	{ID: wt.L(), Accept: protobuf, Content: protobuf, Surrogate: maxAge10, URL: "/quake?MMI=1"},
@@ -2,11 +2,15 @@ package main
 
 import (
 	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/quakecsv"
+	"github.com/GeoNet/haz/sns"
 	"github.com/GeoNet/weft"
 	_ "github.com/lib/pq"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -32,6 +36,12 @@ const (
 	Atom = "application/xml"
 )
 
+// Quake formats for /quake and /quake/{publicID} that are not versioned by Accept.
+const (
+	QuakeML = "application/xml"
+	CSV     = quakecsv.ContentType
+)
+
 const (
 	ErrContent  = "text/plain; charset=utf-8"
 	HtmlContent = "text/html; charset=utf-8"
@@ -39,11 +49,16 @@ const (
 
 var (
 	db     database.DB
+	store  database.QuakeStore = &db
 	client *http.Client
 )
 
 // main connects to the database, sets up request routing, and starts the http server.
 func main() {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR: problem with INTENSITY_RULES: %s", err)
+	}
+
 	var err error
 	db, err = database.InitPG()
 	if err != nil {
@@ -52,6 +67,10 @@ func main() {
 	}
 	defer db.Close()
 
+	if err = db.InitLocalities(); err != nil {
+		log.Fatalf("ERROR: problem with localities: %s", err)
+	}
+
 	if err = db.Ping(); err != nil {
 		log.Println("ERROR: problem pinging DB - is it up and contactable? 500s will be served")
 	}
@@ -62,9 +81,31 @@ func main() {
 		Timeout: timeout,
 	}
 
+	if s := os.Getenv("CACHE_SIZE_MB"); s != "" {
+		var n int
+		if n, err = strconv.Atoi(s); err != nil || n < 0 {
+			log.Fatalf("ERROR: invalid CACHE_SIZE_MB %s", s)
+		}
+		responses.maxBytes = n << 20
+	}
+
+	// felt reports are published to SNS if there is a topic to send them to.
+	if os.Getenv("SNS_TOPIC_ARN") != "" {
+		var s sns.SNS
+		if s, err = sns.Init(); err != nil {
+			log.Fatalf("ERROR: problem with SNS config: %s", err)
+		}
+		feltReports = &s
+	} else {
+		log.Println("WARN: SNS_TOPIC_ARN is not set, felt reports are not enabled.")
+	}
+
+	go listenQuakes(broker)
+
 	log.Println("starting server")
 	http.Handle("/", handler())
-	log.Fatal(http.ListenAndServe(":"+os.Getenv("WEB_SERVER_PORT"), nil))
+	s := &http.Server{Addr: ":" + os.Getenv("WEB_SERVER_PORT"), ConnContext: connContext}
+	log.Fatal(s.ListenAndServe())
 }
 
 // handler creates a mux and wraps it with default handlers.  Separate function to enable testing.
@@ -87,6 +128,17 @@ func inbound(h http.Handler) http.Handler {
 			w.Header().Set("Vary", "Accept")
 
 			h.ServeHTTP(w, r)
+		case "POST":
+			// POSTs are not routed on Accept and are never cached.
+			w.Header().Set("Cache-Control", "no-store")
+
+			if _, p := muxPost.Handler(r); p == "" {
+				weft.Write(w, r, &weft.MethodNotAllowed)
+				weft.MethodNotAllowed.Count()
+				return
+			}
+
+			muxPost.ServeHTTP(w, r)
 		default:
 			weft.Write(w, r, &weft.MethodNotAllowed)
 			weft.MethodNotAllowed.Count()
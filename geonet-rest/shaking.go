@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/shaking"
+	"github.com/GeoNet/weft"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const shakingSuffix = "/shaking"
+
+// ESRI ASCII grid
+const ASCIIGrid = "text/plain; charset=us-ascii"
+
+// biasRadius is the distance (km) over which measured intensities adjust the shaking grid.
+const biasRadius = 10.0
+
+// quakeShakingCached is quakeShaking with the response cache.  Calculating the grid is expensive.
+var quakeShakingCached = cached(quakeShaking)
+
+// quakeShaking serves the calculated shaking for /quake/(publicID)/shaking
+// as GeoJSON contours or an ESRI ASCII grid.
+func quakeShaking(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	if res := weft.CheckQuery(r, []string{}, []string{"format"}); !res.Ok {
+		return res
+	}
+
+	f := r.URL.Query().Get("format")
+	switch f {
+	case "", "geojson", "ascii":
+	default:
+		return weft.BadRequest("invalid format: " + f)
+	}
+
+	publicID := strings.TrimSuffix(r.URL.Path[quakeLen:], shakingSuffix)
+
+	if !publicIDRe.MatchString(publicID) {
+		return weft.BadRequest("invalid publicID: " + publicID)
+	}
+
+	q := msg.Quake{PublicID: publicID}
+	var deleted bool
+
+	err := db.QueryRow(quakeShakingSQL, publicID).Scan(&q.Type, &q.Time, &q.Latitude, &q.Longitude, &q.Depth, &q.Magnitude, &deleted)
+	if err == sql.ErrNoRows || deleted {
+		return &weft.NotFound
+	}
+	if err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	g, err := shaking.New(&q, shaking.Options{})
+	if err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	// only observations that can be in the grid are needed for the bias.
+	rows, err := db.Query(shakingMeasuredSQL,
+		q.Time.Add(time.Duration(-1*time.Minute)), q.Time.Add(time.Duration(15*time.Minute)),
+		q.Longitude, q.Latitude, g.Radius*1000.0)
+	if err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+	defer rows.Close()
+
+	var obs []shaking.Observation
+
+	for rows.Next() {
+		var o shaking.Observation
+		if err = rows.Scan(&o.Latitude, &o.Longitude, &o.MMI); err != nil {
+			return weft.ServiceUnavailableError(err)
+		}
+		obs = append(obs, o)
+	}
+	if err = rows.Err(); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+	rows.Close()
+
+	g.Bias(&q, obs, biasRadius)
+
+	switch f {
+	case "ascii":
+		if err = g.WriteASCII(b); err != nil {
+			return weft.ServiceUnavailableError(err)
+		}
+		h.Set("Content-Type", ASCIIGrid)
+	default:
+		j, err := g.GeoJSON(3)
+		if err != nil {
+			return weft.ServiceUnavailableError(err)
+		}
+		b.Write(j)
+		h.Set("Content-Type", V2GeoJSON)
+	}
+
+	return &weft.StatusOK
+}
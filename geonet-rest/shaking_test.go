@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
+	wt "github.com/GeoNet/weft/wefttest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestQuakeShakingDeleted checks there is no shaking for a deleted quake.
+func TestQuakeShakingDeleted(t *testing.T) {
+	setup()
+	defer teardown()
+
+	database.DBUser = "hazard_w"
+	tdb, err := database.InitPG()
+	database.DBUser = "hazard_r"
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tdb.Close()
+
+	q := msg.ReadSC3ML07("etc/test/files/2013p407387.xml")
+	if q.Err() != nil {
+		t.Fatal(q.Err())
+	}
+
+	q.PublicID = "2013p407388"
+	q.Type = "not existing"
+	q.Time = time.Now().UTC()
+
+	clean := func() {
+		for _, table := range []string{"haz.quake", "haz.quakeapi", "haz.quakehistory"} {
+			if _, err := tdb.Exec(`DELETE FROM `+table+` WHERE publicid = $1`, q.PublicID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	clean()
+	defer clean()
+
+	if err = tdb.SaveQuake(q); err != nil {
+		t.Fatal(err)
+	}
+
+	r := wt.Request{Accept: V2GeoJSON, Content: ErrContent, Status: http.StatusNotFound, Surrogate: maxAge10, URL: "/quake/2013p407388/shaking"}
+
+	if _, err = r.Do(ts.URL); err != nil {
+		t.Error(err)
+	}
+}
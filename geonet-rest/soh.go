@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"github.com/GeoNet/haz/database"
 	"github.com/GeoNet/weft"
 	"log"
 	"net/http"
@@ -45,30 +46,20 @@ func sohEsb(w http.ResponseWriter, r *http.Request) {
 	b.Write([]byte(`<h3>Messaging</h3>`))
 
 	var bad bool
-	var s string
-	var t time.Time
 
 	b.Write([]byte(`<table><tr><th>Service</th><th>Time Received</th></tr>`))
 
-	rows, err := db.Query("select serverid, timereceived from haz.soh")
+	hb, err := store.HeartBeats()
 	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			err := rows.Scan(&s, &t)
-			if err == nil {
-				if t.Before(time.Now().UTC().Add(old)) {
-					bad = true
-					b.Write([]byte(`<tr class="tr error">`))
-				} else {
-					b.Write([]byte(`<tr>`))
-				}
-				b.Write([]byte(`<td>` + s + `</td><td>` + t.String() + `</td></tr>`))
-			} else {
+		for _, v := range hb {
+			if v.SentTime.Before(time.Now().UTC().Add(old)) {
 				bad = true
-				b.Write([]byte(`<tr class="tr error"><td>DB error</td><td>` + err.Error() + `</td></tr>`))
+				b.Write([]byte(`<tr class="tr error">`))
+			} else {
+				b.Write([]byte(`<tr>`))
 			}
+			b.Write([]byte(`<td>` + v.ServiceID + `</td><td>` + v.SentTime.String() + `</td></tr>`))
 		}
-		rows.Close()
 	} else {
 		log.Printf("ERROR: %v", err)
 		bad = true
@@ -97,8 +88,7 @@ func impactSOH(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var meas int
-	err := db.QueryRow("select count(*) from impact.intensity_measured").Scan(&meas)
+	meas, err := store.CountIntensities(database.IntensityQuery{Quality: "measured"})
 	if err != nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		log.Printf("ERROR: %v", err)
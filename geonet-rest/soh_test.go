@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSohStore uses an in memory store so doesn't need the DB.
+func TestSohStore(t *testing.T) {
+	m := database.NewMemStore()
+	store = m
+	defer func() { store = &db }()
+
+	get := func(h http.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest("GET", "/soh/esb", nil))
+		return w
+	}
+
+	m.SaveHeartBeat(msg.HeartBeat{ServiceID: "test.ok", SentTime: time.Now().UTC()})
+
+	w := get(sohEsb)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "test.ok") {
+		t.Errorf("expected ok for a new heart beat got %d %s", w.Code, w.Body.String())
+	}
+
+	m.SaveHeartBeat(msg.HeartBeat{ServiceID: "test.old", SentTime: time.Now().UTC().Add(-time.Hour)})
+
+	if w = get(sohEsb); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected service unavailable for an old heart beat got %d", w.Code)
+	}
+
+	if w = get(impactSOH); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected service unavailable for no measured intensity got %d", w.Code)
+	}
+
+	for i := 0; i < 50; i++ {
+		m.SaveIntensity(msg.Intensity{Source: fmt.Sprintf("NZ.%d", i), Quality: "measured", MMI: 3, Time: time.Now().UTC()})
+	}
+
+	if w = get(impactSOH); w.Code != http.StatusOK {
+		t.Errorf("expected ok for measured intensity got %d", w.Code)
+	}
+}
@@ -14,14 +14,20 @@ const quakeHistoryProtoSQL = `SELECT time, modificationTime, depth, magnitude, l
 				ST_Y(geom::geometry) as latitude
 			FROM haz.quakehistory WHERE publicid = $1 ORDER BY modificationtime DESC`
 
-const quakesProtoSQL = `SELECT publicid, time, modificationTime, depth, magnitude, locality,
+// quakesProtoColumns are the columns for a page of quakes as protobufs, see quakePage.
+const quakesProtoColumns = `publicid, time, modificationTime, depth, magnitude, locality,
 				floor(mmid_newzealand) as "mmi",
 				quality,
 				ST_X(geom::geometry) as longitude,
-				ST_Y(geom::geometry) as latitude
-			FROM haz.quakeapi where mmid_newzealand >= $1
-			AND In_newzealand = true
-			ORDER BY time DESC  limit 100`
+				ST_Y(geom::geometry) as latitude`
+
+// quakeStreamColumns are the columns for a streamQuake from haz.quake or haz.quakehistory.
+const quakeStreamColumns = `publicid, modificationtimeunixmicro, time, modificationtime, depth, magnitude, locality,
+				floor(mmid_newzealand) as "mmi",
+				quality,
+				ST_X(geom::geometry) as longitude,
+				ST_Y(geom::geometry) as latitude,
+				in_newzealand`
 
 const quakeV2SQL = `SELECT row_to_json(fc)
 FROM ( SELECT 'FeatureCollection' as type, array_to_json(array_agg(f)) as features
@@ -40,11 +46,10 @@ FROM ( SELECT 'FeatureCollection' as type, array_to_json(array_agg(f)) as featur
 				) as l
 )) as properties FROM haz.quake as q where publicid = $1 ) As f )  as fc`
 
-const quakesV2SQL = `SELECT row_to_json(fc)
-FROM ( SELECT 'FeatureCollection' as type, COALESCE(array_to_json(array_agg(f)), '[]') as features
-	FROM (SELECT 'Feature' as type,
-		ST_AsGeoJSON(q.geom)::json as geometry,
-		row_to_json((SELECT l FROM
+// quakesV2Columns are the columns for a page of quakes as GeoJSON features, see quakePage.
+const quakesV2Columns = `publicid, time, json_build_object('type', 'Feature',
+		'geometry', ST_AsGeoJSON(q.geom)::json,
+		'properties', (SELECT row_to_json(l) FROM
 			(
 				SELECT
 				publicid AS "publicID",
@@ -54,10 +59,7 @@ FROM ( SELECT 'FeatureCollection' as type, COALESCE(array_to_json(array_agg(f)),
 				locality,
 				floor(mmid_newzealand) as "mmi",
 				quality
-				) as l
-)) as properties FROM haz.quakeapi as q where mmid_newzealand >= $1
-AND In_newzealand = true
-ORDER BY time DESC  limit 100 ) as f ) as fc`
+			) as l))`
 
 const quakeHistoryV2SQL = `SELECT row_to_json(fc)
 FROM ( SELECT 'FeatureCollection' as type, array_to_json(array_agg(f)) as features
@@ -169,14 +171,19 @@ and h.modificationtimeunixmicro >= first_review.modificationtimeunixmicro
 and status in ('reviewed','deleted') 
 AND modificationTime - time < interval '1 hour' ORDER BY time DESC, modificationTime DESC`
 
+// quakeStatsDays is the window for the year counts and the rate in the quake stats.  Use it for $1 in
+// quakeStatsV2SQL, quakesPerDaySQL, and quakeStatsModifiedSQL and with fmt.Sprintf for sumMagsSQL.
+const quakeStatsDays = 365
+
 const quakeStatsV2SQL = `with mags as (
 	select floor(magnitude) as magnitude, time, date_trunc('day', time) as day
 	from haz.quakeapi 
 	where in_newzealand and not deleted
+	and time >= (now() - make_interval(days => $1))
 	), year as (
 		select COALESCE(json_object_agg(summ.magnitude, summ.count), '{}') as count_mags 
 		from (select magnitude, count(magnitude) as count 
-			from mags where  time >= (now() - interval '364 days') group by magnitude) as summ
+			from mags group by magnitude) as summ
 ),
 month as (
 	select COALESCE(json_object_agg(summ.magnitude, summ.count), '{}') as count_mags 
@@ -205,13 +212,14 @@ const quakesPerDaySQL = `WITH perday AS (
 SELECT date_trunc('day', time) as day
 FROM haz.quakeapi
 WHERE in_newzealand AND NOT deleted
+AND time >= (now() - make_interval(days => $1))
 )
 SELECT day, count(day)
 FROM perday GROUP BY day ORDER BY day
 `
 
 // use this query with fmt.Sprintf to set the days interval e.g.
-//   if rows, err = db.Query(fmt.Sprintf(sumMagsSQL, 365)); err != nil {
+//   if rows, err = db.Query(fmt.Sprintf(sumMagsSQL, quakeStatsDays)); err != nil {
 const sumMagsSQL = `WITH mags AS (
 SELECT time, floor(magnitude) AS magnitude
 FROM haz.quakeapi
@@ -304,3 +312,73 @@ row_to_json(
 )) as properties FROM haz.quakeapi as q where publicid = $1
 AND In_newzealand = true
 ORDER BY time DESC  limit 100 ) as f) as fc`
+
+// These find the version of the data for conditional GET, see conditional.go.
+const quakeModifiedSQL = `SELECT modificationtime FROM haz.quake WHERE publicid = $1`
+
+const quakeHistoryModifiedSQL = `SELECT max(modificationtime), count(*) FROM haz.quakehistory WHERE publicid = $1`
+
+const intensityMeasuredModifiedSQL = `SELECT max(time) FROM impact.intensity_measured`
+
+const valModifiedSQL = `SELECT max(modificationtime) FROM haz.volcano`
+
+/*
+The stats change when a quake changes, is added or removed, and when a quake moves out of the 7, 28, or
+quakeStatsDays day counts.  Each part is a lookup on the quakeapi time or modificationtime index.
+*/
+const quakeStatsModifiedSQL = `SELECT greatest(
+	(SELECT max(modificationtime) FROM haz.quakeapi),
+	(SELECT max(time) FROM haz.quakeapi WHERE time <= now() - interval '7 days') + interval '7 days',
+	(SELECT max(time) FROM haz.quakeapi WHERE time <= now() - interval '28 days') + interval '28 days',
+	(SELECT max(time) FROM haz.quakeapi WHERE time <= now() - make_interval(days => $1)) + make_interval(days => $1)),
+	(SELECT count(*) FROM haz.quakeapi WHERE time >= now() - make_interval(days => $1))`
+
+/*
+quakeTileSQL is the query for a Mapbox Vector Tile of quakes.  It is formatted with the parameter
+numbers for the tile bounds in web mercator, the where clause from quakePage, and the parameter
+number for the limit.
+*/
+const quakeTileSQL = `WITH t AS (
+	SELECT ST_AsMVTGeom(ST_Transform(geom::geometry, 3857), ST_MakeEnvelope($%d, $%d, $%d, $%d, 3857), 4096, 64, true) AS geom,
+		publicid AS "publicID",
+		to_char(time, 'YYYY-MM-DD"T"HH24:MI:SS.MS"Z"') as "time",
+		depth,
+		magnitude,
+		locality,
+		floor(mmid_newzealand)::int as "mmi",
+		quality
+	FROM haz.quake%s
+	ORDER BY magnitude DESC, publicid
+	LIMIT $%d
+)
+SELECT ST_AsMVT(t, 'quake', 4096, 'geom') FROM t`
+
+/*
+intensityTileSQL is the query for a Mapbox Vector Tile of reported intensity aggregated to a geohash.
+It is formatted with the geohash column (geohash5 or geohash6).  The parameters are the time window,
+the tile bounds in degrees, and the tile bounds in web mercator.
+*/
+const intensityTileSQL = `WITH r AS (
+	SELECT st_pointfromgeohash(%[1]s) AS location,
+		max(mmi) AS mmi,
+		count(mmi) AS count
+	FROM impact.intensity_reported
+	WHERE time >= $1
+	AND time < $2
+	AND location::geometry && ST_MakeEnvelope($3, $4, $5, $6, 4326)
+	GROUP BY %[1]s
+), t AS (
+	SELECT ST_AsMVTGeom(ST_Transform(location, 3857), ST_MakeEnvelope($7, $8, $9, $10, 3857), 4096, 64, true) AS geom,
+		mmi,
+		count
+	FROM r
+)
+SELECT ST_AsMVT(t, 'intensity', 4096, 'geom') FROM t`
+
+const quakeShakingSQL = `SELECT type, time, latitude, longitude, depth, magnitude, deleted
+		FROM haz.quake WHERE publicid = $1`
+
+// shakingMeasuredSQL is the measured intensity within $5 metres of the epicentre ($3, $4) between $1 and $2.
+const shakingMeasuredSQL = `SELECT ST_Y(location::geometry), ST_X(location::geometry), mmi
+		FROM impact.intensity_measured WHERE time >= $1 AND time <= $2
+		AND ST_DWithin(location, ST_SetSRID(ST_MakePoint($3, $4), 4326)::geography, $5)`
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/GeoNet/haz"
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/weft"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+The quake stream pushes new and updated quakes to clients using Server-Sent Events or a WebSocket.
+Updates come from Postgres notifications on database.QuakeChannel (a trigger on haz.quake).  One listener
+per server fans them out to the clients.  Clients can resume from haz.quakehistory after the last event
+they received.
+*/
+
+var (
+	streamHeartbeat = time.Duration(30) * time.Second
+	streamRetry     = 5000 // ms for SSE clients to wait before reconnecting.
+	// streamWriteTimeout bounds each write to a client so a stalled client doesn't block its handler forever.
+	streamWriteTimeout = time.Duration(10) * time.Second
+)
+
+const (
+	streamBuffer     = 32  // quakes buffered for each client.  Slow clients are disconnected.
+	streamResumePage = 500 // quakes read from history at a time when resuming.
+	// streamReplay is how far back clients can resume.  Resuming from an older event starts at the beginning of the window.
+	streamReplay = time.Duration(24) * time.Hour
+)
+
+var broker = &quakeBroker{subs: make(map[chan streamQuake]bool)}
+
+// streamID identifies a version of a quake.  It is the event id for the stream.
+type streamID struct {
+	publicID  string
+	unixMicro int64 // the modification time.
+}
+
+func (i streamID) String() string {
+	return i.publicID + "." + strconv.FormatInt(i.unixMicro, 10)
+}
+
+func parseStreamID(s string) (streamID, error) {
+	n := strings.LastIndex(s, ".")
+	if n < 1 || !publicIDRe.MatchString(s[:n]) {
+		return streamID{}, fmt.Errorf("Invalid last event id: %s", s)
+	}
+
+	u, err := strconv.ParseInt(s[n+1:], 10, 64)
+	if err != nil {
+		return streamID{}, fmt.Errorf("Invalid last event id: %s", s)
+	}
+
+	return streamID{publicID: s[:n], unixMicro: u}, nil
+}
+
+// streamQuake is a version of a quake for the stream.
+type streamQuake struct {
+	id    streamID
+	quake haz.Quake
+	inNZ  bool
+}
+
+func scanStreamQuake(s interface {
+	Scan(...interface{}) error
+}) (streamQuake, error) {
+	var q streamQuake
+	var t, mt time.Time
+
+	err := s.Scan(&q.quake.PublicID, &q.id.unixMicro, &t, &mt, &q.quake.Depth,
+		&q.quake.Magnitude, &q.quake.Locality, &q.quake.Mmi, &q.quake.Quality,
+		&q.quake.Longitude, &q.quake.Latitude, &q.inNZ)
+
+	q.id.publicID = q.quake.PublicID
+	q.quake.Time = &haz.Timestamp{Sec: t.Unix(), Nsec: int64(t.Nanosecond())}
+	q.quake.ModificationTime = &haz.Timestamp{Sec: mt.Unix(), Nsec: int64(mt.Nanosecond())}
+
+	return q, err
+}
+
+type streamFeature struct {
+	Type       string           `json:"type"`
+	ID         string           `json:"id"`
+	Geometry   streamGeometry   `json:"geometry"`
+	Properties streamProperties `json:"properties"`
+}
+
+type streamGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type streamProperties struct {
+	PublicID         string  `json:"publicID"`
+	Time             string  `json:"time"`
+	ModificationTime string  `json:"modificationTime"`
+	Depth            float64 `json:"depth"`
+	Magnitude        float64 `json:"magnitude"`
+	Locality         string  `json:"locality"`
+	MMI              int32   `json:"mmi"`
+	Quality          string  `json:"quality"`
+}
+
+func streamTime(t *haz.Timestamp) string {
+	return time.Unix(t.Sec, t.Nsec).UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// encode returns the quake as a haz.Quake protobuf or a GeoJSON feature with the stream id as the feature id.
+func (s streamQuake) encode(protobuf bool) ([]byte, error) {
+	if protobuf {
+		return proto.Marshal(&s.quake)
+	}
+
+	return json.Marshal(streamFeature{
+		Type:     "Feature",
+		ID:       s.id.String(),
+		Geometry: streamGeometry{Type: "Point", Coordinates: []float64{s.quake.Longitude, s.quake.Latitude}},
+		Properties: streamProperties{
+			PublicID:         s.quake.PublicID,
+			Time:             streamTime(s.quake.Time),
+			ModificationTime: streamTime(s.quake.ModificationTime),
+			Depth:            s.quake.Depth,
+			Magnitude:        s.quake.Magnitude,
+			Locality:         s.quake.Locality,
+			MMI:              s.quake.Mmi,
+			Quality:          s.quake.Quality,
+		},
+	})
+}
+
+// match returns true if s matches the MMI and bbox for q.  Other values in q are ignored.
+func (q quakePage) match(s streamQuake) bool {
+	if !s.inNZ || int(s.quake.Mmi) < q.mmi {
+		return false
+	}
+
+	if q.bbox == nil {
+		return true
+	}
+
+	if s.quake.Latitude < q.bbox[1] || s.quake.Latitude > q.bbox[3] {
+		return false
+	}
+
+	if q.bbox[0] <= q.bbox[2] {
+		return s.quake.Longitude >= q.bbox[0] && s.quake.Longitude <= q.bbox[2]
+	}
+
+	return s.quake.Longitude >= q.bbox[0] || s.quake.Longitude <= q.bbox[2]
+}
+
+// quakeBroker fans out quakes to stream clients.
+type quakeBroker struct {
+	sync.Mutex
+	subs map[chan streamQuake]bool
+}
+
+func (b *quakeBroker) subscribe() chan streamQuake {
+	b.Lock()
+	defer b.Unlock()
+
+	c := make(chan streamQuake, streamBuffer)
+	b.subs[c] = true
+
+	return c
+}
+
+func (b *quakeBroker) unsubscribe(c chan streamQuake) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.subs[c] {
+		delete(b.subs, c)
+		close(c)
+	}
+}
+
+// publish sends s to all clients without blocking.  Clients that are not keeping up are unsubscribed.
+func (b *quakeBroker) publish(s streamQuake) {
+	b.Lock()
+	defer b.Unlock()
+
+	for c := range b.subs {
+		select {
+		case c <- s:
+		default:
+			delete(b.subs, c)
+			close(c)
+		}
+	}
+}
+
+func (b *quakeBroker) len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return len(b.subs)
+}
+
+/*
+listenQuakes publishes quakes to b as they change in haz.quake.  It also invalidates the response
+cache (cache.go) for each change and when the listener reconnects.  It doesn't return.
+*/
+func listenQuakes(b *quakeBroker) {
+	l, err := database.ListenQuakes(func(err error) {
+		log.Printf("WARN: quake stream listener: %s", err)
+	})
+	if err != nil {
+		log.Printf("ERROR: quake stream listener: %s", err)
+		return
+	}
+
+	for {
+		select {
+		case n := <-l.Notify:
+			responses.invalidate()
+
+			if n == nil {
+				log.Print("WARN: quake stream listener reconnected, updates may have been missed")
+				continue
+			}
+
+			s, err := scanStreamQuake(db.QueryRow(`SELECT `+quakeStreamColumns+
+				` FROM haz.quake WHERE publicid = $1 AND status != 'duplicate'`, n.Extra))
+			switch err {
+			case nil:
+				b.publish(s)
+			case sql.ErrNoRows:
+			default:
+				log.Printf("WARN: quake stream reading %s: %s", n.Extra, err)
+			}
+		case <-time.After(time.Duration(90) * time.Second):
+			go l.Ping()
+		}
+	}
+}
+
+// quakeSender sends quakes to a stream client.
+type quakeSender interface {
+	quake(s streamQuake) error
+	heartbeat(t time.Time) error
+	done() <-chan struct{}
+}
+
+type connKey struct{}
+
+// connContext adds the client connection to the request context so SSE writes can have a deadline.
+// Use it as the http.Server ConnContext.
+func connContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, c)
+}
+
+// sseSender sends Server-Sent Events.  Protobufs are base64 encoded.
+type sseSender struct {
+	w        http.ResponseWriter
+	f        http.Flusher
+	r        *http.Request
+	conn     net.Conn // the client connection from connContext.  Nil if there is none.
+	protobuf bool
+}
+
+func (s sseSender) quake(q streamQuake) error {
+	b, err := q.encode(s.protobuf)
+	if err != nil {
+		return err
+	}
+
+	d := string(b)
+	if s.protobuf {
+		d = base64.StdEncoding.EncodeToString(b)
+	}
+
+	return s.write(fmt.Sprintf("id: %s\nevent: quake\ndata: %s\n\n", q.id, d))
+}
+
+func (s sseSender) heartbeat(t time.Time) error {
+	return s.write(fmt.Sprintf("event: heartbeat\ndata: %s\n\n", t.UTC().Format(time.RFC3339)))
+}
+
+/*
+write sends m to the client.  If the connection is known the write (and flush) must complete within
+streamWriteTimeout, after that the write fails and the handler returns.
+*/
+func (s sseSender) write(m string) error {
+	if s.conn != nil {
+		s.conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	}
+
+	if _, err := s.w.Write([]byte(m)); err != nil {
+		return err
+	}
+
+	s.f.Flush()
+	return nil
+}
+
+func (s sseSender) done() <-chan struct{} {
+	return s.r.Context().Done()
+}
+
+// wsSender sends GeoJSON as text messages or protobufs as binary messages.  Heartbeats are pings.
+type wsSender struct {
+	c        *wsConn
+	protobuf bool
+}
+
+func (s wsSender) quake(q streamQuake) error {
+	b, err := q.encode(s.protobuf)
+	if err != nil {
+		return err
+	}
+
+	if s.protobuf {
+		return s.c.write(websocket.BinaryMessage, b)
+	}
+
+	return s.c.write(websocket.TextMessage, b)
+}
+
+func (s wsSender) heartbeat(t time.Time) error {
+	return s.c.ping([]byte(t.UTC().Format(time.RFC3339)))
+}
+
+func (s wsSender) done() <-chan struct{} {
+	return s.c.Done()
+}
+
+/*
+quakeStream streams new and updated quakes that match the MMI and bbox query parameters.  A WebSocket is used
+for upgrade requests, otherwise Server-Sent Events.  The format is GeoJSON or protobuf from the format
+query parameter or the Accept header.  Clients resume after the Last-Event-ID header or the lastEventID query
+parameter.  Not useful in app metrics so weft not used.
+*/
+func quakeStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if res := weft.CheckQuery(r, []string{"MMI"}, []string{"bbox", "format", "lastEventID"}); !res.Ok {
+		streamError(w, r, res)
+		return
+	}
+
+	p, err := getQuakePage(r)
+	if err != nil {
+		streamError(w, r, weft.BadRequest(err.Error()))
+		return
+	}
+
+	var pb bool
+
+	switch r.URL.Query().Get("format") {
+	case "":
+		pb = r.Header.Get("Accept") == protobuf
+	case "geojson":
+	case "protobuf":
+		pb = true
+	default:
+		streamError(w, r, weft.BadRequest("Invalid query parameter format"))
+		return
+	}
+
+	var last *streamID
+
+	id := r.Header.Get("Last-Event-ID")
+	if id == "" {
+		id = r.URL.Query().Get("lastEventID")
+	}
+	if id != "" {
+		i, err := parseStreamID(id)
+		if err != nil {
+			streamError(w, r, weft.BadRequest(err.Error()))
+			return
+		}
+		i = replayFrom(i, time.Now().UTC())
+		last = &i
+	}
+
+	var s quakeSender
+
+	if isWebSocket(r) {
+		c, err := wsUpgrade(w, r)
+		if err != nil {
+			log.Printf("WARN: quake stream: %s", err)
+			return
+		}
+		defer c.Close()
+
+		s = wsSender{c: c, protobuf: pb}
+	} else {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			streamError(w, r, weft.InternalServerError(fmt.Errorf("streaming not supported")))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+
+		x := sseSender{w: w, f: f, r: r, protobuf: pb}
+		if c, ok := r.Context().Value(connKey{}).(net.Conn); ok {
+			x.conn = c
+			// the connection can be reused after the stream.
+			defer c.SetWriteDeadline(time.Time{})
+		}
+
+		if err = x.write(fmt.Sprintf("retry: %d\n\n", streamRetry)); err != nil {
+			return
+		}
+
+		s = x
+	}
+
+	// subscribe before resuming so there is no gap.
+	c := broker.subscribe()
+	defer broker.unsubscribe(c)
+
+	seen := make(map[streamID]bool)
+
+	if last != nil {
+		if err = resumeStream(s, p, *last, seen); err != nil {
+			log.Printf("WARN: quake stream resuming after %s: %s", last, err)
+			return
+		}
+	}
+
+	t := time.NewTicker(streamHeartbeat)
+	defer t.Stop()
+
+	for {
+		select {
+		case q, ok := <-c:
+			if !ok {
+				// not keeping up, the client can resume from the last event.
+				return
+			}
+			if seen[q.id] || !p.match(q) {
+				continue
+			}
+			if err = s.quake(q); err != nil {
+				return
+			}
+		case now := <-t.C:
+			if err = s.heartbeat(now); err != nil {
+				return
+			}
+			pruneSeen(seen, now)
+		case <-s.done():
+			return
+		}
+	}
+}
+
+/*
+resumeStream sends the quakes from haz.quakehistory matching p that changed after last, oldest change first.
+The quakes sent are added to seen.
+*/
+func resumeStream(s quakeSender, p quakePage, last streamID, seen map[streamID]bool) error {
+	for {
+		w, a := p.where()
+		a = append(a, last.unixMicro, last.publicID)
+
+		rows, err := db.Query(fmt.Sprintf(`SELECT %s FROM haz.quakehistory%s AND (modificationtimeunixmicro, publicid) > ($%d, $%d)
+			ORDER BY modificationtimeunixmicro, publicid LIMIT %d`, quakeStreamColumns, w, len(a)-1, len(a), streamResumePage), a...)
+		if err != nil {
+			return err
+		}
+
+		// read the page before sending so a slow client doesn't hold a DB connection.
+		var q []streamQuake
+
+		for rows.Next() {
+			k, err := scanStreamQuake(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			q = append(q, k)
+		}
+		rows.Close()
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, k := range q {
+			if err = s.quake(k); err != nil {
+				return err
+			}
+			seen[k.id] = true
+			last = k.id
+		}
+
+		if len(q) < streamResumePage {
+			return nil
+		}
+	}
+}
+
+// replayFrom returns last or, if it is older than streamReplay before now, the start of the replay window.
+func replayFrom(last streamID, now time.Time) streamID {
+	if from := now.Add(-streamReplay).UnixMicro(); last.unixMicro < from {
+		return streamID{unixMicro: from}
+	}
+
+	return last
+}
+
+// pruneSeen removes quakes older than the replay window from seen.  They can't be sent again by the broker.
+func pruneSeen(seen map[streamID]bool, now time.Time) {
+	from := now.Add(-streamReplay).UnixMicro()
+
+	for k := range seen {
+		if k.unixMicro < from {
+			delete(seen, k)
+		}
+	}
+}
+
+func streamError(w http.ResponseWriter, r *http.Request, res *weft.Result) {
+	w.Header().Set("Content-Type", ErrContent)
+	weft.Write(w, r, res)
+}
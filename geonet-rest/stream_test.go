@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/GeoNet/haz"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var streamTest = streamQuake{
+	id:   streamID{publicID: "2016p858000", unixMicro: 1479035200123456},
+	inNZ: true,
+	quake: haz.Quake{
+		PublicID:         "2016p858000",
+		Time:             &haz.Timestamp{Sec: 1479034922, Nsec: 456000000},
+		ModificationTime: &haz.Timestamp{Sec: 1479035200, Nsec: 123456000},
+		Latitude:         -42.69,
+		Longitude:        173.02,
+		Depth:            15.1,
+		Magnitude:        7.8,
+		Locality:         "15 km north-east of Culverden",
+		Mmi:              8,
+		Quality:          "best",
+	},
+}
+
+func TestStreamID(t *testing.T) {
+	i, err := parseStreamID(streamTest.id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if i != streamTest.id {
+		t.Errorf("expected %v got %v", streamTest.id, i)
+	}
+
+	for _, v := range []string{"", "2016p858000", ".1479035200123456", "2016P858000.1", "2016p858000.x"} {
+		if _, err = parseStreamID(v); err == nil {
+			t.Errorf("%s: expected error", v)
+		}
+	}
+}
+
+func TestStreamMatch(t *testing.T) {
+	out := streamTest
+	out.inNZ = false
+
+	in := []struct {
+		id    string
+		p     quakePage
+		s     streamQuake
+		match bool
+	}{
+		{id: "all", p: quakePage{mmi: -9}, s: streamTest, match: true},
+		{id: "mmi", p: quakePage{mmi: 8}, s: streamTest, match: true},
+		{id: "mmi above", p: quakePage{mmi: 9}, s: streamTest},
+		{id: "not nz", p: quakePage{mmi: -9}, s: out},
+		{id: "bbox", p: quakePage{mmi: -9, bbox: []float64{172, -43, 174, -42}}, s: streamTest, match: true},
+		{id: "bbox outside", p: quakePage{mmi: -9, bbox: []float64{174, -43, 176, -42}}, s: streamTest},
+		{id: "bbox anti-meridian", p: quakePage{mmi: -9, bbox: []float64{165, -48, -175, -34}}, s: streamTest, match: true},
+		{id: "bbox anti-meridian outside", p: quakePage{mmi: -9, bbox: []float64{175, -48, -175, -34}}, s: streamTest},
+	}
+
+	for _, v := range in {
+		if v.p.match(v.s) != v.match {
+			t.Errorf("%s: expected match %t", v.id, v.match)
+		}
+	}
+}
+
+// waitSubscribed waits for n stream clients.
+func waitSubscribed(t *testing.T, n int) {
+	for i := 0; i < 100; i++ {
+		if broker.len() == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected %d stream clients got %d", n, broker.len())
+}
+
+// readEvent reads an SSE event as a map of field to value.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	e := make(map[string]string)
+
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l = strings.TrimSuffix(l, "\n")
+		if l == "" {
+			return e
+		}
+
+		p := strings.SplitN(l, ": ", 2)
+		if len(p) == 2 {
+			e[p[0]] = p[1]
+		}
+	}
+}
+
+func TestQuakeStreamSSE(t *testing.T) {
+	h := streamHeartbeat
+	streamHeartbeat = 50 * time.Millisecond
+	defer func() { streamHeartbeat = h }()
+
+	ts := httptest.NewServer(handler())
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/quake/stream?MMI=3&bbox=165,-48,-175,-34")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+
+	r := bufio.NewReader(res.Body)
+
+	if e := readEvent(t, r); e["retry"] != "5000" {
+		t.Errorf("expected retry got %v", e)
+	}
+
+	waitSubscribed(t, 1)
+
+	small := streamTest
+	small.quake.Mmi = 2
+	small.id.publicID = "2016p000001"
+
+	broker.publish(small) // filtered by MMI.
+	broker.publish(streamTest)
+
+	e := readEvent(t, r)
+	for e["event"] == "heartbeat" {
+		e = readEvent(t, r)
+	}
+
+	if e["event"] != "quake" || e["id"] != "2016p858000.1479035200123456" {
+		t.Fatalf("unexpected event %v", e)
+	}
+
+	var f streamFeature
+
+	if err = json.Unmarshal([]byte(e["data"]), &f); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.ID != e["id"] || f.Properties.PublicID != "2016p858000" || f.Properties.Time != "2016-11-13T11:02:02.456Z" ||
+		f.Properties.MMI != 8 || f.Geometry.Coordinates[0] != 173.02 || f.Geometry.Coordinates[1] != -42.69 {
+		t.Errorf("unexpected feature %+v", f)
+	}
+
+	e = readEvent(t, r)
+	for e["event"] == "quake" {
+		e = readEvent(t, r)
+	}
+	if e["event"] != "heartbeat" {
+		t.Errorf("expected a heartbeat got %v", e)
+	}
+
+	res.Body.Close()
+	waitSubscribed(t, 0)
+}
+
+// TestQuakeStreamSSEWriteTimeout checks the handler for an SSE client that stops reading returns
+// once a write takes longer than streamWriteTimeout.
+func TestQuakeStreamSSEWriteTimeout(t *testing.T) {
+	h, w := streamHeartbeat, streamWriteTimeout
+	streamHeartbeat = time.Millisecond
+	streamWriteTimeout = 100 * time.Millisecond
+	defer func() { streamHeartbeat, streamWriteTimeout = h, w }()
+
+	ts := httptest.NewUnstartedServer(handler())
+	ts.Config.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		// small buffers so the heartbeats soon fill them.
+		c.(*net.TCPConn).SetWriteBuffer(1024)
+		return connContext(ctx, c)
+	}
+	ts.Start()
+	defer ts.Close()
+
+	c, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.(*net.TCPConn).SetReadBuffer(1024)
+
+	if _, err = c.Write([]byte("GET /quake/stream?MMI=3 HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	waitSubscribed(t, 1)
+
+	// the client never reads.
+	for i := 0; i < 1000 && broker.len() != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if broker.len() != 0 {
+		t.Error("expected the stalled client to be disconnected")
+	}
+}
+
+func TestQuakeStreamSSEProto(t *testing.T) {
+	ts := httptest.NewServer(handler())
+	defer ts.Close()
+
+	req, err := http.NewRequest("GET", ts.URL+"/quake/stream?MMI=-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", protobuf)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	r := bufio.NewReader(res.Body)
+	readEvent(t, r)
+
+	waitSubscribed(t, 1)
+	broker.publish(streamTest)
+
+	e := readEvent(t, r)
+
+	b, err := base64.StdEncoding.DecodeString(e["data"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var q haz.Quake
+
+	if err = proto.Unmarshal(b, &q); err != nil {
+		t.Fatal(err)
+	}
+
+	if q.PublicID != "2016p858000" || q.Mmi != 8 {
+		t.Errorf("unexpected quake %+v", q)
+	}
+
+	res.Body.Close()
+	waitSubscribed(t, 0)
+}
+
+func TestQuakeStreamBadRequest(t *testing.T) {
+	ts := httptest.NewServer(handler())
+	defer ts.Close()
+
+	for _, v := range []string{
+		"/quake/stream",
+		"/quake/stream?MMI=9",
+		"/quake/stream?MMI=3&bbox=1,2",
+		"/quake/stream?MMI=3&format=xml",
+		"/quake/stream?MMI=3&lastEventID=bad",
+		"/quake/stream?MMI=3&limit=10",
+	} {
+		res, err := http.Get(ts.URL + v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400 got %d", v, res.StatusCode)
+		}
+	}
+}
+
+func TestQuakeStreamWebSocket(t *testing.T) {
+	ts := httptest.NewServer(handler())
+	defer ts.Close()
+
+	c, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/quake/stream?MMI=3&format=protobuf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected handshake %d %v", res.StatusCode, res.Header)
+	}
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	waitSubscribed(t, 1)
+	broker.publish(streamTest)
+
+	op, p, err := c.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if op != websocket.BinaryMessage {
+		t.Fatalf("expected a binary message got %d", op)
+	}
+
+	var q haz.Quake
+
+	if err = proto.Unmarshal(p, &q); err != nil {
+		t.Fatal(err)
+	}
+
+	if q.PublicID != "2016p858000" {
+		t.Errorf("unexpected quake %+v", q)
+	}
+
+	pong := make(chan string, 1)
+	c.SetPongHandler(func(s string) error {
+		pong <- s
+		return nil
+	})
+
+	if err = c.WriteControl(websocket.PingMessage, []byte("hello"), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the pong is handled while reading, the close is returned as an error.
+	if _, _, err = c.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("expected close got %v", err)
+	}
+
+	select {
+	case s := <-pong:
+		if s != "hello" {
+			t.Errorf("expected pong hello got %s", s)
+		}
+	default:
+		t.Error("expected a pong")
+	}
+
+	waitSubscribed(t, 0)
+}
+
+func TestStreamReplay(t *testing.T) {
+	now := time.Date(2016, 11, 14, 0, 0, 0, 0, time.UTC)
+	from := now.Add(-streamReplay).UnixMicro()
+
+	recent := streamID{publicID: "2016p858000", unixMicro: now.Add(-time.Hour).UnixMicro()}
+	old := streamID{publicID: "2016p000001", unixMicro: now.Add(-streamReplay - time.Second).UnixMicro()}
+
+	if r := replayFrom(recent, now); r != recent {
+		t.Errorf("expected %s got %s", recent, r)
+	}
+
+	if r := replayFrom(old, now); r.unixMicro != from || r.publicID != "" {
+		t.Errorf("expected the start of the replay window got %s", r)
+	}
+
+	seen := map[streamID]bool{recent: true, old: true}
+
+	pruneSeen(seen, now)
+
+	if len(seen) != 1 || !seen[recent] {
+		t.Errorf("expected only the recent quake in seen got %v", seen)
+	}
+}
+
+func TestQuakeStreamSlowClient(t *testing.T) {
+	c := broker.subscribe()
+
+	for i := 0; i <= streamBuffer; i++ {
+		broker.publish(streamTest)
+	}
+
+	broker.Lock()
+	ok := broker.subs[c]
+	broker.Unlock()
+
+	if ok {
+		t.Error("expected the slow client to be unsubscribed")
+	}
+
+	var n int
+	for range c {
+		n++
+	}
+
+	if n != streamBuffer {
+		t.Errorf("expected %d buffered quakes got %d", streamBuffer, n)
+	}
+
+	broker.unsubscribe(c) // safe after the broker has closed c.
+}
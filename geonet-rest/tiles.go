@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/GeoNet/weft"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Mapbox Vector Tiles for quakes and reported intensity.  Tiles are /tiles/(layer)/{z}/{x}/{y}.mvt in
+the web mercator (EPSG:3857) tile scheme and are made with PostGIS ST_AsMVT.  PostGIS 2.5 doesn't
+have ST_TileEnvelope so the tile bounds are found here.
+
+Quake tiles have the same query as /quake without the paging and are limited to the largest quakeTileLimit
+quakes.  Reported intensity is aggregated to the geohash5 or geohash6 precomputed in impact.intensity_reported
+depending on the zoom.
+
+Features are selected from the tile plus the buffer so symbols at the tile edges aren't cut off.
+*/
+
+const (
+	mvt            = "application/vnd.mapbox-vector-tile"
+	tileMaxZoom    = 18
+	mercatorExtent = 20037508.342789244 // half the width of the web mercator projection in metres.
+	tileExtent     = 4096               // the tile size in MVT units.
+	tileBuffer     = 64                 // the buffer around the tile in MVT units.  Used in the tile SQL.
+	quakeTileLimit = 1000
+)
+
+// geohash6Zoom is the zoom level that reported intensity is aggregated to geohash6 at.  Below this geohash5 is used.
+const geohash6Zoom = 10
+
+// The default and the longest time window for reported intensity tiles.
+const (
+	intensityTileWindow    = time.Duration(60 * time.Minute)
+	intensityTileWindowMax = time.Duration(7 * 24 * time.Hour)
+)
+
+// quakeTileOptional are the optional query parameters for quake tiles.
+var quakeTileOptional = []string{"startTime", "endTime", "minMagnitude", "maxMagnitude", "modifiedSince"}
+
+type tile struct {
+	z, x, y int
+}
+
+/*
+parseTile parses the tile from a path of the form prefix{z}/{x}/{y}.mvt
+*/
+func parseTile(path, prefix string) (tile, error) {
+	var t tile
+
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, ".mvt") {
+		return t, fmt.Errorf("invalid tile path %s", path)
+	}
+
+	p := strings.Split(strings.TrimSuffix(strings.TrimPrefix(path, prefix), ".mvt"), "/")
+	if len(p) != 3 {
+		return t, fmt.Errorf("invalid tile path %s", path)
+	}
+
+	var err error
+
+	for i, v := range []*int{&t.z, &t.x, &t.y} {
+		if *v, err = strconv.Atoi(p[i]); err != nil {
+			return t, fmt.Errorf("invalid tile path %s", path)
+		}
+	}
+
+	if t.z < 0 || t.z > tileMaxZoom {
+		return t, fmt.Errorf("invalid tile zoom %d, the maximum is %d", t.z, tileMaxZoom)
+	}
+
+	if n := 1 << uint(t.z); t.x < 0 || t.x >= n || t.y < 0 || t.y >= n {
+		return t, fmt.Errorf("invalid tile %d/%d/%d", t.z, t.x, t.y)
+	}
+
+	return t, nil
+}
+
+// mercator returns the tile bounds in web mercator; minX, minY, maxX, maxY.
+func (t tile) mercator() []float64 {
+	s := 2 * mercatorExtent / float64(int(1)<<uint(t.z))
+
+	x := -mercatorExtent + float64(t.x)*s
+	y := mercatorExtent - float64(t.y)*s
+
+	return []float64{x, y - s, x + s, y}
+}
+
+// bbox returns the tile bounds in degrees; minLon, minLat, maxLon, maxLat.
+func (t tile) bbox() []float64 {
+	n := float64(int(1) << uint(t.z))
+
+	lon := func(x int) float64 {
+		return float64(x)/n*360.0 - 180.0
+	}
+
+	lat := func(y int) float64 {
+		return math.Atan(math.Sinh(math.Pi*(1-2*float64(y)/n))) * 180.0 / math.Pi
+	}
+
+	return []float64{lon(t.x), lat(t.y + 1), lon(t.x + 1), lat(t.y)}
+}
+
+/*
+bufferedBBox returns the tile bounds plus the buffer in degrees; minLon, minLat, maxLon, maxLat.  Longitude
+is clamped to -180..180.
+*/
+func (t tile) bufferedBBox() []float64 {
+	n := float64(int(1) << uint(t.z))
+	f := float64(tileBuffer) / float64(tileExtent)
+
+	lon := func(x float64) float64 {
+		return math.Max(-180.0, math.Min(180.0, x/n*360.0-180.0))
+	}
+
+	lat := func(y float64) float64 {
+		return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180.0 / math.Pi
+	}
+
+	x, y := float64(t.x), float64(t.y)
+
+	return []float64{lon(x - f), lat(y + 1 + f), lon(x + 1 + f), lat(y - f)}
+}
+
+/*
+tileMaxAge sets the Surrogate-Control for a tile with data up to end.  Tiles for a time window that
+has finished change less often.  The zero time is a tile that includes the latest data.
+*/
+func tileMaxAge(h http.Header, end time.Time) {
+	if !end.IsZero() && end.Before(time.Now()) {
+		h.Set("Surrogate-Control", maxAge300)
+	}
+}
+
+func quakeTile(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	if res := weft.CheckQuery(r, []string{"MMI"}, quakeTileOptional); !res.Ok {
+		return res
+	}
+
+	t, err := parseTile(r.URL.Path, "/tiles/quake/")
+	if err != nil {
+		return weft.BadRequest(err.Error())
+	}
+
+	q, err := getQuakePage(r)
+	if err != nil {
+		return weft.BadRequest(err.Error())
+	}
+
+	q.bbox = t.bufferedBBox()
+
+	w, a := q.where()
+	m := t.mercator()
+
+	s := fmt.Sprintf(quakeTileSQL, len(a)+1, len(a)+2, len(a)+3, len(a)+4, w, len(a)+5)
+	a = append(a, m[0], m[1], m[2], m[3], quakeTileLimit)
+
+	var d []byte
+
+	if err = db.QueryRow(s, a...).Scan(&d); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	b.Write(d)
+	h.Set("Content-Type", mvt)
+	tileMaxAge(h, q.end)
+
+	return contentResult(r, h, b, time.Time{})
+}
+
+func intensityTile(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	if res := weft.CheckQuery(r, []string{}, []string{"startTime", "endTime"}); !res.Ok {
+		return res
+	}
+
+	t, err := parseTile(r.URL.Path, "/tiles/intensity/")
+	if err != nil {
+		return weft.BadRequest(err.Error())
+	}
+
+	start, end, latest, err := getTileWindow(r)
+	if err != nil {
+		return weft.BadRequest(err.Error())
+	}
+
+	geohash := "geohash5"
+	if t.z >= geohash6Zoom {
+		geohash = "geohash6"
+	}
+
+	bb := t.bufferedBBox()
+	m := t.mercator()
+
+	var d []byte
+
+	if err = db.QueryRow(fmt.Sprintf(intensityTileSQL, geohash), start, end,
+		bb[0], bb[1], bb[2], bb[3], m[0], m[1], m[2], m[3]).Scan(&d); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
+	if latest {
+		end = time.Time{}
+	}
+
+	b.Write(d)
+	h.Set("Content-Type", mvt)
+	tileMaxAge(h, end)
+
+	return contentResult(r, h, b, time.Time{})
+}
+
+/*
+getTileWindow returns the time window for reported intensity from the startTime and endTime query
+parameters.  latest is true if endTime isn't set and the window is up to now.  The default window is
+the intensityTileWindow before the end.
+*/
+func getTileWindow(r *http.Request) (start, end time.Time, latest bool, err error) {
+	v := r.URL.Query()
+
+	end = time.Now().UTC()
+	latest = true
+
+	if s := v.Get("endTime"); s != "" {
+		if end, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return start, end, latest, fmt.Errorf("Invalid query parameter endTime")
+		}
+		latest = false
+	}
+
+	start = end.Add(-intensityTileWindow)
+
+	if s := v.Get("startTime"); s != "" {
+		if start, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return start, end, latest, fmt.Errorf("Invalid query parameter startTime")
+		}
+	}
+
+	if !start.Before(end) {
+		return start, end, latest, fmt.Errorf("Invalid query parameters startTime must be before endTime")
+	}
+
+	if end.Sub(start) > intensityTileWindowMax {
+		return start, end, latest, fmt.Errorf("Invalid query parameters the time window must be no more than %s", intensityTileWindowMax)
+	}
+
+	return start, end, latest, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	wt "github.com/GeoNet/weft/wefttest"
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTile(t *testing.T) {
+	in := []struct {
+		path string
+		t    tile
+		err  bool
+	}{
+		{path: "/tiles/quake/0/0/0.mvt", t: tile{}},
+		{path: "/tiles/quake/5/31/20.mvt", t: tile{z: 5, x: 31, y: 20}},
+		{path: "/tiles/quake/18/262143/262143.mvt", t: tile{z: 18, x: 262143, y: 262143}},
+		{path: "/tiles/quake/5/31/20", err: true},
+		{path: "/tiles/quake/5/31.mvt", err: true},
+		{path: "/tiles/quake/5/31/20/1.mvt", err: true},
+		{path: "/tiles/quake/a/31/20.mvt", err: true},
+		{path: "/tiles/quake/19/0/0.mvt", err: true},
+		{path: "/tiles/quake/-1/0/0.mvt", err: true},
+		{path: "/tiles/quake/5/32/20.mvt", err: true},
+		{path: "/tiles/quake/5/31/-1.mvt", err: true},
+		{path: "/tiles/intensity/5/31/20.mvt", err: true},
+	}
+
+	for _, v := range in {
+		p, err := parseTile(v.path, "/tiles/quake/")
+
+		if (err != nil) != v.err {
+			t.Errorf("%s: expected error %t got %v", v.path, v.err, err)
+			continue
+		}
+
+		if !v.err && p != v.t {
+			t.Errorf("%s: expected %v got %v", v.path, v.t, p)
+		}
+	}
+}
+
+func TestTileBounds(t *testing.T) {
+	m := tile{}.mercator()
+	if m[0] != -mercatorExtent || m[1] != -mercatorExtent || m[2] != mercatorExtent || m[3] != mercatorExtent {
+		t.Errorf("unexpected mercator bounds for 0/0/0 %v", m)
+	}
+
+	b := tile{}.bbox()
+	if b[0] != -180 || b[2] != 180 || math.Abs(b[1]+85.0511) > 0.0001 || math.Abs(b[3]-85.0511) > 0.0001 {
+		t.Errorf("unexpected bbox for 0/0/0 %v", b)
+	}
+
+	// 2013p407387 is at 172.94479, -43.359699
+	b = tile{z: 5, x: 31, y: 20}.bbox()
+	if b[0] > 172.94479 || b[2] < 172.94479 || b[1] > -43.359699 || b[3] < -43.359699 {
+		t.Errorf("expected 5/31/20 to contain 2013p407387 got %v", b)
+	}
+
+	// the buffered bbox contains the tile plus 64/4096 of a tile on each side.
+	b = tile{z: 5, x: 30, y: 20}.bbox()
+	bb := tile{z: 5, x: 30, y: 20}.bufferedBBox()
+	w := (b[2] - b[0]) * tileBuffer / tileExtent
+	if math.Abs(b[0]-w-bb[0]) > 1e-9 || math.Abs(b[2]+w-bb[2]) > 1e-9 || bb[1] >= b[1] || bb[3] <= b[3] {
+		t.Errorf("unexpected buffered bbox for 5/30/20 %v tile %v", bb, b)
+	}
+
+	if bb = (tile{}).bufferedBBox(); bb[0] != -180 || bb[2] != 180 {
+		t.Errorf("expected the buffered bbox longitude clamped for 0/0/0 got %v", bb)
+	}
+
+	// adjacent tiles share edges.
+	a := tile{z: 5, x: 30, y: 19}.mercator()
+	m = tile{z: 5, x: 31, y: 20}.mercator()
+	if math.Abs(a[2]-m[0]) > 1e-6 || math.Abs(a[1]-m[3]) > 1e-6 {
+		t.Errorf("expected adjacent tiles to share edges got %v %v", a, m)
+	}
+}
+
+func TestTileWindow(t *testing.T) {
+	in := []struct {
+		url    string
+		window time.Duration
+		latest bool
+		err    bool
+	}{
+		{url: "/tiles/intensity/5/31/20.mvt", window: intensityTileWindow, latest: true},
+		{url: "/tiles/intensity/5/31/20.mvt?endTime=2016-11-13T12:00:00Z", window: intensityTileWindow},
+		{url: "/tiles/intensity/5/31/20.mvt?startTime=2016-11-13T11:00:00Z&endTime=2016-11-14T11:00:00Z", window: 24 * time.Hour},
+		{url: "/tiles/intensity/5/31/20.mvt?startTime=2016-11-13T11:00:00Z&endTime=2016-11-13T11:00:00Z", err: true},
+		{url: "/tiles/intensity/5/31/20.mvt?startTime=2016-11-01T11:00:00Z&endTime=2016-11-14T11:00:00Z", err: true},
+		{url: "/tiles/intensity/5/31/20.mvt?endTime=yesterday", err: true},
+	}
+
+	for _, v := range in {
+		start, end, latest, err := getTileWindow(httptest.NewRequest("GET", v.url, nil))
+
+		if (err != nil) != v.err {
+			t.Errorf("%s: expected error %t got %v", v.url, v.err, err)
+			continue
+		}
+
+		if !v.err && (end.Sub(start) != v.window || latest != v.latest) {
+			t.Errorf("%s: expected window %s latest %t got %s %t", v.url, v.window, v.latest, end.Sub(start), latest)
+		}
+	}
+}
+
+func TestTiles(t *testing.T) {
+	setup()
+	defer teardown()
+
+	for _, v := range []struct {
+		url, layer string
+		empty      bool
+	}{
+		{url: "/tiles/quake/5/31/20.mvt?MMI=3", layer: "quake"},
+		{url: "/tiles/quake/0/0/0.mvt?MMI=-1", layer: "quake"},
+		{url: "/tiles/quake/5/0/0.mvt?MMI=3", empty: true},
+		// the reported intensity test data is at 176.489868, -40.201721.
+		{url: "/tiles/intensity/5/31/19.mvt", layer: "intensity"},
+		{url: "/tiles/intensity/12/4056/2548.mvt", layer: "intensity"},
+		{url: "/tiles/intensity/5/31/20.mvt", empty: true},
+	} {
+		b, err := wt.Request{Accept: mvt, URL: v.url}.Do(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.empty {
+			if len(b) != 0 {
+				t.Errorf("%s: expected an empty tile got %d bytes", v.url, len(b))
+			}
+			continue
+		}
+
+		// the layer name is a string in the protobuf.
+		if !bytes.Contains(b, []byte(v.layer)) {
+			t.Errorf("%s: expected the %s layer", v.url, v.layer)
+		}
+	}
+}
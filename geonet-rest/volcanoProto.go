@@ -7,6 +7,7 @@ import (
 	"github.com/GeoNet/weft"
 	"github.com/golang/protobuf/proto"
 	"net/http"
+	"time"
 )
 
 func valProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
@@ -16,6 +17,11 @@ func valProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 
 	var err error
 	var rows *sql.Rows
+	var mt time.Time
+
+	if mt, err = queryModified(valModifiedSQL); err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
 
 	if rows, err = db.Query(`SELECT id, title, alert_level, activity, hazards,
 				ST_X(location::geometry), ST_Y(location::geometry)
@@ -45,5 +51,5 @@ func valProto(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 	b.Write(by)
 
 	h.Set("Content-Type", protobuf)
-	return &weft.StatusOK
+	return contentResult(r, h, b, mt)
 }
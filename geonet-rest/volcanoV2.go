@@ -11,9 +11,14 @@ func valV2(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 		return res
 	}
 
+	mt, err := queryModified(valModifiedSQL)
+	if err != nil {
+		return weft.ServiceUnavailableError(err)
+	}
+
 	var d string
 
-	err := db.QueryRow(`SELECT row_to_json(fc)
+	err = db.QueryRow(`SELECT row_to_json(fc)
                          FROM ( SELECT 'FeatureCollection' as type, array_to_json(array_agg(f)) as features
                          FROM (SELECT 'Feature' as type,
                          ST_AsGeoJSON(v.location)::json as geometry,
@@ -33,7 +38,7 @@ func valV2(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
 
 	b.WriteString(d)
 	h.Set("Content-Type", V2GeoJSON)
-	return &weft.StatusOK
+	return contentResult(r, h, b, mt)
 }
 
 
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/*
+WebSockets for the quake stream use github.com/gorilla/websocket.  The server only sends messages.
+Messages from the client are read and discarded, pings are answered, and a close from the client
+ends the connection.
+*/
+
+// wsMaxRead is the largest message read from a client.
+const wsMaxRead = 4096
+
+var wsUpgrader = websocket.Upgrader{
+	// the stream is public and read only so any origin can use it.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type wsConn struct {
+	conn *websocket.Conn
+	done chan struct{}
+	once sync.Once
+}
+
+// isWebSocket returns true if r is a WebSocket upgrade request.
+func isWebSocket(r *http.Request) bool {
+	return websocket.IsWebSocketUpgrade(r)
+}
+
+/*
+wsUpgrade completes the WebSocket handshake for r and starts reading from the client.  If the
+handshake fails an error response has been written to w.
+*/
+func wsUpgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetReadLimit(wsMaxRead)
+
+	c := &wsConn{conn: conn, done: make(chan struct{})}
+
+	go c.read()
+
+	return c, nil
+}
+
+// read reads messages from the client until the connection is closed.  Pings and closes
+// are answered by the websocket package.
+func (c *wsConn) read() {
+	defer c.Close()
+
+	for {
+		if _, _, err := c.conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+// write sends p to the client as a message of type t.  Only one goroutine may call write.
+func (c *wsConn) write(t int, p []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+
+	return c.conn.WriteMessage(t, p)
+}
+
+// ping sends a ping to the client.  It is safe to call at the same time as write.
+func (c *wsConn) ping(p []byte) error {
+	return c.conn.WriteControl(websocket.PingMessage, p, time.Now().Add(streamWriteTimeout))
+}
+
+// Done is closed when the connection is closed.
+func (c *wsConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the connection.  It is safe to call more than once.
+func (c *wsConn) Close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.done)
+		err = c.conn.Close()
+	})
+	return err
+}
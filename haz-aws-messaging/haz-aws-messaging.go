@@ -17,7 +17,9 @@ import (
 var queueNames = []string{
 	`haz-duty-consumer`,
 	`haz-eqnews-consumer`,
+	`haz-mqtt-consumer`,
 	`haz-pim-consumer`,
+	`haz-push-consumer`,
 	`haz-twitter-consumer`,
 	`haz-twitter-consumer-above4`,
 	`haz-twitter-consumer-above5`,
@@ -3,16 +3,21 @@
 package main
 
 import (
+	"fmt"
 	"github.com/GeoNet/haz/database"
 	"github.com/GeoNet/haz/msg"
 	"github.com/GeoNet/haz/sqs"
 	_ "github.com/lib/pq"
 	"log"
+	"os"
+	"strconv"
+	"time"
 )
 
 //go:generate configer haz-db-consumer.json
 var (
-	db database.DB
+	db    database.DB
+	store database.QuakeStore = &db
 )
 
 func init() {
@@ -26,6 +31,10 @@ type message struct {
 }
 
 func main() {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR: problem with INTENSITY_RULES: %s", err)
+	}
+
 	var err error
 
 	db, err = database.InitPG()
@@ -36,10 +45,76 @@ func main() {
 
 	db.Check()
 
+	if err = db.InitLocalities(); err != nil {
+		log.Fatalf("ERROR: problem with localities: %s", err)
+	}
+
+	r, every, err := retentionConfig()
+	if err != nil {
+		log.Fatalf("ERROR: retention config: %s", err)
+	}
+
+	if every > 0 {
+		go retain(r, every)
+	}
+
 	log.Println("starting message listener.")
 	listen()
 }
 
+/*
+retentionConfig reads the retention config from the env.  RETENTION_INTERVAL (a time.Duration,
+default 1h, 0 to disable), RETENTION_HISTORY_DAYS, RETENTION_QUAKEAPI_DAYS, and RETENTION_ARCHIVE_DIR.
+*/
+func retentionConfig() (database.Retention, time.Duration, error) {
+	r := database.RetentionDefault
+	every := time.Duration(1) * time.Hour
+
+	var err error
+
+	if s := os.Getenv("RETENTION_INTERVAL"); s != "" {
+		if every, err = time.ParseDuration(s); err != nil {
+			return r, 0, fmt.Errorf("RETENTION_INTERVAL: %s", err)
+		}
+	}
+
+	for _, v := range []struct {
+		env string
+		d   *int
+	}{
+		{"RETENTION_HISTORY_DAYS", &r.HistoryDays},
+		{"RETENTION_QUAKEAPI_DAYS", &r.QuakeAPIDays},
+	} {
+		if s := os.Getenv(v.env); s != "" {
+			if *v.d, err = strconv.Atoi(s); err != nil || *v.d < 1 {
+				return r, 0, fmt.Errorf("%s: invalid days %s", v.env, s)
+			}
+		}
+	}
+
+	r.ArchiveDir = os.Getenv("RETENTION_ARCHIVE_DIR")
+
+	return r, every, nil
+}
+
+// retain applies the retention r to the DB every interval.
+func retain(r database.Retention, every time.Duration) {
+	for {
+		res, err := db.Retain(r, time.Now().UTC())
+		switch {
+		case err != nil:
+			log.Printf("WARN retention: %s", err)
+		case res.Skipped:
+			log.Print("retention running elsewhere, skipped.")
+		default:
+			log.Printf("retention: created %v archived %v dropped %v deleted %d history and %d quakeapi rows.",
+				res.Created, res.Archived, res.Dropped, res.HistoryDeleted, res.QuakeAPIDeleted)
+		}
+
+		time.Sleep(every)
+	}
+}
+
 // listen for haz messages and saves them to the DB.
 func listen() {
 	rx, dx, err := sqs.InitRx()
@@ -66,10 +141,10 @@ func (m *message) Process() bool {
 		return false
 	case m.HeartBeat != nil:
 		m.HeartBeat.RxLog()
-		m.SetErr(db.SaveHeartBeat(*m.HeartBeat))
+		m.SetErr(store.SaveHeartBeat(*m.HeartBeat))
 	case m.Quake != nil:
 		m.Quake.RxLog()
-		m.SetErr(db.SaveQuake(*m.Quake))
+		m.SetErr(store.SaveQuake(*m.Quake))
 	}
 
 	// Block processing here if we can't contact the DB (the most likely source of
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
+	"testing"
+	"time"
+)
+
+func TestProcess(t *testing.T) {
+	m := database.NewMemStore()
+	store = m
+	defer func() { store = &db }()
+
+	now := time.Now().UTC()
+
+	q := msg.Quake{PublicID: "2016p000001", ModificationTime: now, Time: now, Magnitude: 4.0}
+	q1 := q
+	q1.ModificationTime = now.Add(-time.Minute)
+	q1.Magnitude = 3.0
+
+	in := []message{
+		{msg.Haz{Quake: &q}},
+		{msg.Haz{Quake: &q1}},
+		{msg.Haz{HeartBeat: &msg.HeartBeat{ServiceID: "test", SentTime: now}}},
+	}
+
+	for i := range in {
+		if in[i].Process() {
+			t.Errorf("%d: unexpected redelivery", i)
+		}
+	}
+
+	k, err := m.Quake("2016p000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Magnitude != 4.0 {
+		t.Errorf("older quake information saved over newer %+v", k)
+	}
+
+	h, err := m.QuakeHistory("2016p000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 {
+		t.Errorf("expected 2 history versions got %d", len(h))
+	}
+
+	hb, err := m.HeartBeats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hb) != 1 || hb[0].ServiceID != "test" {
+		t.Errorf("unexpected heart beats %+v", hb)
+	}
+}
@@ -0,0 +1,96 @@
+// haz-db-migrate applies or rolls back the schema migrations in database/migrations.
+//
+//	haz-db-migrate status      # list the applied and pending migrations.
+//	haz-db-migrate up          # apply all pending migrations.
+//	haz-db-migrate to 3        # apply or roll back to version 3.
+//	haz-db-migrate down        # roll back the latest migration.
+//
+// Connects using the DB_* env vars.  The DB user must own the schemas e.g., postgres.
+package main
+
+import (
+	"fmt"
+	"github.com/GeoNet/haz/database"
+	_ "github.com/lib/pq"
+	"log"
+	"os"
+	"strconv"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ms, err := database.Migrations()
+	if err != nil {
+		log.Fatalf("ERROR: reading migrations: %s", err)
+	}
+
+	db, err := database.InitPG()
+	if err != nil {
+		log.Fatalf("ERROR: problem with DB config: %s", err)
+	}
+	defer db.Close()
+
+	db.Check()
+
+	l := func(s string) { log.Print(s) }
+
+	switch os.Args[1] {
+	case "status":
+		status(&db, ms)
+		return
+	case "up":
+		err = db.Migrate(ms, database.Latest(ms), l)
+	case "down":
+		// the version to roll back to is found once the migration lock is held.
+		err = db.MigrateDown(ms, l)
+	case "to":
+		if len(os.Args) != 3 {
+			usage()
+		}
+		var target int
+		if target, err = strconv.Atoi(os.Args[2]); err != nil {
+			log.Fatalf("ERROR: invalid version %s", os.Args[2])
+		}
+		err = db.Migrate(ms, target, l)
+	default:
+		usage()
+	}
+
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+
+	v, err := db.SchemaVersion()
+	if err != nil {
+		log.Fatalf("ERROR: reading schema version: %s", err)
+	}
+
+	log.Printf("schema version %d", v)
+}
+
+func status(db *database.DB, ms []database.Migration) {
+	a, err := db.AppliedMigrations()
+	if err != nil {
+		log.Fatalf("ERROR: reading applied migrations: %s", err)
+	}
+
+	applied := make(map[int]string)
+	for _, v := range a {
+		applied[v.Version] = v.Applied
+		fmt.Printf("%04d %-30s applied %s\n", v.Version, v.Name, v.Applied)
+	}
+
+	for _, v := range ms {
+		if _, ok := applied[v.Version]; !ok {
+			fmt.Printf("%04d %-30s pending\n", v.Version, v.Name)
+		}
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: haz-db-migrate status | up | down | to <version>")
+	os.Exit(1)
+}
@@ -26,6 +26,10 @@ type message struct {
 }
 
 func main() {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR: problem with INTENSITY_RULES: %s", err)
+	}
+
 	var err error
 
 	db, err = database.InitPG()
@@ -34,6 +38,10 @@ func main() {
 	}
 	defer db.Close()
 
+	if err = db.InitLocalities(); err != nil {
+		log.Fatalf("ERROR: problem with localities: %s", err)
+	}
+
 	db.Check()
 
 	log.Println("starting message listener.")
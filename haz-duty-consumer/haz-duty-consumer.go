@@ -5,15 +5,25 @@ import (
 	"github.com/GeoNet/haz/pagerduty"
 	"github.com/GeoNet/haz/sqs"
 	"log"
+	"os"
+	"strings"
 )
 
 var (
 	idp = msg.IdpQuake{}
 	pd  *pagerduty.Client
+	// languages for the alert text from LANGUAGES e.g., en,mi
+	langs []msg.Lang
 )
 
 func init() {
 	pd = pagerduty.Init()
+
+	var err error
+	if langs, err = msg.ParseLangs(os.Getenv("LANGUAGES")); err != nil {
+		log.Fatalf("ERROR: LANGUAGES: %s", err)
+	}
+
 	sqs.MaxNumberOfMessages = 1
 	sqs.VisibilityTimeout = 600
 	sqs.WaitTimeSeconds = 20
@@ -24,6 +34,14 @@ type message struct {
 }
 
 func main() {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR - problem with INTENSITY_RULES: %s", err)
+	}
+
+	if err := msg.InitLocalities(); err != nil {
+		log.Fatalf("ERROR - problem with localities: %s", err)
+	}
+
 	rx, dx, err := sqs.InitRx()
 	if err != nil {
 		log.Fatalf("ERROR - problem creating SQS from config: %s", err)
@@ -55,7 +73,7 @@ func (m *message) Process() bool {
 			return false
 		}
 
-		alert, message := m.Quake.AlertDuty()
+		alert, message := alertText(m.Quake)
 		if alert {
 			log.Printf("Notifying the duty officer for quake %s", m.Quake.PublicID)
 			err := pd.Trigger(message, m.Quake.PublicID, 3)
@@ -70,3 +88,18 @@ func (m *message) Process() bool {
 
 	return false
 }
+
+// alertText returns the alert message in each of langs, one per line.
+func alertText(q *msg.Quake) (alert bool, message string) {
+	var m []string
+
+	for _, l := range langs {
+		a, t := q.AlertDutyIn(l)
+		if !a {
+			return
+		}
+		m = append(m, t)
+	}
+
+	return true, strings.Join(m, "\n")
+}
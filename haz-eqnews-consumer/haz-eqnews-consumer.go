@@ -1,32 +1,38 @@
+// haz-eqnews-consumer listens to an AWS SQS queue for Haz JSON messages and
+// emails felt quakes to the recipient lists whose thresholds they exceed.
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/GeoNet/haz/msg"
 	"github.com/GeoNet/haz/sqs"
+	"io/ioutil"
 	"log"
-	"net/smtp"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 var (
-	idp      = msg.IdpQuake{}
-	mailHost string
-	auth     smtp.Auth
-	smtpFrom = os.Getenv("SMTP_FROM")
-	smtpTo   = os.Getenv("SMTP_TO")
+	lists  []*recipients
+	tmpls  map[msg.Lang]*msg.EqNewsTemplate
+	sender *mailer
 )
 
+// recipients is an email list with its own alerting threshold.
+type recipients struct {
+	Name string
+	To   []string
+	Lang msg.Lang // the language for the email.  Empty for English.
+	msg.EqNewsThreshold
+	idp msg.IdpQuake
+}
+
 func init() {
 	sqs.MaxNumberOfMessages = 1
 	sqs.VisibilityTimeout = 600
 	sqs.WaitTimeSeconds = 20
-
-	mailHost = os.Getenv("SMTP_HOST") + ":" + os.Getenv("SMTP_PORT")
-
-	auth = smtp.PlainAuth("",
-		os.Getenv("SMTP_USER"),
-		os.Getenv("SMTP_PASSWORD"),
-		os.Getenv("SMTP_HOST"))
 }
 
 type message struct {
@@ -34,11 +40,39 @@ type message struct {
 }
 
 func main() {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR - problem with INTENSITY_RULES: %s", err)
+	}
+
+	if err := msg.InitLocalities(); err != nil {
+		log.Fatalf("ERROR - problem with localities: %s", err)
+	}
+
+	var err error
+
+	sender, err = newMailer(os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"), os.Getenv("SMTP_TLS"),
+		os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"), os.Getenv("SMTP_FROM"))
+	if err != nil {
+		log.Fatalf("ERROR - problem with SMTP config: %s", err)
+	}
+
+	if lists, err = loadRecipients(os.Getenv("EQNEWS_RECIPIENTS"), os.Getenv("SMTP_TO")); err != nil {
+		log.Fatalf("ERROR - problem loading recipient lists: %s", err)
+	}
+
+	if tmpls, err = loadTemplates(os.Getenv("EQNEWS_TEMPLATES"), lists); err != nil {
+		log.Fatalf("ERROR - problem loading eqnews templates: %s", err)
+	}
+
 	rx, dx, err := sqs.InitRx()
 	if err != nil {
 		log.Fatalf("ERROR - problem creating SQS from config: %s", err)
 	}
 
+	for _, r := range lists {
+		log.Printf("recipient list %s MMI %.1f MMIDistance %.1f", r.Name, r.MMI, r.MMIDistance)
+	}
+
 	log.Print("starting message listner")
 
 	for {
@@ -51,6 +85,121 @@ func main() {
 	}
 }
 
+/*
+loadRecipients reads the recipient lists from the JSON file f e.g.,
+
+	[{"Name": "eqnews", "To": ["a@example.com"], "MMI": 7, "MMIDistance": 3.5},
+	 {"Name": "eqnews-mi", "To": ["b@example.com"], "Lang": "mi", "MMI": 7, "MMIDistance": 3.5}]
+
+If f is empty a single list is created for the comma separated addresses in to
+with the default eqnews threshold.
+*/
+func loadRecipients(f, to string) ([]*recipients, error) {
+	var l []*recipients
+
+	if f == "" {
+		r := &recipients{
+			Name:            "eqnews",
+			EqNewsThreshold: msg.EqNewsDefault,
+		}
+
+		for _, a := range strings.Split(to, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				r.To = append(r.To, a)
+			}
+		}
+
+		l = append(l, r)
+	} else {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal(b, &l); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, r := range l {
+		if len(r.To) == 0 {
+			return nil, fmt.Errorf("no recipients for list %d %s", i, r.Name)
+		}
+
+		if r.Lang == "" {
+			r.Lang = msg.English
+		}
+
+		if _, err := msg.ParseLangs(string(r.Lang)); err != nil {
+			return nil, fmt.Errorf("list %d %s: %s", i, r.Name, err)
+		}
+	}
+
+	return l, nil
+}
+
+/*
+loadTemplates loads the templates for English and the language of each list in l.
+See loadTemplate.
+*/
+func loadTemplates(dir string, l []*recipients) (map[msg.Lang]*msg.EqNewsTemplate, error) {
+	t := make(map[msg.Lang]*msg.EqNewsTemplate)
+
+	for _, v := range append([]msg.Lang{msg.English}, langs(l)...) {
+		if _, ok := t[v]; ok {
+			continue
+		}
+
+		e, err := loadTemplate(dir, v)
+		if err != nil {
+			return nil, err
+		}
+
+		t[v] = e
+	}
+
+	return t, nil
+}
+
+func langs(l []*recipients) []msg.Lang {
+	var s []msg.Lang
+	for _, r := range l {
+		s = append(s, r.Lang)
+	}
+
+	return s
+}
+
+/*
+loadTemplate returns the eqnews template for l.  If dir is empty the template compiled into msg is used.
+Otherwise eqnews.txt and, if it exists, eqnews.html are read from dir.  Other languages are read from
+eqnews.<lang>.txt and eqnews.<lang>.html, if there is no text template for the language the compiled
+in template is used.
+*/
+func loadTemplate(dir string, l msg.Lang) (*msg.EqNewsTemplate, error) {
+	name := "eqnews"
+	if l != msg.English {
+		name = "eqnews." + string(l)
+	}
+
+	text := filepath.Join(dir, name+".txt")
+
+	if _, err := os.Stat(text); dir == "" || (os.IsNotExist(err) && l != msg.English) {
+		t, ok := msg.EqNewsTemplates[l]
+		if !ok {
+			return nil, fmt.Errorf("no eqnews template for language %s", l)
+		}
+		return t, nil
+	}
+
+	html := filepath.Join(dir, name+".html")
+	if _, err := os.Stat(html); os.IsNotExist(err) {
+		html = ""
+	}
+
+	return msg.ParseEqNewsTemplate(text, html)
+}
+
 func (m *message) Process() bool {
 	switch {
 	case m.Err() != nil:
@@ -60,29 +209,37 @@ func (m *message) Process() bool {
 	case m.Quake != nil:
 		m.Quake.RxLog()
 
-		if idp.Seen(*m.Quake) {
-			log.Printf("Already sent email for %s", m.Quake.PublicID)
-			return false
-		}
+		var reprocess bool
 
-		alert, subject, body := m.Quake.AlertEqNews()
+		for _, r := range lists {
+			if r.idp.Seen(*m.Quake) {
+				log.Printf("Already sent email for %s to %s", m.Quake.PublicID, r.Name)
+				continue
+			}
 
-		if alert {
-			log.Printf("Sending email for quake %s", m.Quake.PublicID)
+			alert, e := m.Quake.AlertEqNewsMessageIn(tmpls[r.Lang], r.EqNewsThreshold, r.Lang)
+			if m.Quake.Err() != nil {
+				return true
+			}
 
-			mail := "Subject: " + subject + "\r\n\r\n" + body
+			if !alert {
+				continue
+			}
 
-			err := smtp.SendMail(mailHost, auth,
-				smtpFrom, []string{smtpTo},
-				[]byte(mail))
+			log.Printf("Sending email for quake %s to %s", m.Quake.PublicID, r.Name)
 
-			if err != nil {
-				m.SetErr(err)
-				return true
+			if err := sender.send(r.To, e); err != nil {
+				// carry on with the other lists.  idp stops the lists that were
+				// sent to being sent again when the message is reprocessed.
+				m.SetErr(fmt.Errorf("sending email for quake %s to %s: %s", m.Quake.PublicID, r.Name, err))
+				reprocess = true
+				continue
 			}
 
-			idp.Add(*m.Quake)
+			r.idp.Add(*m.Quake)
 		}
+
+		return reprocess
 	}
 
 	return false
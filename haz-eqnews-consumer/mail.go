@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// TLS modes for connecting to the SMTP server.  The default ("") uses
+// STARTTLS if the server supports it, the same as smtp.SendMail.
+const (
+	tlsNone     = "none"     // plain connection, no encryption.
+	tlsStartTLS = "starttls" // upgrade the connection with STARTTLS.
+	tlsImplicit = "tls"      // connect with TLS (usually port 465).
+)
+
+// mailer sends email via an SMTP server.
+type mailer struct {
+	host    string // host name without the port.
+	addr    string // host:port
+	tlsMode string
+	auth    smtp.Auth // nil for no auth.
+	from    string
+	tls     *tls.Config
+}
+
+// newMailer returns a mailer.  tlsMode is one of tlsNone, tlsStartTLS, or tlsImplicit.
+// Auth is only used if user is not empty.
+func newMailer(host, port, tlsMode, user, password, from string) (*mailer, error) {
+	switch tlsMode {
+	case "", tlsNone, tlsStartTLS, tlsImplicit:
+	default:
+		return nil, fmt.Errorf("invalid SMTP TLS mode %s", tlsMode)
+	}
+
+	m := &mailer{
+		host:    host,
+		addr:    net.JoinHostPort(host, port),
+		tlsMode: tlsMode,
+		from:    from,
+		tls:     &tls.Config{ServerName: host},
+	}
+
+	if user != "" {
+		m.auth = smtp.PlainAuth("", user, password, host)
+	}
+
+	return m, nil
+}
+
+// send sends the eqnews message e to the recipients in to.
+func (m *mailer) send(to []string, e msg.EqNewsMessage) error {
+	b, err := m.compose(to, e)
+	if err != nil {
+		return err
+	}
+
+	var conn net.Conn
+
+	switch m.tlsMode {
+	case tlsImplicit:
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", m.addr, m.tls)
+	default:
+		conn, err = net.DialTimeout("tcp", m.addr, 30*time.Second)
+	}
+	if err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	switch ok, _ := c.Extension("STARTTLS"); {
+	case m.tlsMode == tlsStartTLS && !ok:
+		return fmt.Errorf("SMTP server %s does not support STARTTLS", m.addr)
+	case (m.tlsMode == tlsStartTLS || m.tlsMode == "") && ok:
+		if err = c.StartTLS(m.tls); err != nil {
+			return err
+		}
+	}
+
+	if m.auth != nil {
+		if err = c.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+
+	if err = c.Mail(m.from); err != nil {
+		return err
+	}
+
+	for _, r := range to {
+		if err = c.Rcpt(r); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(b); err != nil {
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// compose returns the MIME encoded email for e.  If e.HTML is not empty the email is
+// multipart/alternative with plain text and HTML parts.
+func (m *mailer) compose(to []string, e msg.EqNewsMessage) ([]byte, error) {
+	var b bytes.Buffer
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", e.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", base64.RawURLEncoding.EncodeToString(id), m.host)
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if e.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQP(&b, e.Text); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&b)
+
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+
+	for _, p := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", e.Text},
+		{"text/html; charset=utf-8", e.HTML},
+	} {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", p.contentType)
+		h.Set("Content-Transfer-Encoding", "quoted-printable")
+
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = writeQP(pw, p.body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+func writeQP(w io.Writer, s string) error {
+	q := quotedprintable.NewWriter(w)
+
+	if _, err := q.Write([]byte(s)); err != nil {
+		return err
+	}
+
+	return q.Close()
+}
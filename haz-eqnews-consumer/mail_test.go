@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"github.com/GeoNet/haz/msg"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpServer is a minimal local SMTP stand-in.  It does not support STARTTLS or AUTH.
+type smtpServer struct {
+	l    net.Listener
+	mu   sync.Mutex
+	msgs []smtpMsg
+}
+
+type smtpMsg struct {
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &smtpServer{l: l}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+
+	return s
+}
+
+func (s *smtpServer) hostPort() (string, string) {
+	h, p, _ := net.SplitHostPort(s.l.Addr().String())
+	return h, p
+}
+
+func (s *smtpServer) received() []smtpMsg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]smtpMsg{}, s.msgs...)
+}
+
+func (s *smtpServer) serve(c net.Conn) {
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	w := func(l string) {
+		io.WriteString(c, l+"\r\n")
+	}
+
+	var m smtpMsg
+
+	w("220 localhost ESMTP test")
+
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		l = strings.TrimRight(l, "\r\n")
+
+		switch cmd := strings.ToUpper(strings.SplitN(l, " ", 2)[0]); cmd {
+		case "EHLO", "HELO":
+			w("250-localhost")
+			w("250 8BITMIME")
+		case "MAIL":
+			m = smtpMsg{from: l[len("MAIL FROM:"):]}
+			w("250 OK")
+		case "RCPT":
+			m.to = append(m.to, l[len("RCPT TO:"):])
+			w("250 OK")
+		case "DATA":
+			w("354 go ahead")
+			var d []string
+			for {
+				l, err = r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				d = append(d, strings.TrimPrefix(l, "."))
+			}
+			m.data = strings.Join(d, "")
+			s.mu.Lock()
+			s.msgs = append(s.msgs, m)
+			s.mu.Unlock()
+			w("250 OK")
+		case "QUIT":
+			w("221 bye")
+			return
+		default:
+			w("502 not implemented")
+		}
+	}
+}
+
+func testQuake() msg.Quake {
+	return msg.Quake{
+		PublicID:              "2015p278423",
+		Time:                  time.Now().UTC(),
+		Latitude:              -37.92257397,
+		Longitude:             178.3544071,
+		Depth:                 9.62890625,
+		EvaluationStatus:      "automatic",
+		UsedPhaseCount:        25,
+		AzimuthalGap:          180,
+		MinimumDistance:       2.4,
+		Magnitude:             6.0,
+		MagnitudeStationCount: 12,
+	}
+}
+
+func TestSendMultipart(t *testing.T) {
+	s := newSMTPServer(t)
+	defer s.l.Close()
+
+	h, p := s.hostPort()
+
+	var err error
+	if sender, err = newMailer(h, p, tlsNone, "", "", "test@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if tmpls, err = loadTemplates("", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	lists = []*recipients{
+		{Name: "all", To: []string{"all@example.com", "duty@example.com"}, Lang: msg.English, EqNewsThreshold: msg.EqNewsDefault},
+		{Name: "big", To: []string{"big@example.com"}, Lang: msg.English, EqNewsThreshold: msg.EqNewsThreshold{MMI: 12, MMIDistance: 12}},
+	}
+
+	m := message{msg.Haz{Quake: &msg.Quake{}}}
+	*m.Quake = testQuake()
+
+	if m.Process() {
+		t.Errorf("unexpected reprocess %v", m.Err())
+	}
+
+	r := s.received()
+	if len(r) != 1 {
+		t.Fatalf("expected 1 message got %d", len(r))
+	}
+
+	if len(r[0].to) != 2 {
+		t.Errorf("expected 2 recipients got %d", len(r[0].to))
+	}
+
+	e, err := mail.ReadMessage(strings.NewReader(r[0].data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := new(mime.WordDecoder)
+	subject, err := d.DecodeHeader(e.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if subject != "NZ EQ: M6.0, severe intensity, 10km deep, 5 km south-east of Ruatoria" {
+		t.Errorf("unexpected subject %s", subject)
+	}
+
+	mt, params, err := mime.ParseMediaType(e.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mt != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative got %s", mt)
+	}
+
+	mr := multipart.NewReader(e.Body, params["boundary"])
+
+	var types []string
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		types = append(types, part.Header.Get("Content-Type"))
+
+		if !strings.Contains(string(b), "http://www.geonet.org.nz/quakes/2015p278423") {
+			t.Errorf("%s part missing quake link", part.Header.Get("Content-Type"))
+		}
+
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/html") && !strings.Contains(string(b), "<img src=") {
+			t.Error("html part missing map image")
+		}
+	}
+
+	if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+		t.Errorf("unexpected parts %v", types)
+	}
+
+	// should not send to the same lists again.
+	if m.Process() {
+		t.Errorf("unexpected reprocess %v", m.Err())
+	}
+
+	if len(s.received()) != 1 {
+		t.Errorf("expected 1 message got %d", len(s.received()))
+	}
+}
+
+func TestSendStartTLSRequired(t *testing.T) {
+	s := newSMTPServer(t)
+	defer s.l.Close()
+
+	h, p := s.hostPort()
+
+	m, err := newMailer(h, p, tlsStartTLS, "", "", "test@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.send([]string{"a@example.com"}, msg.EqNewsMessage{Subject: "test", Text: "test"}); err == nil {
+		t.Error("expected error for server without STARTTLS")
+	}
+
+	if len(s.received()) != 0 {
+		t.Error("should not have sent a message")
+	}
+
+	if _, err = newMailer(h, p, "ssl", "", "", "test@example.com"); err == nil {
+		t.Error("expected error for invalid tls mode")
+	}
+}
+
+func TestLoadRecipients(t *testing.T) {
+	l, err := loadRecipients("", "a@example.com, b@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 1 || len(l[0].To) != 2 || l[0].EqNewsThreshold != msg.EqNewsDefault || l[0].Lang != msg.English {
+		t.Errorf("unexpected default list %+v", l)
+	}
+
+	if _, err = loadRecipients("", ""); err == nil {
+		t.Error("expected error for empty recipients")
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	l := []*recipients{{Name: "mi", To: []string{"a@example.com"}, Lang: msg.Maori}}
+
+	tm, err := loadTemplates("", l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tm) != 2 || tm[msg.English] != msg.EqNewsTemplates[msg.English] || tm[msg.Maori] != msg.EqNewsTemplates[msg.Maori] {
+		t.Fatalf("expected the compiled in English and Māori templates got %v", tm)
+	}
+
+	d, err := ioutil.TempDir("", "eqnews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	if err = ioutil.WriteFile(filepath.Join(d, "eqnews.txt"), []byte("{{.Q.PublicID}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if tm, err = loadTemplates(d, l); err != nil {
+		t.Fatal(err)
+	}
+
+	if tm[msg.English] == nil || tm[msg.English] == msg.EqNewsTemplates[msg.English] {
+		t.Error("expected the English template from the dir")
+	}
+
+	// no eqnews.mi.txt in the dir so the compiled in template is used.
+	if tm[msg.Maori] != msg.EqNewsTemplates[msg.Maori] {
+		t.Error("expected the compiled in Māori template")
+	}
+
+	l[0].Lang = "fr"
+
+	if _, err = loadTemplates("", l); err == nil {
+		t.Error("expected error for language without a template")
+	}
+}
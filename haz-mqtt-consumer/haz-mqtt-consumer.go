@@ -0,0 +1,232 @@
+// haz-mqtt-consumer listens to AWS SQS queues for Haz and Intensity messages and
+// republishes them to an MQTT broker for dashboards and field devices.
+//
+// Topics (with the default prefix haz):
+//
+//	haz/quake/{publicID}    retained, the latest information for the quake.
+//	haz/quake/latest        retained, the most recently published quake.
+//	haz/quake/mmi/{n}       retained, the latest quake with MMI n at the closest locality.
+//	haz/heartbeat/{service} heartbeats.
+//	haz/intensity/{quality} measured or reported intensity.
+//
+// Payloads are JSON (the msg wire format) or protobuf (haz.proto).  Heartbeats
+// are always JSON as there is no protobuf for them.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GeoNet/haz"
+	"github.com/GeoNet/haz/mqtt"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/sqs"
+	"github.com/golang/protobuf/proto"
+	"log"
+	"os"
+)
+
+// publisher is implemented by *mqtt.Client.
+type publisher interface {
+	Publish(mqtt.Message) error
+}
+
+var (
+	pub    publisher
+	prefix = "haz"
+	format = "json"
+)
+
+func init() {
+	sqs.MaxNumberOfMessages = 1
+	sqs.VisibilityTimeout = 600
+	sqs.WaitTimeSeconds = 20
+}
+
+type message struct {
+	msg.Haz
+}
+
+type intensity struct {
+	msg.Intensity
+}
+
+func main() {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR - problem with INTENSITY_RULES: %s", err)
+	}
+
+	if err := msg.InitLocalities(); err != nil {
+		log.Fatalf("ERROR - problem with localities: %s", err)
+	}
+
+	if p := os.Getenv("MQTT_TOPIC_PREFIX"); p != "" {
+		prefix = p
+	}
+
+	switch f := os.Getenv("MQTT_FORMAT"); f {
+	case "", "json":
+	case "protobuf":
+		format = f
+	default:
+		log.Fatalf("ERROR - invalid MQTT_FORMAT %s", f)
+	}
+
+	c, err := mqtt.New(mqtt.Options{
+		Broker:   os.Getenv("MQTT_BROKER"),
+		ClientID: os.Getenv("MQTT_CLIENT_ID"),
+		Username: os.Getenv("MQTT_USER"),
+		Password: os.Getenv("MQTT_PASSWORD"),
+	})
+	if err != nil {
+		log.Fatalf("ERROR - problem with MQTT config: %s", err)
+	}
+	defer c.Close()
+	pub = c
+
+	rx, dx, err := sqs.InitRx()
+	if err != nil {
+		log.Fatalf("ERROR - problem creating SQS from config: %s", err)
+	}
+
+	if q := os.Getenv("SQS_IMPACT_QUEUE_NAME"); q != "" {
+		irx, idx, err := sqs.InitRxQueue(q)
+		if err != nil {
+			log.Fatalf("ERROR - problem creating impact SQS from config: %s", err)
+		}
+
+		go func() {
+			for {
+				r := <-irx
+				i := intensity{}
+				i.Decode([]byte(r.Body))
+				if !msg.Process(&i) {
+					idx <- r.ReceiptHandle
+				}
+			}
+		}()
+	}
+
+	log.Printf("publishing %s to %s", format, os.Getenv("MQTT_BROKER"))
+
+	log.Print("starting message listner")
+
+	for {
+		r := <-rx
+		h := message{}
+		h.Decode([]byte(r.Body))
+		if !msg.Process(&h) {
+			dx <- r.ReceiptHandle
+		}
+	}
+}
+
+func (m *message) Process() bool {
+	switch {
+	case m.Err() != nil:
+		log.Println("WARN received errored message: " + m.Err().Error())
+	case m.HeartBeat != nil:
+		if m.HeartBeat.RxLog() {
+			return false
+		}
+
+		b, err := json.Marshal(m.HeartBeat)
+		if err != nil {
+			m.SetErr(err)
+			return false
+		}
+
+		m.publish(mqtt.Message{Topic: prefix + "/heartbeat/" + m.HeartBeat.ServiceID, Payload: b})
+	case m.Quake != nil:
+		m.Quake.RxLog()
+
+		if !m.Quake.Publish() {
+			return false
+		}
+
+		b, mmi, err := encodeQuake(m.Quake)
+		if err != nil {
+			m.SetErr(err)
+			return false
+		}
+
+		for _, t := range []string{
+			prefix + "/quake/" + m.Quake.PublicID,
+			prefix + "/quake/latest",
+			fmt.Sprintf("%s/quake/mmi/%d", prefix, mmi),
+		} {
+			m.publish(mqtt.Message{Topic: t, Payload: b, QoS: 1, Retain: true})
+		}
+	}
+
+	return false
+}
+
+func (m *message) publish(p mqtt.Message) {
+	if err := pub.Publish(p); err != nil {
+		m.SetErr(err)
+	}
+}
+
+// encodeQuake encodes q in the configured format.  Also returns the MMI at the closest locality.
+func encodeQuake(q *msg.Quake) ([]byte, int, error) {
+	c, err := q.Closest()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	mmi := int(c.MMIDistance)
+	if mmi < 0 {
+		mmi = 0
+	}
+
+	if format == "json" {
+		b, err := json.Marshal(q)
+		return b, mmi, err
+	}
+
+	p := haz.Quake{
+		PublicID:         q.PublicID,
+		Time:             &haz.Timestamp{Sec: q.Time.Unix(), Nsec: int64(q.Time.Nanosecond())},
+		ModificationTime: &haz.Timestamp{Sec: q.ModificationTime.Unix(), Nsec: int64(q.ModificationTime.Nanosecond())},
+		Latitude:         q.Latitude,
+		Longitude:        q.Longitude,
+		Depth:            q.Depth,
+		Magnitude:        q.Magnitude,
+		Locality:         c.Location(),
+		Quality:          q.Quality(),
+		Mmi:              int32(mmi),
+	}
+
+	b, err := proto.Marshal(&p)
+
+	return b, mmi, err
+}
+
+func (i *intensity) Process() bool {
+	i.Valid()
+	i.Future()
+
+	if i.Err() != nil {
+		return false
+	}
+
+	var b []byte
+	var err error
+
+	switch format {
+	case "protobuf":
+		b, err = proto.Marshal(&haz.MMI{Latitude: i.Latitude, Longitude: i.Longitude, Mmi: int32(i.MMI), Count: 1})
+	default:
+		b, err = i.Encode()
+	}
+	if err != nil {
+		i.SetErr(err)
+		return false
+	}
+
+	if err = pub.Publish(mqtt.Message{Topic: prefix + "/intensity/" + i.Quality, Payload: b}); err != nil {
+		i.SetErr(err)
+	}
+
+	return false
+}
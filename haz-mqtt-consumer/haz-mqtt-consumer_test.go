@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/GeoNet/haz"
+	"github.com/GeoNet/haz/mqtt"
+	"github.com/GeoNet/haz/msg"
+	"github.com/golang/protobuf/proto"
+	"testing"
+	"time"
+)
+
+type testPublisher struct {
+	msgs []mqtt.Message
+}
+
+func (t *testPublisher) Publish(m mqtt.Message) error {
+	t.msgs = append(t.msgs, m)
+	return nil
+}
+
+func testQuake() *msg.Quake {
+	return &msg.Quake{
+		PublicID:              "2015p278423",
+		Time:                  time.Now().UTC(),
+		Latitude:              -37.92257397,
+		Longitude:             178.3544071,
+		Depth:                 9.62890625,
+		EvaluationStatus:      "confirmed",
+		UsedPhaseCount:        25,
+		AzimuthalGap:          180,
+		MinimumDistance:       2.4,
+		Magnitude:             6.0,
+		MagnitudeStationCount: 12,
+	}
+}
+
+func TestQuakeJSON(t *testing.T) {
+	p := &testPublisher{}
+	pub = p
+	format = "json"
+
+	m := message{msg.Haz{Quake: testQuake()}}
+	m.Process()
+
+	if m.Err() != nil {
+		t.Fatal(m.Err())
+	}
+
+	topics := []string{"haz/quake/2015p278423", "haz/quake/latest", "haz/quake/mmi/8"}
+
+	if len(p.msgs) != len(topics) {
+		t.Fatalf("expected %d messages got %d", len(topics), len(p.msgs))
+	}
+
+	for i, topic := range topics {
+		if p.msgs[i].Topic != topic {
+			t.Errorf("expected topic %s got %s", topic, p.msgs[i].Topic)
+		}
+
+		if !p.msgs[i].Retain || p.msgs[i].QoS != 1 {
+			t.Errorf("%s should be retained QoS 1", topic)
+		}
+
+		var q msg.Quake
+		if err := json.Unmarshal(p.msgs[i].Payload, &q); err != nil {
+			t.Fatal(err)
+		}
+
+		if q.PublicID != "2015p278423" {
+			t.Errorf("expected publicID 2015p278423 got %s", q.PublicID)
+		}
+	}
+
+	// not suitable for publishing.
+	p.msgs = nil
+	m.Quake.EvaluationStatus = ""
+	m.Quake.AzimuthalGap = 340
+	m.Process()
+
+	if len(p.msgs) != 0 {
+		t.Errorf("expected no messages got %d", len(p.msgs))
+	}
+}
+
+func TestQuakeProtobuf(t *testing.T) {
+	p := &testPublisher{}
+	pub = p
+	format = "protobuf"
+	defer func() { format = "json" }()
+
+	m := message{msg.Haz{Quake: testQuake()}}
+	m.Process()
+
+	if len(p.msgs) != 3 {
+		t.Fatalf("expected 3 messages got %d", len(p.msgs))
+	}
+
+	var q haz.Quake
+	if err := proto.Unmarshal(p.msgs[0].Payload, &q); err != nil {
+		t.Fatal(err)
+	}
+
+	if q.PublicID != "2015p278423" || q.Mmi != 8 || q.Quality != "best" || q.Locality != "5 km south-east of Ruatoria" {
+		t.Errorf("unexpected quake %+v", q)
+	}
+}
+
+func TestHeartBeatAndIntensity(t *testing.T) {
+	p := &testPublisher{}
+	pub = p
+
+	m := message{msg.Haz{HeartBeat: &msg.HeartBeat{ServiceID: "haz-sc3-producer", SentTime: time.Now().UTC()}}}
+	m.Process()
+
+	i := intensity{msg.Intensity{Source: "test.test", Quality: "measured", MMI: 4, Latitude: -41.2, Longitude: 174.8, Time: time.Now().UTC()}}
+	i.Process()
+
+	// invalid intensity is not published.
+	bad := intensity{msg.Intensity{Source: "test test", Quality: "measured", MMI: 4, Time: time.Now().UTC()}}
+	bad.Process()
+
+	if len(p.msgs) != 2 {
+		t.Fatalf("expected 2 messages got %d", len(p.msgs))
+	}
+
+	if p.msgs[0].Topic != "haz/heartbeat/haz-sc3-producer" || p.msgs[0].Retain {
+		t.Errorf("unexpected heartbeat message %+v", p.msgs[0])
+	}
+
+	if p.msgs[1].Topic != "haz/intensity/measured" {
+		t.Errorf("unexpected intensity topic %s", p.msgs[1].Topic)
+	}
+}
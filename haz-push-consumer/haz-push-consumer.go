@@ -0,0 +1,244 @@
+// haz-push-consumer listens to an AWS SQS queue for Haz JSON messages and
+// sends push notifications to the subscribers (stored in push.subscription) who
+// will feel the quake at their location.  Delivery is through the push provider for
+// each subscription.
+package main
+
+import (
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/push"
+	"github.com/GeoNet/haz/sqs"
+	"github.com/GeoNet/haz/ua"
+	_ "github.com/lib/pq"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+)
+
+// subscriptions is implemented by database.DB
+type subscriptions interface {
+	SubscriptionsForQuake(q *msg.Quake) ([]push.Subscription, error)
+	DeleteSubscription(provider, token string) error
+}
+
+// retry is the subscribers for a quake that are still to be notified.
+type retry struct {
+	subs     []push.Subscription
+	attempts int
+	added    time.Time
+}
+
+var (
+	idp       = msg.IdpQuake{}
+	subs      subscriptions
+	providers = map[string]push.Provider{}
+	// langs are the languages for the notification text from LANGUAGES e.g., en,mi
+	// The first is the message, the others are sent in the payload data as message_<lang>.
+	langs = []msg.Lang{msg.English}
+	// retries are the subscribers that failed transiently keyed by quake PublicID.
+	// When the message is reprocessed only these are pushed to.
+	retries = make(map[string]retry)
+)
+
+// ttl is how long providers should keep trying to deliver a notification.
+// Old quake notifications aren't useful.
+const ttl = time.Duration(1) * time.Hour
+
+// maxAttempts is how many times a quake is pushed to subscribers that fail transiently.
+const maxAttempts = 3
+
+func init() {
+	sqs.MaxNumberOfMessages = 1
+	sqs.VisibilityTimeout = 600
+	sqs.WaitTimeSeconds = 20
+}
+
+type message struct {
+	msg.Haz
+}
+
+func main() {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR: problem with INTENSITY_RULES: %s", err)
+	}
+
+	db, err := database.InitPG()
+	if err != nil {
+		log.Fatalf("ERROR: problem with DB config: %s", err)
+	}
+	defer db.Close()
+
+	if err = db.InitLocalities(); err != nil {
+		log.Fatalf("ERROR: problem with localities: %s", err)
+	}
+
+	db.Check()
+	subs = &db
+
+	if langs, err = msg.ParseLangs(os.Getenv("LANGUAGES")); err != nil {
+		log.Fatalf("ERROR: LANGUAGES: %s", err)
+	}
+
+	providers["ua"] = push.UA{Client: ua.Init()}
+
+	if err = initDirect(); err != nil {
+		log.Fatalf("ERROR: problem with push provider config: %s", err)
+	}
+
+	rx, dx, err := sqs.InitRx()
+	if err != nil {
+		log.Fatalf("ERROR - problem creating SQS from config: %s", err)
+	}
+
+	log.Print("starting message listner")
+
+	for {
+		r := <-rx
+		h := message{}
+		h.Decode([]byte(r.Body))
+		if !msg.Process(&h) {
+			dx <- r.ReceiptHandle
+		}
+	}
+}
+
+func (m *message) Process() bool {
+	switch {
+	case m.Err() != nil:
+		log.Println("WARN received errored message: " + m.Err().Error())
+	case m.HeartBeat != nil:
+		m.HeartBeat.RxLog()
+	case m.Quake != nil:
+		m.Quake.RxLog()
+		return m.processPush()
+	}
+
+	return false
+}
+
+func (m *message) processPush() bool {
+	if idp.Seen(*m.Quake) {
+		log.Printf("%s already pushed.", m.Quake.PublicID)
+		return false
+	}
+
+	alert, message := m.Quake.AlertPushIn(langs[0])
+	if !alert {
+		log.Printf("Quake %s not suitable for pushing.", m.Quake.PublicID)
+		return false
+	}
+
+	for k, v := range retries {
+		if time.Since(v.added) > ttl {
+			delete(retries, k)
+		}
+	}
+
+	r, ok := retries[m.Quake.PublicID]
+	if !ok {
+		s, err := subs.SubscriptionsForQuake(m.Quake)
+		if err != nil {
+			m.SetErr(err)
+			return true
+		}
+		r = retry{subs: s, added: time.Now().UTC()}
+	}
+
+	sent, failed, gone, err := push.Partition(push.Send(m.Quake, notification(m.Quake, message), r.subs, providers))
+
+	log.Printf("Sent quake %s to %d of %d candidate subscribers, %d failed, %d unregistered.",
+		m.Quake.PublicID, sent, len(r.subs), len(failed), len(gone))
+
+	for _, v := range gone {
+		if e := subs.DeleteSubscription(v.Provider, v.Token); e != nil {
+			log.Printf("WARN: removing unregistered %s subscription: %s", v.Provider, e)
+		}
+	}
+
+	if err != nil {
+		m.SetErr(err)
+
+		// only retry the subscribers that failed so that no one is notified twice.
+		r.subs = failed
+		r.attempts++
+
+		if r.attempts < maxAttempts {
+			retries[m.Quake.PublicID] = r
+			return true
+		}
+
+		log.Printf("WARN: giving up on %d subscribers for quake %s after %d attempts.", len(failed), m.Quake.PublicID, r.attempts)
+	}
+
+	delete(retries, m.Quake.PublicID)
+	idp.Add(*m.Quake)
+
+	return false
+}
+
+// notification returns the notification for q with message and the text in any other langs.
+func notification(q *msg.Quake, message string) push.Notification {
+	n := push.Notification{PublicID: q.PublicID, Message: message, TTL: ttl}
+
+	if len(langs) < 2 {
+		return n
+	}
+
+	d := make(map[string]string)
+
+	for _, l := range langs[1:] {
+		if _, t := q.AlertPushIn(l); t != "" {
+			d["message_"+string(l)] = t
+		}
+	}
+
+	n.Platform = map[string]push.Payload{
+		"ios":     {Data: d},
+		"android": {Data: d},
+	}
+
+	return n
+}
+
+// initDirect adds the FCM and APNs providers for subscriptions that are
+// delivered directly rather than through UA.  Each is optional.
+func initDirect() error {
+	if p := os.Getenv("FCM_PROJECT"); p != "" {
+		b, err := ioutil.ReadFile(os.Getenv("FCM_CREDENTIALS"))
+		if err != nil {
+			return err
+		}
+
+		f, err := push.NewFCM(p, b)
+		if err != nil {
+			return err
+		}
+
+		providers["fcm"] = f
+		log.Printf("FCM push enabled for project %s", p)
+	}
+
+	if k := os.Getenv("APNS_KEY_FILE"); k != "" {
+		b, err := ioutil.ReadFile(k)
+		if err != nil {
+			return err
+		}
+
+		e := os.Getenv("APNS_ENDPOINT")
+		if e == "" {
+			e = push.APNsProduction
+		}
+
+		a, err := push.NewAPNs(e, os.Getenv("APNS_TOPIC"), os.Getenv("APNS_KEY_ID"), os.Getenv("APNS_TEAM_ID"), b)
+		if err != nil {
+			return err
+		}
+
+		providers["apns"] = a
+		log.Printf("APNs push enabled for %s", e)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/push"
+	"testing"
+	"time"
+)
+
+type testSubs struct {
+	subs    []push.Subscription
+	deleted []string
+}
+
+func (t *testSubs) SubscriptionsForQuake(q *msg.Quake) ([]push.Subscription, error) {
+	return t.subs, nil
+}
+
+func (t *testSubs) DeleteSubscription(provider, token string) error {
+	t.deleted = append(t.deleted, token)
+	return nil
+}
+
+// testProvider fails for tokens in fail once and always for tokens in gone.
+type testProvider struct {
+	n    []push.Notification
+	subs []push.Subscription
+	fail map[string]bool
+	gone map[string]bool
+}
+
+func (p *testProvider) Push(n push.Notification, subs []push.Subscription) []push.Result {
+	p.n = append(p.n, n)
+
+	var r []push.Result
+
+	for _, s := range subs {
+		switch {
+		case p.gone[s.Token]:
+			r = append(r, push.Result{Subscription: s, Err: push.ErrUnregistered})
+		case p.fail[s.Token]:
+			delete(p.fail, s.Token)
+			r = append(r, push.Result{Subscription: s, Err: errors.New("try again")})
+		default:
+			p.subs = append(p.subs, s)
+			r = append(r, push.Result{Subscription: s})
+		}
+	}
+
+	return r
+}
+
+func testQuake() *msg.Quake {
+	return &msg.Quake{
+		PublicID:              "2015p278423",
+		Time:                  time.Now().UTC(),
+		Latitude:              -37.92257397,
+		Longitude:             178.3544071,
+		Depth:                 9.62890625,
+		EvaluationStatus:      "automatic",
+		UsedPhaseCount:        25,
+		AzimuthalGap:          180,
+		MinimumDistance:       2.4,
+		Magnitude:             6.0,
+		MagnitudeStationCount: 12,
+	}
+}
+
+func TestProcessPush(t *testing.T) {
+	p := &testProvider{}
+	providers = map[string]push.Provider{"ua": p}
+
+	subs = &testSubs{subs: []push.Subscription{
+		{Provider: "ua", Platform: "ios", Token: "gisborne", Latitude: -38.67, Longitude: 178.02, MinMMI: 3},
+		{Provider: "ua", Platform: "ios", Token: "dunedin", Latitude: -45.88, Longitude: 170.5, MinMMI: 3},
+	}}
+
+	m := message{msg.Haz{Quake: testQuake()}}
+
+	if m.processPush() {
+		t.Errorf("unexpected reprocess %v", m.Err())
+	}
+
+	if len(p.subs) != 1 || p.subs[0].Token != "gisborne" {
+		t.Errorf("expected push to gisborne got %v", p.subs)
+	}
+
+	if p.n[0].Message != "M6.0 quake causing severe shaking near Ruatoria" {
+		t.Errorf("unexpected message %s", p.n[0].Message)
+	}
+
+	// already pushed.
+	m.processPush()
+
+	if len(p.n) != 1 {
+		t.Errorf("expected 1 push got %d", len(p.n))
+	}
+}
+
+func TestProcessPushRetry(t *testing.T) {
+	idp = msg.IdpQuake{}
+	defer func() { idp = msg.IdpQuake{} }()
+
+	p := &testProvider{fail: map[string]bool{"napier": true}, gone: map[string]bool{"old-phone": true}}
+	providers = map[string]push.Provider{"ua": p}
+
+	s := &testSubs{subs: []push.Subscription{
+		{Provider: "ua", Platform: "ios", Token: "gisborne", Latitude: -38.67, Longitude: 178.02, MinMMI: 3},
+		{Provider: "ua", Platform: "ios", Token: "napier", Latitude: -39.49, Longitude: 176.91, MinMMI: 3},
+		{Provider: "ua", Platform: "ios", Token: "old-phone", Latitude: -38.67, Longitude: 178.02, MinMMI: 3},
+	}}
+	subs = s
+
+	m := message{msg.Haz{Quake: testQuake()}}
+
+	if !m.processPush() {
+		t.Error("expected a reprocess for the transient failure")
+	}
+
+	if len(s.deleted) != 1 || s.deleted[0] != "old-phone" {
+		t.Errorf("expected old-phone to be removed got %v", s.deleted)
+	}
+
+	if m.processPush() {
+		t.Errorf("unexpected reprocess %v", m.Err())
+	}
+
+	// gisborne is only notified once and napier on the retry.
+	var tokens []string
+	for _, v := range p.subs {
+		tokens = append(tokens, v.Token)
+	}
+
+	if fmt.Sprintf("%v", tokens) != "[gisborne napier]" {
+		t.Errorf("expected pushes to gisborne then napier got %v", tokens)
+	}
+
+	if len(retries) != 0 {
+		t.Errorf("expected no retries left got %v", retries)
+	}
+}
+
+func TestNotificationLangs(t *testing.T) {
+	defer func() { langs = []msg.Lang{msg.English} }()
+
+	q := &msg.Quake{
+		PublicID:              "2015p278423",
+		Time:                  time.Now().UTC(),
+		Latitude:              -37.92257397,
+		Longitude:             178.3544071,
+		Depth:                 9.62890625,
+		EvaluationStatus:      "automatic",
+		UsedPhaseCount:        25,
+		AzimuthalGap:          180,
+		MinimumDistance:       2.4,
+		Magnitude:             6.0,
+		MagnitudeStationCount: 12,
+	}
+
+	if n := notification(q, "hello"); n.Platform != nil {
+		t.Errorf("expected no platform payloads for English only got %v", n.Platform)
+	}
+
+	langs = []msg.Lang{msg.English, msg.Maori}
+
+	n := notification(q, "hello")
+
+	if n.Message != "hello" {
+		t.Errorf("expected message hello got %s", n.Message)
+	}
+
+	for _, p := range []string{"ios", "android"} {
+		d := n.For(p).Data
+		if d["message_mi"] != "He rū whenua M6.0, he tino kaha te wiri ki te takiwā o Ruatoria" {
+			t.Errorf("%s: unexpected message_mi %s", p, d["message_mi"])
+		}
+		if d["publicid"] != q.PublicID {
+			t.Errorf("%s: expected publicid %s got %s", p, q.PublicID, d["publicid"])
+		}
+	}
+}
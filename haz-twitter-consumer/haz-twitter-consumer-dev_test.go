@@ -4,7 +4,6 @@ package main
 
 import (
 	"github.com/GeoNet/haz/msg"
-	"github.com/GeoNet/haz/twitter"
 	"log"
 	"testing"
 	"time"
@@ -12,7 +11,7 @@ import (
 
 func setup() {
 	var err error
-	ttr, err = twitter.Init()
+	accounts, err = initAccounts("")
 	if err != nil {
 		log.Fatalf("ERROR: Twitter init error: %s", err.Error())
 	}
@@ -46,7 +45,7 @@ func TestTweet(t *testing.T) {
 		},
 	}
 
-	if false != m.processTweet() {
+	if false != m.processPosts() {
 		t.Errorf("TestTweet failed")
 	}
 
@@ -55,7 +54,7 @@ func TestTweet(t *testing.T) {
 	m.Quake.Longitude = 175.62
 	m.Quake.Latitude = -40.37
 
-	if false != m.processTweet() {
+	if false != m.processPosts() {
 		t.Errorf("TestTweet failed")
 	}
 
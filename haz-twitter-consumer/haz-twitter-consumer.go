@@ -1,9 +1,12 @@
 // haz-twitter-consumer listens to an AWS SQS queue for Haz JSON messages and
-// post it to twitter if it passed the given threshold.
+// posts it to each social media account (Twitter or Mastodon) if it passed the
+// account's threshold.
 package main
 
 import (
+	"fmt"
 	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/social"
 	"github.com/GeoNet/haz/sqs"
 	"github.com/GeoNet/haz/twitter"
 	"log"
@@ -11,20 +14,21 @@ import (
 	"strconv"
 )
 
-var (
-	idp       = msg.IdpQuake{}
-	ttr       twitter.Twitter
+var accounts []*account
+
+// account is a social media account with its own threshold.
+type account struct {
+	name      string
 	threshold float64
-)
+	poster    social.Poster
+	lang      msg.Lang // the language for the post text.
+	idp       msg.IdpQuake
+}
 
 func init() {
 	sqs.MaxNumberOfMessages = 1
 	sqs.VisibilityTimeout = 600
 	sqs.WaitTimeSeconds = 20
-	var err error
-	if threshold, err = strconv.ParseFloat(os.Getenv("TWITTER_THRESHOLD"), 64); err != nil {
-		log.Fatalln("TWITTER_THRESHOLD format error: ", err.Error())
-	}
 }
 
 type message struct {
@@ -32,17 +36,26 @@ type message struct {
 }
 
 func main() {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR - problem with INTENSITY_RULES: %s", err)
+	}
+
+	if err := msg.InitLocalities(); err != nil {
+		log.Fatalf("ERROR - problem with localities: %s", err)
+	}
+
 	rx, dx, err := sqs.InitRx()
 	if err != nil {
 		log.Fatalf("ERROR - problem creating SQS from config: %s", err)
 	}
 
-	ttr, err = twitter.Init()
-	if err != nil {
-		log.Fatalf("ERROR: Twitter init error: %s", err.Error())
+	if accounts, err = initAccounts(os.Getenv("SOCIAL_ACCOUNTS")); err != nil {
+		log.Fatalf("ERROR: social account init error: %s", err.Error())
 	}
 
-	log.Printf("Twitter magnitude threshold %.1f", threshold)
+	for _, a := range accounts {
+		log.Printf("%s magnitude threshold %.1f", a.name, a.threshold)
+	}
 
 	log.Print("starting message listner")
 
@@ -56,6 +69,50 @@ func main() {
 	}
 }
 
+// initAccounts creates the accounts from the social.Account JSON file f.
+// If f is empty a single Twitter account is created from the TWITTER_* env var config.
+func initAccounts(f string) ([]*account, error) {
+	if f == "" {
+		threshold, err := strconv.ParseFloat(os.Getenv("TWITTER_THRESHOLD"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("TWITTER_THRESHOLD format error: %s", err.Error())
+		}
+
+		t, err := twitter.Init()
+		if err != nil {
+			return nil, err
+		}
+
+		return []*account{{name: "twitter", threshold: threshold, poster: &t, lang: msg.English}}, nil
+	}
+
+	c, err := social.Load(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var a []*account
+
+	for _, s := range c {
+		p, err := s.Poster()
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := msg.ParseLangs(s.Language)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", s.Name, err)
+		}
+		if len(l) != 1 {
+			return nil, fmt.Errorf("%s: expected one language got %s", s.Name, s.Language)
+		}
+
+		a = append(a, &account{name: s.Name, threshold: s.Threshold, poster: p, lang: l[0]})
+	}
+
+	return a, nil
+}
+
 func (m *message) Process() bool {
 	switch {
 	case m.Err() != nil:
@@ -64,32 +121,44 @@ func (m *message) Process() bool {
 		m.HeartBeat.RxLog()
 	case m.Quake != nil:
 		m.Quake.RxLog()
-		return m.processTweet()
+		return m.processPosts()
 	}
 
 	return false
 }
 
-func (m *message) processTweet() bool {
-	if idp.Seen(*m.Quake) {
-		log.Printf("%s already tweeted.", m.Quake.PublicID)
-		return false
-	}
+// processPosts posts the quake to every account it is suitable for.  An error posting
+// to one account does not stop posting to the others.  The message will be reprocessed
+// and idp stops reposting to accounts that succeeded.
+func (m *message) processPosts() bool {
+	var reprocess bool
 
-	alert, message := m.Quake.AlertTwitter(threshold)
-	if alert {
-		log.Printf("Tweeting quake %s.", m.Quake.PublicID)
-		err := ttr.PostTweet(message, m.Quake.Longitude, m.Quake.Latitude)
+	for _, a := range accounts {
+		if a.idp.Seen(*m.Quake) {
+			log.Printf("%s already posted to %s.", m.Quake.PublicID, a.name)
+			continue
+		}
 
-		if err != nil {
-			m.SetErr(err)
+		alert, message := m.Quake.AlertSocialIn(a.threshold, a.poster.Limits(), a.lang)
+		if m.Quake.Err() != nil {
 			return true
 		}
 
-		idp.Add(*m.Quake)
-	} else {
-		log.Printf("quake %s not suitable for tweeting.", m.Quake.PublicID)
+		if !alert {
+			log.Printf("quake %s not suitable for posting to %s.", m.Quake.PublicID, a.name)
+			continue
+		}
+
+		log.Printf("Posting quake %s to %s.", m.Quake.PublicID, a.name)
+
+		if err := a.poster.Post(m.Quake.PublicID, message, m.Quake.Longitude, m.Quake.Latitude); err != nil {
+			m.SetErr(fmt.Errorf("posting quake %s to %s: %s", m.Quake.PublicID, a.name, err))
+			reprocess = true
+			continue
+		}
+
+		a.idp.Add(*m.Quake)
 	}
 
-	return false
+	return reprocess
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"testing"
+	"time"
+)
+
+type testPoster struct {
+	limits msg.SocialLimits
+	posts  []string
+	err    error
+}
+
+func (t *testPoster) Post(publicID, message string, longitude, latitude float64) error {
+	if t.err != nil {
+		return t.err
+	}
+
+	t.posts = append(t.posts, message)
+	return nil
+}
+
+func (t *testPoster) Limits() msg.SocialLimits {
+	return t.limits
+}
+
+func TestProcessPosts(t *testing.T) {
+	above4 := &testPoster{limits: msg.TwitterLimits}
+	above7 := &testPoster{limits: msg.TwitterLimits}
+	broken := &testPoster{limits: msg.MastodonLimits, err: fmt.Errorf("server error")}
+
+	accounts = []*account{
+		{name: "above4", threshold: 4, poster: above4},
+		{name: "above7", threshold: 7, poster: above7},
+		{name: "broken", threshold: 4, poster: broken},
+	}
+
+	m := message{msg.Haz{Quake: &msg.Quake{
+		PublicID:              "2015p278423",
+		Time:                  time.Now().UTC(),
+		Latitude:              -37.92257397,
+		Longitude:             178.3544071,
+		Depth:                 9.62890625,
+		EvaluationStatus:      "automatic",
+		UsedPhaseCount:        25,
+		AzimuthalGap:          180,
+		MinimumDistance:       2.4,
+		Magnitude:             6.0,
+		MagnitudeStationCount: 12,
+	}}}
+
+	if !m.processPosts() {
+		t.Error("expected reprocess for errored account")
+	}
+
+	if len(above4.posts) != 1 || len(above7.posts) != 0 {
+		t.Errorf("expected 1 and 0 posts got %d and %d", len(above4.posts), len(above7.posts))
+	}
+
+	// reprocessing should only retry the account that failed.
+	broken.err = nil
+	m.SetErr(nil)
+
+	if m.processPosts() {
+		t.Errorf("unexpected reprocess %v", m.Err())
+	}
+
+	if len(above4.posts) != 1 || len(broken.posts) != 1 {
+		t.Errorf("expected 1 and 1 posts got %d and %d", len(above4.posts), len(broken.posts))
+	}
+}
+
+func TestProcessPostsLang(t *testing.T) {
+	en := &testPoster{limits: msg.MastodonLimits}
+	mi := &testPoster{limits: msg.MastodonLimits}
+
+	accounts = []*account{
+		{name: "en", threshold: 4, poster: en, lang: msg.English},
+		{name: "mi", threshold: 4, poster: mi, lang: msg.Maori},
+	}
+
+	m := message{msg.Haz{Quake: &msg.Quake{
+		PublicID:              "2015p278423",
+		Time:                  time.Now().UTC(),
+		Latitude:              -37.92257397,
+		Longitude:             178.3544071,
+		Depth:                 9.62890625,
+		EvaluationStatus:      "automatic",
+		UsedPhaseCount:        25,
+		AzimuthalGap:          180,
+		MinimumDistance:       2.4,
+		Magnitude:             6.0,
+		MagnitudeStationCount: 12,
+	}}}
+
+	if m.processPosts() {
+		t.Errorf("unexpected reprocess %v", m.Err())
+	}
+
+	if len(en.posts) != 1 || en.posts[0] != "M6.0 quake causing severe shaking near Ruatoria http://geonet.org.nz/quakes/2015p278423" {
+		t.Errorf("unexpected English posts %v", en.posts)
+	}
+
+	if len(mi.posts) != 1 || mi.posts[0] != "He rū whenua M6.0, he tino kaha te wiri ki te takiwā o Ruatoria http://geonet.org.nz/quakes/2015p278423" {
+		t.Errorf("unexpected Māori posts %v", mi.posts)
+	}
+}
@@ -7,15 +7,25 @@ import (
 	"github.com/GeoNet/haz/sqs"
 	"github.com/GeoNet/haz/ua"
 	"log"
+	"os"
+	"strings"
 )
 
 var (
 	idp = msg.IdpQuake{}
 	uac *ua.Client
+	// languages for the push text from LANGUAGES e.g., en,mi
+	langs []msg.Lang
 )
 
 func init() {
 	uac = ua.Init()
+
+	var err error
+	if langs, err = msg.ParseLangs(os.Getenv("LANGUAGES")); err != nil {
+		log.Fatalf("ERROR: LANGUAGES: %s", err)
+	}
+
 	sqs.MaxNumberOfMessages = 1
 	sqs.VisibilityTimeout = 600
 	sqs.WaitTimeSeconds = 20
@@ -26,6 +36,14 @@ type message struct {
 }
 
 func main() {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR - problem with INTENSITY_RULES: %s", err)
+	}
+
+	if err := msg.InitLocalities(); err != nil {
+		log.Fatalf("ERROR - problem with localities: %s", err)
+	}
+
 	rx, dx, err := sqs.InitRx()
 	if err != nil {
 		log.Fatalf("ERROR - problem creating SQS from config: %s", err)
@@ -63,7 +81,16 @@ func (m *message) processPush() bool {
 		return false
 	}
 
-	message, tags := m.Quake.AlertUAPush()
+	var text []string
+	var tags []string
+
+	for _, l := range langs {
+		var t string
+		t, tags = m.Quake.AlertUAPushIn(l)
+		text = append(text, t)
+	}
+
+	message := strings.Join(text, "\n")
 	if tags == nil {
 		log.Printf("Quake %s didn't produce any tag.", m.Quake.PublicID)
 		return false
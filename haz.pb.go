@@ -103,6 +103,8 @@ func (*Timestamp) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1
 
 type Quakes struct {
 	Quakes []*Quake `protobuf:"bytes,1,rep,name=quakes" json:"quakes,omitempty"`
+	// the cursor for the next page of quakes.  Empty for the last page.
+	NextCursor string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor" json:"next_cursor,omitempty"`
 }
 
 func (m *Quakes) Reset()                    { *m = Quakes{} }
@@ -535,88 +537,89 @@ func init() {
 func init() { proto.RegisterFile("haz.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 1314 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xbc, 0x57, 0xdb, 0x6e, 0x1c, 0x45,
-	0x13, 0xd6, 0xee, 0xec, 0x69, 0xca, 0xf6, 0x7a, 0xdd, 0x7f, 0x92, 0x7f, 0x30, 0x89, 0x88, 0x06,
-	0x10, 0x01, 0x12, 0x03, 0xe1, 0x82, 0x08, 0x14, 0x09, 0x82, 0x03, 0x8a, 0x84, 0x51, 0x32, 0xb6,
-	0x12, 0xc1, 0xcd, 0xaa, 0xb3, 0xd3, 0xd9, 0x1d, 0xed, 0x1c, 0x36, 0x33, 0xbd, 0x36, 0x9b, 0xe7,
-	0xe0, 0x96, 0x27, 0xe0, 0x09, 0x50, 0x6e, 0x78, 0x20, 0x1e, 0x82, 0xaa, 0xea, 0x9e, 0xc3, 0x5a,
-	0xb6, 0x65, 0xdf, 0x70, 0xb5, 0x5d, 0x5f, 0x7d, 0x35, 0xdd, 0xfd, 0x55, 0x75, 0x75, 0x2f, 0xb8,
-	0x33, 0xf9, 0x66, 0x6f, 0x91, 0x67, 0x3a, 0x13, 0x0e, 0x0e, 0xfd, 0xb7, 0x6d, 0xe8, 0x3e, 0x5b,
-	0xca, 0xb9, 0x12, 0xef, 0x82, 0xbb, 0x58, 0xbe, 0x8c, 0xa3, 0xc9, 0x38, 0xda, 0xf7, 0x5a, 0xb7,
-	0x5b, 0x77, 0xdc, 0x60, 0x60, 0x80, 0x27, 0xfb, 0xc2, 0x87, 0x8e, 0x8e, 0x12, 0xe5, 0xb5, 0x11,
-	0xdf, 0xb8, 0x3f, 0xdc, 0xa3, 0xaf, 0x1c, 0x21, 0x50, 0x68, 0x99, 0x2c, 0x02, 0xf6, 0x89, 0x6f,
-	0x60, 0x27, 0xc9, 0xc2, 0xe8, 0x55, 0x34, 0x91, 0x3a, 0xca, 0xd2, 0x31, 0x07, 0x38, 0x67, 0x06,
-	0x8c, 0x9a, 0x44, 0x82, 0xc5, 0x2e, 0x0c, 0x62, 0xb4, 0xf4, 0x32, 0x54, 0x5e, 0x07, 0x63, 0x5a,
-	0x41, 0x65, 0x8b, 0x9b, 0xe0, 0xc6, 0x59, 0x3a, 0x35, 0xce, 0x2e, 0x3b, 0x6b, 0x40, 0x5c, 0x83,
-	0x6e, 0xa8, 0x16, 0x7a, 0xe6, 0xf5, 0xd8, 0x63, 0x0c, 0x8a, 0x49, 0xe4, 0x34, 0x35, 0x31, 0x7d,
-	0x13, 0x53, 0x01, 0x3c, 0x5b, 0x36, 0x91, 0x71, 0xa4, 0x57, 0xde, 0xc0, 0x6c, 0xb5, 0xb4, 0x85,
-	0x07, 0xfd, 0xd7, 0x4b, 0xe3, 0x72, 0xd9, 0x55, 0x9a, 0x62, 0x04, 0x4e, 0x92, 0x44, 0x1e, 0x20,
-	0xda, 0x0d, 0x68, 0xe8, 0x7f, 0x01, 0x6e, 0xb5, 0x29, 0x72, 0x17, 0x6a, 0xc2, 0xd2, 0x39, 0x01,
-	0x0d, 0x85, 0x80, 0x4e, 0x4a, 0x50, 0x9b, 0x21, 0x1e, 0xfb, 0x77, 0xa1, 0xc7, 0x7a, 0x17, 0xa8,
-	0x69, 0xef, 0x35, 0x8f, 0x30, 0xc4, 0x41, 0x91, 0x80, 0x45, 0x62, 0x67, 0x60, 0x3d, 0xfe, 0xef,
-	0x2d, 0xe8, 0x3f, 0xcf, 0xe2, 0x89, 0x4c, 0x33, 0x71, 0x0b, 0xe0, 0xd8, 0x0c, 0xeb, 0x0c, 0xb9,
-	0x16, 0xc1, 0x14, 0xa1, 0x0e, 0xa8, 0x57, 0x6c, 0x72, 0xe4, 0x06, 0xc6, 0x58, 0xd3, 0xd5, 0xb9,
-	0x48, 0xd7, 0xce, 0x69, 0x5d, 0x77, 0xc1, 0x39, 0x96, 0x31, 0xeb, 0xbd, 0x71, 0x7f, 0xc0, 0x6b,
-	0x7b, 0xfe, 0xdd, 0x4f, 0x01, 0x81, 0xfe, 0x33, 0x70, 0x70, 0x4c, 0x53, 0xc6, 0xea, 0x58, 0xc5,
-	0xbc, 0x98, 0x6e, 0x60, 0x0c, 0x9a, 0x52, 0x4e, 0x74, 0x74, 0x4c, 0x0a, 0x9a, 0xb5, 0x54, 0x36,
-	0x89, 0x8b, 0x1f, 0x92, 0x79, 0x58, 0xf0, 0x6a, 0x50, 0x5c, 0x6b, 0xfa, 0x5f, 0x81, 0x6b, 0x37,
-	0x8a, 0xd2, 0x7c, 0x02, 0xe5, 0xc6, 0x2a, 0x75, 0x36, 0xcd, 0x0a, 0x0c, 0x1a, 0xd4, 0x6e, 0x7f,
-	0x0a, 0xce, 0xc1, 0xc1, 0x93, 0xb5, 0x8d, 0xb6, 0x2e, 0xda, 0x68, 0xfb, 0xf4, 0x46, 0x6d, 0x5a,
-	0x9d, 0x2a, 0xad, 0xb4, 0xaf, 0x49, 0xb6, 0x4c, 0x35, 0x8b, 0x82, 0xfb, 0x62, 0xc3, 0xff, 0x1b,
-	0x73, 0x71, 0x38, 0x93, 0xf3, 0x28, 0x9d, 0x92, 0x38, 0x14, 0x63, 0x96, 0x66, 0xc4, 0xc1, 0x45,
-	0x98, 0xe8, 0x87, 0xb0, 0x81, 0x3f, 0xe3, 0x62, 0x99, 0x24, 0x32, 0x27, 0x09, 0x88, 0x73, 0x93,
-	0x39, 0x36, 0x7c, 0xef, 0x20, 0x89, 0x0e, 0x8d, 0xfb, 0x71, 0xaa, 0xf3, 0x55, 0x00, 0x49, 0x05,
-	0xd0, 0x39, 0xa4, 0x70, 0x9d, 0x69, 0x54, 0xdf, 0x2c, 0x6a, 0x80, 0xc0, 0x11, 0xd9, 0xbb, 0x0f,
-	0x61, 0xfb, 0x54, 0x2c, 0x2d, 0x7f, 0xae, 0x56, 0x36, 0x05, 0x34, 0xa4, 0xe5, 0x63, 0x92, 0x96,
-	0x66, 0xab, 0xb8, 0x7c, 0x36, 0xbe, 0x6e, 0x3f, 0x68, 0xf9, 0x63, 0xe8, 0x1e, 0xea, 0x2c, 0x67,
-	0xca, 0x11, 0x17, 0x4b, 0xab, 0x59, 0x2c, 0x58, 0xaf, 0x71, 0x94, 0xce, 0x6d, 0xd6, 0x78, 0x2c,
-	0xee, 0xda, 0xb6, 0x50, 0xcc, 0x54, 0x78, 0xce, 0x69, 0xae, 0x09, 0x58, 0xdd, 0x9d, 0x9f, 0xd5,
-	0x49, 0x21, 0x3e, 0x80, 0x7e, 0x81, 0x13, 0x45, 0xa7, 0x8a, 0x9b, 0x27, 0x0f, 0x4a, 0x97, 0xff,
-	0x2d, 0x74, 0x02, 0xa9, 0x55, 0xd5, 0x5d, 0x5a, 0x17, 0x74, 0x97, 0x2a, 0x27, 0xed, 0x66, 0x4e,
-	0xfe, 0x69, 0x03, 0xf0, 0x89, 0x39, 0xd4, 0x52, 0xd3, 0x91, 0xea, 0x2f, 0x54, 0x3e, 0x0e, 0xe5,
-	0xca, 0x4e, 0xeb, 0xf2, 0xb7, 0x68, 0x92, 0xa0, 0x87, 0x9e, 0x7d, 0xb9, 0x12, 0xf7, 0xa0, 0x73,
-	0xa2, 0xd4, 0xdc, 0xe6, 0xe5, 0x9d, 0xfa, 0xd0, 0xf1, 0x27, 0xf6, 0x5e, 0xa0, 0xcf, 0x24, 0x85,
-	0x69, 0xe2, 0x73, 0xe8, 0x26, 0x59, 0x8a, 0xed, 0xc5, 0x61, 0xfe, 0xee, 0x69, 0xfe, 0x01, 0x39,
-	0x4d, 0x80, 0x21, 0xd2, 0x04, 0x2b, 0x25, 0x73, 0x2c, 0x9e, 0x33, 0x27, 0xf8, 0x05, 0x7d, 0x76,
-	0x02, 0xa2, 0xed, 0x62, 0xe1, 0x57, 0x73, 0x5e, 0x25, 0x99, 0xbb, 0x0f, 0x00, 0xea, 0xc9, 0xaf,
-	0x14, 0x89, 0x53, 0x56, 0xab, 0xb8, 0x52, 0xfd, 0xbc, 0xed, 0xc3, 0x90, 0xb7, 0x72, 0xa4, 0x26,
-	0xb3, 0x14, 0xfb, 0x77, 0x7c, 0xf1, 0xb5, 0x81, 0x05, 0xa5, 0x57, 0x8b, 0xb2, 0x25, 0xf1, 0x58,
-	0xdc, 0x80, 0x9e, 0x9c, 0xaa, 0x74, 0xb2, 0xb2, 0x1d, 0xc0, 0x5a, 0x55, 0x11, 0x74, 0xae, 0x7a,
-	0xc5, 0x74, 0x2f, 0x79, 0xc5, 0xdc, 0x6b, 0x74, 0x88, 0x1e, 0xc7, 0xec, 0x98, 0xea, 0x50, 0x32,
-	0xc6, 0x4d, 0xa5, 0xe8, 0x5a, 0x35, 0x9a, 0xc6, 0x67, 0xcd, 0xa6, 0xd1, 0x3f, 0x8f, 0xdf, 0xe8,
-	0x23, 0x1f, 0x95, 0x17, 0xd1, 0xe0, 0x3c, 0xb2, 0xbd, 0x9b, 0xb0, 0x91, 0xf3, 0x60, 0xcc, 0xda,
-	0x98, 0x4b, 0xc6, 0x65, 0xe4, 0xc8, 0x0a, 0x94, 0x28, 0x3d, 0xcb, 0x42, 0xbe, 0x69, 0x50, 0x20,
-	0x63, 0x89, 0xf7, 0x60, 0x03, 0x93, 0x86, 0x61, 0xb8, 0x33, 0xec, 0xb9, 0x1b, 0xec, 0x04, 0x86,
-	0x0e, 0x08, 0xc1, 0x05, 0x6c, 0x2b, 0xca, 0x95, 0xd1, 0x86, 0x58, 0xde, 0x26, 0x93, 0x86, 0x35,
-	0x4c, 0x4c, 0xf1, 0x29, 0xec, 0x34, 0x88, 0xa8, 0x97, 0x5e, 0x16, 0xde, 0x16, 0x53, 0x47, 0xb5,
-	0xe3, 0x90, 0x71, 0x71, 0x07, 0x46, 0xcb, 0x42, 0x85, 0xe3, 0xc5, 0x4c, 0x16, 0x6a, 0x6c, 0xce,
-	0xe0, 0x90, 0x2f, 0xb4, 0x21, 0xe1, 0x4f, 0x09, 0xfe, 0x9e, 0x50, 0x6c, 0x15, 0x82, 0x99, 0xf4,
-	0x41, 0xfa, 0xb0, 0xe1, 0x6e, 0x33, 0x97, 0xbf, 0x71, 0x68, 0x1c, 0x86, 0xfd, 0x21, 0x0c, 0x91,
-	0x98, 0x86, 0xd8, 0xfd, 0xc7, 0x2a, 0xcf, 0xb3, 0xdc, 0x1b, 0x71, 0x67, 0xde, 0x2a, 0xd1, 0xc7,
-	0x04, 0x8a, 0xf7, 0x61, 0x4b, 0xbe, 0x89, 0x92, 0xa5, 0x9e, 0xc9, 0x78, 0x3c, 0x95, 0x0b, 0x6f,
-	0x87, 0x59, 0x9b, 0x15, 0xf8, 0xa3, 0x5c, 0x88, 0x8f, 0x61, 0x94, 0x44, 0x29, 0x02, 0xc9, 0x38,
-	0x8c, 0x28, 0x7e, 0xa2, 0x3c, 0xc1, 0xbc, 0x6d, 0x8b, 0xef, 0x5b, 0x98, 0xa9, 0xf2, 0xb7, 0x75,
-	0xea, 0xff, 0x2c, 0xd5, 0xe0, 0x15, 0x15, 0xf5, 0x4c, 0x54, 0x18, 0xc9, 0xb4, 0x66, 0x5e, 0x63,
-	0xe6, 0xd0, 0xc0, 0x15, 0xf1, 0x16, 0x74, 0x16, 0xd1, 0x64, 0xee, 0x5d, 0x6f, 0xf4, 0x9c, 0xa7,
-	0x08, 0x04, 0x0c, 0x53, 0x25, 0xd5, 0x6f, 0x91, 0x1b, 0xe7, 0x56, 0x52, 0xfd, 0x3c, 0x41, 0x69,
-	0x2a, 0xc3, 0x14, 0xc9, 0xff, 0x39, 0x39, 0x5b, 0x15, 0xca, 0x85, 0xb2, 0x07, 0x50, 0x01, 0x85,
-	0xe7, 0xf1, 0xe4, 0xe6, 0x18, 0x1c, 0x94, 0x70, 0xd0, 0x60, 0xf8, 0x3f, 0xc0, 0x66, 0x73, 0xc6,
-	0xfa, 0x9c, 0x9b, 0xfb, 0xd2, 0x18, 0xe2, 0x36, 0x6c, 0x2c, 0x71, 0x53, 0xb9, 0x96, 0x51, 0x6a,
-	0x6f, 0xf0, 0x56, 0xd0, 0x84, 0xfc, 0x3f, 0xda, 0x30, 0xb2, 0xa9, 0xac, 0x26, 0x42, 0x5d, 0x07,
-	0x27, 0xf2, 0x58, 0xbd, 0xca, 0xf2, 0xc4, 0xf6, 0xf1, 0x2d, 0x5e, 0xca, 0x0b, 0x0b, 0x06, 0x95,
-	0x7b, 0x5d, 0x8f, 0xf6, 0x25, 0xf4, 0x28, 0xdb, 0x88, 0xd3, 0x68, 0x23, 0xf8, 0x92, 0xb0, 0x25,
-	0x60, 0x9f, 0x2e, 0xa5, 0x49, 0x2f, 0x81, 0x2a, 0x5f, 0xe6, 0xb5, 0x58, 0xd9, 0xe4, 0xcb, 0x55,
-	0x11, 0x85, 0xf8, 0xa2, 0xb3, 0xef, 0xc5, 0xca, 0xa6, 0x73, 0x77, 0xa2, 0xa2, 0xe9, 0x4c, 0xdb,
-	0xf7, 0xa2, 0xb5, 0x68, 0xb9, 0xd8, 0x50, 0x62, 0xb3, 0xdc, 0x73, 0xcf, 0x76, 0xcd, 0xf1, 0xff,
-	0x6a, 0x81, 0x5b, 0x0b, 0xb3, 0xb6, 0xdb, 0xd6, 0x15, 0x76, 0xdb, 0x6c, 0x9a, 0x78, 0x0a, 0xd6,
-	0x4f, 0x95, 0xc3, 0xa7, 0x6a, 0xb3, 0x68, 0x9e, 0xa8, 0x47, 0xb0, 0x53, 0x92, 0xea, 0x19, 0xcd,
-	0x2d, 0x74, 0xdd, 0x5e, 0xbf, 0xeb, 0x49, 0x0b, 0x46, 0xc5, 0x29, 0xc4, 0xff, 0xb3, 0x0d, 0x1d,
-	0x2a, 0xdd, 0xab, 0xe4, 0xf3, 0x32, 0x7f, 0x0e, 0xb0, 0xd6, 0xb8, 0x81, 0xd8, 0x1c, 0x1a, 0xe3,
-	0x3f, 0x4c, 0xe2, 0x19, 0xbd, 0x71, 0x70, 0xf9, 0xde, 0xe8, 0x9e, 0xdd, 0x1b, 0x7d, 0x0d, 0x83,
-	0x52, 0x0f, 0xda, 0x4b, 0xaa, 0xf4, 0x49, 0x96, 0xcf, 0xed, 0x35, 0x58, 0x9a, 0xe4, 0xb1, 0x3a,
-	0xdb, 0x9c, 0x96, 0x66, 0xf9, 0x3f, 0x84, 0x5d, 0x4e, 0xfd, 0x3f, 0x84, 0x7d, 0x18, 0x35, 0x99,
-	0xc9, 0x34, 0xc5, 0x56, 0xdf, 0x31, 0x51, 0xd6, 0x7c, 0xd4, 0xfd, 0x95, 0xfe, 0xba, 0xbd, 0xec,
-	0xf1, 0xdf, 0xb8, 0x2f, 0xff, 0x0d, 0x00, 0x00, 0xff, 0xff, 0x99, 0xb2, 0x1d, 0x22, 0xd3, 0x0d,
-	0x00, 0x00,
+	// 1342 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xbc, 0x57, 0xdb, 0x6e, 0xdb, 0x46,
+	0x13, 0x06, 0x45, 0xea, 0xc0, 0x91, 0x2d, 0xcb, 0xfb, 0x27, 0xf9, 0x59, 0x35, 0x41, 0x05, 0xa6,
+	0x45, 0x9c, 0x36, 0x71, 0xdb, 0xf4, 0x22, 0x41, 0x8b, 0x00, 0x4d, 0xe2, 0xb4, 0x30, 0x10, 0x15,
+	0x09, 0x6d, 0x24, 0x68, 0x6f, 0x88, 0x0d, 0xb9, 0x11, 0x17, 0xe2, 0x41, 0x21, 0x97, 0x76, 0xe4,
+	0xe7, 0xe8, 0x6d, 0x9f, 0xa0, 0x4f, 0x50, 0xe4, 0xa6, 0x0f, 0xd4, 0x87, 0x28, 0x76, 0x76, 0x79,
+	0x90, 0x61, 0x1b, 0xf6, 0x4d, 0xef, 0x76, 0xbe, 0xf9, 0x66, 0x67, 0x77, 0x66, 0x67, 0x86, 0x04,
+	0x3b, 0xa2, 0x27, 0xbb, 0xcb, 0x3c, 0x13, 0x19, 0x31, 0x23, 0x7a, 0xe2, 0x7e, 0xec, 0x40, 0xf7,
+	0x55, 0x49, 0x17, 0x8c, 0x7c, 0x0a, 0xf6, 0xb2, 0x7c, 0x1b, 0xf3, 0xc0, 0xe7, 0x7b, 0x8e, 0x31,
+	0x35, 0x76, 0x6c, 0x6f, 0xa0, 0x80, 0xfd, 0x3d, 0xe2, 0x82, 0x25, 0x78, 0xc2, 0x9c, 0xce, 0xd4,
+	0xd8, 0x19, 0x3e, 0x18, 0xed, 0xca, 0x5d, 0x0e, 0x79, 0xc2, 0x0a, 0x41, 0x93, 0xa5, 0x87, 0x3a,
+	0xf2, 0x03, 0x6c, 0x27, 0x59, 0xc8, 0xdf, 0xf1, 0x80, 0x0a, 0x9e, 0xa5, 0x3e, 0x1a, 0x98, 0x67,
+	0x1a, 0x8c, 0xdb, 0x44, 0x09, 0x93, 0x09, 0x0c, 0x62, 0x2a, 0xb8, 0x28, 0x43, 0xe6, 0x58, 0x53,
+	0x63, 0xc7, 0xf0, 0x6a, 0x99, 0xdc, 0x04, 0x3b, 0xce, 0xd2, 0xb9, 0x52, 0x76, 0x51, 0xd9, 0x00,
+	0xe4, 0x1a, 0x74, 0x43, 0xb6, 0x14, 0x91, 0xd3, 0x43, 0x8d, 0x12, 0xa4, 0x4d, 0x42, 0xe7, 0xa9,
+	0xb2, 0xe9, 0x2b, 0x9b, 0x1a, 0x40, 0x6f, 0x59, 0x40, 0x63, 0x2e, 0x56, 0xce, 0x40, 0x5d, 0xb5,
+	0x92, 0x89, 0x03, 0xfd, 0xf7, 0xa5, 0x52, 0xd9, 0xa8, 0xaa, 0x44, 0x32, 0x06, 0x33, 0x49, 0xb8,
+	0x03, 0x53, 0x63, 0xa7, 0xeb, 0xc9, 0xa5, 0xfb, 0x2d, 0xd8, 0xf5, 0xa5, 0xa4, 0xba, 0x60, 0x01,
+	0x86, 0xce, 0xf4, 0xe4, 0x92, 0x10, 0xb0, 0x52, 0x09, 0x75, 0x10, 0xc2, 0xb5, 0x3b, 0x83, 0x1e,
+	0xc6, 0xbb, 0x20, 0x2e, 0xf4, 0xde, 0xe3, 0xca, 0x31, 0xa6, 0xe6, 0xce, 0xf0, 0x01, 0x60, 0x90,
+	0x50, 0xe9, 0x69, 0x0d, 0xf9, 0x0c, 0x86, 0x29, 0xfb, 0x20, 0xfc, 0xa0, 0xcc, 0x8b, 0x2c, 0xc7,
+	0x8d, 0x6c, 0x0f, 0x24, 0xf4, 0x0c, 0x11, 0xf7, 0x77, 0x03, 0xfa, 0xaf, 0xb3, 0x38, 0xa0, 0x69,
+	0x46, 0x6e, 0x01, 0x1c, 0xa9, 0x65, 0x93, 0x42, 0x5b, 0x23, 0xfb, 0x7b, 0x32, 0x50, 0x82, 0x8b,
+	0x98, 0xe9, 0x5d, 0x94, 0xb0, 0x16, 0x78, 0xf3, 0xa2, 0xc0, 0x5b, 0xa7, 0x03, 0x3f, 0x01, 0xf3,
+	0x88, 0xc6, 0x98, 0x90, 0xe1, 0x83, 0x01, 0x1e, 0xfe, 0xf5, 0x93, 0x17, 0x9e, 0x04, 0xdd, 0x57,
+	0x60, 0xbe, 0x7e, 0xf2, 0x42, 0xba, 0x8c, 0xd9, 0x11, 0x8b, 0xf1, 0x30, 0x5d, 0x4f, 0x09, 0xd2,
+	0x25, 0x0d, 0x04, 0x3f, 0x92, 0x21, 0x56, 0x67, 0xa9, 0x65, 0x19, 0xfd, 0x88, 0x9e, 0xd0, 0x3c,
+	0x2c, 0xf0, 0x34, 0xb6, 0x57, 0x89, 0xee, 0x43, 0xb0, 0xf5, 0x45, 0x59, 0x41, 0xbe, 0x84, 0xea,
+	0x62, 0x75, 0xf8, 0x36, 0xd4, 0x09, 0x14, 0xea, 0x35, 0x6a, 0x77, 0x0e, 0xe6, 0x6c, 0xb6, 0xbf,
+	0x76, 0x51, 0xe3, 0xa2, 0x8b, 0x76, 0x4e, 0x5f, 0x54, 0xe7, 0xdd, 0xac, 0xf3, 0x2e, 0xef, 0x15,
+	0x64, 0x65, 0x2a, 0x30, 0x28, 0x5d, 0x4f, 0x09, 0xee, 0xdf, 0x06, 0xf4, 0x0f, 0x22, 0xba, 0xe0,
+	0xe9, 0x9c, 0x4c, 0x94, 0x8d, 0x3a, 0x9a, 0x0a, 0xce, 0x6c, 0xb6, 0xaf, 0xac, 0x1f, 0xc3, 0x30,
+	0x49, 0xb8, 0x5f, 0x94, 0x49, 0x42, 0x73, 0x19, 0x02, 0xc9, 0xb9, 0x89, 0x1c, 0x6d, 0xbe, 0x3b,
+	0x4b, 0xf8, 0x81, 0x52, 0x3f, 0x4f, 0x45, 0xbe, 0xf2, 0x20, 0xa9, 0x01, 0x59, 0xa8, 0xd2, 0x5c,
+	0x64, 0x82, 0xc6, 0xfa, 0x50, 0x83, 0x24, 0xe1, 0x87, 0x52, 0x9e, 0x3c, 0x86, 0xad, 0x53, 0xb6,
+	0xf2, 0xf8, 0x0b, 0xb6, 0xd2, 0x29, 0x90, 0x4b, 0x79, 0xfc, 0x23, 0x1a, 0x97, 0xea, 0xaa, 0x5d,
+	0x4f, 0x09, 0xdf, 0x77, 0x1e, 0x19, 0xae, 0x0f, 0xdd, 0x03, 0x91, 0xe5, 0x48, 0x39, 0xc4, 0xc7,
+	0x62, 0xb4, 0x1f, 0x0b, 0x01, 0x2b, 0xe6, 0xe9, 0x42, 0x67, 0x0d, 0xd7, 0xe4, 0x9e, 0xee, 0x1b,
+	0x45, 0xc4, 0xc2, 0x73, 0xca, 0xbd, 0x21, 0xb8, 0xf7, 0xc0, 0xfa, 0x85, 0x1d, 0x17, 0xe4, 0x73,
+	0xe8, 0x17, 0x22, 0xcb, 0xf9, 0xa9, 0xd7, 0x8f, 0xce, 0xbd, 0x4a, 0xe5, 0xfe, 0x08, 0x96, 0x47,
+	0x05, 0xab, 0xdb, 0x8f, 0x71, 0x41, 0xfb, 0xa9, 0x73, 0xd2, 0x69, 0xe7, 0xe4, 0x9f, 0x0e, 0x00,
+	0x96, 0xd4, 0x81, 0xa0, 0x42, 0xd6, 0x5c, 0x7f, 0xc9, 0x72, 0x3f, 0xa4, 0x2b, 0xed, 0xd6, 0xc6,
+	0xbd, 0xa4, 0x13, 0xaf, 0xb7, 0x64, 0xf9, 0x1e, 0x5d, 0x91, 0xfb, 0x60, 0x1d, 0x33, 0xb6, 0xd0,
+	0x79, 0xf9, 0xa4, 0xa9, 0x4a, 0xdc, 0x62, 0xf7, 0x0d, 0x63, 0x0b, 0x95, 0x14, 0xa4, 0x91, 0x6f,
+	0xa0, 0x9b, 0x64, 0xa9, 0x88, 0x1c, 0x13, 0xf9, 0x93, 0xd3, 0xfc, 0x99, 0x54, 0x2a, 0x03, 0x45,
+	0x94, 0x0e, 0x56, 0x8c, 0xe6, 0x8e, 0x75, 0xb6, 0x83, 0x5f, 0x19, 0xcd, 0xb5, 0x03, 0x49, 0x9b,
+	0x3c, 0x04, 0xbb, 0xf6, 0x79, 0x95, 0x64, 0x4e, 0x1e, 0x01, 0x34, 0xce, 0xaf, 0x64, 0xf9, 0x10,
+	0xec, 0xfa, 0x14, 0x57, 0x7a, 0x3f, 0x1f, 0xfb, 0x30, 0xc2, 0xab, 0x1c, 0xb2, 0x20, 0x4a, 0x79,
+	0x40, 0xe3, 0x8b, 0xe7, 0x0a, 0x01, 0x4b, 0xac, 0x96, 0x55, 0x4b, 0xc2, 0x35, 0xb9, 0x01, 0x3d,
+	0x3a, 0x67, 0x69, 0xb0, 0xd2, 0x1d, 0x40, 0x4b, 0xf5, 0x23, 0xb0, 0xae, 0x3a, 0x83, 0xba, 0x97,
+	0x9c, 0x41, 0xf7, 0x5b, 0x1d, 0xa2, 0x87, 0x36, 0xdb, 0xea, 0x75, 0x30, 0x1a, 0xbf, 0x2a, 0x69,
+	0x2a, 0xb8, 0x58, 0xb5, 0x9a, 0xc6, 0xd7, 0xed, 0xa6, 0xd1, 0x3f, 0x8f, 0xdf, 0x70, 0xc8, 0x9d,
+	0x6a, 0x52, 0x0d, 0xce, 0x23, 0x2b, 0xbd, 0x6c, 0xe4, 0xb8, 0xf0, 0x31, 0x36, 0x6a, 0x0a, 0xd9,
+	0x88, 0x1c, 0xea, 0x00, 0x25, 0x4c, 0x44, 0x59, 0x88, 0xa3, 0xc8, 0xf6, 0xb4, 0x24, 0x87, 0x05,
+	0xa3, 0xb9, 0x88, 0xfc, 0x24, 0x0b, 0x59, 0xec, 0x0c, 0x51, 0x09, 0x08, 0xcd, 0x24, 0x42, 0xee,
+	0xc0, 0x16, 0x93, 0xb9, 0x52, 0xb1, 0x91, 0x2c, 0x67, 0x03, 0x49, 0xa3, 0x06, 0x96, 0x4c, 0xf2,
+	0x15, 0x6c, 0xb7, 0x88, 0x85, 0xa0, 0xa2, 0x2c, 0x9c, 0x4d, 0xa4, 0x8e, 0x1b, 0xc5, 0x01, 0xe2,
+	0x64, 0x07, 0xc6, 0x65, 0xc1, 0x42, 0x7f, 0x19, 0xd1, 0x82, 0xf9, 0xaa, 0x06, 0x47, 0x38, 0xf1,
+	0x46, 0x12, 0x7f, 0x29, 0xe1, 0x67, 0x12, 0x25, 0xf7, 0x80, 0x20, 0xb3, 0x10, 0x6a, 0x63, 0xc5,
+	0xdd, 0x42, 0x2e, 0xee, 0x71, 0xa0, 0x14, 0x8a, 0xfd, 0x05, 0x8c, 0x0a, 0x41, 0xd3, 0x90, 0xe6,
+	0xa1, 0xcf, 0xf2, 0x3c, 0xcb, 0x9d, 0x31, 0x76, 0xe6, 0xcd, 0x0a, 0x7d, 0x2e, 0x41, 0x72, 0x1b,
+	0x36, 0xe9, 0x09, 0x4f, 0x4a, 0x11, 0xd1, 0xd8, 0x9f, 0xd3, 0xa5, 0xb3, 0x8d, 0xac, 0x8d, 0x1a,
+	0xfc, 0x99, 0x2e, 0xc9, 0x5d, 0x18, 0x27, 0x3c, 0xe5, 0x49, 0x99, 0xf8, 0x21, 0x97, 0xf6, 0x01,
+	0x73, 0x08, 0xf2, 0xb6, 0x34, 0xbe, 0xa7, 0x61, 0xa4, 0xd2, 0x0f, 0xeb, 0xd4, 0xff, 0x69, 0x2a,
+	0xfd, 0xb0, 0x46, 0xbd, 0x03, 0x5b, 0x09, 0x0b, 0x39, 0x4d, 0x1b, 0xe6, 0x35, 0x64, 0x8e, 0x14,
+	0x5c, 0x13, 0x6f, 0x81, 0xb5, 0xe4, 0xc1, 0xc2, 0xb9, 0xde, 0xea, 0x39, 0x2f, 0x79, 0xb0, 0xf0,
+	0x10, 0x96, 0x2f, 0xa9, 0xf9, 0x58, 0xb9, 0x71, 0xee, 0x4b, 0xaa, 0x39, 0x32, 0x34, 0xb5, 0xa0,
+	0x1e, 0xc9, 0xff, 0x31, 0x39, 0x9b, 0x35, 0x8a, 0x0f, 0x65, 0x17, 0xa0, 0x06, 0x0a, 0xc7, 0x99,
+	0x9a, 0x75, 0x19, 0xcc, 0x2a, 0xd8, 0x6b, 0x31, 0xdc, 0x9f, 0x60, 0xa3, 0xed, 0xb1, 0xa9, 0x73,
+	0x35, 0x2f, 0x95, 0x40, 0xa6, 0x30, 0x2c, 0xd3, 0x80, 0xe5, 0x82, 0xf2, 0x54, 0x4f, 0x70, 0xc3,
+	0x6b, 0x43, 0xee, 0x1f, 0x1d, 0x18, 0xeb, 0x54, 0xd6, 0x8e, 0xc8, 0x5d, 0x18, 0x1c, 0xd3, 0x23,
+	0xf6, 0x2e, 0xcb, 0x13, 0xdd, 0xc7, 0x37, 0xf1, 0x28, 0x6f, 0x34, 0xe8, 0xd5, 0xea, 0xf5, 0x78,
+	0x74, 0x2e, 0x11, 0x8f, 0xaa, 0x8d, 0x98, 0xad, 0x36, 0xe2, 0x40, 0x5f, 0x3f, 0x01, 0xfd, 0xe9,
+	0x52, 0x89, 0xf2, 0x4b, 0xa0, 0xce, 0x97, 0xfa, 0x9c, 0xac, 0x65, 0xa9, 0xcb, 0x59, 0xc1, 0xc3,
+	0x92, 0xc6, 0xfa, 0x83, 0xb2, 0x96, 0x65, 0xdd, 0x1d, 0x33, 0x3e, 0x8f, 0x84, 0xfe, 0xa0, 0xd4,
+	0x92, 0x3c, 0x2e, 0x4d, 0x96, 0xb1, 0x3a, 0xee, 0xb9, 0xb5, 0xdd, 0x70, 0xdc, 0xbf, 0x0c, 0xb0,
+	0x9b, 0xc0, 0xac, 0xdd, 0xd6, 0xb8, 0xc2, 0x6d, 0xdb, 0x4d, 0xf3, 0x36, 0x6c, 0xae, 0x57, 0x95,
+	0x89, 0x55, 0xb5, 0x51, 0xb4, 0x2b, 0xea, 0x29, 0x6c, 0x57, 0xa4, 0xc6, 0xa3, 0x9a, 0x42, 0xd7,
+	0xf5, 0xf8, 0x5d, 0x4f, 0x9a, 0x37, 0x2e, 0x4e, 0x21, 0xee, 0x9f, 0x1d, 0xb0, 0xe4, 0xd3, 0xbd,
+	0x4a, 0x3e, 0x2f, 0xf3, 0xf7, 0x70, 0x0d, 0xba, 0xd8, 0x40, 0x74, 0x0e, 0x95, 0xf0, 0x1f, 0x26,
+	0xf1, 0x8c, 0xde, 0x38, 0xb8, 0x7c, 0x6f, 0xb4, 0xcf, 0xee, 0x8d, 0xae, 0x80, 0x41, 0x15, 0x0f,
+	0x79, 0x97, 0x94, 0x89, 0xe3, 0x2c, 0x5f, 0xe8, 0x31, 0x58, 0x89, 0x52, 0xa3, 0xe3, 0xac, 0x73,
+	0x5a, 0x89, 0xd5, 0x8f, 0x0a, 0xaa, 0xcc, 0xe6, 0x47, 0x05, 0x75, 0x0e, 0xf4, 0x83, 0x88, 0xa6,
+	0x29, 0x8b, 0x31, 0x36, 0xb6, 0x57, 0x89, 0x4f, 0xbb, 0xbf, 0xc9, 0x7f, 0xbb, 0xb7, 0x3d, 0xfc,
+	0xcf, 0xfb, 0xee, 0xdf, 0x01, 0x00, 0xdd, 0x3b, 0xc7, 0x02, 0xf4, 0x0d, 0x00, 0x00,
 }
@@ -16,6 +16,7 @@ import (
 
 var (
 	db database.DB
+	store database.QuakeStore = &db
 	retry = time.Duration(30) * time.Second
 	expireInterval = time.Duration(10) * time.Second
 )
@@ -136,7 +137,7 @@ func (m *message) Process() bool {
 }
 
 func (m *message) saveMeasured() {
-	_, err := db.Exec("select impact.add_intensity_measured($1, $2, $3, $4, $5)", m.Source, m.Longitude, m.Latitude, m.Time, m.MMI)
+	err := store.SaveIntensity(m.Intensity)
 
 	if err != nil {
 		m.SetErr(err)
@@ -148,7 +149,7 @@ func (m *message) saveReported() {
 		return
 	}
 
-	_, err := db.Exec("select impact.add_intensity_reported($1, $2, $3, $4, $5, $6)", m.Source, m.Longitude, m.Latitude, m.Time, m.MMI, m.Comment)
+	err := store.SaveIntensity(m.Intensity)
 
 	if err != nil {
 		m.SetErr(err)
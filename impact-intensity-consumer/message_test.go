@@ -1,6 +1,8 @@
 package main
 
 import (
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
 	"testing"
 	"time"
 )
@@ -47,3 +49,40 @@ func TestSeenReported(t *testing.T) {
 		t.Errorf("should get nil error %v", m.Err())
 	}
 }
+
+func TestProcess(t *testing.T) {
+	m := database.NewMemStore()
+	store = m
+	defer func() { store = &db }()
+
+	now := time.Now().UTC()
+
+	in := []message{
+		{msg.Intensity{Source: "NZ.TEST", Quality: "measured", MMI: 4, Latitude: -41, Longitude: 174, Time: now}},
+		{msg.Intensity{Source: "test.process", Quality: "reported", MMI: 5, Latitude: -41, Longitude: 174, Time: now}},
+		// old.
+		{msg.Intensity{Source: "NZ.OLD", Quality: "measured", MMI: 4, Latitude: -41, Longitude: 174, Time: now.Add(-2 * time.Hour)}},
+	}
+
+	for i := range in {
+		if in[i].Process() {
+			t.Errorf("%d: unexpected redelivery", i)
+		}
+	}
+
+	meas, err := m.Intensities(database.IntensityQuery{Quality: "measured"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meas) != 1 || meas[0].Source != "NZ.TEST" {
+		t.Errorf("expected 1 measured intensity got %+v", meas)
+	}
+
+	rep, err := m.Intensities(database.IntensityQuery{Quality: "reported"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep) != 1 || rep[0].MMI != 5 {
+		t.Errorf("expected 1 reported intensity got %+v", rep)
+	}
+}
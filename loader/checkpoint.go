@@ -0,0 +1,98 @@
+package loader
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+)
+
+/*
+Checkpoint records the names of loaded files, one per line, so that a stopped load can be
+restarted without loading them again.  The file is only appended to.  A nil *Checkpoint
+records nothing.
+*/
+type Checkpoint struct {
+	mu   sync.Mutex
+	f    *os.File
+	done map[string]bool
+}
+
+// OpenCheckpoint reads the names already recorded in file (if it exists) and opens it for appending.
+func OpenCheckpoint(file string) (*Checkpoint, error) {
+	c := &Checkpoint{done: make(map[string]bool)}
+
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if n := strings.TrimSpace(s.Text()); n != "" {
+			c.done[n] = true
+		}
+	}
+	if err = s.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	c.f = f
+
+	return c, nil
+}
+
+// Done returns true if name has been loaded.
+func (c *Checkpoint) Done(name string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.done[name]
+}
+
+// Add records name as loaded.
+func (c *Checkpoint) Add(name string) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done[name] {
+		return nil
+	}
+
+	if _, err := c.f.WriteString(name + "\n"); err != nil {
+		return err
+	}
+
+	c.done[name] = true
+
+	return nil
+}
+
+// Len returns the number of names recorded.
+func (c *Checkpoint) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.done)
+}
+
+func (c *Checkpoint) Close() error {
+	if c == nil {
+		return nil
+	}
+
+	return c.f.Close()
+}
@@ -0,0 +1,85 @@
+package loader
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+/*
+FromEnv returns the SC3ML Source and Config from the env
+
+	LOADER_SOURCE      directory, archive, or s3://bucket/prefix.  Default SC3_SPOOL_DIR.
+	LOADER_WORKERS     concurrent loads.  Default 10.
+	LOADER_RETRIES     retries for transient errors.  Default 5.
+	LOADER_BACKOFF     delay before the first retry, doubled for each retry e.g., 2s.  Default 1s.
+	LOADER_CHECKPOINT  file recording loaded files.  Empty for no checkpoint.
+	LOADER_PROGRESS    progress logging interval e.g., 30s.  Default 30s, 0 for none.
+	S3_ENDPOINT        for s3:// sources.  Default https://s3.amazonaws.com
+	S3_ACCESS_KEY      for signing s3:// requests.  Empty for anonymous requests.
+	S3_SECRET_KEY      for signing s3:// requests.
+	AWS_REGION         for signing s3:// requests.  Default us-east-1
+
+Close the Config.Checkpoint when the load is finished.
+*/
+func FromEnv() (Source, Config, error) {
+	var c Config
+	var err error
+
+	spec := os.Getenv("LOADER_SOURCE")
+	if spec == "" {
+		spec = os.Getenv("SC3_SPOOL_DIR")
+	}
+	if spec == "" {
+		return nil, c, fmt.Errorf("set LOADER_SOURCE or SC3_SPOOL_DIR")
+	}
+
+	if c.Workers, err = envInt("LOADER_WORKERS"); err != nil {
+		return nil, c, err
+	}
+
+	if c.Retries, err = envInt("LOADER_RETRIES"); err != nil {
+		return nil, c, err
+	}
+
+	if b := os.Getenv("LOADER_BACKOFF"); b != "" {
+		if c.Backoff, err = time.ParseDuration(b); err != nil || c.Backoff < 0 {
+			return nil, c, fmt.Errorf("invalid LOADER_BACKOFF: %s", b)
+		}
+	}
+
+	c.Progress = time.Duration(30) * time.Second
+	if p := os.Getenv("LOADER_PROGRESS"); p != "" {
+		if c.Progress, err = time.ParseDuration(p); err != nil {
+			return nil, c, fmt.Errorf("invalid LOADER_PROGRESS: %s", err)
+		}
+	}
+
+	s, err := Open(spec, ".xml")
+	if err != nil {
+		return nil, c, err
+	}
+
+	if f := os.Getenv("LOADER_CHECKPOINT"); f != "" {
+		if c.Checkpoint, err = OpenCheckpoint(f); err != nil {
+			return nil, c, err
+		}
+	}
+
+	return s, c, nil
+}
+
+func envInt(k string) (int, error) {
+	v := os.Getenv(k)
+	if v == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", k, v)
+	}
+
+	return i, nil
+}
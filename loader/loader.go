@@ -0,0 +1,275 @@
+/*
+loader bulk loads files (usually SC3ML) from a Source with concurrent workers.
+
+Loaded files are recorded in a Checkpoint so a stopped load can be resumed.  Transient errors
+(e.g., a lost DB connection) are retried with an exponential backoff and stop the load if they
+persist.  Files that are invalid (see Invalid) are logged, skipped, and checkpointed.  Other errors
+fail only that file; it is not checkpointed so it is retried on the next run.
+*/
+package loader
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Config for Run.  Zero values use the defaults.
+type Config struct {
+	Workers    int           // concurrent loads.  Default 10.
+	Retries    int           // retries for a transient error before the load is stopped.  Default 5.
+	Backoff    time.Duration // delay before the first retry, doubled for each retry.  Default 1s.
+	Progress   time.Duration // interval for progress logging.  0 for no progress logging.
+	Checkpoint *Checkpoint   // may be nil.
+}
+
+// Stats for a load.
+type Stats struct {
+	Loaded   int
+	Skipped  int      // already in the checkpoint.
+	Invalid  int      // invalid files that were skipped.
+	Failed   int      // non-transient errors.
+	Retries  int      // retries for transient errors.
+	Failures []string // names of failed files.
+	Elapsed  time.Duration
+}
+
+type invalidError struct {
+	err error
+}
+
+func (e invalidError) Error() string {
+	return e.err.Error()
+}
+
+func (e invalidError) Unwrap() error {
+	return e.err
+}
+
+// Invalid wraps err from a load func for a file that can never be loaded e.g., it isn't valid SC3ML.
+func Invalid(err error) error {
+	return invalidError{err: err}
+}
+
+// IsInvalid returns true if err is from Invalid.
+func IsInvalid(err error) bool {
+	var i invalidError
+	return errors.As(err, &i)
+}
+
+func (s Stats) String() string {
+	var rate float64
+	if s.Elapsed > 0 {
+		rate = float64(s.Loaded) / s.Elapsed.Seconds()
+	}
+
+	return fmt.Sprintf("loaded %d skipped %d invalid %d failed %d retries %d in %s (%.1f files/s)",
+		s.Loaded, s.Skipped, s.Invalid, s.Failed, s.Retries, s.Elapsed.Round(time.Second), rate)
+}
+
+/*
+Run calls load for each file in src that is not already in the checkpoint.  Returns the stats
+and an error if src can't be read or a transient error persisted past the retries, in which
+case the load stops.  Files that failed with other errors are in Stats.Failures.
+*/
+func Run(c Config, src Source, load func(name string, r io.Reader) error) (Stats, error) {
+	if c.Workers <= 0 {
+		c.Workers = 10
+	}
+	if c.Retries <= 0 {
+		c.Retries = 5
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = time.Second
+	}
+
+	start := time.Now()
+
+	var mu sync.Mutex
+	var s Stats
+	var runErr error
+
+	stop := make(chan struct{})
+	var once sync.Once
+
+	abort := func(err error) {
+		once.Do(func() {
+			runErr = err
+			close(stop)
+		})
+	}
+
+	stats := func() Stats {
+		mu.Lock()
+		defer mu.Unlock()
+		r := s
+		r.Failures = append([]string(nil), s.Failures...)
+		r.Elapsed = time.Since(start)
+		return r
+	}
+
+	if c.Progress > 0 {
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			t := time.NewTicker(c.Progress)
+			defer t.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-t.C:
+					log.Printf("progress: %s", stats())
+				}
+			}
+		}()
+	}
+
+	files := make(chan File)
+
+	var wg sync.WaitGroup
+	wg.Add(c.Workers)
+	for i := 0; i < c.Workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for f := range files {
+				select {
+				case <-stop:
+					continue
+				default:
+				}
+
+				n, err := loadFile(c, f, load, stop)
+
+				mu.Lock()
+				s.Retries += n
+				switch {
+				case err == nil:
+					s.Loaded++
+				case IsInvalid(err):
+					s.Invalid++
+				case Transient(err):
+					// already retried so stop the load.
+				default:
+					s.Failed++
+					s.Failures = append(s.Failures, f.Name)
+				}
+				mu.Unlock()
+
+				switch {
+				case err == nil:
+					if err = c.Checkpoint.Add(f.Name); err != nil {
+						abort(fmt.Errorf("checkpoint: %s", err))
+					}
+				case IsInvalid(err):
+					log.Printf("WARN skipping invalid %s: %s", f.Name, err)
+					if err = c.Checkpoint.Add(f.Name); err != nil {
+						abort(fmt.Errorf("checkpoint: %s", err))
+					}
+				case Transient(err):
+					abort(fmt.Errorf("%s: %s", f.Name, err))
+				default:
+					log.Printf("WARN %s: %s", f.Name, err)
+				}
+			}
+		}()
+	}
+
+	errStopped := errors.New("stopped")
+
+	err := src.Each(func(f File) error {
+		if c.Checkpoint.Done(f.Name) {
+			mu.Lock()
+			s.Skipped++
+			mu.Unlock()
+			return nil
+		}
+
+		select {
+		case files <- f:
+			return nil
+		case <-stop:
+			return errStopped
+		}
+	})
+
+	close(files)
+	wg.Wait()
+
+	if err != nil && err != errStopped {
+		abort(err)
+	}
+
+	return stats(), runErr
+}
+
+// loadFile loads f, retrying transient errors.  Returns the number of retries.
+func loadFile(c Config, f File, load func(string, io.Reader) error, stop <-chan struct{}) (int, error) {
+	wait := c.Backoff
+
+	var err error
+
+	for i := 0; ; i++ {
+		if err = loadOnce(f, load); err == nil || !Transient(err) || i == c.Retries {
+			return i, err
+		}
+
+		log.Printf("WARN %s: %s retrying in %s", f.Name, err, wait)
+
+		select {
+		case <-stop:
+			return i, err
+		case <-time.After(wait):
+		}
+
+		wait *= 2
+	}
+}
+
+func loadOnce(f File, load func(string, io.Reader) error) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return load(f.Name, r)
+}
+
+/*
+Transient returns true if err is likely to go away if the operation is retried e.g., connection
+failures, serialization failures, deadlocks, and the server shutting down or running out of resources.
+*/
+func Transient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var p *pq.Error
+	if errors.As(err, &p) {
+		switch p.Code.Class() {
+		case "08", "53", "57": // connection exception, insufficient resources, operator intervention.
+			return true
+		}
+		switch p.Code {
+		case "40001", "40P01": // serialization failure, deadlock detected.
+			return true
+		}
+		return false
+	}
+
+	var n net.Error
+	return errors.As(err, &n)
+}
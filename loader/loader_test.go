@@ -0,0 +1,419 @@
+package loader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/lib/pq"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func gz(s string) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write([]byte(s))
+	w.Close()
+	return b.Bytes()
+}
+
+// read returns the name and content of the files in s.
+func read(t *testing.T, s Source) map[string]string {
+	m := make(map[string]string)
+
+	err := s.Each(func(f File) error {
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		m[f.Name] = string(b)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func expect(t *testing.T, id string, m map[string]string) {
+	if len(m) != 2 || m["a.xml"] != "a" || m["b.xml.gz"] != "b" {
+		t.Errorf("%s: unexpected files %v", id, m)
+	}
+}
+
+func TestDir(t *testing.T) {
+	d, err := ioutil.TempDir("", "loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	ioutil.WriteFile(filepath.Join(d, "a.xml"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(d, "b.xml.gz"), gz("b"), 0644)
+	ioutil.WriteFile(filepath.Join(d, "c.txt"), []byte("c"), 0644)
+
+	s, err := Open(d, ".xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, "dir", read(t, s))
+}
+
+func TestTar(t *testing.T) {
+	d, err := ioutil.TempDir("", "loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	var b bytes.Buffer
+	w := tar.NewWriter(&b)
+
+	for _, v := range []struct {
+		name string
+		body []byte
+	}{
+		{"a.xml", []byte("a")},
+		{"b.xml.gz", gz("b")},
+		{"c.txt", []byte("c")},
+	} {
+		w.WriteHeader(&tar.Header{Name: v.name, Mode: 0644, Size: int64(len(v.body)), Typeflag: tar.TypeReg})
+		w.Write(v.body)
+	}
+	w.Close()
+
+	ioutil.WriteFile(filepath.Join(d, "q.tar"), b.Bytes(), 0644)
+	ioutil.WriteFile(filepath.Join(d, "q.tar.gz"), gz(b.String()), 0644)
+
+	for _, v := range []string{"q.tar", "q.tar.gz"} {
+		s, err := Open(filepath.Join(d, v), ".xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expect(t, v, read(t, s))
+	}
+}
+
+func TestS3(t *testing.T) {
+	objects := map[string][]byte{
+		"2015/a.xml":    []byte("a"),
+		"2015/b.xml.gz": gz("b"),
+		"2015/c.txt":    []byte("c"),
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bucket" {
+			if r.URL.Query().Get("list-type") != "2" || r.URL.Query().Get("prefix") != "2015/" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			// one key per page to exercise the continuation token.
+			var keys []string
+			for k := range objects {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			i := 0
+			if c := r.URL.Query().Get("continuation-token"); c != "" {
+				fmt.Sscanf(c, "%d", &i)
+			}
+
+			fmt.Fprintf(w, `<ListBucketResult><Contents><Key>%s</Key></Contents>`, keys[i])
+			if i < len(keys)-1 {
+				fmt.Fprintf(w, `<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>`, i+1)
+			}
+			fmt.Fprint(w, `</ListBucketResult>`)
+			return
+		}
+
+		b, ok := objects[strings.TrimPrefix(r.URL.Path, "/bucket/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	os.Setenv("S3_ENDPOINT", ts.URL)
+	defer os.Unsetenv("S3_ENDPOINT")
+
+	s, err := Open("s3://bucket/2015/", ".xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := read(t, s)
+	if len(m) != 2 || m["2015/a.xml"] != "a" || m["2015/b.xml.gz"] != "b" {
+		t.Errorf("unexpected files %v", m)
+	}
+}
+
+func TestS3Signed(t *testing.T) {
+	var auth, date string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		date = r.Header.Get("X-Amz-Date")
+		fmt.Fprint(w, `<ListBucketResult></ListBucketResult>`)
+	}))
+	defer ts.Close()
+
+	os.Setenv("S3_ENDPOINT", ts.URL)
+	os.Setenv("S3_ACCESS_KEY", "AKIDEXAMPLE")
+	defer os.Unsetenv("S3_ENDPOINT")
+	defer os.Unsetenv("S3_ACCESS_KEY")
+
+	if _, err := Open("s3://bucket/2015/", ".xml"); err == nil {
+		t.Error("expected error for S3_ACCESS_KEY without S3_SECRET_KEY")
+	}
+
+	os.Setenv("S3_SECRET_KEY", "secret")
+	defer os.Unsetenv("S3_SECRET_KEY")
+
+	s, err := Open("s3://bucket/2015/", ".xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read(t, s)
+
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/us-east-1/s3/aws4_request") {
+		t.Errorf("unexpected Authorization header %s", auth)
+	}
+
+	if date == "" {
+		t.Error("expected X-Amz-Date header")
+	}
+}
+
+// TestSignV4 checks signV4 against the get-vanilla example from the AWS signature version 4 test suite.
+func TestSignV4(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := credentials.Value{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	signV4(req, c, "us-east-1", "service", time.Date(2015, time.August, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+
+	if a := req.Header.Get("Authorization"); a != expected {
+		t.Errorf("expected %s got %s", expected, a)
+	}
+
+	if d := req.Header.Get("X-Amz-Date"); d != "20150830T123600Z" {
+		t.Errorf("unexpected X-Amz-Date %s", d)
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	req := httptest.NewRequest("GET", "/bucket?prefix=2015%2F01+x&list-type=2&continuation-token=a%2Bb", nil)
+
+	if q := canonicalQuery(req); q != "continuation-token=a%2Bb&list-type=2&prefix=2015%2F01%20x" {
+		t.Errorf("unexpected canonical query %s", q)
+	}
+}
+
+func TestFromEnvBackoff(t *testing.T) {
+	d, err := ioutil.TempDir("", "loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	os.Setenv("LOADER_SOURCE", d)
+	defer os.Unsetenv("LOADER_SOURCE")
+
+	os.Setenv("LOADER_BACKOFF", "2s")
+	defer os.Unsetenv("LOADER_BACKOFF")
+
+	_, c, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Backoff != time.Duration(2)*time.Second {
+		t.Errorf("expected backoff 2s got %s", c.Backoff)
+	}
+
+	os.Setenv("LOADER_BACKOFF", "soon")
+
+	if _, _, err = FromEnv(); err == nil {
+		t.Error("expected error for invalid LOADER_BACKOFF")
+	}
+}
+
+func TestDecodeSC3ML(t *testing.T) {
+	if _, err := decodeSC3ML(strings.NewReader("not SC3ML")); !IsInvalid(err) {
+		t.Errorf("expected invalid error got %v", err)
+	}
+
+	if _, err := decodeSC3ML(iotest.TimeoutReader(strings.NewReader("<"))); err == nil || IsInvalid(err) {
+		t.Errorf("expected a read error that isn't invalid got %v", err)
+	}
+}
+
+// memSource is an in memory Source.
+type memSource []string
+
+func (m memSource) String() string {
+	return "mem"
+}
+
+func (m memSource) Each(f func(File) error) error {
+	for _, v := range m {
+		v := v
+		if err := f(File{Name: v, Open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(v)), nil
+		}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	d, err := ioutil.TempDir("", "loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	cp := filepath.Join(d, "checkpoint")
+
+	c, err := OpenCheckpoint(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := memSource{"a", "b", "bad", "c", "flaky", "invalid"}
+
+	var mu sync.Mutex
+	var loaded []string
+	var flaky int
+
+	load := func(name string, r io.Reader) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch name {
+		case "bad":
+			return errors.New("bad SC3ML")
+		case "invalid":
+			return Invalid(errors.New("invalid SC3ML"))
+		case "flaky":
+			flaky++
+			if flaky < 3 {
+				return driver.ErrBadConn
+			}
+		}
+		loaded = append(loaded, name)
+		return nil
+	}
+
+	s, err := Run(Config{Workers: 2, Backoff: time.Millisecond, Checkpoint: c}, src, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	if s.Loaded != 4 || s.Invalid != 1 || s.Failed != 1 || s.Retries != 2 || s.Skipped != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	if len(s.Failures) != 1 || s.Failures[0] != "bad" {
+		t.Errorf("unexpected failures %v", s.Failures)
+	}
+
+	// resume - only the failed file is loaded again, the invalid file was skipped.
+	if c, err = OpenCheckpoint(cp); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Len() != 5 {
+		t.Errorf("expected 5 files in the checkpoint got %d", c.Len())
+	}
+
+	loaded = nil
+
+	if s, err = Run(Config{Checkpoint: c}, src, load); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Skipped != 5 || s.Failed != 1 || s.Loaded != 0 || len(loaded) != 0 {
+		t.Errorf("unexpected stats for resume %+v %v", s, loaded)
+	}
+}
+
+func TestRunStops(t *testing.T) {
+	src := memSource{"a", "b", "c", "d"}
+
+	load := func(name string, r io.Reader) error {
+		if name == "b" {
+			return &pq.Error{Code: "08006"}
+		}
+		return nil
+	}
+
+	s, err := Run(Config{Workers: 1, Retries: 2, Backoff: time.Millisecond}, src, load)
+	if err == nil {
+		t.Fatal("expected error for a persistent transient error")
+	}
+
+	if s.Retries != 2 || s.Loaded != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestTransient(t *testing.T) {
+	in := []struct {
+		err       error
+		transient bool
+	}{
+		{nil, false},
+		{errors.New("nope"), false},
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("wrapped: %w", driver.ErrBadConn), true},
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "40P01"}, true},
+		{&pq.Error{Code: "57P01"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{&pq.Error{Code: "40002"}, false},
+	}
+
+	for i, v := range in {
+		if Transient(v.err) != v.transient {
+			t.Errorf("%d: expected transient %t for %v", i, v.transient, v.err)
+		}
+	}
+}
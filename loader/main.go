@@ -0,0 +1,84 @@
+package loader
+
+import (
+	"bytes"
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+/*
+Main is the main func for the SC3ML loaders.  It loads the Source from FromEnv and saves each quake
+to the DB with save e.g., (*database.DB).SaveQuake.  SC3ML that can't be decoded is logged and skipped.
+Exits non-zero if the load stopped or a quake could not be saved.
+*/
+func Main(save func(*database.DB, msg.Quake) error) {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR: problem with INTENSITY_RULES: %s", err)
+	}
+
+	src, cfg, err := FromEnv()
+	if err != nil {
+		log.Fatalf("ERROR: problem with loader config: %s", err)
+	}
+	defer cfg.Checkpoint.Close()
+
+	db, err := database.InitPG()
+	if err != nil {
+		log.Fatalf("ERROR: problem with DB config: %s", err)
+	}
+	defer db.Close()
+
+	if err = db.InitLocalities(); err != nil {
+		log.Fatalf("ERROR: problem with localities: %s", err)
+	}
+
+	db.Check()
+
+	log.Printf("loading %s (%d files in checkpoint)", src, cfg.Checkpoint.Len())
+
+	s, err := Run(cfg, src, func(name string, r io.Reader) error {
+		q, err := decodeSC3ML(r)
+		if err != nil {
+			return err
+		}
+
+		return save(&db, q)
+	})
+
+	log.Print(s)
+	for _, v := range s.Failures {
+		log.Printf("failed: %s", v)
+	}
+
+	if err != nil {
+		log.Printf("ERROR: load stopped: %s", err)
+	}
+
+	if err != nil || s.Failed > 0 {
+		cfg.Checkpoint.Close()
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+/*
+decodeSC3ML decodes the quake from the SC3ML in r.  The SC3ML is read before it is decoded so
+errors reading it (which may be transient) are not reported as Invalid.
+*/
+func decodeSC3ML(r io.Reader) (msg.Quake, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return msg.Quake{}, err
+	}
+
+	q := msg.DecodeSC3ML07(bytes.NewReader(b))
+	if q.Err() != nil {
+		return q, Invalid(q.Err())
+	}
+
+	return q, nil
+}
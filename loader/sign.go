@@ -0,0 +1,125 @@
+package loader
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+AWS signature version 4 for GET requests with an empty body.  This is all the S3 source needs and
+avoids depending on the SDK's private signer.  See
+http://docs.aws.amazon.com/general/latest/gr/signature-version-4.html
+*/
+
+const (
+	amzDate      = "20060102T150405Z"
+	emptySHA256  = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	sigAlgorithm = "AWS4-HMAC-SHA256"
+)
+
+/*
+signV4 signs req for service in region at t.  The Host header and any X-Amz- headers are signed.
+The request path must not be escaped twice so signV4 is only for S3.
+*/
+func signV4(req *http.Request, c credentials.Value, region, service string, t time.Time) {
+	t = t.UTC()
+	date := t.Format(amzDate)
+	scope := date[:8] + "/" + region + "/" + service + "/aws4_request"
+
+	req.Header.Set("X-Amz-Date", date)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	h := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-amz-") {
+			h[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+
+	var names []string
+	for k := range h {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var headers string
+	for _, k := range names {
+		headers += k + ":" + h[k] + "\n"
+	}
+	signed := strings.Join(names, ";")
+
+	payload := req.Header.Get("X-Amz-Content-Sha256")
+	if payload == "" {
+		payload = emptySHA256
+	}
+
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	canonical := strings.Join([]string{req.Method, awsEscape(path, false), canonicalQuery(req), headers, signed, payload}, "\n")
+
+	toSign := strings.Join([]string{sigAlgorithm, date, scope, hexSHA256(canonical)}, "\n")
+
+	k := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), date[:8])
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	k = hmacSHA256(k, "aws4_request")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigAlgorithm, c.AccessKeyID, scope, signed, hex.EncodeToString(hmacSHA256(k, toSign))))
+}
+
+// canonicalQuery returns the query for req sorted by name then value with each escaped.
+func canonicalQuery(req *http.Request) string {
+	var q []string
+
+	for k, v := range req.URL.Query() {
+		for _, s := range v {
+			q = append(q, awsEscape(k, true)+"="+awsEscape(s, true))
+		}
+	}
+
+	sort.Strings(q)
+
+	return strings.Join(q, "&")
+}
+
+// awsEscape percent encodes everything in s except the unreserved characters and, unless encodeSlash is true, '/'.
+func awsEscape(s string, encodeSlash bool) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+func hexSHA256(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(k []byte, s string) []byte {
+	h := hmac.New(sha256.New, k)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
@@ -0,0 +1,363 @@
+package loader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File is one file from a Source.
+type File struct {
+	Name string                        // the name the file is checkpointed under.
+	Open func() (io.ReadCloser, error) // opens the (decompressed) file content.
+}
+
+/*
+Source is a set of files to load.  Each calls f for each file in name order and stops at
+the first error returned from f.  Files ending .gz are decompressed by Open.
+*/
+type Source interface {
+	Each(f func(File) error) error
+	String() string
+}
+
+/*
+Open returns the Source for spec which is one of
+
+	s3://bucket/prefix      objects in an S3 compatible bucket.
+	file.tar, file.tar.gz, file.tgz, file.tar.bz2
+	dir                     files in a directory.
+
+Only files with names ending suffix (or suffix + ".gz") are included.
+*/
+func Open(spec, suffix string) (Source, error) {
+	switch {
+	case strings.HasPrefix(spec, "s3://"):
+		p := strings.SplitN(strings.TrimPrefix(spec, "s3://"), "/", 2)
+		if p[0] == "" {
+			return nil, fmt.Errorf("no bucket in %s", spec)
+		}
+		c, err := s3Credentials()
+		if err != nil {
+			return nil, err
+		}
+		s := &S3{Endpoint: s3Endpoint(), Bucket: p[0], Suffix: suffix, Credentials: c, Region: s3Region()}
+		if len(p) == 2 {
+			s.Prefix = p[1]
+		}
+		return s, nil
+	case strings.HasSuffix(spec, ".tar"), strings.HasSuffix(spec, ".tar.gz"),
+		strings.HasSuffix(spec, ".tgz"), strings.HasSuffix(spec, ".tar.bz2"):
+		return &Tar{Path: spec, Suffix: suffix}, nil
+	}
+
+	fi, err := os.Stat(spec)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory or archive", spec)
+	}
+
+	return &Dir{Path: spec, Suffix: suffix}, nil
+}
+
+func s3Endpoint() string {
+	if e := os.Getenv("S3_ENDPOINT"); e != "" {
+		return strings.TrimSuffix(e, "/")
+	}
+	return "https://s3.amazonaws.com"
+}
+
+// s3Credentials returns the credentials from S3_ACCESS_KEY and S3_SECRET_KEY or nil for anonymous requests.
+func s3Credentials() (*credentials.Credentials, error) {
+	k, s := os.Getenv("S3_ACCESS_KEY"), os.Getenv("S3_SECRET_KEY")
+
+	switch {
+	case k == "" && s == "":
+		return nil, nil
+	case k == "" || s == "":
+		return nil, fmt.Errorf("set both S3_ACCESS_KEY and S3_SECRET_KEY or neither")
+	}
+
+	return credentials.NewStaticCredentials(k, s, ""), nil
+}
+
+func s3Region() string {
+	if r := os.Getenv("AWS_REGION"); r != "" {
+		return r
+	}
+	return "us-east-1"
+}
+
+func match(name, suffix string) bool {
+	return strings.HasSuffix(name, suffix) || strings.HasSuffix(name, suffix+".gz")
+}
+
+// gunzip wraps r in a gzip reader if name ends .gz.
+func gunzip(name string, r io.ReadCloser) (io.ReadCloser, error) {
+	if !strings.HasSuffix(name, ".gz") {
+		return r, nil
+	}
+
+	g, err := gzip.NewReader(r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return readCloser{Reader: g, c: r}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	c io.Closer
+}
+
+func (r readCloser) Close() error {
+	return r.c.Close()
+}
+
+// Dir is the files in a directory (not recursive).
+type Dir struct {
+	Path   string
+	Suffix string
+}
+
+func (d *Dir) String() string {
+	return d.Path
+}
+
+func (d *Dir) Each(f func(File) error) error {
+	fi, err := ioutil.ReadDir(d.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range fi {
+		if v.IsDir() || !match(v.Name(), d.Suffix) {
+			continue
+		}
+
+		p := filepath.Join(d.Path, v.Name())
+		n := v.Name()
+
+		err = f(File{Name: n, Open: func() (io.ReadCloser, error) {
+			r, err := os.Open(p)
+			if err != nil {
+				return nil, err
+			}
+			return gunzip(n, r)
+		}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Tar is the files in a tar archive, optionally gzip or bzip2 compressed.  The archive is
+read as a stream so each file is read into memory before f is called.
+*/
+type Tar struct {
+	Path   string
+	Suffix string
+}
+
+func (t *Tar) String() string {
+	return t.Path
+}
+
+func (t *Tar) Each(f func(File) error) error {
+	a, err := os.Open(t.Path)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	var r io.Reader = a
+
+	switch {
+	case strings.HasSuffix(t.Path, ".gz"), strings.HasSuffix(t.Path, ".tgz"):
+		g, err := gzip.NewReader(a)
+		if err != nil {
+			return err
+		}
+		defer g.Close()
+		r = g
+	case strings.HasSuffix(t.Path, ".bz2"):
+		r = bzip2.NewReader(a)
+	}
+
+	tr := tar.NewReader(r)
+
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if h.Typeflag != tar.TypeReg || !match(h.Name, t.Suffix) {
+			continue
+		}
+
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+
+		n := h.Name
+
+		err = f(File{Name: n, Open: func() (io.ReadCloser, error) {
+			return gunzip(n, ioutil.NopCloser(bytes.NewReader(b)))
+		}})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+/*
+S3 is the objects under Prefix in an S3 compatible Bucket.  Requests are signed with AWS signature
+version 4 if Credentials is set, otherwise the bucket must allow anonymous list and get.
+Endpoint is used with path style URLs e.g., https://s3.amazonaws.com or a local minio.
+*/
+type S3 struct {
+	Endpoint    string
+	Bucket      string
+	Prefix      string
+	Suffix      string
+	Credentials *credentials.Credentials // nil for anonymous requests.
+	Region      string                   // the region for signing requests.
+	Client      *http.Client             // http.DefaultClient if nil.
+}
+
+func (s *S3) String() string {
+	return "s3://" + s.Bucket + "/" + s.Prefix
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return &http.Client{Timeout: time.Duration(5) * time.Minute}
+}
+
+func (s *S3) Each(f func(File) error) error {
+	var token string
+
+	for {
+		l, err := s.list(token)
+		if err != nil {
+			return err
+		}
+
+		var keys []string
+		for _, v := range l.Contents {
+			if match(v.Key, s.Suffix) {
+				keys = append(keys, v.Key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			k := k
+			err = f(File{Name: k, Open: func() (io.ReadCloser, error) {
+				return s.get(k)
+			}})
+			if err != nil {
+				return err
+			}
+		}
+
+		if !l.IsTruncated || l.NextContinuationToken == "" {
+			return nil
+		}
+		token = l.NextContinuationToken
+	}
+}
+
+func (s *S3) list(token string) (l listBucketResult, err error) {
+	v := url.Values{}
+	v.Set("list-type", "2")
+	v.Set("prefix", s.Prefix)
+	if token != "" {
+		v.Set("continuation-token", token)
+	}
+
+	r, err := s.do(s.Endpoint + "/" + url.PathEscape(s.Bucket) + "?" + v.Encode())
+	if err != nil {
+		return
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		err = fmt.Errorf("listing %s: %s", s, r.Status)
+		return
+	}
+
+	err = xml.NewDecoder(r.Body).Decode(&l)
+	return
+}
+
+func (s *S3) get(key string) (io.ReadCloser, error) {
+	u := s.Endpoint + "/" + url.PathEscape(s.Bucket) + "/" + (&url.URL{Path: key}).EscapedPath()
+
+	r, err := s.do(u)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.StatusCode != http.StatusOK {
+		r.Body.Close()
+		return nil, fmt.Errorf("get %s: %s", key, r.Status)
+	}
+
+	return gunzip(key, r.Body)
+}
+
+// do makes a GET request for u, signed if s has Credentials.
+func (s *S3) do(u string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Credentials != nil {
+		c, err := s.Credentials.Get()
+		if err != nil {
+			return nil, fmt.Errorf("signing request for %s: %s", s, err)
+		}
+
+		req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+		signV4(req, c, s.Region, "s3", time.Now())
+	}
+
+	return s.client().Do(req)
+}
@@ -0,0 +1,117 @@
+// Package mastodon posts statuses to a Mastodon (ActivityPub) account using the REST API.
+// https://docs.joinmastodon.org/methods/statuses/
+package mastodon
+
+import (
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Status visibility values.
+const (
+	Public   = "public"
+	Unlisted = "unlisted"
+	Private  = "private"
+	Direct   = "direct"
+)
+
+type Client struct {
+	h          *http.Client
+	server     string
+	token      string
+	visibility string
+	// Language is the ISO 639 language code for posts.  Optional.
+	Language string
+	// MaxChars overrides the instance post length limit if not zero.
+	MaxChars int
+}
+
+// New returns a Client for posting to the account with the access token on the
+// Mastodon server e.g., https://mastodon.social.  visibility may be empty for the account default.
+func New(server, token, visibility string) (*Client, error) {
+	switch visibility {
+	case "", Public, Unlisted, Private, Direct:
+	default:
+		return nil, fmt.Errorf("invalid visibility %s", visibility)
+	}
+
+	if token == "" {
+		return nil, fmt.Errorf("empty access token for %s", server)
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %s", server)
+	}
+
+	c := &Client{
+		h: &http.Client{
+			Timeout: time.Duration(30 * time.Second),
+		},
+		server:     strings.TrimRight(server, "/"),
+		token:      token,
+		visibility: visibility,
+	}
+
+	return c, nil
+}
+
+// Post posts message as a status.  publicID is used as the idempotency key so retries
+// do not create duplicate statuses.  Mastodon has no location for statuses so
+// longitude and latitude are not used.
+func (c *Client) Post(publicID, message string, longitude, latitude float64) error {
+	v := url.Values{}
+	v.Set("status", message)
+
+	if c.visibility != "" {
+		v.Set("visibility", c.visibility)
+	}
+
+	if c.Language != "" {
+		v.Set("language", c.Language)
+	}
+
+	req, err := http.NewRequest("POST", c.server+"/api/v1/statuses", strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if publicID != "" {
+		req.Header.Set("Idempotency-Key", publicID)
+	}
+
+	res, err := c.h.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("Response error from Mastodon %s: %d %s", c.server, res.StatusCode, string(b))
+	}
+
+	return nil
+}
+
+// Limits returns the message length rules for the Mastodon instance.
+func (c *Client) Limits() msg.SocialLimits {
+	l := msg.MastodonLimits
+
+	if c.MaxChars > 0 {
+		l.MaxChars = c.MaxChars
+	}
+
+	return l
+}
@@ -0,0 +1,97 @@
+package mastodon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPost(t *testing.T) {
+	var r *http.Request
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r = req
+		req.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "1"}`))
+	}))
+	defer ts.Close()
+
+	c, err := New(ts.URL+"/", "test-token", Unlisted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Language = "en"
+
+	if err = c.Post("2015p278423", "M6.0 quake causing severe shaking near Ruatoria", 178.35, -37.92); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.URL.Path != "/api/v1/statuses" {
+		t.Errorf("expected path /api/v1/statuses got %s", r.URL.Path)
+	}
+
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		t.Errorf("unexpected Authorization header %s", r.Header.Get("Authorization"))
+	}
+
+	if r.Header.Get("Idempotency-Key") != "2015p278423" {
+		t.Errorf("unexpected Idempotency-Key header %s", r.Header.Get("Idempotency-Key"))
+	}
+
+	for k, v := range map[string]string{
+		"status":     "M6.0 quake causing severe shaking near Ruatoria",
+		"visibility": "unlisted",
+		"language":   "en",
+	} {
+		if r.PostForm.Get(k) != v {
+			t.Errorf("expected %s=%s got %s", k, v, r.PostForm.Get(k))
+		}
+	}
+}
+
+func TestPostError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, `{"error": "Validation failed: Text character limit of 500 exceeded"}`, http.StatusUnprocessableEntity)
+	}))
+	defer ts.Close()
+
+	c, err := New(ts.URL, "test-token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Post("2015p278423", "test", 0, 0); err == nil {
+		t.Error("expected error for 422 response")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("https://mastodon.example", "token", "everyone"); err == nil {
+		t.Error("expected error for invalid visibility")
+	}
+
+	if _, err := New("mastodon.example", "token", ""); err == nil {
+		t.Error("expected error for server without scheme")
+	}
+
+	if _, err := New("https://mastodon.example", "", ""); err == nil {
+		t.Error("expected error for empty token")
+	}
+
+	c, err := New("https://mastodon.example", "token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Limits().MaxChars != 500 {
+		t.Errorf("expected 500 chars got %d", c.Limits().MaxChars)
+	}
+
+	c.MaxChars = 1000
+
+	if c.Limits().MaxChars != 1000 || c.Limits().URLLength != 23 {
+		t.Errorf("unexpected limits %+v", c.Limits())
+	}
+}
@@ -0,0 +1,259 @@
+// Package mqtt publishes messages to an MQTT broker using the Eclipse Paho client.
+//
+// Messages are buffered while the client is disconnected and sent in order once
+// the broker is available.  Paho handles the protocol, reconnection with backoff,
+// and redelivery (with the DUP flag set) of QoS 1 messages that were not
+// acknowledged before the connection was lost.
+package mqtt
+
+import (
+	"crypto/tls"
+	"fmt"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Options for connecting to a broker.
+type Options struct {
+	// Broker is the broker URL e.g., tcp://localhost:1883 or ssl://broker:8883
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// KeepAlive is the keep alive interval.  Defaults to 60s.
+	KeepAlive time.Duration
+	// BufferSize is the maximum number of messages buffered while waiting to be sent.
+	// It does not include the message being sent.  When the buffer is full the oldest
+	// buffered message is dropped.  Defaults to 1000.
+	BufferSize int
+	// MaxBackoff is the longest wait between reconnection attempts.  Defaults to 60s.
+	MaxBackoff time.Duration
+	// TLS config for ssl:// brokers.  Optional.
+	TLS *tls.Config
+}
+
+// Message is an application message.
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte // 0 or 1.
+	Retain  bool
+}
+
+// Client publishes messages to a broker.
+type Client struct {
+	o         Options
+	client    paho.Client
+	mu        sync.Mutex
+	buf       []Message
+	inflight  *Message      // the message being sent.  It is not in buf so can't be dropped.  Protected by mu.
+	ready     chan struct{} // signalled when a message is added to buf.
+	connected chan struct{} // signalled when the connection to the broker is up.
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New returns a Client that connects to the broker in the background.
+func New(o Options) (*Client, error) {
+	u, err := url.Parse(o.Broker)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts":
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %s", u.Scheme)
+	}
+
+	if o.ClientID == "" {
+		return nil, fmt.Errorf("empty client id")
+	}
+
+	if o.KeepAlive == 0 {
+		o.KeepAlive = time.Duration(60) * time.Second
+	}
+
+	if o.BufferSize == 0 {
+		o.BufferSize = 1000
+	}
+
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = time.Duration(60) * time.Second
+	}
+
+	c := &Client{
+		o:         o,
+		ready:     make(chan struct{}, 1),
+		connected: make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+
+	// Paho backs off up to MaxReconnectInterval after a connection is lost but retries
+	// the first connection at a fixed interval.
+	retry := time.Second
+	if o.MaxBackoff < retry {
+		retry = o.MaxBackoff
+	}
+
+	p := paho.NewClientOptions().
+		AddBroker(o.Broker).
+		SetClientID(o.ClientID).
+		SetUsername(o.Username).
+		SetPassword(o.Password).
+		SetKeepAlive(o.KeepAlive).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(o.MaxBackoff).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(retry).
+		SetOnConnectHandler(func(paho.Client) {
+			log.Printf("mqtt connected to %s", o.Broker)
+			select {
+			case c.connected <- struct{}{}:
+			default:
+			}
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Printf("WARN mqtt connection to %s: %s", o.Broker, err)
+		})
+
+	if o.TLS != nil {
+		p.SetTLSConfig(o.TLS)
+	}
+
+	c.client = paho.NewClient(p)
+	c.client.Connect()
+
+	c.wg.Add(1)
+	go c.run()
+
+	return c, nil
+}
+
+// Publish buffers m for sending to the broker.  It does not block waiting for
+// the broker.  If the buffer is full the oldest buffered message is dropped.
+// The message being sent is never dropped.
+func (c *Client) Publish(m Message) error {
+	if m.QoS > 1 {
+		return fmt.Errorf("unsupported QoS %d", m.QoS)
+	}
+
+	select {
+	case <-c.done:
+		return fmt.Errorf("client closed")
+	default:
+	}
+
+	c.mu.Lock()
+	if len(c.buf) >= c.o.BufferSize {
+		log.Printf("WARN mqtt buffer full dropping message for %s", c.buf[0].Topic)
+		c.buf = c.buf[1:]
+	}
+	c.buf = append(c.buf, m)
+	c.mu.Unlock()
+
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Buffered returns the number of messages waiting to be sent, including the message being sent.
+func (c *Client) Buffered() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inflight != nil {
+		return len(c.buf) + 1
+	}
+
+	return len(c.buf)
+}
+
+// Close disconnects from the broker.  Buffered messages that have not been sent are lost.
+func (c *Client) Close() {
+	close(c.done)
+	c.client.Disconnect(250)
+	c.wg.Wait()
+}
+
+// next returns the message to send.  A message that failed to send is returned again,
+// otherwise the head of the buffer is moved to inflight.  Returns false if there is nothing to send.
+func (c *Client) next() (Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inflight != nil {
+		return *c.inflight, true
+	}
+
+	if len(c.buf) == 0 {
+		return Message{}, false
+	}
+
+	m := c.buf[0]
+	c.buf = c.buf[1:]
+	c.inflight = &m
+
+	return m, true
+}
+
+// sent clears the inflight message.
+func (c *Client) sent() {
+	c.mu.Lock()
+	c.inflight = nil
+	c.mu.Unlock()
+}
+
+// run sends messages one at a time, in order, while the client is connected.
+func (c *Client) run() {
+	defer c.wg.Done()
+
+	for {
+		if !c.client.IsConnectionOpen() {
+			select {
+			case <-c.connected:
+			case <-time.After(time.Second):
+			case <-c.done:
+				return
+			}
+			continue
+		}
+
+		m, ok := c.next()
+		if !ok {
+			select {
+			case <-c.ready:
+			case <-c.done:
+				return
+			}
+			continue
+		}
+
+		t := c.client.Publish(m.Topic, m.QoS, m.Retain, m.Payload)
+
+		select {
+		case <-t.Done():
+		case <-c.done:
+			return
+		}
+
+		if err := t.Error(); err != nil {
+			// the message stays inflight and is sent again.
+			log.Printf("WARN mqtt publishing to %s: %s", m.Topic, err)
+			select {
+			case <-time.After(time.Second):
+			case <-c.done:
+				return
+			}
+			continue
+		}
+
+		c.sent()
+	}
+}
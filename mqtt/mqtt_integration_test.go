@@ -0,0 +1,64 @@
+//go:build integration
+// +build integration
+
+package mqtt
+
+// Tests against a local broker e.g.,
+//   docker run -d -p 1883:1883 eclipse-mosquitto
+//   go test -tags integration ./mqtt
+
+import (
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"os"
+	"testing"
+	"time"
+)
+
+func brokerURL() string {
+	if b := os.Getenv("MQTT_BROKER"); b != "" {
+		return b
+	}
+	return "tcp://localhost:1883"
+}
+
+func TestRetainedMosquitto(t *testing.T) {
+	c, err := New(Options{Broker: brokerURL(), ClientID: "haz-mqtt-integration-pub"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+
+	if err = c.Publish(Message{Topic: "haz/test/latest", Payload: payload, QoS: 1, Retain: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100 && c.Buffered() > 0; i++ {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	c.Close()
+
+	// a new subscriber should get the retained message.
+	s := paho.NewClient(paho.NewClientOptions().AddBroker(brokerURL()).SetClientID("haz-mqtt-integration-sub"))
+
+	if tk := s.Connect(); tk.WaitTimeout(10*time.Second) && tk.Error() != nil {
+		t.Fatal(tk.Error())
+	}
+	defer s.Disconnect(250)
+
+	rx := make(chan paho.Message, 1)
+
+	if tk := s.Subscribe("haz/test/latest", 1, func(_ paho.Client, m paho.Message) { rx <- m }); tk.WaitTimeout(10*time.Second) && tk.Error() != nil {
+		t.Fatal(tk.Error())
+	}
+
+	select {
+	case m := <-rx:
+		if !m.Retained() || string(m.Payload()) != string(payload) {
+			t.Errorf("expected retained %s got %s retain %t", payload, m.Payload(), m.Retained())
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("timed out waiting for the retained message")
+	}
+}
@@ -0,0 +1,301 @@
+package mqtt
+
+import (
+	"bytes"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// broker is a minimal local MQTT broker stand-in that records published messages.
+type broker struct {
+	l net.Listener
+	// refuse is the number of connections to refuse with CONNACK return code 3.
+	refuse int
+	// drop is the number of QoS 1 publishes to close the connection on without acking.
+	drop int
+	// hold is true if QoS 1 publishes are not acked.
+	hold     bool
+	mu       sync.Mutex
+	msgs     []Message
+	dups     []bool
+	connects int
+}
+
+func newBroker(t *testing.T) *broker {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &broker{l: l}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(c)
+		}
+	}()
+
+	return b
+}
+
+func (b *broker) url() string {
+	return "tcp://" + b.l.Addr().String()
+}
+
+func (b *broker) received() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Message{}, b.msgs...)
+}
+
+func (b *broker) serve(c net.Conn) {
+	defer c.Close()
+
+	p, err := packets.ReadPacket(c)
+	if err != nil {
+		return
+	}
+
+	if _, ok := p.(*packets.ConnectPacket); !ok {
+		return
+	}
+
+	b.mu.Lock()
+	b.connects++
+	refuse := b.refuse > 0
+	if refuse {
+		b.refuse--
+	}
+	b.mu.Unlock()
+
+	ack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+	if refuse {
+		ack.ReturnCode = packets.ErrRefusedServerUnavailable
+		ack.Write(c)
+		return
+	}
+
+	if err = ack.Write(c); err != nil {
+		return
+	}
+
+	for {
+		p, err := packets.ReadPacket(c)
+		if err != nil {
+			return
+		}
+
+		switch p := p.(type) {
+		case *packets.PublishPacket:
+			b.mu.Lock()
+			b.msgs = append(b.msgs, Message{Topic: p.TopicName, Payload: p.Payload, QoS: p.Qos, Retain: p.Retain})
+			b.dups = append(b.dups, p.Dup)
+			drop := p.Qos == 1 && b.drop > 0
+			if drop {
+				b.drop--
+			}
+			hold := b.hold
+			b.mu.Unlock()
+
+			if drop {
+				return
+			}
+
+			if p.Qos == 1 && !hold {
+				a := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				a.MessageID = p.MessageID
+				a.Write(c)
+			}
+		case *packets.PingreqPacket:
+			packets.NewControlPacket(packets.Pingresp).Write(c)
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+func wait(t *testing.T, f func() bool) {
+	for i := 0; i < 100; i++ {
+		if f() {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatal("timed out waiting")
+}
+
+// state returns the inflight message topic and the buffered message topics.
+func (c *Client) state() (string, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var inflight string
+	if c.inflight != nil {
+		inflight = c.inflight.Topic
+	}
+
+	var buf []string
+	for _, m := range c.buf {
+		buf = append(buf, m.Topic)
+	}
+
+	return inflight, buf
+}
+
+func TestPublish(t *testing.T) {
+	b := newBroker(t)
+	defer b.l.Close()
+
+	c, err := New(Options{Broker: b.url(), ClientID: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err = c.Publish(Message{Topic: "haz/quake/2015p278423", Payload: []byte(`{"PublicID":"2015p278423"}`), QoS: 1, Retain: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Publish(Message{Topic: "haz/quake/mmi/5", Payload: []byte(`test`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	wait(t, func() bool { return len(b.received()) == 2 && c.Buffered() == 0 })
+
+	m := b.received()
+
+	if m[0].Topic != "haz/quake/2015p278423" || !m[0].Retain || m[0].QoS != 1 || !bytes.Equal(m[0].Payload, []byte(`{"PublicID":"2015p278423"}`)) {
+		t.Errorf("unexpected message %+v", m[0])
+	}
+
+	if m[1].Topic != "haz/quake/mmi/5" || m[1].Retain || m[1].QoS != 0 {
+		t.Errorf("unexpected message %+v", m[1])
+	}
+
+	if err = c.Publish(Message{Topic: "haz/quake/mmi/5", QoS: 2}); err == nil {
+		t.Error("expected error for QoS 2")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	b := newBroker(t)
+	defer b.l.Close()
+
+	b.refuse = 1 // the first connection is refused so messages are buffered.
+	b.drop = 1   // the first publish is not acked so it is redelivered.
+
+	c, err := New(Options{Broker: b.url(), ClientID: "test", MaxBackoff: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, topic := range []string{"haz/quake/1", "haz/quake/2", "haz/quake/3"} {
+		if err = c.Publish(Message{Topic: topic, QoS: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wait(t, func() bool { return len(b.received()) == 4 && c.Buffered() == 0 })
+
+	m := b.received()
+
+	for i, topic := range []string{"haz/quake/1", "haz/quake/1", "haz/quake/2", "haz/quake/3"} {
+		if m[i].Topic != topic {
+			t.Errorf("message %d expected topic %s got %s", i, topic, m[i].Topic)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dups[0] || !b.dups[1] || b.dups[2] {
+		t.Errorf("unexpected DUP flags %v", b.dups)
+	}
+
+	if b.connects != 3 {
+		t.Errorf("expected 3 connections got %d", b.connects)
+	}
+}
+
+func TestBufferFull(t *testing.T) {
+	// nothing listening on the broker port so all messages are buffered.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	c, err := New(Options{Broker: "tcp://" + addr, ClientID: "test", BufferSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, topic := range []string{"a", "b", "c"} {
+		c.Publish(Message{Topic: topic})
+	}
+
+	if c.Buffered() != 2 {
+		t.Errorf("expected 2 buffered messages got %d", c.Buffered())
+	}
+
+	if _, buf := c.state(); len(buf) != 2 || buf[0] != "b" {
+		t.Errorf("expected oldest message to be dropped got buffer %v", buf)
+	}
+}
+
+func TestBufferFullInflight(t *testing.T) {
+	b := newBroker(t)
+	defer b.l.Close()
+
+	b.hold = true // the first message stays inflight.
+
+	c, err := New(Options{Broker: b.url(), ClientID: "test", BufferSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Publish(Message{Topic: "a", QoS: 1})
+
+	wait(t, func() bool { return len(b.received()) == 1 })
+
+	for _, topic := range []string{"b", "c"} {
+		c.Publish(Message{Topic: topic, QoS: 1})
+	}
+
+	inflight, buf := c.state()
+
+	if inflight != "a" {
+		t.Errorf("expected inflight message a got %s", inflight)
+	}
+
+	if len(buf) != 1 || buf[0] != "c" {
+		t.Errorf("expected buffer [c] got %v", buf)
+	}
+
+	if c.Buffered() != 2 {
+		t.Errorf("expected 2 buffered messages got %d", c.Buffered())
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Options{Broker: "http://localhost:1883", ClientID: "test"}); err == nil {
+		t.Error("expected error for http scheme")
+	}
+
+	if _, err := New(Options{Broker: "tcp://localhost:1883"}); err == nil {
+		t.Error("expected error for empty client id")
+	}
+}
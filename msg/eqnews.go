@@ -0,0 +1,213 @@
+package msg
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	htemplate "html/template"
+	"io/ioutil"
+	ttemplate "text/template"
+	"time"
+)
+
+const (
+	eqNewsUTC   = "2006/01/02 at 15:04:05"
+	eqNewsLocal = "(MST):      Monday 2 Jan 2006 at 3:04 pm"
+)
+
+/*
+eqNewsNow is the time.Format layout by language for the report time and, with the zone, the local
+quake time.  Layouts are kept out of the message catalogs so a translation can't break the time format.
+Languages without a layout use English.
+*/
+var eqNewsNow = map[Lang]string{
+	English: "Mon 2 Jan 2006 at 3:04 pm",
+	Maori:   "Monday 2 January 2006, 3:04 pm",
+}
+
+// EqNewsMapURL is the format for the static map image linked from the HTML eqnews email.
+// It is formatted with the quake longitude and latitude.
+var EqNewsMapURL = "http://static.geonet.org.nz/maps/4/quake/%.2f/%.2f/600x400.png"
+
+// The compiled in eqnews templates are the files in data/tmpl.  Templates for languages
+// other than English are named eqnews.<lang>.txt and eqnews.<lang>.html
+var (
+	//go:embed data/tmpl/eqnews.txt
+	eqNewsText string
+	//go:embed data/tmpl/eqnews.html
+	eqNewsHTML string
+	//go:embed data/tmpl/eqnews.mi.txt
+	eqNewsTextMi string
+	//go:embed data/tmpl/eqnews.mi.html
+	eqNewsHTMLMi string
+)
+
+// DefaultEqNewsTemplate is the compiled in eqnews template.
+var DefaultEqNewsTemplate = &EqNewsTemplate{
+	text: ttemplate.Must(ttemplate.New("eqNews").Parse(eqNewsText)),
+	html: htemplate.Must(htemplate.New("eqNews").Parse(eqNewsHTML)),
+}
+
+// EqNewsTemplates are the compiled in eqnews templates by language.
+var EqNewsTemplates = map[Lang]*EqNewsTemplate{
+	English: DefaultEqNewsTemplate,
+	Maori: &EqNewsTemplate{
+		text: ttemplate.Must(ttemplate.New("eqNews").Parse(eqNewsTextMi)),
+		html: htemplate.Must(htemplate.New("eqNews").Parse(eqNewsHTMLMi)),
+	},
+}
+
+// EqNewsTemplate holds the plain text and HTML templates for eqnews emails.
+type EqNewsTemplate struct {
+	text *ttemplate.Template
+	html *htemplate.Template
+}
+
+// ParseEqNewsTemplate parses the text and html template files for eqnews emails.
+// If htmlFile is empty only a plain text body will be rendered.
+func ParseEqNewsTemplate(textFile, htmlFile string) (*EqNewsTemplate, error) {
+	var e EqNewsTemplate
+
+	b, err := ioutil.ReadFile(textFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.text, err = ttemplate.New("eqNews").Parse(string(b)); err != nil {
+		return nil, err
+	}
+
+	if htmlFile == "" {
+		return &e, nil
+	}
+
+	if b, err = ioutil.ReadFile(htmlFile); err != nil {
+		return nil, err
+	}
+
+	if e.html, err = htemplate.New("eqNews").Parse(string(b)); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// EqNewsThreshold is the minimum shaking for sending eqnews to a recipient list.
+// An alert is sent if either the maximum MMI for the quake is >= MMI or
+// the MMI at the closest locality is >= MMIDistance.
+type EqNewsThreshold struct {
+	MMI         float64
+	MMIDistance float64
+}
+
+// EqNewsDefault is the threshold for the eqnews list.
+var EqNewsDefault = EqNewsThreshold{MMI: 7.0, MMIDistance: 3.5}
+
+// EqNewsMessage is a rendered eqnews email.  HTML is empty if
+// there is no HTML template.
+type EqNewsMessage struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type eqNewsD struct {
+	Q         *Quake
+	MMI       int
+	Subject   string
+	Location  string
+	Now       string
+	TZ        string // timezone for the quake.
+	UT        string // quake time in UTC
+	LT        string // quake in local time
+	LocalTime string // quake in local time without the text alignment.
+	LL        string // lon lat string
+	Intensity string // word version of MMI
+	MapURL    string // static map image for the quake.
+}
+
+/*
+AlertEqNewsMessage returns alert = true and the message rendered using tmpl if the quake
+is suitable for alerting and above th.  alert = false if not.
+*/
+func (q *Quake) AlertEqNewsMessage(tmpl *EqNewsTemplate, th EqNewsThreshold) (alert bool, m EqNewsMessage) {
+	return q.AlertEqNewsMessageIn(tmpl, th, English)
+}
+
+/*
+AlertEqNewsMessageIn is AlertEqNewsMessage with the subject, intensity, location, and times
+in language l.  tmpl should be written for l, see EqNewsTemplates.
+*/
+func (q *Quake) AlertEqNewsMessageIn(tmpl *EqNewsTemplate, th EqNewsThreshold, l Lang) (alert bool, m EqNewsMessage) {
+	if q.Err() != nil {
+		return
+	}
+
+	if !q.AlertQuality() {
+		return
+	}
+
+	mmi := q.MMI()
+
+	c, err := q.Closest()
+	if err != nil {
+		q.SetErr(err)
+		return
+	}
+
+	if !(mmi >= th.MMI || c.MMIDistance >= th.MMIDistance) {
+		return
+	}
+
+	// NZ EQ: M3.5, weak intensity, 5km deep, 20 km N of Reefton
+	m.Subject = l.Text("eqnews.subject",
+		q.Magnitude,
+		l.Intensity(mmi),
+		q.Depth,
+		l.Distance(c.Distance),
+		l.Compass(c.Bearing),
+		c.Locality.Name)
+
+	now, ok := eqNewsNow[l]
+	if !ok {
+		now = eqNewsNow[English]
+	}
+
+	d := &eqNewsD{
+		Q:         q,
+		MMI:       int(mmi),
+		Subject:   m.Subject,
+		Location:  l.Location(c),
+		Now:       l.Date(time.Now().In(nz), now),
+		UT:        q.Time.Format(eqNewsUTC),
+		LT:        l.Date(q.Time.In(nz), eqNewsLocal),
+		LocalTime: l.Date(q.Time.In(nz), now+" (MST)"),
+		LL:        q.eqNewsLonLat(),
+		Intensity: l.Intensity(mmi),
+		MapURL:    fmt.Sprintf(EqNewsMapURL, q.Longitude, q.Latitude),
+	}
+
+	buf := new(bytes.Buffer)
+
+	if err = tmpl.text.ExecuteTemplate(buf, "eqNews", d); err != nil {
+		q.SetErr(err)
+		return
+	}
+
+	m.Text = buf.String()
+
+	if tmpl.html != nil {
+		buf.Reset()
+
+		if err = tmpl.html.ExecuteTemplate(buf, "eqNews", d); err != nil {
+			q.SetErr(err)
+			return
+		}
+
+		m.HTML = buf.String()
+	}
+
+	alert = true
+
+	return
+}
@@ -0,0 +1,218 @@
+package msg
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+)
+
+// Lang is an IETF language tag for alert text.
+type Lang string
+
+const (
+	English Lang = "en"
+	Maori   Lang = "mi"
+)
+
+/*
+catalog is the text for a language.  Intensity and compass words are keyed by the English word
+(as returned by MMIIntensity and Compass).  Messages are fmt format strings keyed by message id.
+The args for each message are the same in all languages.
+*/
+type catalog struct {
+	Intensity map[string]string `json:"intensity"`
+	Compass   map[string]string `json:"compass"`
+	Weekdays  []string          `json:"weekdays"`
+	Months    []string          `json:"months"`
+	Messages  map[string]string `json:"messages"`
+}
+
+var (
+	//go:embed data/i18n/en.json
+	enJSON []byte
+	//go:embed data/i18n/mi.json
+	miJSON []byte
+)
+
+var catalogs = make(map[Lang]*catalog)
+
+func init() {
+	for l, b := range map[Lang][]byte{English: enJSON, Maori: miJSON} {
+		if err := SetCatalog(l, b); err != nil {
+			panic(fmt.Sprintf("catalog %s: %s", l, err))
+		}
+	}
+}
+
+/*
+SetCatalog parses the JSON catalog b and sets it as the text for l.  See data/i18n/en.json for the format.
+Missing words and messages fall back to English.  It is not safe to call this while quakes are being
+processed; set catalogs at start up.
+*/
+func SetCatalog(l Lang, b []byte) error {
+	var c catalog
+
+	if err := json.Unmarshal(b, &c); err != nil {
+		return err
+	}
+
+	if c.Weekdays != nil && len(c.Weekdays) != 7 {
+		return fmt.Errorf("expected 7 weekdays got %d", len(c.Weekdays))
+	}
+
+	if c.Months != nil && len(c.Months) != 12 {
+		return fmt.Errorf("expected 12 months got %d", len(c.Months))
+	}
+
+	if en, ok := catalogs[English]; ok {
+		for k, v := range c.Messages {
+			e, ok := en.Messages[k]
+			if !ok {
+				return fmt.Errorf("unknown message %s", k)
+			}
+			if verbs(v) != verbs(e) {
+				return fmt.Errorf("message %s: format verbs differ from English", k)
+			}
+		}
+	}
+
+	catalogs[l] = &c
+
+	return nil
+}
+
+// LoadCatalog reads the JSON catalog for l from file.  See SetCatalog.
+func LoadCatalog(l Lang, file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	return SetCatalog(l, b)
+}
+
+// verbs returns the fmt verbs in s so translations can be checked against English.
+func verbs(s string) string {
+	var v []string
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			continue
+		}
+		j := i + 1
+		for j < len(s) && strings.IndexByte("0123456789.+-# ", s[j]) >= 0 {
+			j++
+		}
+		if j < len(s) {
+			v = append(v, s[i:j+1])
+		}
+		i = j
+	}
+
+	return strings.Join(v, " ")
+}
+
+/*
+ParseLangs parses a comma separated list of language tags e.g., "en,mi".  An empty string
+is English only.  It is an error to ask for a language without a catalog.
+*/
+func ParseLangs(s string) ([]Lang, error) {
+	if strings.TrimSpace(s) == "" {
+		return []Lang{English}, nil
+	}
+
+	var l []Lang
+
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		if _, ok := catalogs[Lang(v)]; !ok {
+			return nil, fmt.Errorf("no catalog for language %s", v)
+		}
+
+		l = append(l, Lang(v))
+	}
+
+	return l, nil
+}
+
+func (l Lang) catalog() *catalog {
+	if c, ok := catalogs[l]; ok {
+		return c
+	}
+
+	return catalogs[English]
+}
+
+func (l Lang) word(f func(*catalog) map[string]string, key string) string {
+	if s, ok := f(l.catalog())[key]; ok {
+		return s
+	}
+
+	if s, ok := f(catalogs[English])[key]; ok {
+		return s
+	}
+
+	return key
+}
+
+// Text returns the message with id key formatted with args.
+func (l Lang) Text(key string, args ...interface{}) string {
+	f := l.word(func(c *catalog) map[string]string { return c.Messages }, key)
+
+	return fmt.Sprintf(f, args...)
+}
+
+// Intensity returns the word describing mmi.
+func (l Lang) Intensity(mmi float64) string {
+	return l.word(func(c *catalog) map[string]string { return c.Intensity }, MMIIntensity(mmi))
+}
+
+// Compass converts bearing (0-360) to a compass bearing name.
+func (l Lang) Compass(bearing float64) string {
+	return l.word(func(c *catalog) map[string]string { return c.Compass }, Compass(bearing))
+}
+
+// Distance is the distance km rounded down to 5 km.
+func (l Lang) Distance(km float64) string {
+	d := math.Floor(km / 5.0)
+	if d > 0 {
+		return l.Text("distance", d*5)
+	}
+
+	return l.Text("distance.near")
+}
+
+// Location describes the location of a quake relative to the locality e.g., 10 km north of Taupo.
+func (l Lang) Location(c LocalityQuake) string {
+	if c.Distance < 5 {
+		return l.Text("location.near", c.Locality.Name)
+	}
+
+	return l.Text("location", math.Floor(c.Distance/5.0)*5, l.Compass(c.Bearing), c.Locality.Name)
+}
+
+/*
+Date formats t with layout (see time.Format) and replaces the English weekday and month names
+with those for l.  Full names are replaced before abbreviations.
+*/
+func (l Lang) Date(t time.Time, layout string) string {
+	s := t.Format(layout)
+
+	c := l.catalog()
+	if l == English || c.Weekdays == nil || c.Months == nil {
+		return s
+	}
+
+	w, m := t.Weekday().String(), t.Month().String()
+	lw, lm := c.Weekdays[t.Weekday()], c.Months[t.Month()-1]
+
+	return strings.NewReplacer(w, lw, m, lm, w[:3], lw, m[:3], lm).Replace(s)
+}
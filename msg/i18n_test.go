@@ -0,0 +1,157 @@
+package msg
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCatalogs(t *testing.T) {
+	for l := range catalogs {
+		for mmi := 0.0; mmi <= 12; mmi++ {
+			if _, ok := l.catalog().Intensity[MMIIntensity(mmi)]; !ok {
+				t.Errorf("%s: no intensity for MMI %.f", l, mmi)
+			}
+		}
+
+		for b := 0.0; b <= 360; b += 22.5 {
+			if _, ok := l.catalog().Compass[Compass(b)]; !ok {
+				t.Errorf("%s: no compass point for bearing %.1f", l, b)
+			}
+		}
+
+		for k := range catalogs[English].Messages {
+			if _, ok := l.catalog().Messages[k]; !ok {
+				t.Errorf("%s: no message %s", l, k)
+			}
+		}
+	}
+}
+
+func TestLangWords(t *testing.T) {
+	eq(t, "moderate", English.Intensity(5.5))
+	eq(t, "āhua kaha", Maori.Intensity(5.5))
+	eq(t, "tino kaha", Maori.Intensity(8))
+
+	eq(t, "south-east", English.Compass(135))
+	eq(t, "tonga-mā-rāwhiti", Maori.Compass(135))
+	eq(t, "raki", Maori.Compass(359))
+
+	// unknown languages are English.
+	eq(t, "severe", Lang("fr").Intensity(7))
+
+	c := LocalityQuake{Locality: Locality{Name: "Taupō"}, Distance: 12, Bearing: 90}
+
+	eq(t, "10 km east of Taupō", English.Location(c))
+	eq(t, "10 km ki te rāwhiti o Taupō", Maori.Location(c))
+
+	c.Distance = 3
+
+	eq(t, "Within 5 km of Taupō", English.Location(c))
+	eq(t, "I roto i te 5 km o Taupō", Maori.Location(c))
+
+	eq(t, "Within 5 km of", English.Distance(4.9))
+	eq(t, "25 km", Maori.Distance(27))
+}
+
+func TestLangDate(t *testing.T) {
+	d := time.Date(2016, time.November, 14, 0, 2, 56, 0, time.UTC)
+
+	eq(t, "Mon 14 Nov 2016 at 12:02 am", English.Date(d, "Mon 2 Jan 2006 at 3:04 pm"))
+	eq(t, "Rāhina 14 Whiringa-ā-rangi 2016, 12:02 am", Maori.Date(d, "Monday 2 January 2006, 3:04 pm"))
+	eq(t, "Rāhina 14 Whiringa-ā-rangi 2016", Maori.Date(d, "Mon 2 Jan 2006"))
+
+	// May is the full and abbreviated month.
+	d = time.Date(2016, time.May, 1, 0, 0, 0, 0, time.UTC)
+	eq(t, "Rātapu 1 Haratua", Maori.Date(d, "Monday 2 January"))
+}
+
+func TestParseLangs(t *testing.T) {
+	l, err := ParseLangs("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, 1, len(l))
+	eq(t, English, l[0])
+
+	l, err = ParseLangs("mi, en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, 2, len(l))
+	eq(t, Maori, l[0])
+	eq(t, English, l[1])
+
+	if _, err = ParseLangs("en,fr"); err == nil {
+		t.Error("expected error for language without a catalog")
+	}
+}
+
+func TestSetCatalog(t *testing.T) {
+	defer delete(catalogs, "xx")
+
+	if err := SetCatalog("xx", []byte(`{"messages": {"social": "M%0.1f %s %s %s"}}`)); err == nil {
+		t.Error("expected error for different format verbs")
+	}
+
+	if err := SetCatalog("xx", []byte(`{"messages": {"nope": "nope"}}`)); err == nil {
+		t.Error("expected error for unknown message")
+	}
+
+	if err := SetCatalog("xx", []byte(`{"weekdays": ["a"]}`)); err == nil {
+		t.Error("expected error for short weekdays")
+	}
+
+	if err := SetCatalog("xx", []byte(`{"intensity": {"weak": "xx-weak"}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// missing text falls back to English.
+	eq(t, "xx-weak", Lang("xx").Intensity(3))
+	eq(t, "light", Lang("xx").Intensity(4))
+	eq(t, "Within 5 km of", Lang("xx").Distance(1))
+}
+
+func TestAlertsIn(t *testing.T) {
+	q := Quake{
+		PublicID:              "2015p278423",
+		Time:                  time.Now().UTC(),
+		Latitude:              -37.92257397,
+		Longitude:             178.3544071,
+		Depth:                 9.62890625,
+		EvaluationStatus:      "automatic",
+		UsedPhaseCount:        25,
+		AzimuthalGap:          180,
+		MinimumDistance:       2.4,
+		Magnitude:             6.0,
+		MagnitudeStationCount: 12,
+	}
+
+	ab, am := q.AlertDutyIn(Maori)
+	eq(t, true, ab)
+	eq(t, true, strings.HasPrefix(am, "Pūrongo Rū: RAHI 6.0, MM8, HŌHONU 10, WĀHI 5 km ki te tonga-mā-rāwhiti o Ruatoria,"))
+
+	ab, am = q.AlertPIMIn(Maori)
+	eq(t, true, ab)
+	eq(t, true, strings.HasPrefix(am, "Pūrongo Rū: RAHI 6.0, MM8,"))
+
+	ab, am = q.AlertPushIn(Maori)
+	eq(t, true, ab)
+	eq(t, "He rū whenua M6.0, he tino kaha te wiri ki te takiwā o Ruatoria", am)
+
+	ab, am = q.AlertSocialIn(0, TwitterLimits, Maori)
+	eq(t, true, ab)
+	eq(t, "He rū whenua M6.0, he tino kaha te wiri ki te takiwā o Ruatoria http://geonet.org.nz/quakes/2015p278423", am)
+
+	ab, e := q.AlertEqNewsMessageIn(EqNewsTemplates[Maori], EqNewsDefault, Maori)
+	eq(t, true, ab)
+	eq(t, "RŪ WHENUA: M6.0, he tino kaha te wiri, 10km te hōhonu, 5 km ki te tonga-mā-rāwhiti o Ruatoria", e.Subject)
+	eq(t, true, strings.Contains(e.Text, "Wāhi:                   5 km ki te tonga-mā-rāwhiti o Ruatoria"))
+	eq(t, true, strings.Contains(e.HTML, `<html lang="mi">`))
+	eq(t, true, strings.Contains(e.Text, "Wā ā-rohe:              "+Maori.Date(q.Time.In(nz), "Monday 2 January 2006, 3:04 pm (MST)")))
+
+	// English is unchanged.
+	ab, am = q.AlertPushIn(English)
+	eq(t, true, ab)
+	eq(t, "M6.0 quake causing severe shaking near Ruatoria", am)
+}
@@ -21,6 +21,9 @@ var future = time.Duration(10) * time.Second
 //     "Quality": "measured",
 //     "Source": "test.test"
 //  }
+//
+// Reported intensity can also have a Questionnaire e.g.,
+//     "Questionnaire": {"Situation": "inside", "Shaking": "strong", "Damage": "none"}
 type Intensity struct {
 	// Source is used to uniquely identify the intensity source.
 	// 'measured' and 'reported' values are stored separately.
@@ -35,7 +38,27 @@ type Intensity struct {
 	Latitude  float64   //  WGS84, -90 to 90.
 	Longitude float64   // WGS84, -180 to 180.
 	Time      time.Time // date time ISO8601 UTC.
-	err       error
+	// Questionnaire is optional and only for 'reported' intensity.
+	Questionnaire *Questionnaire `json:",omitempty"`
+	err           error
+}
+
+// Questionnaire is the structured part of a felt report.  All answers are optional and
+// an empty string is no answer.  Answers must be one of the values in questionnaireAnswers.
+type Questionnaire struct {
+	Situation string // where the reporter was.
+	Shaking   string // how the shaking felt.
+	Reaction  string // how the reporter reacted.
+	Objects   string // what happened to objects.
+	Damage    string // damage to the building the reporter was in or near.
+}
+
+var questionnaireAnswers = map[string][]string{
+	"Situation": {"inside", "outside", "vehicle"},
+	"Shaking":   {"weak", "light", "moderate", "strong", "severe", "extreme"},
+	"Reaction":  {"none", "calm", "alarmed", "frightened", "panic"},
+	"Objects":   {"none", "rattled", "moved", "fell", "broke"},
+	"Damage":    {"none", "slight", "moderate", "heavy", "collapse"},
 }
 
 func (i *Intensity) Err() error {
@@ -65,6 +88,22 @@ func (i *Intensity) Valid() {
 		i.err = fmt.Errorf("invalid MMI: %d", i.MMI)
 	}
 
+	if i.Latitude < -90 || i.Latitude > 90 {
+		i.err = fmt.Errorf("invalid latitude: %f", i.Latitude)
+	}
+
+	if i.Longitude < -180 || i.Longitude > 180 {
+		i.err = fmt.Errorf("invalid longitude: %f", i.Longitude)
+	}
+
+	if i.Questionnaire != nil {
+		if i.Quality != "reported" {
+			i.err = fmt.Errorf("invalid questionnaire for quality: %s", i.Quality)
+		} else if err := i.Questionnaire.valid(); err != nil {
+			i.err = err
+		}
+	}
+
 	if len(i.Comment) > 139 {
 		i.Comment = i.Comment[0:139]
 	}
@@ -72,6 +111,35 @@ func (i *Intensity) Valid() {
 	return
 }
 
+// valid returns an error if any answer in q is not allowed.
+func (q *Questionnaire) valid() error {
+	for k, a := range map[string]string{
+		"Situation": q.Situation,
+		"Shaking":   q.Shaking,
+		"Reaction":  q.Reaction,
+		"Objects":   q.Objects,
+		"Damage":    q.Damage,
+	} {
+		if a == "" {
+			continue
+		}
+
+		var ok bool
+		for _, v := range questionnaireAnswers[k] {
+			if a == v {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			return fmt.Errorf("invalid questionnaire %s: %s", k, a)
+		}
+	}
+
+	return nil
+}
+
 // Old sets i.err if the intensity pointed to by i is older then 60 minutes.
 func (i *Intensity) Old() {
 	if i.err != nil {
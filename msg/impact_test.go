@@ -77,3 +77,29 @@ func TestImpact(t *testing.T) {
 		t.Error("less than 10s in the future should have nil i.err.")
 	}
 }
+
+func TestImpactQuestionnaire(t *testing.T) {
+	in := []struct {
+		quality string
+		q       *Questionnaire
+		lat     float64
+		ok      bool
+	}{
+		{quality: "reported", ok: true},
+		{quality: "reported", q: &Questionnaire{}, ok: true},
+		{quality: "reported", q: &Questionnaire{Situation: "inside", Shaking: "strong", Reaction: "frightened", Objects: "fell", Damage: "none"}, ok: true},
+		{quality: "reported", q: &Questionnaire{Shaking: "very strong"}},
+		{quality: "reported", q: &Questionnaire{Damage: "None"}},
+		{quality: "measured", q: &Questionnaire{Shaking: "strong"}},
+		{quality: "reported", lat: -91},
+	}
+
+	for _, v := range in {
+		i := Intensity{Source: "test.test", Quality: v.quality, MMI: 4, Latitude: v.lat, Questionnaire: v.q}
+
+		i.Valid()
+		if (i.Err() == nil) != v.ok {
+			t.Errorf("%s %+v: expected ok %t got %v", v.quality, v.q, v.ok, i.Err())
+		}
+	}
+}
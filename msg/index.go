@@ -0,0 +1,172 @@
+package msg
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+index is a k-d tree of localities on the unit sphere.  It is used to find the candidate localities
+near a quake using a cheap straight line (chord) distance before the exact ellipsoid distance calculation.
+
+Distances on a sphere differ from the ellipsoid by less than 0.6% so candidates are searched with a margin
+of searchMargin.
+*/
+type index struct {
+	locs  []Locality
+	nodes []node // the tree stored as an implicit balanced binary tree.
+}
+
+type node struct {
+	p [3]float64
+	i int // index into locs.
+}
+
+const (
+	earthRadius  = 6371.0088 // mean radius km
+	searchMargin = 1.01
+	searchPad    = 1.0 // km
+)
+
+func newIndex(l []Locality) *index {
+	x := &index{locs: l, nodes: make([]node, len(l))}
+
+	for i, v := range l {
+		x.nodes[i] = node{p: unit(v.Latitude, v.Longitude), i: i}
+	}
+
+	build(x.nodes, 0)
+
+	return x
+}
+
+// build arranges n so that the median on axis is at the middle with lower values before it.
+func build(n []node, axis int) {
+	if len(n) <= 1 {
+		return
+	}
+
+	sort.Slice(n, func(i, j int) bool { return n[i].p[axis] < n[j].p[axis] })
+
+	m := len(n) / 2
+	build(n[:m], (axis+1)%3)
+	build(n[m+1:], (axis+1)%3)
+}
+
+// unit returns the cartesian coordinates on the unit sphere for latitude, longitude.
+func unit(latitude, longitude float64) [3]float64 {
+	lat := latitude * math.Pi / 180.0
+	lon := longitude * math.Pi / 180.0
+
+	return [3]float64{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+}
+
+// chord converts a distance (km) on the surface to a straight line distance on the unit sphere.
+func chord(km float64) float64 {
+	a := km / earthRadius
+	if a >= math.Pi {
+		return 2.0
+	}
+
+	return 2.0 * math.Sin(a/2.0)
+}
+
+// km converts a chord on the unit sphere to a distance (km) on the surface.
+func km(c float64) float64 {
+	return 2.0 * math.Asin(math.Min(c/2.0, 1.0)) * earthRadius
+}
+
+func dist2(a, b [3]float64) float64 {
+	x, y, z := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return x*x + y*y + z*z
+}
+
+/*
+within returns the indexes (in the order of the localities) of the localities that may be within
+distance km of latitude, longitude.  The caller should check the exact distance.
+*/
+func (x *index) within(latitude, longitude, distance float64) []int {
+	c := chord(distance*searchMargin + searchPad)
+
+	var r []int
+
+	x.search(x.nodes, 0, unit(latitude, longitude), c*c, &r)
+
+	sort.Ints(r)
+
+	return r
+}
+
+func (x *index) search(n []node, axis int, p [3]float64, c2 float64, r *[]int) {
+	if len(n) == 0 {
+		return
+	}
+
+	m := len(n) / 2
+
+	if dist2(n[m].p, p) <= c2 {
+		*r = append(*r, n[m].i)
+	}
+
+	d := p[axis] - n[m].p[axis]
+
+	if d <= 0 || d*d <= c2 {
+		x.search(n[:m], (axis+1)%3, p, c2, r)
+	}
+	if d >= 0 || d*d <= c2 {
+		x.search(n[m+1:], (axis+1)%3, p, c2, r)
+	}
+}
+
+/*
+nearest returns the indexes of the localities that may be the closest to latitude, longitude
+amongst the localities for which ok returns true.  The caller should find the closest
+with the exact distance.
+*/
+func (x *index) nearest(latitude, longitude float64, ok func(Locality) bool) []int {
+	p := unit(latitude, longitude)
+
+	best := math.MaxFloat64
+	x.closest(x.nodes, 0, p, ok, &best)
+
+	if best == math.MaxFloat64 {
+		return nil
+	}
+
+	var r []int
+
+	for _, i := range x.within(latitude, longitude, km(math.Sqrt(best))) {
+		if ok(x.locs[i]) {
+			r = append(r, i)
+		}
+	}
+
+	return r
+}
+
+func (x *index) closest(n []node, axis int, p [3]float64, ok func(Locality) bool, best *float64) {
+	if len(n) == 0 {
+		return
+	}
+
+	m := len(n) / 2
+
+	if ok(x.locs[n[m].i]) {
+		if d := dist2(n[m].p, p); d < *best {
+			*best = d
+		}
+	}
+
+	d := p[axis] - n[m].p[axis]
+
+	near, far := n[:m], n[m+1:]
+	if d > 0 {
+		near, far = far, near
+	}
+
+	x.closest(near, (axis+1)%3, p, ok, best)
+
+	if d*d < *best {
+		x.closest(far, (axis+1)%3, p, ok, best)
+	}
+}
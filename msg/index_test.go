@@ -0,0 +1,213 @@
+package msg
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// closestLinear is the closest locality search without the index.
+func (q *Quake) closestLinear(r RegionID) (loc LocalityQuake) {
+	distance := 20000.0
+	var bearing float64
+	var locality Locality
+
+	for _, l := range regions[r] {
+		d, b := geo.To(l.Latitude, l.Longitude, q.Latitude, q.Longitude)
+		if d < distance {
+			distance = d
+			locality = l
+			bearing = b
+		}
+	}
+
+	if distance > 300 && locality.Size >= 2 {
+		larger := 20000.0
+
+		for _, l := range regions[r] {
+			if l.Size == 0 || l.Size == 1 {
+				d, b := geo.To(l.Latitude, l.Longitude, q.Latitude, q.Longitude)
+				if d < larger {
+					larger = d
+					distance = d
+					locality = l
+					bearing = b
+				}
+			}
+		}
+	}
+
+	loc.Locality = locality
+	loc.Distance = distance
+	loc.Bearing = bearing
+	loc.MMIDistance = q.MMIAtDistance(distance)
+
+	return
+}
+
+// localitiesLinear is Localities without the index.
+func (q Quake) localitiesLinear(minMMIDistance float64) (l []LocalityQuake) {
+	for _, loc := range regions[NewZealand] {
+		d, b := geo.To(loc.Latitude, loc.Longitude, q.Latitude, q.Longitude)
+
+		mmid := q.MMIAtDistance(d)
+
+		if mmid >= minMMIDistance {
+			l = append(l, LocalityQuake{Locality: loc, Distance: d, Bearing: b, MMIDistance: mmid})
+		}
+	}
+
+	return
+}
+
+// uaLocalitiesLinear returns the UA locality names with MMI >= 3 without the index.
+func (q *Quake) uaLocalitiesLinear() (n []string) {
+	for _, l := range uaLocalities {
+		d, _ := geo.To(l.Latitude, l.Longitude, q.Latitude, q.Longitude)
+		if q.MMIAtDistance(d) >= 3.0 {
+			n = append(n, l.Name)
+		}
+	}
+
+	return
+}
+
+func (q *Quake) uaLocalitiesIndex() (n []string) {
+	for _, i := range uaIndex.within(q.Latitude, q.Longitude, q.MMIRadius(3.0)) {
+		l := uaLocalities[i]
+		d, _ := geo.To(l.Latitude, l.Longitude, q.Latitude, q.Longitude)
+		if q.MMIAtDistance(d) >= 3.0 {
+			n = append(n, l.Name)
+		}
+	}
+
+	return
+}
+
+// testQuakes is a grid of quakes covering New Zealand, the Kermadecs, and further away.
+func testQuakes() (q []Quake) {
+	for lat := -52.0; lat <= -26.0; lat += 1.3 {
+		for lon := 160.0; lon <= 186.0; lon += 1.3 {
+			for _, m := range []float64{3.0, 5.5, 7.5} {
+				l := lon
+				if l > 180.0 {
+					l -= 360.0
+				}
+				q = append(q, Quake{Latitude: lat, Longitude: l, Depth: 25.0, Magnitude: m})
+			}
+		}
+	}
+
+	q = append(q, Quake{Latitude: 51.5, Longitude: -0.1, Depth: 10.0, Magnitude: 5.0})
+
+	return
+}
+
+func TestIndexClosest(t *testing.T) {
+	for _, r := range []RegionID{NewZealand, Kermadec} {
+		for _, q := range testQuakes() {
+			e := q.closestLinear(r)
+
+			c, err := q.ClosestInRegion(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(e, c) {
+				t.Errorf("%s %.2f %.2f expected %+v got %+v", r, q.Latitude, q.Longitude, e, c)
+			}
+		}
+	}
+}
+
+func TestIndexLocalities(t *testing.T) {
+	for _, q := range testQuakes() {
+		for _, m := range []float64{3.0, 5.0} {
+			e := q.localitiesLinear(m)
+			l := q.Localities(m)
+
+			if !reflect.DeepEqual(e, l) {
+				t.Errorf("%.2f %.2f M%.1f expected %d localities got %d", q.Latitude, q.Longitude, q.Magnitude, len(e), len(l))
+			}
+		}
+	}
+}
+
+func TestIndexUA(t *testing.T) {
+	for _, q := range testQuakes() {
+		e := q.uaLocalitiesLinear()
+		l := q.uaLocalitiesIndex()
+
+		if !reflect.DeepEqual(e, l) {
+			t.Errorf("%.2f %.2f M%.1f expected %v got %v", q.Latitude, q.Longitude, q.Magnitude, e, l)
+		}
+	}
+}
+
+func TestIndexWithin(t *testing.T) {
+	// points either side of the anti-meridian.
+	x := newIndex([]Locality{
+		{Name: "a", Latitude: -30.0, Longitude: 179.9},
+		{Name: "b", Latitude: -30.0, Longitude: -179.9},
+		{Name: "c", Latitude: -30.0, Longitude: 170.0},
+	})
+
+	if r := x.within(-30.0, 180.0, 20.0); !reflect.DeepEqual(r, []int{0, 1}) {
+		t.Errorf("expected [0 1] got %v", r)
+	}
+
+	if r := x.nearest(-30.0, -179.0, func(l Locality) bool { return l.Name != "b" }); !reflect.DeepEqual(r, []int{0}) {
+		t.Errorf("expected [0] got %v", r)
+	}
+
+	if r := x.nearest(-30.0, -179.0, func(Locality) bool { return false }); r != nil {
+		t.Errorf("expected no localities got %v", r)
+	}
+}
+
+var benchQuake = Quake{Latitude: -37.92257397, Longitude: 178.3544071, Depth: 9.6, Magnitude: 6.0}
+
+func BenchmarkClosestLinear(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		benchQuake.closestLinear(NewZealand)
+	}
+}
+
+func BenchmarkClosest(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		benchQuake.ClosestInRegion(NewZealand)
+	}
+}
+
+func BenchmarkLocalitiesLinear(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		benchQuake.localitiesLinear(5.0)
+	}
+}
+
+func BenchmarkLocalities(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		benchQuake.Localities(5.0)
+	}
+}
+
+func BenchmarkUALinear(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		benchQuake.uaLocalitiesLinear()
+	}
+}
+
+func BenchmarkUA(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		benchQuake.uaLocalitiesIndex()
+	}
+}
+
+func ExampleQuake_ClosestInRegion() {
+	q := Quake{Latitude: -41.29, Longitude: 174.78, Depth: 20.0, Magnitude: 5.0}
+
+	c, _ := q.ClosestInRegion(NewZealand)
+
+	fmt.Println(c.Locality.Name)
+	// Output: Wellington
+}
@@ -0,0 +1,226 @@
+package msg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// IntensityModel calculates Modified Mercalli Intensity (MMI) for a quake.
+// Depth and distance are in km.
+type IntensityModel interface {
+	// Name identifies the model e.g., in logs.
+	Name() string
+	// MMI returns the maximum MMI for a quake of magnitude at depth.
+	MMI(magnitude, depth float64) float64
+	// MMIDistance returns the MMI at an epicentral distance from a quake of magnitude at depth.
+	MMIDistance(magnitude, depth, distance float64) float64
+}
+
+/*
+IntensityRule selects Model for quakes that match all the rule fields that are set.
+The zero value for a field matches all quakes.
+*/
+type IntensityRule struct {
+	Model IntensityModel
+	Type  string // quake type e.g., earthquake
+	// the epicentre must be within the bounds (degrees).  Longitude is 0-360 so that
+	// regions can cross the anti-meridian.
+	Region             *Bounds
+	MinDepth, MaxDepth float64 // MaxDepth 0 for no maximum.
+	MinMagnitude       float64
+}
+
+// Bounds is a latitude and longitude box.
+type Bounds struct {
+	MinLatitude, MaxLatitude   float64
+	MinLongitude, MaxLongitude float64
+}
+
+var (
+	// DefaultIntensityModel is used for quakes that don't match any IntensityRules.
+	DefaultIntensityModel IntensityModel = NZIntensity{}
+	// IntensityRules are checked in order, the first rule that matches selects the model for a quake.
+	IntensityRules []IntensityRule
+	// IntensityModels are the available models by Name() e.g., for selecting a model from config.
+	IntensityModels = map[string]IntensityModel{
+		NZIntensity{}.Name():   NZIntensity{},
+		AW07Intensity{}.Name(): AW07Intensity{},
+	}
+)
+
+/*
+InitIntensityRules sets IntensityRules from the INTENSITY_RULES env var.  It is a JSON list of rules with the
+model by name e.g.,
+
+	[{"Model":"aw07","Type":"earthquake","MaxDepth":40,"Region":{"MinLatitude":-48,"MaxLatitude":-40,"MinLongitude":165,"MaxLongitude":175}}]
+
+The rules are not changed if INTENSITY_RULES is not set.  Call it from main in every service that calculates MMI
+so they all use the same models.
+*/
+func InitIntensityRules() error {
+	s := os.Getenv("INTENSITY_RULES")
+	if s == "" {
+		return nil
+	}
+
+	r, err := ParseIntensityRules(s)
+	if err != nil {
+		return err
+	}
+
+	IntensityRules = r
+
+	return nil
+}
+
+// ParseIntensityRules parses the JSON rules in s, see InitIntensityRules.
+func ParseIntensityRules(s string) ([]IntensityRule, error) {
+	var c []struct {
+		Model              string
+		Type               string
+		Region             *Bounds
+		MinDepth, MaxDepth float64
+		MinMagnitude       float64
+	}
+
+	d := json.NewDecoder(bytes.NewBufferString(s))
+	d.DisallowUnknownFields()
+
+	if err := d.Decode(&c); err != nil {
+		return nil, fmt.Errorf("invalid intensity rules: %s", err)
+	}
+
+	var r []IntensityRule
+
+	for _, v := range c {
+		m, ok := IntensityModels[v.Model]
+		if !ok {
+			return nil, fmt.Errorf("unknown intensity model %q", v.Model)
+		}
+
+		r = append(r, IntensityRule{Model: m, Type: v.Type, Region: v.Region,
+			MinDepth: v.MinDepth, MaxDepth: v.MaxDepth, MinMagnitude: v.MinMagnitude})
+	}
+
+	return r, nil
+}
+
+// IntensityModel returns the model used to calculate MMI for q.
+func (q *Quake) IntensityModel() IntensityModel {
+	for _, r := range IntensityRules {
+		if r.Model != nil && r.match(q) {
+			return r.Model
+		}
+	}
+
+	return DefaultIntensityModel
+}
+
+func (r IntensityRule) match(q *Quake) bool {
+	d := math.Abs(q.Depth)
+
+	switch {
+	case r.Type != "" && r.Type != q.Type:
+		return false
+	case d < r.MinDepth:
+		return false
+	case r.MaxDepth > 0 && d > r.MaxDepth:
+		return false
+	case q.Magnitude < r.MinMagnitude:
+		return false
+	case r.Region != nil && !r.Region.Contains(q.Latitude, q.Longitude):
+		return false
+	}
+
+	return true
+}
+
+// Contains returns true if latitude, longitude is in b.
+func (b Bounds) Contains(latitude, longitude float64) bool {
+	if longitude < 0 {
+		longitude += 360.0
+	}
+
+	return latitude >= b.MinLatitude && latitude <= b.MaxLatitude &&
+		longitude >= b.MinLongitude && longitude <= b.MaxLongitude
+}
+
+/*
+NZIntensity is the New Zealand attenuation relation with a depth dependent approximation
+for the rupture width.  The relation has separate terms for crustal (< 70 km) and deeper quakes.
+*/
+type NZIntensity struct{}
+
+func (n NZIntensity) Name() string {
+	return "nz"
+}
+
+func (n NZIntensity) MMI(magnitude, depth float64) float64 {
+	var w, m float64
+	d := math.Abs(depth)
+	rupture := d
+
+	if d < 100 {
+		w = math.Min(0.5*math.Pow(10, magnitude-5.39), 30.0)
+		rupture = math.Max(d-0.5*w*0.85, 0.0)
+	}
+
+	if d < 70.0 {
+		m = 4.40 + 1.26*magnitude - 3.67*math.Log10(rupture*rupture*rupture+1634.691752)/3.0 + 0.012*d + 0.409
+	} else {
+		m = 3.76 + 1.48*magnitude - 3.50*math.Log10(rupture*rupture*rupture)/3.0 + 0.0031*d
+	}
+
+	return m
+}
+
+func (n NZIntensity) MMIDistance(magnitude, depth, distance float64) float64 {
+	mmi := n.MMI(magnitude, depth)
+	if mmi < 3.0 {
+		mmi = -1.0
+	}
+
+	return MMIDistance(distance, depth, mmi)
+}
+
+/*
+AW07Intensity is the Atkinson and Wald (2007) intensity prediction equation with
+the coefficients for California.  It is derived from 'Did You Feel It?' reports for
+shallow crustal quakes.  Hypocentral distance is used in place of rupture distance.
+
+Atkinson, G. M. and Wald, D. J. (2007). "Did You Feel It?" intensity data: a surprisingly
+good measure of earthquake ground motion. Seismological Research Letters 78(3), 362-368.
+*/
+type AW07Intensity struct{}
+
+const (
+	aw07c1 = 12.27
+	aw07c2 = 2.270
+	aw07c3 = 0.1304
+	aw07c4 = -1.30
+	aw07c5 = -0.0007070
+	aw07c6 = 1.95
+	aw07c7 = -0.577
+	aw07h  = 14.0
+	aw07Rt = 30.0
+)
+
+func (a AW07Intensity) Name() string {
+	return "aw07"
+}
+
+func (a AW07Intensity) MMI(magnitude, depth float64) float64 {
+	return a.MMIDistance(magnitude, depth, 0.0)
+}
+
+func (a AW07Intensity) MMIDistance(magnitude, depth, distance float64) float64 {
+	r := math.Sqrt(distance*distance + depth*depth + aw07h*aw07h)
+	lr := math.Log10(r)
+	b := math.Max(0.0, math.Log10(r/aw07Rt))
+	m := magnitude - 6.0
+
+	return aw07c1 + aw07c2*m + aw07c3*m*m + aw07c4*lr + aw07c5*r + aw07c6*b + aw07c7*magnitude*lr
+}
@@ -0,0 +1,154 @@
+package msg
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	gridMagnitude = []float64{3.0, 4.0, 5.0, 6.0, 7.0, 8.0}
+	gridDepth     = []float64{5.0, 15.0, 30.0, 60.0, 100.0, 200.0, 300.0}
+	gridDistance  = []float64{0.0, 10.0, 20.0, 50.0, 100.0, 200.0, 300.0, 500.0}
+)
+
+// The default model must give the same results as the original relation.
+func TestNZIntensity(t *testing.T) {
+	n := NZIntensity{}
+
+	for _, m := range gridMagnitude {
+		for _, d := range gridDepth {
+			q := Quake{Magnitude: m, Depth: d}
+			mmi := q.MMI()
+
+			for _, r := range gridDistance {
+				delta(t, MMIDistance(r, d, mmi), q.MMIAtDistance(r), 0.000001)
+				delta(t, math.Max(n.MMIDistance(m, d, r), -1.0), q.MMIAtDistance(r), 0.000001)
+			}
+		}
+	}
+}
+
+// Intensity must not increase with distance or decrease with magnitude.
+func TestIntensityModelsGrid(t *testing.T) {
+	for name, model := range IntensityModels {
+		for _, d := range gridDepth {
+			for _, r := range gridDistance {
+				last := -1.0
+				for _, m := range gridMagnitude {
+					v := model.MMIDistance(m, d, r)
+					if v < last {
+						t.Errorf("%s: MMI decreased with magnitude M%.1f depth %.0f distance %.0f: %.2f < %.2f", name, m, d, r, v, last)
+					}
+					if v > 12.5 {
+						t.Errorf("%s: MMI too large M%.1f depth %.0f distance %.0f: %.2f", name, m, d, r, v)
+					}
+					last = v
+				}
+			}
+
+			for _, m := range gridMagnitude {
+				last := model.MMI(m, d)
+				for _, r := range gridDistance {
+					v := model.MMIDistance(m, d, r)
+					if v > last+0.000001 {
+						t.Errorf("%s: MMI increased with distance M%.1f depth %.0f distance %.0f: %.2f > %.2f", name, m, d, r, v, last)
+					}
+					last = v
+				}
+			}
+		}
+	}
+}
+
+// For shallow crustal quakes the models should broadly agree.
+func TestIntensityModelsCompare(t *testing.T) {
+	n := NZIntensity{}
+	a := AW07Intensity{}
+
+	for _, m := range []float64{5.0, 6.0, 7.0, 8.0} {
+		for _, d := range []float64{5.0, 15.0, 30.0} {
+			for _, r := range gridDistance {
+				nv := n.MMIDistance(m, d, r)
+				av := a.MMIDistance(m, d, r)
+
+				if math.Abs(nv-av) > 2.5 {
+					t.Errorf("M%.1f depth %.0f distance %.0f: nz %.2f aw07 %.2f differ by more than 2.5", m, d, r, nv, av)
+				}
+			}
+		}
+	}
+}
+
+/*
+AW07 reference values worked by hand from equation 1 and the California coefficients in Table 1 of
+Atkinson and Wald (2007), not from AW07Intensity.  R = sqrt(distance^2 + depth^2 + 14^2) and
+B = max(0, log10(R/30)).
+*/
+func TestAW07Reference(t *testing.T) {
+	a := AW07Intensity{}
+
+	// M6.0 at the epicentre: R = 14, log10(R) = 1.146128, B = 0
+	// 12.27 - 1.30*1.146128 - 0.000707*14 - 0.577*6*1.146128 = 6.802
+	delta(t, 6.802, a.MMIDistance(6.0, 0.0, 0.0), 0.001)
+
+	// M6.0 at 100 km: R = 100.975, log10(R) = 2.004215, B = log10(100.975/30) = 0.527094
+	// 12.27 - 1.30*2.004215 - 0.000707*100.975 + 1.95*0.527094 - 0.577*6*2.004215 = 3.682
+	delta(t, 3.682, a.MMIDistance(6.0, 0.0, 100.0), 0.001)
+
+	// M7.1 at 11 km depth (Darfield 2010): R = 17.8045, log10(R) = 1.250530, B = 0
+	// 12.27 + 2.27*1.1 + 0.1304*1.21 - 1.30*1.250530 - 0.000707*17.8045 - 0.577*7.1*1.250530 = 8.163
+	delta(t, 8.163, a.MMI(7.1, 11.0), 0.001)
+}
+
+func TestIntensityRules(t *testing.T) {
+	defer func() { IntensityRules = nil }()
+
+	IntensityRules = []IntensityRule{
+		{Model: AW07Intensity{}, Type: "earthquake", MaxDepth: 40.0, Region: &Bounds{MinLatitude: -48.0, MaxLatitude: -40.0, MinLongitude: 165.0, MaxLongitude: 175.0}},
+	}
+
+	in := []struct {
+		id    string
+		q     Quake
+		model string
+	}{
+		{id: loc(), q: Quake{Type: "earthquake", Depth: 11.0, Latitude: -43.6, Longitude: 172.2}, model: "aw07"},
+		{id: loc(), q: Quake{Type: "earthquake", Depth: 50.0, Latitude: -43.6, Longitude: 172.2}, model: "nz"},
+		{id: loc(), q: Quake{Type: "earthquake", Depth: 11.0, Latitude: -38.6, Longitude: 178.2}, model: "nz"},
+		{id: loc(), q: Quake{Type: "", Depth: 11.0, Latitude: -43.6, Longitude: 172.2}, model: "nz"},
+	}
+
+	for _, v := range in {
+		if m := v.q.IntensityModel().Name(); m != v.model {
+			t.Errorf("%s expected model %s got %s", v.id, v.model, m)
+		}
+	}
+
+	b := Bounds{MinLatitude: -50.0, MaxLatitude: -30.0, MinLongitude: 170.0, MaxLongitude: 190.0}
+	if !b.Contains(-30.0, -175.0) {
+		t.Error("expected bounds across the anti-meridian to contain -175")
+	}
+}
+
+func TestParseIntensityRules(t *testing.T) {
+	r, err := ParseIntensityRules(`[{"Model":"aw07","Type":"earthquake","MaxDepth":40,"Region":{"MinLatitude":-48,"MaxLatitude":-40,"MinLongitude":165,"MaxLongitude":175}},{"Model":"nz"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r) != 2 || r[0].Model.Name() != "aw07" || r[0].Type != "earthquake" || r[0].MaxDepth != 40 ||
+		r[0].Region == nil || *r[0].Region != (Bounds{MinLatitude: -48, MaxLatitude: -40, MinLongitude: 165, MaxLongitude: 175}) ||
+		r[1].Model.Name() != "nz" || r[1].Region != nil {
+		t.Errorf("unexpected rules %+v", r)
+	}
+
+	for _, v := range []string{
+		`[{"Model":"gmice"}]`,
+		`[{"Model":"nz","Depth":10}]`,
+		`{"Model":"nz"}`,
+	} {
+		if _, err = ParseIntensityRules(v); err == nil {
+			t.Errorf("expected error for %s", v)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package msg
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The compiled in localities.  Update the data files rather than adding localities in code.
+var (
+	//go:embed data/localities.csv
+	localitiesCSV []byte
+	//go:embed data/ua-localities.csv
+	uaLocalitiesCSV []byte
+)
+
+var regionIDRe = regexp.MustCompile(`^[a-z0-9]+$`)
+
+/*
+LoadLocalities replaces the localities for all regions with those read from file.
+The file format is chosen from the extension; .csv or .geojson (.json).
+
+CSV files must have a header row with the columns region, name, longitude, latitude, and size.
+A locality in more than one region is repeated for each region.
+
+GeoJSON files are a FeatureCollection of Points.  Feature properties are name, size, and regions
+(an array of region ids).
+*/
+func LoadLocalities(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r map[RegionID][]Locality
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		r, err = ReadLocalitiesCSV(f)
+	case ".geojson", ".json":
+		r, err = ReadLocalitiesGeoJSON(f)
+	default:
+		err = fmt.Errorf("unknown localities file type %s", file)
+	}
+	if err != nil {
+		return err
+	}
+
+	return SetLocalities(r)
+}
+
+/*
+SetLocalities validates r and replaces the localities for all regions.  It is not safe
+to call this while quakes are being processed; set localities at start up.
+*/
+func SetLocalities(r map[RegionID][]Locality) error {
+	if len(r) == 0 {
+		return fmt.Errorf("no regions")
+	}
+
+	for k, v := range r {
+		if err := validRegion(k, v); err != nil {
+			return err
+		}
+	}
+
+	x := make(map[RegionID]*index)
+	for k, v := range r {
+		x[k] = newIndex(v)
+	}
+
+	regions = r
+	regionIndex = x
+
+	return nil
+}
+
+// Regions returns the ids for the regions with localities.
+func Regions() []RegionID {
+	var r []RegionID
+	for k := range regions {
+		r = append(r, k)
+	}
+
+	return r
+}
+
+// RegionLocalities returns the localities for region r.
+func RegionLocalities(r RegionID) []Locality {
+	return regions[r]
+}
+
+func validRegion(r RegionID, l []Locality) error {
+	if !regionIDRe.MatchString(string(r)) {
+		return fmt.Errorf("invalid region id '%s'", r)
+	}
+
+	if len(l) == 0 {
+		return fmt.Errorf("region %s has no localities", r)
+	}
+
+	names := make(map[string]bool)
+
+	for _, v := range l {
+		switch {
+		case strings.TrimSpace(v.Name) == "":
+			return fmt.Errorf("region %s: locality with empty name", r)
+		case names[v.Name]:
+			return fmt.Errorf("region %s: duplicate locality %s", r, v.Name)
+		case v.Latitude < -90.0 || v.Latitude > 90.0:
+			return fmt.Errorf("region %s: locality %s invalid latitude %f", r, v.Name, v.Latitude)
+		case v.Longitude < -180.0 || v.Longitude > 360.0:
+			return fmt.Errorf("region %s: locality %s invalid longitude %f", r, v.Name, v.Longitude)
+		case v.Size < 0 || v.Size > 2:
+			return fmt.Errorf("region %s: locality %s invalid size %d", r, v.Name, v.Size)
+		}
+		names[v.Name] = true
+	}
+
+	return nil
+}
+
+// ReadLocalitiesCSV reads localities by region from CSV.  See LoadLocalities.
+func ReadLocalitiesCSV(r io.Reader) (map[RegionID][]Locality, error) {
+	return readLocalitiesCSV(r, "")
+}
+
+// readLocalitiesCSV reads localities from CSV.  If the CSV has no region column all
+// localities are added to region.  The size column is optional and defaults to 0.
+func readLocalitiesCSV(r io.Reader, region RegionID) (map[RegionID][]Locality, error) {
+	c := csv.NewReader(r)
+	c.TrimLeadingSpace = true
+
+	h, err := c.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	col := make(map[string]int)
+	for i, v := range h {
+		col[strings.ToLower(strings.TrimSpace(v))] = i
+	}
+
+	for _, v := range []string{"name", "longitude", "latitude"} {
+		if _, ok := col[v]; !ok {
+			return nil, fmt.Errorf("missing column %s", v)
+		}
+	}
+
+	if _, ok := col["region"]; !ok && region == "" {
+		return nil, fmt.Errorf("missing column region")
+	}
+
+	l := make(map[RegionID][]Locality)
+
+	for {
+		rec, err := c.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := c.FieldPos(0)
+
+		var loc Locality
+		loc.Name = strings.TrimSpace(rec[col["name"]])
+
+		if loc.Longitude, err = strconv.ParseFloat(rec[col["longitude"]], 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid longitude: %s", line, err)
+		}
+
+		if loc.Latitude, err = strconv.ParseFloat(rec[col["latitude"]], 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid latitude: %s", line, err)
+		}
+
+		if i, ok := col["size"]; ok && rec[i] != "" {
+			if loc.Size, err = strconv.Atoi(rec[i]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid size: %s", line, err)
+			}
+		}
+
+		id := region
+		if i, ok := col["region"]; ok {
+			id = RegionID(strings.TrimSpace(rec[i]))
+		}
+
+		l[id] = append(l[id], loc)
+	}
+
+	return l, nil
+}
+
+type localityFeatures struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Geometry struct {
+			Type        string    `json:"type"`
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+		Properties struct {
+			Name    string     `json:"name"`
+			Size    int        `json:"size"`
+			Regions []RegionID `json:"regions"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+// ReadLocalitiesGeoJSON reads localities by region from GeoJSON.  See LoadLocalities.
+func ReadLocalitiesGeoJSON(r io.Reader) (map[RegionID][]Locality, error) {
+	var f localityFeatures
+
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+
+	if f.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("expected FeatureCollection got %s", f.Type)
+	}
+
+	l := make(map[RegionID][]Locality)
+
+	for i, v := range f.Features {
+		if v.Geometry.Type != "Point" || len(v.Geometry.Coordinates) < 2 {
+			return nil, fmt.Errorf("feature %d: expected Point geometry", i)
+		}
+
+		if len(v.Properties.Regions) == 0 {
+			return nil, fmt.Errorf("feature %d: %s has no regions", i, v.Properties.Name)
+		}
+
+		loc := Locality{
+			Name:      strings.TrimSpace(v.Properties.Name),
+			Longitude: v.Geometry.Coordinates[0],
+			Latitude:  v.Geometry.Coordinates[1],
+			Size:      v.Properties.Size,
+		}
+
+		for _, r := range v.Properties.Regions {
+			l[r] = append(l[r], loc)
+		}
+	}
+
+	return l, nil
+}
@@ -0,0 +1,165 @@
+package msg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompiledLocalities(t *testing.T) {
+	if len(RegionLocalities(NewZealand)) != 105 {
+		t.Errorf("expected 105 New Zealand localities got %d", len(RegionLocalities(NewZealand)))
+	}
+
+	if len(RegionLocalities(Kermadec)) == 0 {
+		t.Error("expected Kermadec localities")
+	}
+
+	if len(uaLocalities) != 265 {
+		t.Errorf("expected 265 UA localities got %d", len(uaLocalities))
+	}
+}
+
+func TestClosestInRegion(t *testing.T) {
+	// Kermadec Islands
+	q := Quake{Latitude: -29.5, Longitude: -177.5, Depth: 30.0, Magnitude: 6.0}
+
+	c, err := q.ClosestInRegion(Kermadec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Locality.Name != "Raoul Island" {
+		t.Errorf("expected Raoul Island got %s", c.Locality.Name)
+	}
+
+	if _, err = q.ClosestInRegion(RegionID("nowhere")); err == nil {
+		t.Error("expected error for region with no localities")
+	}
+}
+
+func TestReadLocalitiesCSV(t *testing.T) {
+	in := `region,name,longitude,latitude,size
+newzealand,Wellington,174.78,-41.29,0
+newzealand,Lower Hutt,174.92,-41.22,1
+wellington,Lower Hutt,174.92,-41.22,1
+`
+	r, err := ReadLocalitiesCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r[NewZealand]) != 2 || len(r["wellington"]) != 1 {
+		t.Errorf("unexpected regions %v", r)
+	}
+
+	if r[NewZealand][1] != (Locality{Name: "Lower Hutt", Longitude: 174.92, Latitude: -41.22, Size: 1}) {
+		t.Errorf("unexpected locality %+v", r[NewZealand][1])
+	}
+
+	for _, v := range []string{
+		"name,longitude,latitude\nWellington,174.78,-41.29\n",
+		"region,name,longitude,latitude\nnewzealand,Wellington,east,-41.29\n",
+		"region,name,longitude,latitude,size\nnewzealand,Wellington,174.78,-41.29,big\n",
+	} {
+		if _, err = ReadLocalitiesCSV(strings.NewReader(v)); err == nil {
+			t.Errorf("expected error for %q", v)
+		}
+	}
+}
+
+func TestReadLocalitiesGeoJSON(t *testing.T) {
+	in := `{"type": "FeatureCollection", "features": [
+	{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-175.2, -21.14]}, "properties": {"name": "Nuku'alofa", "size": 0, "regions": ["tonga", "pacific"]}}
+	]}`
+
+	r, err := ReadLocalitiesGeoJSON(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r["tonga"]) != 1 || len(r["pacific"]) != 1 {
+		t.Fatalf("unexpected regions %v", r)
+	}
+
+	if r["tonga"][0].Name != "Nuku'alofa" || r["tonga"][0].Longitude != -175.2 || r["tonga"][0].Latitude != -21.14 {
+		t.Errorf("unexpected locality %+v", r["tonga"][0])
+	}
+
+	if _, err = ReadLocalitiesGeoJSON(strings.NewReader(`{"type": "FeatureCollection", "features": [
+	{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-175.2, -21.14]}, "properties": {"name": "Nuku'alofa"}}]}`)); err == nil {
+		t.Error("expected error for locality with no regions")
+	}
+}
+
+func TestSetLocalities(t *testing.T) {
+	r, x := regions, regionIndex
+	defer func() { regions, regionIndex = r, x }()
+
+	in := []struct {
+		id string
+		r  map[RegionID][]Locality
+	}{
+		{id: loc(), r: map[RegionID][]Locality{}},
+		{id: loc(), r: map[RegionID][]Locality{"New Zealand": {{Name: "Wellington", Longitude: 174.78, Latitude: -41.29}}}},
+		{id: loc(), r: map[RegionID][]Locality{"empty": {}}},
+		{id: loc(), r: map[RegionID][]Locality{"nz": {{Name: " ", Longitude: 174.78, Latitude: -41.29}}}},
+		{id: loc(), r: map[RegionID][]Locality{"nz": {{Name: "Wellington", Longitude: 174.78, Latitude: -91.0}}}},
+		{id: loc(), r: map[RegionID][]Locality{"nz": {{Name: "Wellington", Longitude: 400.0, Latitude: -41.29}}}},
+		{id: loc(), r: map[RegionID][]Locality{"nz": {{Name: "Wellington", Longitude: 174.78, Latitude: -41.29, Size: 3}}}},
+		{id: loc(), r: map[RegionID][]Locality{"nz": {{Name: "Wellington", Longitude: 174.78, Latitude: -41.29}, {Name: "Wellington", Longitude: 174.78, Latitude: -41.29}}}},
+	}
+
+	for _, v := range in {
+		if err := SetLocalities(v.r); err == nil {
+			t.Errorf("%s expected error", v.id)
+		}
+	}
+
+	if len(RegionLocalities(NewZealand)) == 0 {
+		t.Error("invalid localities should not replace the current localities")
+	}
+}
+
+func TestLoadLocalities(t *testing.T) {
+	r, x := regions, regionIndex
+	defer func() { regions, regionIndex = r, x }()
+
+	d, err := ioutil.TempDir("", "localities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	f := filepath.Join(d, "localities.csv")
+
+	err = ioutil.WriteFile(f, []byte("region,name,longitude,latitude,size\npacific,Apia,-171.76,-13.83,0\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = LoadLocalities(f); err != nil {
+		t.Fatal(err)
+	}
+
+	q := Quake{Latitude: -15.0, Longitude: -173.0, Depth: 30.0, Magnitude: 6.0}
+
+	c, err := q.ClosestInRegion("pacific")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Locality.Name != "Apia" {
+		t.Errorf("expected Apia got %s", c.Locality.Name)
+	}
+
+	if _, err = q.Closest(); err == nil {
+		t.Error("expected error for New Zealand after replacing localities")
+	}
+
+	if err = LoadLocalities(filepath.Join(d, "localities.txt")); err == nil {
+		t.Error("expected error for unknown file type")
+	}
+}
@@ -1,12 +1,10 @@
 package msg
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/GeoNet/Golang-Ellipsoid/ellipsoid"
 	"log"
 	"math"
-	"text/template"
 	"time"
 )
 
@@ -14,53 +12,12 @@ var (
 	geo      ellipsoid.Ellipsoid
 	alertAge = time.Duration(-60) * time.Minute
 	nz       *time.Location
-	t        = template.Must(template.New("eqNews").Parse(eqNews))
 )
 
 const (
-	dutyTime    = "3:04 PM, 02/01/2006 MST"
-	eqNewsNow   = "Mon 2 Jan 2006 at 3:04 pm"
-	eqNewsUTC   = "2006/01/02 at 15:04:05"
-	eqNewsLocal = "(MST):      Monday 2 Jan 2006 at 3:04 pm"
-	tcoUrlLen   = len("https://t.co/7gZ0yUcmSx") // 09/06/2015 Twitter's t.co url sample (22 chars)
+	dutyTime = "3:04 PM, 02/01/2006 MST"
 )
 
-const eqNews = `                PRELIMINARY EARTHQUAKE REPORT
-
-                      GeoNet Data Centre
-                         GNS Science
-                   Lower Hutt, New Zealand
-                   http://www.geonet.org.nz
-
-        Report Issued at: {{.Now}}
-
-
-A likely felt earthquake has been detected by GeoNet; this is PRELIMINARY information only:
-
-        Public ID:              {{.Q.PublicID}}
-        Universal Time:         {{.UT}}
-        Local Time {{.LT}}
-        Latitude, Longitude:    {{.LL}}
-        Location:               {{.Location}}
-        Intensity:              {{.Intensity}} (MM{{.MMI}})
-        Depth:                  {{ printf "%.f"  .Q.Depth}} km
-        Magnitude:              {{ printf "%.1f"  .Q.Magnitude}}
-
-Check for the LATEST information at http://www.geonet.org.nz/quakes/{{.Q.PublicID}}
-`
-
-type eqNewsD struct {
-	Q         *Quake
-	MMI       int
-	Location  string
-	Now       string
-	TZ        string // timezone for the quake.
-	UT        string // quake time in UTC
-	LT        string // quake in local time
-	LL        string // lon lat string
-	Intensity string // word version of MMI
-}
-
 func init() {
 	geo = ellipsoid.Init("WGS84", ellipsoid.Degrees, ellipsoid.Kilometer, ellipsoid.LongitudeIsSymmetric, ellipsoid.BearingNotSymmetric)
 	var err error
@@ -162,26 +119,13 @@ func (q *Quake) TxLog() {
 	log.Printf("Sending quake %s", q.PublicID)
 }
 
-// MMI calculates the maximum Modificed Mercalli Intensity for the quake.
+// MMI calculates the maximum Modificed Mercalli Intensity for the quake using q.IntensityModel().
 func (q *Quake) MMI() float64 {
 	if q.err != nil {
 		return -1. - 0
 	}
 
-	var w, m float64
-	d := math.Abs(q.Depth)
-	rupture := d
-
-	if d < 100 {
-		w = math.Min(0.5*math.Pow(10, q.Magnitude-5.39), 30.0)
-		rupture = math.Max(d-0.5*w*0.85, 0.0)
-	}
-
-	if d < 70.0 {
-		m = 4.40 + 1.26*q.Magnitude - 3.67*math.Log10(rupture*rupture*rupture+1634.691752)/3.0 + 0.012*d + 0.409
-	} else {
-		m = 3.76 + 1.48*q.Magnitude - 3.50*math.Log10(rupture*rupture*rupture)/3.0 + 0.0031*d
-	}
+	m := q.IntensityModel().MMI(q.Magnitude, q.Depth)
 
 	if m < 3.0 {
 		m = -1.0
@@ -190,6 +134,15 @@ func (q *Quake) MMI() float64 {
 	return m
 }
 
+// MMIAtDistance calculates the MMI at distance (km) from the quake epicentre using q.IntensityModel().
+func (q *Quake) MMIAtDistance(distance float64) float64 {
+	if q.err != nil {
+		return -1.0
+	}
+
+	return math.Max(q.IntensityModel().MMIDistance(q.Magnitude, q.Depth, distance), -1.0)
+}
+
 // MMIDistance calculates the MMI at distance for New Zealand.  Distance and depth are in km.
 func MMIDistance(distance, depth, mmi float64) float64 {
 	// Minimum depth of 5 for numerical instability.
@@ -199,6 +152,47 @@ func MMIDistance(distance, depth, mmi float64) float64 {
 	return math.Max(mmi-1.18*math.Log(s/d)-0.0044*(s-d), -1.0)
 }
 
+// MMIAt returns the distance (km) from the quake to the location and the calculated MMI there.
+func (q *Quake) MMIAt(latitude, longitude float64) (distance, mmi float64) {
+	if q.err != nil {
+		return 0, -1.0
+	}
+
+	distance, _ = geo.To(latitude, longitude, q.Latitude, q.Longitude)
+	mmi = q.MMIAtDistance(distance)
+
+	return
+}
+
+/*
+MMIRadius returns the greatest distance (km) from the quake where the MMI calculated by q.MMIAtDistance
+is >= mmi.  Returns -1 if the MMI at the epicentre is less than mmi.  It is the radius used for push
+subscriptions, localities, and UA tags so they all agree on where the MMI drops below mmi.
+*/
+func (q *Quake) MMIRadius(mmi float64) float64 {
+	if q.MMIAtDistance(0) < mmi {
+		return -1
+	}
+
+	// MMI decreases with distance so search between the epicentre and the other side of the Earth.
+	lo, hi := 0.0, math.Pi*earthRadius
+
+	if q.MMIAtDistance(hi) >= mmi {
+		return hi
+	}
+
+	for hi-lo > 0.01 {
+		m := (lo + hi) / 2
+		if q.MMIAtDistance(m) >= mmi {
+			lo = m
+		} else {
+			hi = m
+		}
+	}
+
+	return lo
+}
+
 // MMIIntensity returns the string describing mmi.
 func MMIIntensity(mmi float64) string {
 	switch {
@@ -248,43 +242,44 @@ func (q *Quake) ClosestInRegion(r RegionID) (loc LocalityQuake, err error) {
 		return
 	}
 
-	distance := 20000.0
-	var bearing float64
-	var locality Locality
-
-	for _, l := range regions[r] {
-		d, b := geo.To(l.Latitude, l.Longitude, q.Latitude, q.Longitude)
-		if d < distance {
-			distance = d
-			locality = l
-			bearing = b
-		}
+	x, ok := regionIndex[r]
+	if !ok {
+		err = fmt.Errorf("no localities for region %s", r)
+		return
 	}
 
+	loc, _ = q.closest(x, func(Locality) bool { return true })
+
 	// ensure larger locality when distant quake.
-	if distance > 300 && locality.size >= 2 {
-		distance = 20000
-
-		for _, l := range regions[r] {
-			if l.size == 0 || l.size == 1 {
-				d, b := geo.To(l.Latitude, l.Longitude, q.Latitude, q.Longitude)
-				if d < distance {
-					distance = d
-					locality = l
-					bearing = b
-				}
-			}
+	if loc.Distance > 300 && loc.Locality.Size >= 2 {
+		if l, ok := q.closest(x, func(l Locality) bool { return l.Size == 0 || l.Size == 1 }); ok {
+			loc = l
 		}
 	}
 
-	loc.Locality = locality
-	loc.Distance = distance
-	loc.Bearing = bearing
-	loc.MMIDistance = MMIDistance(distance, q.Depth, q.MMI())
+	loc.MMIDistance = q.MMIAtDistance(loc.Distance)
 
 	return loc, nil
 }
 
+// closest returns the closest locality in x for which ok returns true.
+func (q *Quake) closest(x *index, ok func(Locality) bool) (loc LocalityQuake, found bool) {
+	loc.Distance = 20000.0
+
+	for _, i := range x.nearest(q.Latitude, q.Longitude, ok) {
+		l := x.locs[i]
+		d, b := geo.To(l.Latitude, l.Longitude, q.Latitude, q.Longitude)
+		if d < loc.Distance {
+			loc.Distance = d
+			loc.Locality = l
+			loc.Bearing = b
+			found = true
+		}
+	}
+
+	return
+}
+
 /*
 LocalitiesQuake returns localities in New Zealand that have an MMI at a distance >= minMMIDistance
 for the quake.
@@ -294,12 +289,21 @@ func (q Quake) Localities(minMMIDistance float64) (l []LocalityQuake) {
 		return
 	}
 
-	mmi := q.MMI()
+	x, ok := regionIndex[NewZealand]
+	if !ok {
+		return
+	}
 
-	for _, loc := range regions[NewZealand] {
+	r := q.MMIRadius(minMMIDistance)
+	if r < 0 {
+		return
+	}
+
+	for _, i := range x.within(q.Latitude, q.Longitude, r) {
+		loc := x.locs[i]
 		d, b := geo.To(loc.Latitude, loc.Longitude, q.Latitude, q.Longitude)
 
-		mmid := MMIDistance(d, q.Depth, mmi)
+		mmid := q.MMIAtDistance(d)
 
 		if mmid >= minMMIDistance {
 			c := LocalityQuake{
@@ -367,6 +371,11 @@ func (q *Quake) Publish() bool {
 // AlertDuty returns alert = true and message formated if the quake is suitable for alerting the
 // duty people, alert = false and empty message if not.
 func (q *Quake) AlertDuty() (alert bool, message string) {
+	return q.AlertDutyIn(English)
+}
+
+// AlertDutyIn is AlertDuty with the message in language l.
+func (q *Quake) AlertDutyIn(l Lang) (alert bool, message string) {
 	if q.Err() != nil {
 		return
 	}
@@ -387,12 +396,12 @@ func (q *Quake) AlertDuty() (alert bool, message string) {
 		}
 
 		// Eq Rpt: MAG 5.0, MM7, DEP 10, LOC 105 km N of White Island, TIME 08:33 AM, 26/02/2015
-		message = fmt.Sprintf("Eq Rpt: MAG %.1f, MM%d, DEP %.f, LOC %s %s of %s, TIME %s",
+		message = l.Text("duty",
 			q.Magnitude,
 			int(mmi),
 			q.Depth,
-			Distance(c.Distance),
-			Compass(c.Bearing),
+			l.Distance(c.Distance),
+			l.Compass(c.Bearing),
 			c.Locality.Name,
 			q.Time.In(nz).Format(dutyTime))
 	}
@@ -403,6 +412,11 @@ func (q *Quake) AlertDuty() (alert bool, message string) {
 // AlertPIM returns alert = true and message formated if the quake is suitable for alerting the
 // Pubilc Information people, alert = false and empty message if not.
 func (q *Quake) AlertPIM() (alert bool, message string) {
+	return q.AlertPIMIn(English)
+}
+
+// AlertPIMIn is AlertPIM with the message in language l.
+func (q *Quake) AlertPIMIn(l Lang) (alert bool, message string) {
 	if q.Err() != nil {
 		return
 	}
@@ -423,12 +437,12 @@ func (q *Quake) AlertPIM() (alert bool, message string) {
 		}
 
 		// Eq Rpt: MAG 5.0, MM7, DEP 10, LOC 105 km N of White Island, TIME 08:33 AM, 26/02/2015
-		message = fmt.Sprintf("Eq Rpt: MAG %.1f, MM%d, DEP %.f, LOC %s %s of %s, TIME %s",
+		message = l.Text("duty",
 			q.Magnitude,
 			int(mmi),
 			q.Depth,
-			Distance(c.Distance),
-			Compass(c.Bearing),
+			l.Distance(c.Distance),
+			l.Compass(c.Bearing),
 			c.Locality.Name,
 			q.Time.In(nz).Format(dutyTime))
 	}
@@ -442,45 +456,34 @@ the quake is suitable for alerting and above the minMagnitude threshold.  alert
 and message empty if not.
 */
 func (q *Quake) AlertTwitter(minMagnitude float64) (alert bool, message string) {
-	if q.Err() != nil {
-		return
-	}
-
-	if !q.AlertQuality() {
-		return
-	}
-
-	if q.Magnitude < minMagnitude {
-		return
-	}
+	return q.AlertSocial(minMagnitude, TwitterLimits)
+}
 
-	c, err := q.Closest()
-	if err != nil {
-		q.SetErr(err)
-		return
-	}
+func (q *Quake) AlertUAPush() (message string, tags []string) {
+	return q.AlertUAPushIn(English)
+}
 
-	if c.MMIDistance < 3.0 {
+// AlertUAPushIn is AlertUAPush with the message in language l.
+func (q *Quake) AlertUAPushIn(l Lang) (message string, tags []string) {
+	alert, message := q.AlertPushIn(l)
+	if !alert {
 		return
 	}
 
-	alert = true
-
-	// Quake 85 km east of Ruatoria, intensity moderate, approx. M3.6, depth 6 km http://geonet.org.nz/quakes/2011a868660 Fri Nov 18 2011 10:42 PM (NZDT)
-	qUrl := fmt.Sprintf("http://geonet.org.nz/quakes/%s", q.PublicID)
-	message = fmt.Sprintf("M%0.1f quake causing %s shaking near %s %s", q.Magnitude, MMIIntensity(c.MMIDistance), c.Locality.Name, qUrl)
-
-	// Make sure we'll only send message less than 140 chars (after url shortened with t.co)
-	t := len(message) - len(qUrl) + tcoUrlLen - 140
-	if t > 0 {
-		message = message[0 : len(message)-t]
-		log.Println("WARNING: Twitter message truncated", t, "chars to:", message)
-	}
+	tags = q.uaTags()
 
 	return
 }
 
-func (q *Quake) AlertUAPush() (message string, tags []string) {
+// AlertPush returns alert = true and message formatted for push notifications if
+// the quake is suitable for alerting and causes shaking at the closest locality.
+// alert = false and message empty if not.
+func (q *Quake) AlertPush() (alert bool, message string) {
+	return q.AlertPushIn(English)
+}
+
+// AlertPushIn is AlertPush with the message in language l.
+func (q *Quake) AlertPushIn(l Lang) (alert bool, message string) {
 	if q.Err() != nil {
 		return
 	}
@@ -499,75 +502,26 @@ func (q *Quake) AlertUAPush() (message string, tags []string) {
 		return
 	}
 
-	tags = q.uaTags()
-	message = fmt.Sprintf("M%0.1f quake causing %s shaking near %s", q.Magnitude, MMIIntensity(c.MMIDistance), c.Locality.Name)
+	alert = true
+	message = l.Text("social", q.Magnitude, l.Intensity(c.MMIDistance), c.Locality.Name)
 
 	return
 }
 
+// AlertEqNews returns alert = true, the subject, and the plain text body formatted for the
+// eqnews email list if the quake is suitable for alerting and above the default eqnews threshold.
+// alert = false if not.
 func (q *Quake) AlertEqNews() (alert bool, subject, body string) {
-	if q.Err() != nil {
-		return
-	}
-
-	if !q.AlertQuality() {
-		return
-	}
-
-	mmi := q.MMI()
-
-	c, err := q.Closest()
-	if err != nil {
-		q.SetErr(err)
-		return
-	}
-
-	if mmi >= 7.0 || c.MMIDistance >= 3.5 {
-		alert = true
-
-		// NZ EQ: M3.5, weak intensity, 5km deep, 20 km N of Reefton
-		subject = fmt.Sprintf("NZ EQ: M%.1f, %s intensity, %.fkm deep, %s %s of %s",
-			q.Magnitude,
-			MMIIntensity(mmi),
-			q.Depth,
-			Distance(c.Distance),
-			Compass(c.Bearing),
-			c.Locality.Name)
-
-	}
-
-	buf := new(bytes.Buffer)
-
-	err = t.ExecuteTemplate(buf, "eqNews", &eqNewsD{
-		Q:         q,
-		MMI:       int(mmi),
-		Location:  c.Location(),
-		Now:       time.Now().In(nz).Format(eqNewsNow),
-		UT:        q.Time.Format(eqNewsUTC),
-		LT:        q.Time.In(nz).Format(eqNewsLocal),
-		LL:        q.eqNewsLonLat(),
-		Intensity: MMIIntensity(mmi),
-	})
-	if err != nil {
-		q.SetErr(err)
-		alert = false
-		return
-	}
-
-	body = buf.String()
+	alert, e := q.AlertEqNewsMessage(DefaultEqNewsTemplate, EqNewsDefault)
+	subject = e.Subject
+	body = e.Text
 
 	return
-
 }
 
+// Distance is the distance km rounded down to 5 km in English.  See Lang.Distance.
 func Distance(km float64) string {
-	s := "Within 5 km of"
-
-	d := math.Floor(km / 5.0)
-	if d > 0 {
-		s = fmt.Sprintf("%.f km", d*5)
-	}
-	return s
+	return English.Distance(km)
 }
 
 func (q *Quake) eqNewsLonLat() string {
@@ -61,6 +61,23 @@ func TestMMI(t *testing.T) {
 
 }
 
+func TestMMIRadius(t *testing.T) {
+	// Darfield 2010
+	q := Quake{Depth: 11.0, Magnitude: 7.1}
+
+	for _, mmi := range []float64{1, 3, 6, 9} {
+		r := q.MMIRadius(mmi)
+
+		if q.MMIAtDistance(r) < mmi || q.MMIAtDistance(r+0.1) >= mmi {
+			t.Errorf("MMI %.0f: radius %.2f km is not where the MMI drops below %.0f", mmi, r, mmi)
+		}
+	}
+
+	if r := q.MMIRadius(11); r != -1 {
+		t.Errorf("expected -1 for MMI above the epicentral MMI got %f", r)
+	}
+}
+
 func TestClosest(t *testing.T) {
 	q := Quake{}
 	q.Longitude = 171.29
@@ -338,6 +355,32 @@ func TestAlertEqNews(t *testing.T) {
 	fmt.Println(body)
 }
 
+func TestAlertEqNewsMessage(t *testing.T) {
+	q := Quake{
+		PublicID:              "2015p278423",
+		Time:                  time.Now().UTC(),
+		Latitude:              -37.92257397,
+		Longitude:             178.3544071,
+		Depth:                 9.62890625,
+		EvaluationStatus:      "automatic",
+		UsedPhaseCount:        25,
+		AzimuthalGap:          180,
+		MinimumDistance:       2.4,
+		Magnitude:             6.0,
+		MagnitudeStationCount: 12,
+	}
+
+	ab, m := q.AlertEqNewsMessage(DefaultEqNewsTemplate, EqNewsDefault)
+	eq(t, true, ab)
+	eq(t, "NZ EQ: M6.0, severe intensity, 10km deep, 5 km south-east of Ruatoria", m.Subject)
+	eq(t, true, strings.Contains(m.Text, "Public ID:              2015p278423"))
+	eq(t, true, strings.Contains(m.HTML, `<img src="http://static.geonet.org.nz/maps/4/quake/178.35/-37.92/600x400.png"`))
+
+	ab, m = q.AlertEqNewsMessage(DefaultEqNewsTemplate, EqNewsThreshold{MMI: 9, MMIDistance: 9})
+	eq(t, false, ab)
+	eq(t, "", m.Text)
+}
+
 func TestAlertTwitter(t *testing.T) {
 	q := Quake{
 		PublicID:              "2015p278423",
@@ -363,6 +406,35 @@ func TestAlertTwitter(t *testing.T) {
 
 }
 
+func TestAlertSocial(t *testing.T) {
+	q := Quake{
+		PublicID:              "2015p278423",
+		Time:                  time.Now().UTC(),
+		Latitude:              -37.92257397,
+		Longitude:             178.3544071,
+		Depth:                 9.62890625,
+		EvaluationStatus:      "automatic",
+		UsedPhaseCount:        25,
+		AzimuthalGap:          180,
+		MinimumDistance:       2.4,
+		Magnitude:             6.0,
+		MagnitudeStationCount: 12,
+	}
+
+	a, m := q.AlertSocial(0, MastodonLimits)
+	eq(t, true, a)
+	eq(t, `M6.0 quake causing severe shaking near Ruatoria http://geonet.org.nz/quakes/2015p278423`, m)
+
+	// the text is truncated, the url is kept.
+	a, m = q.AlertSocial(0, SocialLimits{MaxChars: 50, URLLength: 23})
+	eq(t, true, a)
+	eq(t, `M6.0 quake causing severe http://geonet.org.nz/quakes/2015p278423`, m)
+
+	a, m = q.AlertSocial(7, MastodonLimits)
+	eq(t, false, a)
+	eq(t, "", m)
+}
+
 func TestAlertUAPush(t *testing.T) {
 	q := Quake{
 		PublicID:              "2015p278423",
@@ -393,7 +465,7 @@ func delta(t *testing.T, expected, actual, delta float64) {
 
 func eq(t *testing.T, expected, actual interface{}) {
 	if expected != actual {
-		t.Error("%s not equal", loc())
+		t.Errorf("%s not equal", loc())
 	}
 }
 
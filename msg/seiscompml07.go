@@ -3,6 +3,7 @@ package msg
 import (
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"sort"
 	"strconv"
@@ -146,6 +147,15 @@ func ReadSC3ML07(filename string) Quake {
 	return s.quake()
 }
 
+// DecodeSC3ML07 is ReadSC3ML07 for SC3ML read from r.
+func DecodeSC3ML07(r io.Reader) Quake {
+	s := sc3ml07{}
+
+	s.Error = xml.NewDecoder(r).Decode(&s)
+	s.init()
+	return s.quake()
+}
+
 // returns the most recent modificationTime or creationTime for the
 // components of the SC3ML that we are interested in for a Quake.
 // Returns without processing if s.Error is not nil
@@ -2,7 +2,9 @@ package msg
 
 import (
 
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -120,3 +122,26 @@ func TestUnmarshalEmptySC3ML07(t *testing.T) {
 		t.Fatal("es.Err not set")
 	}
 }
+
+func TestDecodeSC3ML07Reader(t *testing.T) {
+	f, err := os.Open("etc/2016p408314-201606010431276083.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	es := DecodeSC3ML07(f)
+
+	if es.Err() != nil {
+		t.Fatalf("es.Err non nil: %s", es.Err().Error())
+	}
+
+	if !reflect.DeepEqual(ReadSC3ML07("etc/2016p408314-201606010431276083.xml"), es) {
+		t.Error("events from the reader and file not equal")
+	}
+
+	bad := DecodeSC3ML07(strings.NewReader("<nope"))
+	if bad.Err() == nil {
+		t.Error("expected error for invalid SC3ML")
+	}
+}
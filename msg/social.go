@@ -0,0 +1,86 @@
+package msg
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// SocialLimits are the message length rules for a social posting service.
+type SocialLimits struct {
+	MaxChars  int // the maximum message length in characters.
+	URLLength int // the length every URL counts as.  0 to count URLs at their actual length.
+}
+
+var (
+	// TwitterLimits - Twitter shortens all URLs with t.co.
+	TwitterLimits = SocialLimits{
+		MaxChars:  140,
+		URLLength: len("https://t.co/7gZ0yUcmSx"), // 09/06/2015 Twitter's t.co url sample (22 chars)
+	}
+	// MastodonLimits are the defaults for a Mastodon instance.  URLs always count as 23 characters.
+	MastodonLimits = SocialLimits{
+		MaxChars:  500,
+		URLLength: 23,
+	}
+)
+
+/*
+AlertSocial returns alert = true and message formatted for posting to a social media account
+with limits l if the quake is suitable for alerting and above the minMagnitude threshold.  alert = false
+and message empty if not.  The text of the message is truncated to fit l, the quake URL is never truncated.
+*/
+func (q *Quake) AlertSocial(minMagnitude float64, l SocialLimits) (alert bool, message string) {
+	return q.AlertSocialIn(minMagnitude, l, English)
+}
+
+// AlertSocialIn is AlertSocial with the message in language lang.
+func (q *Quake) AlertSocialIn(minMagnitude float64, l SocialLimits, lang Lang) (alert bool, message string) {
+	if q.Err() != nil {
+		return
+	}
+
+	if !q.AlertQuality() {
+		return
+	}
+
+	if q.Magnitude < minMagnitude {
+		return
+	}
+
+	c, err := q.Closest()
+	if err != nil {
+		q.SetErr(err)
+		return
+	}
+
+	if c.MMIDistance < 3.0 {
+		return
+	}
+
+	alert = true
+
+	// M3.6 quake causing moderate shaking near Ruatoria http://geonet.org.nz/quakes/2011a868660
+	qUrl := fmt.Sprintf("http://geonet.org.nz/quakes/%s", q.PublicID)
+	text := lang.Text("social", q.Magnitude, lang.Intensity(c.MMIDistance), c.Locality.Name)
+
+	urlLen := utf8.RuneCountInString(qUrl)
+	if l.URLLength > 0 {
+		urlLen = l.URLLength
+	}
+
+	// Make sure we'll only send a message less than l.MaxChars (after the url is shortened).
+	if t := utf8.RuneCountInString(text) + 1 + urlLen - l.MaxChars; t > 0 && l.MaxChars > 0 {
+		r := []rune(text)
+		if t > len(r) {
+			t = len(r)
+		}
+		text = strings.TrimSpace(string(r[0 : len(r)-t]))
+		log.Println("WARNING: social message truncated", t, "chars to:", text)
+	}
+
+	message = text + " " + qUrl
+
+	return
+}
@@ -0,0 +1,166 @@
+package push
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APNs servers.
+const (
+	APNsProduction = "https://api.push.apple.com"
+	APNsSandbox    = "https://api.sandbox.push.apple.com"
+)
+
+// APNs tokens must be refreshed at least once an hour.
+const apnsTokenAge = time.Duration(40) * time.Minute
+
+// APNs delivers notifications to iOS devices with the Apple Push Notification service
+// using token-based (.p8 key) authentication.
+type APNs struct {
+	endpoint string
+	topic    string // the app bundle id.
+	keyID    string
+	teamID   string
+	key      crypto.Signer
+	h        *http.Client
+	mu       sync.Mutex
+	token    string
+	issued   time.Time
+}
+
+// NewAPNs returns an APNs provider.  endpoint is APNsProduction or APNsSandbox, topic is
+// the app bundle id, and p8 is the PEM encoded signing key with keyID for the developer teamID.
+func NewAPNs(endpoint, topic, keyID, teamID string, p8 []byte) (*APNs, error) {
+	k, err := parseKey(p8)
+	if err != nil {
+		return nil, err
+	}
+
+	if topic == "" || keyID == "" || teamID == "" {
+		return nil, fmt.Errorf("APNs topic, key id, and team id are required")
+	}
+
+	return &APNs{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		topic:    topic,
+		keyID:    keyID,
+		teamID:   teamID,
+		key:      k,
+		h:        &http.Client{Timeout: time.Duration(30) * time.Second},
+	}, nil
+}
+
+func (a *APNs) Push(n Notification, subs []Subscription) []Result {
+	b, err := json.Marshal(a.payload(n))
+	if err != nil {
+		return results(subs, err)
+	}
+
+	return each(subs, func(s Subscription) error {
+		return a.send(n, s.Token, b)
+	})
+}
+
+func (a *APNs) payload(n Notification) map[string]interface{} {
+	p := n.For("ios")
+
+	aps := map[string]interface{}{
+		"alert": map[string]string{
+			"title": p.Title,
+			"body":  p.Message,
+		},
+		"sound": p.Sound,
+	}
+
+	if p.Badge > 0 {
+		aps["badge"] = p.Badge
+	}
+
+	m := map[string]interface{}{"aps": aps}
+
+	for k, v := range p.Data {
+		m[k] = v
+	}
+
+	return m
+}
+
+func (a *APNs) send(n Notification, token string, body []byte) error {
+	t, err := a.bearer()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", a.endpoint+"/3/device/"+token, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "bearer "+t)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("apns-collapse-id", n.CollapseKey())
+
+	if n.TTL > 0 {
+		req.Header.Set("apns-expiration", strconv.FormatInt(time.Now().Add(n.TTL).Unix(), 10))
+	}
+
+	res, err := a.h.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		r, _ := ioutil.ReadAll(res.Body)
+
+		var e struct {
+			Reason string `json:"reason"`
+		}
+		json.Unmarshal(r, &e)
+
+		// 410 is for a token that is no longer active.  BadDeviceToken is for a token that was never valid.
+		if res.StatusCode == http.StatusGone || e.Reason == "BadDeviceToken" {
+			return fmt.Errorf("%w: APNs %d %s", ErrUnregistered, res.StatusCode, string(r))
+		}
+
+		return fmt.Errorf("Response error from APNs: %d %s", res.StatusCode, string(r))
+	}
+
+	return nil
+}
+
+// bearer returns the provider authentication token, creating a new one if the
+// current token is too old.
+func (a *APNs) bearer() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Since(a.issued) < apnsTokenAge {
+		return a.token, nil
+	}
+
+	now := time.Now()
+
+	t, err := jwt("ES256", a.keyID, map[string]interface{}{
+		"iss": a.teamID,
+		"iat": now.Unix(),
+	}, a.key)
+	if err != nil {
+		return "", err
+	}
+
+	a.token = t
+	a.issued = now
+
+	return a.token, nil
+}
@@ -0,0 +1,174 @@
+package push
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAPNs(t *testing.T) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := x509.MarshalPKCS8PrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	got := make(map[string]map[string]interface{})
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/3/device/") {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		a := strings.TrimPrefix(r.Header.Get("Authorization"), "bearer ")
+		p := strings.Split(a, ".")
+		if len(p) != 3 {
+			t.Errorf("expected 3 part JWT got %d", len(p))
+			http.Error(w, `{"reason":"InvalidProviderToken"}`, http.StatusForbidden)
+			return
+		}
+
+		var h map[string]string
+		hb, _ := base64.RawURLEncoding.DecodeString(p[0])
+		json.Unmarshal(hb, &h)
+		if h["alg"] != "ES256" || h["kid"] != "KEY123" {
+			t.Errorf("unexpected JWT header %v", h)
+		}
+
+		sig, _ := base64.RawURLEncoding.DecodeString(p[2])
+		if len(sig) != 64 {
+			t.Errorf("expected 64 byte signature got %d", len(sig))
+		}
+		dg := sha256.Sum256([]byte(p[0] + "." + p[1]))
+		if !ecdsa.Verify(&k.PublicKey, dg[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			t.Error("JWT signature did not verify")
+		}
+
+		for k, v := range map[string]string{
+			"apns-topic":       "nz.org.geonet.quake",
+			"apns-push-type":   "alert",
+			"apns-priority":    "10",
+			"apns-collapse-id": "2015p278423",
+		} {
+			if r.Header.Get(k) != v {
+				t.Errorf("expected header %s %s got %s", k, v, r.Header.Get(k))
+			}
+		}
+
+		if r.Header.Get("apns-expiration") == "" {
+			t.Error("expected apns-expiration header")
+		}
+
+		var b map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+			t.Error(err)
+		}
+
+		mu.Lock()
+		got[strings.TrimPrefix(r.URL.Path, "/3/device/")] = b
+		mu.Unlock()
+	}))
+	defer s.Close()
+
+	a, err := NewAPNs(s.URL, "nz.org.geonet.quake", "KEY123", "TEAM123", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: d}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := Notification{
+		PublicID: "2015p278423",
+		Message:  "Quake near Gisborne",
+		TTL:      time.Duration(1) * time.Hour,
+		Platform: map[string]Payload{"ios": {Title: "Strong quake", Badge: 1}},
+	}
+
+	for _, r := range a.Push(n, []Subscription{{Platform: "ios", Token: "i1"}, {Platform: "ios", Token: "i2"}}) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 notifications got %d", len(got))
+	}
+
+	b := got["i1"]
+
+	if b["publicid"] != "2015p278423" {
+		t.Errorf("expected publicid in payload got %v", b["publicid"])
+	}
+
+	aps, ok := b["aps"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected aps in payload")
+	}
+
+	if aps["sound"] != "default" {
+		t.Errorf("expected sound default got %v", aps["sound"])
+	}
+
+	if aps["badge"] != 1.0 {
+		t.Errorf("expected badge 1 got %v", aps["badge"])
+	}
+
+	alert, ok := aps["alert"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected alert in aps")
+	}
+
+	if alert["title"] != "Strong quake" || alert["body"] != "Quake near Gisborne" {
+		t.Errorf("unexpected alert %v", alert)
+	}
+}
+
+func TestAPNsUnregistered(t *testing.T) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := x509.MarshalPKCS8PrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/3/device/") {
+		case "gone":
+			http.Error(w, `{"reason":"Unregistered","timestamp":1458114061260}`, http.StatusGone)
+		case "bad":
+			http.Error(w, `{"reason":"BadDeviceToken"}`, http.StatusBadRequest)
+		default:
+			http.Error(w, `{"reason":"ServiceUnavailable"}`, http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+
+	a, err := NewAPNs(s.URL, "nz.org.geonet.quake", "KEY123", "TEAM123", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: d}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range a.Push(Notification{PublicID: "2015p278423"}, []Subscription{{Token: "gone"}, {Token: "bad"}, {Token: "busy"}}) {
+		if r.Err == nil || r.Unregistered() != (r.Token != "busy") {
+			t.Errorf("%s: unexpected result %v", r.Token, r.Err)
+		}
+	}
+}
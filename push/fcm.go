@@ -0,0 +1,245 @@
+package push
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCM delivers notifications with the Firebase Cloud Messaging HTTP v1 API.
+// https://firebase.google.com/docs/reference/fcm/rest/v1/projects.messages
+type FCM struct {
+	// Endpoint is the FCM API server.  Defaults to https://fcm.googleapis.com
+	Endpoint  string
+	projectID string
+	email     string
+	tokenURI  string
+	key       crypto.Signer
+	h         *http.Client
+	mu        sync.Mutex
+	token     string
+	expires   time.Time
+}
+
+// serviceAccount is the subset of a Google service account JSON key file used by FCM.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewFCM returns an FCM provider for projectID using the service account JSON key credentials.
+func NewFCM(projectID string, credentials []byte) (*FCM, error) {
+	var s serviceAccount
+
+	if err := json.Unmarshal(credentials, &s); err != nil {
+		return nil, err
+	}
+
+	if s.ClientEmail == "" || s.TokenURI == "" {
+		return nil, fmt.Errorf("service account credentials missing client_email or token_uri")
+	}
+
+	k, err := parseKey([]byte(s.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return &FCM{
+		Endpoint:  "https://fcm.googleapis.com",
+		projectID: projectID,
+		email:     s.ClientEmail,
+		tokenURI:  s.TokenURI,
+		key:       k,
+		h:         &http.Client{Timeout: time.Duration(30) * time.Second},
+	}, nil
+}
+
+type fcmMessage struct {
+	Message fcmMsg `json:"message"`
+}
+
+type fcmMsg struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      *fcmAndroid       `json:"android,omitempty"`
+	APNS         *fcmAPNS          `json:"apns,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	CollapseKey  string                 `json:"collapse_key,omitempty"`
+	Priority     string                 `json:"priority,omitempty"`
+	TTL          string                 `json:"ttl,omitempty"`
+	Notification fcmAndroidNotification `json:"notification"`
+}
+
+type fcmAndroidNotification struct {
+	Sound     string `json:"sound,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"`
+	Tag       string `json:"tag,omitempty"` // replaces an existing notification with the same tag.
+}
+
+type fcmAPNS struct {
+	Headers map[string]string      `json:"headers,omitempty"`
+	Payload map[string]interface{} `json:"payload"`
+}
+
+func (f *FCM) Push(n Notification, subs []Subscription) []Result {
+	return each(subs, func(s Subscription) error {
+		return f.send(f.message(n, s))
+	})
+}
+
+func (f *FCM) message(n Notification, s Subscription) fcmMessage {
+	p := n.For(s.Platform)
+
+	m := fcmMsg{
+		Token: s.Token,
+		Notification: fcmNotification{
+			Title: p.Title,
+			Body:  p.Message,
+		},
+		Data: p.Data,
+	}
+
+	switch s.Platform {
+	case "ios":
+		aps := map[string]interface{}{"sound": p.Sound}
+		if p.Badge > 0 {
+			aps["badge"] = p.Badge
+		}
+
+		m.APNS = &fcmAPNS{
+			Headers: map[string]string{"apns-collapse-id": n.CollapseKey()},
+			Payload: map[string]interface{}{"aps": aps},
+		}
+
+		if n.TTL > 0 {
+			m.APNS.Headers["apns-expiration"] = strconv.FormatInt(time.Now().Add(n.TTL).Unix(), 10)
+		}
+	default:
+		m.Android = &fcmAndroid{
+			CollapseKey: n.CollapseKey(),
+			Priority:    "high",
+			Notification: fcmAndroidNotification{
+				Sound:     p.Sound,
+				ChannelID: p.ChannelID,
+				Tag:       n.CollapseKey(),
+			},
+		}
+
+		if n.TTL > 0 {
+			m.Android.TTL = fmt.Sprintf("%ds", int64(n.TTL.Seconds()))
+		}
+	}
+
+	return fcmMessage{Message: m}
+}
+
+func (f *FCM) send(m fcmMessage) error {
+	t, err := f.accessToken()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimRight(f.Endpoint, "/")+"/v1/projects/"+f.projectID+"/messages:send", bytes.NewBuffer(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+t)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := f.h.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		r, _ := ioutil.ReadAll(res.Body)
+
+		// FCM returns 404 with the error code UNREGISTERED for tokens that are no longer valid.
+		if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone || bytes.Contains(r, []byte("UNREGISTERED")) {
+			return fmt.Errorf("%w: FCM %d %s", ErrUnregistered, res.StatusCode, string(r))
+		}
+
+		return fmt.Errorf("Response error from FCM: %d %s", res.StatusCode, string(r))
+	}
+
+	return nil
+}
+
+// accessToken returns an OAuth2 access token for the service account, exchanging
+// a signed JWT for a new token if the cached one is about to expire.
+func (f *FCM) accessToken() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.token != "" && time.Now().Before(f.expires) {
+		return f.token, nil
+	}
+
+	now := time.Now().Unix()
+
+	a, err := jwt("RS256", "", map[string]interface{}{
+		"iss":   f.email,
+		"scope": fcmScope,
+		"aud":   f.tokenURI,
+		"iat":   now,
+		"exp":   now + 3600,
+	}, f.key)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := f.h.PostForm(f.tokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {a},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		r, _ := ioutil.ReadAll(res.Body)
+		return "", fmt.Errorf("Response error getting FCM access token: %d %s", res.StatusCode, string(r))
+	}
+
+	var t struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+
+	if err = json.NewDecoder(res.Body).Decode(&t); err != nil {
+		return "", err
+	}
+
+	f.token = t.AccessToken
+	// refresh a minute early.
+	f.expires = time.Now().Add(time.Duration(t.ExpiresIn-60) * time.Second)
+
+	return f.token, nil
+}
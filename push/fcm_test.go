@@ -0,0 +1,208 @@
+package push
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFCM(t *testing.T) {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := x509.MarshalPKCS8PrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var tokens int
+	var got []fcmMessage
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("unexpected grant_type %s", r.FormValue("grant_type"))
+		}
+
+		p := strings.Split(r.FormValue("assertion"), ".")
+		if len(p) != 3 {
+			t.Errorf("expected 3 part JWT got %d", len(p))
+			http.Error(w, "bad assertion", http.StatusBadRequest)
+			return
+		}
+
+		sig, _ := base64.RawURLEncoding.DecodeString(p[2])
+		h := sha256.Sum256([]byte(p[0] + "." + p[1]))
+		if err := rsa.VerifyPKCS1v15(&k.PublicKey, crypto.SHA256, h[:], sig); err != nil {
+			t.Errorf("JWT signature: %s", err)
+		}
+
+		mu.Lock()
+		tokens++
+		mu.Unlock()
+
+		w.Write([]byte(`{"access_token":"test-token","expires_in":3600,"token_type":"Bearer"}`))
+	})
+
+	mux.HandleFunc("/v1/projects/test-project/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("unexpected Authorization header %s", r.Header.Get("Authorization"))
+		}
+
+		var m fcmMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Error(err)
+		}
+
+		mu.Lock()
+		got = append(got, m)
+		mu.Unlock()
+
+		w.Write([]byte(`{"name":"projects/test-project/messages/1"}`))
+	})
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	c, err := json.Marshal(serviceAccount{
+		ClientEmail: "push@test-project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: d})),
+		TokenURI:    s.URL + "/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewFCM("test-project", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Endpoint = s.URL
+
+	n := Notification{
+		PublicID: "2015p278423",
+		Message:  "Quake near Gisborne",
+		Title:    "Strong quake",
+		TTL:      time.Duration(1) * time.Hour,
+		Platform: map[string]Payload{"android": {ChannelID: "quakes"}},
+	}
+
+	for _, r := range f.Push(n, []Subscription{
+		{Provider: "fcm", Platform: "android", Token: "a1"},
+		{Provider: "fcm", Platform: "android", Token: "a2"},
+		{Provider: "fcm", Platform: "ios", Token: "i1"},
+	}) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+	}
+
+	if tokens != 1 {
+		t.Errorf("expected 1 access token request got %d", tokens)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 messages got %d", len(got))
+	}
+
+	for _, v := range got {
+		m := v.Message
+
+		if m.Notification.Body != "Quake near Gisborne" || m.Notification.Title != "Strong quake" {
+			t.Errorf("%s: unexpected notification %+v", m.Token, m.Notification)
+		}
+
+		if m.Data["publicid"] != "2015p278423" {
+			t.Errorf("%s: expected publicid in data got %v", m.Token, m.Data)
+		}
+
+		switch m.Token {
+		case "i1":
+			if m.APNS == nil || m.Android != nil {
+				t.Fatalf("%s: expected apns config only", m.Token)
+			}
+			if m.APNS.Headers["apns-collapse-id"] != "2015p278423" {
+				t.Errorf("%s: unexpected apns-collapse-id %s", m.Token, m.APNS.Headers["apns-collapse-id"])
+			}
+			if m.APNS.Headers["apns-expiration"] == "" {
+				t.Errorf("%s: expected apns-expiration", m.Token)
+			}
+		default:
+			if m.Android == nil || m.APNS != nil {
+				t.Fatalf("%s: expected android config only", m.Token)
+			}
+			if m.Android.CollapseKey != "2015p278423" {
+				t.Errorf("%s: unexpected collapse_key %s", m.Token, m.Android.CollapseKey)
+			}
+			if m.Android.TTL != "3600s" {
+				t.Errorf("%s: expected ttl 3600s got %s", m.Token, m.Android.TTL)
+			}
+			if m.Android.Notification.ChannelID != "quakes" {
+				t.Errorf("%s: expected channel_id quakes got %s", m.Token, m.Android.Notification.ChannelID)
+			}
+		}
+	}
+}
+
+func TestFCMError(t *testing.T) {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"test-token","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v1/projects/test-project/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		var m fcmMessage
+		json.NewDecoder(r.Body).Decode(&m)
+
+		switch m.Message.Token {
+		case "gone":
+			http.Error(w, `{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`, http.StatusNotFound)
+		default:
+			http.Error(w, `{"error":{"status":"UNAVAILABLE"}}`, http.StatusServiceUnavailable)
+		}
+	})
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	c, _ := json.Marshal(serviceAccount{
+		ClientEmail: "push@test-project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)})),
+		TokenURI:    s.URL + "/token",
+	})
+
+	f, err := NewFCM("test-project", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Endpoint = s.URL
+
+	r := f.Push(Notification{PublicID: "2015p278423", Message: "test"}, []Subscription{{Platform: "android", Token: "gone"}, {Platform: "android", Token: "busy"}})
+
+	if len(r) != 2 || r[0].Token != "gone" || !r[0].Unregistered() {
+		t.Errorf("expected an unregistered token got %+v", r)
+	}
+
+	if len(r) != 2 || r[1].Err == nil || r[1].Unregistered() {
+		t.Errorf("expected a transient error got %+v", r)
+	}
+}
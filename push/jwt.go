@@ -0,0 +1,89 @@
+package push
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// jwt returns a signed JSON Web Token for the claims.  alg is RS256 or ES256.
+func jwt(alg, kid string, claims map[string]interface{}, key crypto.Signer) (string, error) {
+	h := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		h["kid"] = kid
+	}
+
+	hb, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+
+	cb, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	s := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(cb)
+
+	d := sha256.Sum256([]byte(s))
+
+	var sig []byte
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, d[:]); err != nil {
+			return "", err
+		}
+	case *ecdsa.PrivateKey:
+		// JWS ES256 signatures are the fixed length r|s not ASN.1.
+		r, ss, err := ecdsa.Sign(rand.Reader, k, d[:])
+		if err != nil {
+			return "", err
+		}
+		sig = append(pad(r, 32), pad(ss, 32)...)
+	default:
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+
+	return s + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func pad(i *big.Int, n int) []byte {
+	b := i.Bytes()
+	if len(b) >= n {
+		return b
+	}
+
+	return append(make([]byte, n-len(b)), b...)
+}
+
+// parseKey parses a PEM encoded PKCS8 (or PKCS1 RSA) private key.
+func parseKey(b []byte) (crypto.Signer, error) {
+	p, _ := pem.Decode(b)
+	if p == nil {
+		return nil, fmt.Errorf("no PEM data found for private key")
+	}
+
+	k, err := x509.ParsePKCS8PrivateKey(p.Bytes)
+	if err != nil {
+		if r, e := x509.ParsePKCS1PrivateKey(p.Bytes); e == nil {
+			return r, nil
+		}
+		return nil, err
+	}
+
+	s, ok := k.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", k)
+	}
+
+	return s, nil
+}
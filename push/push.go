@@ -0,0 +1,273 @@
+// Package push targets quake push notifications at individual subscribers and
+// delivers them through pluggable push providers.
+package push
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Subscription is a device subscribed to quake notifications near a location.
+type Subscription struct {
+	Provider string // the delivery backend for the device e.g., ua
+	Platform string // ios or android
+	Token    string // the device token or channel for the provider.
+	// the subscriber location.
+	Latitude  float64
+	Longitude float64
+	// RadiusKm - notify for quakes within this distance of the location regardless of MMI.
+	// 0 to only use MinMMI.
+	RadiusKm float64
+	// MinMMI - notify when the calculated MMI at the location is >= MinMMI.
+	MinMMI float64
+	// MinMagnitude - never notify for quakes smaller than this.
+	MinMagnitude float64
+	Modified     time.Time
+}
+
+// Notification is a push notification for a quake.
+type Notification struct {
+	PublicID string
+	Message  string
+	Title    string
+	// TTL is how long providers should keep trying to deliver the notification.
+	// 0 for the provider default.
+	TTL time.Duration
+	// Platform customises the payload for a platform (ios or android).
+	Platform map[string]Payload
+}
+
+// Payload is the notification content for a platform.
+type Payload struct {
+	Message   string
+	Title     string
+	Sound     string
+	ChannelID string // Android notification channel.
+	Badge     int    // iOS badge count.  0 to leave the badge unchanged.
+	Data      map[string]string
+}
+
+// CollapseKey is the same for every revision of a quake so that providers
+// replace earlier notifications for the quake.
+func (n Notification) CollapseKey() string {
+	return n.PublicID
+}
+
+// For returns the payload for platform, customised with n.Platform.
+// Data always includes the publicid.
+func (n Notification) For(platform string) Payload {
+	p := Payload{
+		Message: n.Message,
+		Title:   n.Title,
+		Sound:   "default",
+	}
+
+	c, ok := n.Platform[platform]
+	if ok {
+		if c.Message != "" {
+			p.Message = c.Message
+		}
+		if c.Title != "" {
+			p.Title = c.Title
+		}
+		if c.Sound != "" {
+			p.Sound = c.Sound
+		}
+		p.ChannelID = c.ChannelID
+		p.Badge = c.Badge
+	}
+
+	p.Data = map[string]string{"publicid": n.PublicID}
+	for k, v := range c.Data {
+		p.Data[k] = v
+	}
+
+	return p
+}
+
+// ErrUnregistered is the error for a device token that the provider says is no longer valid.
+// Pushing to the token again will never succeed and the subscription should be removed.
+var ErrUnregistered = errors.New("device token is not registered")
+
+// Provider delivers notifications to devices.
+type Provider interface {
+	// Push sends n to the devices for subs and returns a Result for each of subs.
+	// All subs have the same Provider.
+	Push(n Notification, subs []Subscription) []Result
+}
+
+// Result is the outcome of pushing a notification to a subscription.
+type Result struct {
+	Subscription
+	Err error // nil if the notification was sent.
+}
+
+// Unregistered returns true if the device token for r has been permanently rejected by the provider.
+func (r Result) Unregistered() bool {
+	return errors.Is(r.Err, ErrUnregistered)
+}
+
+// results returns a Result with err for each of subs.
+func results(subs []Subscription, err error) []Result {
+	r := make([]Result, len(subs))
+
+	for i, s := range subs {
+		r[i] = Result{Subscription: s, Err: err}
+	}
+
+	return r
+}
+
+// Recipient is a subscription targeted by a quake.
+type Recipient struct {
+	Subscription
+	Distance float64 // km from the quake.
+	MMI      float64 // calculated MMI at the subscriber location.
+}
+
+// Valid returns an error if s is not valid.
+func (s Subscription) Valid() error {
+	switch {
+	case s.Provider == "":
+		return fmt.Errorf("empty provider")
+	case s.Token == "":
+		return fmt.Errorf("empty token")
+	case s.Latitude < -90 || s.Latitude > 90:
+		return fmt.Errorf("invalid latitude %f", s.Latitude)
+	case s.Longitude < -180 || s.Longitude > 180:
+		return fmt.Errorf("invalid longitude %f", s.Longitude)
+	case s.RadiusKm < 0:
+		return fmt.Errorf("invalid radius %f", s.RadiusKm)
+	case s.MinMMI < 1 || s.MinMMI > 12:
+		return fmt.Errorf("invalid MMI %f", s.MinMMI)
+	}
+
+	return nil
+}
+
+/*
+Target returns the subscriptions in subs that should be notified about q.  A subscriber is notified if
+the quake magnitude is >= MinMagnitude and either the MMI calculated at the subscriber location is >= MinMMI
+or the subscriber is within RadiusKm of the quake.
+*/
+func Target(q *msg.Quake, subs []Subscription) (r []Recipient) {
+	if q.Err() != nil {
+		return
+	}
+
+	for _, s := range subs {
+		if q.Magnitude < s.MinMagnitude {
+			continue
+		}
+
+		d, mmi := q.MMIAt(s.Latitude, s.Longitude)
+
+		if mmi >= s.MinMMI || (s.RadiusKm > 0 && d <= s.RadiusKm) {
+			r = append(r, Recipient{Subscription: s, Distance: d, MMI: mmi})
+		}
+	}
+
+	return
+}
+
+// ByProvider groups r by subscription provider.
+func ByProvider(r []Recipient) map[string][]Subscription {
+	p := make(map[string][]Subscription)
+
+	for _, v := range r {
+		p[v.Provider] = append(p[v.Provider], v.Subscription)
+	}
+
+	return p
+}
+
+/*
+Send targets q at subs and pushes n through the matching provider in p.  Returns a Result
+for each subscriber targeted.  A failure for one subscriber or provider does not stop delivery
+to the others.
+*/
+func Send(q *msg.Quake, n Notification, subs []Subscription, p map[string]Provider) []Result {
+	g := ByProvider(Target(q, subs))
+
+	// deterministic order for logging and tests.
+	var names []string
+	for k := range g {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var r []Result
+
+	for _, k := range names {
+		v, ok := p[k]
+		if !ok {
+			r = append(r, results(g[k], fmt.Errorf("no push provider %s", k))...)
+			continue
+		}
+
+		r = append(r, v.Push(n, g[k])...)
+	}
+
+	return r
+}
+
+/*
+Partition splits r into the number sent, the subscriptions that failed and can be tried again,
+and the subscriptions with unregistered device tokens.  err is the first failure that can be tried again.
+*/
+func Partition(r []Result) (sent int, retry, unregistered []Subscription, err error) {
+	for _, v := range r {
+		switch {
+		case v.Err == nil:
+			sent++
+		case v.Unregistered():
+			unregistered = append(unregistered, v.Subscription)
+		default:
+			if err == nil {
+				err = fmt.Errorf("%s %s: %s", v.Provider, v.Token, v.Err)
+			}
+			retry = append(retry, v.Subscription)
+		}
+	}
+
+	if len(retry) > 1 {
+		err = fmt.Errorf("%d of %d notifications failed, first error: %s", len(retry), len(r), err)
+	}
+
+	return
+}
+
+// workers is the number of concurrent requests for providers that send one request per device.
+const workers = 8
+
+// each calls f for every subscription in subs using a small pool of workers.
+// All subscriptions are tried and a Result is returned for each.
+func each(subs []Subscription, f func(Subscription) error) []Result {
+	r := make([]Result, len(subs))
+	c := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers && i < len(subs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range c {
+				r[i] = Result{Subscription: subs[i], Err: f(subs[i])}
+			}
+		}()
+	}
+
+	for i := range subs {
+		c <- i
+	}
+	close(c)
+
+	wg.Wait()
+
+	return r
+}
@@ -0,0 +1,136 @@
+package push
+
+import (
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"testing"
+	"time"
+)
+
+var subs = []Subscription{
+	// Gisborne, close to the quake.
+	{Provider: "ua", Platform: "ios", Token: "gisborne", Latitude: -38.67, Longitude: 178.02, MinMMI: 3},
+	// Gisborne, only wants big quakes.
+	{Provider: "ua", Platform: "ios", Token: "gisborne-big", Latitude: -38.67, Longitude: 178.02, MinMMI: 3, MinMagnitude: 7},
+	// Gisborne, only wants strong shaking.
+	{Provider: "fcm", Platform: "android", Token: "gisborne-strong", Latitude: -38.67, Longitude: 178.02, MinMMI: 7},
+	// Dunedin, a long way away.
+	{Provider: "ua", Platform: "android", Token: "dunedin", Latitude: -45.88, Longitude: 170.5, MinMMI: 3},
+	// Dunedin, wants everything within 1200 km.
+	{Provider: "fcm", Platform: "android", Token: "dunedin-radius", Latitude: -45.88, Longitude: 170.5, MinMMI: 3, RadiusKm: 1200},
+}
+
+func testQuake() *msg.Quake {
+	return &msg.Quake{
+		PublicID:              "2015p278423",
+		Time:                  time.Now().UTC(),
+		Latitude:              -37.92257397,
+		Longitude:             178.3544071,
+		Depth:                 9.62890625,
+		EvaluationStatus:      "automatic",
+		UsedPhaseCount:        25,
+		AzimuthalGap:          180,
+		MinimumDistance:       2.4,
+		Magnitude:             6.0,
+		MagnitudeStationCount: 12,
+	}
+}
+
+func TestTarget(t *testing.T) {
+	r := Target(testQuake(), subs)
+
+	var tokens []string
+	for _, v := range r {
+		tokens = append(tokens, v.Token)
+	}
+
+	if fmt.Sprintf("%v", tokens) != "[gisborne dunedin-radius]" {
+		t.Errorf("unexpected recipients %v", tokens)
+	}
+
+	if r[0].MMI < 5 || r[0].MMI >= 7 {
+		t.Errorf("expected Gisborne MMI 5-7 got %f", r[0].MMI)
+	}
+
+	q := testQuake()
+	q.SetErr(fmt.Errorf("errored quake"))
+
+	if len(Target(q, subs)) != 0 {
+		t.Error("expected no recipients for errored quake")
+	}
+}
+
+type testProvider struct {
+	pushed []Subscription
+	err    error
+	gone   map[string]bool // unregistered tokens.
+}
+
+func (p *testProvider) Push(n Notification, subs []Subscription) []Result {
+	var r []Result
+
+	for _, s := range subs {
+		switch {
+		case p.gone[s.Token]:
+			r = append(r, Result{Subscription: s, Err: fmt.Errorf("%w: gone", ErrUnregistered)})
+		case p.err != nil:
+			r = append(r, Result{Subscription: s, Err: p.err})
+		default:
+			p.pushed = append(p.pushed, s)
+			r = append(r, Result{Subscription: s})
+		}
+	}
+
+	return r
+}
+
+func TestSend(t *testing.T) {
+	u := &testProvider{}
+	f := &testProvider{err: fmt.Errorf("fcm down")}
+
+	r := Send(testQuake(), Notification{PublicID: "2015p278423", Message: "test"}, subs, map[string]Provider{"ua": u, "fcm": f})
+
+	sent, retry, gone, err := Partition(r)
+	if err == nil {
+		t.Error("expected error for fcm provider")
+	}
+
+	if sent != 1 || len(u.pushed) != 1 || u.pushed[0].Token != "gisborne" {
+		t.Errorf("expected 1 ua push got %d %v", sent, u.pushed)
+	}
+
+	if len(retry) != 1 || retry[0].Token != "dunedin-radius" || len(gone) != 0 {
+		t.Errorf("expected to retry dunedin-radius only got %v %v", retry, gone)
+	}
+
+	if _, retry, _, err = Partition(Send(testQuake(), Notification{}, subs, map[string]Provider{"ua": u})); err == nil || len(retry) != 1 {
+		t.Errorf("expected error for missing provider got %v %v", err, retry)
+	}
+
+	// unregistered tokens are not retried.
+	f = &testProvider{gone: map[string]bool{"dunedin-radius": true}}
+
+	sent, retry, gone, err = Partition(Send(testQuake(), Notification{}, subs, map[string]Provider{"ua": u, "fcm": f}))
+	if err != nil || sent != 1 || len(retry) != 0 || len(gone) != 1 || gone[0].Token != "dunedin-radius" {
+		t.Errorf("expected dunedin-radius to be unregistered got %d %v %v %v", sent, retry, gone, err)
+	}
+}
+
+func TestValid(t *testing.T) {
+	for i, s := range []Subscription{
+		{Token: "t", MinMMI: 3},
+		{Provider: "ua", MinMMI: 3},
+		{Provider: "ua", Token: "t", Latitude: -91, MinMMI: 3},
+		{Provider: "ua", Token: "t", Longitude: 181, MinMMI: 3},
+		{Provider: "ua", Token: "t", RadiusKm: -1, MinMMI: 3},
+		{Provider: "ua", Token: "t", MinMMI: 0},
+	} {
+		if s.Valid() == nil {
+			t.Errorf("%d expected error for invalid subscription", i)
+		}
+	}
+
+	if err := subs[0].Valid(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,81 @@
+package push
+
+import (
+	"github.com/GeoNet/haz/ua"
+)
+
+// uaBatch is the maximum number of devices in one UA push.
+const uaBatch = 1000
+
+// UA delivers notifications to devices through Urban Airship.
+type UA struct {
+	Client *ua.Client
+}
+
+// Push sends n in batches.  A failed batch fails all the subscriptions in it.
+func (u UA) Push(n Notification, subs []Subscription) []Result {
+	var ios, android []Subscription
+
+	for _, s := range subs {
+		switch s.Platform {
+		case "ios":
+			ios = append(ios, s)
+		default:
+			android = append(android, s)
+		}
+	}
+
+	var r []Result
+
+	for len(ios) > 0 || len(android) > 0 {
+		var i, a []Subscription
+		i, ios = split(ios, uaBatch)
+		a, android = split(android, uaBatch-len(i))
+
+		err := u.Client.PushDevices(uaDevices(n, tokens(i), tokens(a)))
+		r = append(r, results(i, err)...)
+		r = append(r, results(a, err)...)
+	}
+
+	return r
+}
+
+// tokens returns the device tokens for subs.
+func tokens(subs []Subscription) []string {
+	var t []string
+
+	for _, s := range subs {
+		t = append(t, s.Token)
+	}
+
+	return t
+}
+
+func uaDevices(n Notification, ios, android []string) ua.Devices {
+	i := n.For("ios")
+	a := n.For("android")
+
+	return ua.Devices{
+		IOS:          ios,
+		Android:      android,
+		PublicID:     n.PublicID,
+		IOSAlert:     i.Message,
+		AndroidAlert: a.Message,
+		IOSTitle:     i.Title,
+		AndroidTitle: a.Title,
+		Sound:        i.Sound,
+		Badge:        i.Badge,
+		CollapseKey:  n.CollapseKey(),
+		TTL:          n.TTL,
+		Extra:        i.Data,
+	}
+}
+
+// split returns the first n elements of s and the remainder.
+func split(s []Subscription, n int) ([]Subscription, []Subscription) {
+	if len(s) <= n {
+		return s, nil
+	}
+
+	return s[:n], s[n:]
+}
@@ -0,0 +1,104 @@
+/*
+quakecsv encodes quakes as CSV with a header row.  The columns are the quakesearch CSV columns.
+Times are UTC in ISO8601 with milliseconds and depth is in km.
+*/
+package quakecsv
+
+import (
+	"encoding/csv"
+	"github.com/GeoNet/haz/msg"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ContentType is the media type for CSV.
+const ContentType = "text/csv"
+
+// Header is the CSV header row.
+var Header = []string{
+	"publicid",
+	"eventtype",
+	"origintime",
+	"modificationtime",
+	"longitude",
+	"latitude",
+	"magnitude",
+	"depth",
+	"magnitudetype",
+	"depthtype",
+	"evaluationmethod",
+	"evaluationstatus",
+	"evaluationmode",
+	"earthmodel",
+	"usedphasecount",
+	"usedstationcount",
+	"magnitudestationcount",
+	"minimumdistance",
+	"azimuthalgap",
+	"originerror",
+	"magnitudeuncertainty",
+}
+
+// Write writes the quakes q to w as CSV.
+func Write(w io.Writer, q []msg.Quake) error {
+	c := csv.NewWriter(w)
+
+	if err := c.Write(Header); err != nil {
+		return err
+	}
+
+	return write(c, q)
+}
+
+// WriteRecords writes the quakes q to w as CSV without the header row.
+func WriteRecords(w io.Writer, q []msg.Quake) error {
+	return write(csv.NewWriter(w), q)
+}
+
+func write(c *csv.Writer, q []msg.Quake) error {
+	for _, v := range q {
+		if err := c.Write(Record(v)); err != nil {
+			return err
+		}
+	}
+
+	c.Flush()
+	return c.Error()
+}
+
+// Record returns the CSV record for q in the order of Header.
+func Record(q msg.Quake) []string {
+	return []string{
+		q.PublicID,
+		q.Type,
+		Time(q.Time),
+		Time(q.ModificationTime),
+		float(q.Longitude),
+		float(q.Latitude),
+		float(q.Magnitude),
+		float(q.Depth),
+		q.MagnitudeType,
+		q.DepthType,
+		q.MethodID,
+		q.EvaluationStatus,
+		q.EvaluationMode,
+		q.EarthModelID,
+		strconv.Itoa(q.UsedPhaseCount),
+		strconv.Itoa(q.UsedStationCount),
+		strconv.Itoa(q.MagnitudeStationCount),
+		float(q.MinimumDistance),
+		float(q.AzimuthalGap),
+		float(q.StandardError),
+		float(q.MagnitudeUncertainty),
+	}
+}
+
+// Time formats t for CSV.
+func Time(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func float(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
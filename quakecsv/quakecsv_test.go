@@ -0,0 +1,69 @@
+package quakecsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/GeoNet/haz/msg"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	q := msg.Quake{
+		PublicID:              "2016p408314",
+		Type:                  "earthquake",
+		Time:                  time.Date(2016, 6, 1, 4, 31, 27, 608300000, time.UTC),
+		ModificationTime:      time.Date(2016, 6, 1, 4, 40, 0, 0, time.FixedZone("NZST", 12*3600)),
+		Latitude:              -41.5,
+		Longitude:             174.0,
+		Depth:                 12.5,
+		DepthType:             "from location",
+		MethodID:              "LOCSAT",
+		EarthModelID:          "iasp91, nz",
+		EvaluationMode:        "manual",
+		EvaluationStatus:      "confirmed",
+		UsedPhaseCount:        20,
+		UsedStationCount:      10,
+		StandardError:         0.35,
+		AzimuthalGap:          80.5,
+		MinimumDistance:       0.1,
+		Magnitude:             4.2,
+		MagnitudeUncertainty:  0.1,
+		MagnitudeType:         "M",
+		MagnitudeStationCount: 12,
+	}
+
+	var b bytes.Buffer
+
+	if err := Write(&b, []msg.Quake{q, q}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r) != 3 {
+		t.Fatalf("expected a header and 2 records got %d rows", len(r))
+	}
+
+	for i, v := range r[0] {
+		if v != Header[i] {
+			t.Errorf("header column %d expected %s got %s", i, Header[i], v)
+		}
+	}
+
+	e := []string{"2016p408314", "earthquake", "2016-06-01T04:31:27.608Z", "2016-05-31T16:40:00.000Z", "174", "-41.5", "4.2", "12.5",
+		"M", "from location", "LOCSAT", "confirmed", "manual", "iasp91, nz", "20", "10", "12", "0.1", "80.5", "0.35", "0.1"}
+
+	if len(r[1]) != len(e) {
+		t.Fatalf("expected %d columns got %d", len(e), len(r[1]))
+	}
+
+	for i := range e {
+		if r[1][i] != e[i] {
+			t.Errorf("%s expected %s got %s", Header[i], e[i], r[1][i])
+		}
+	}
+}
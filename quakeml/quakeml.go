@@ -0,0 +1,209 @@
+/*
+quakeml encodes quakes as QuakeML 1.2 BED (basic event description).  Each quake has one
+origin and one magnitude, the preferred solution.
+
+Resource identifiers use the prefix smi:nz.org.geonet/ e.g., smi:nz.org.geonet/2016p408314.
+*/
+package quakeml
+
+import (
+	"encoding/xml"
+	"github.com/GeoNet/haz/msg"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	Prefix    = "smi:nz.org.geonet/"
+	namespace = "http://quakeml.org/xmlns/bed/1.2"
+	qNS       = "http://quakeml.org/xmlns/quakeml/1.2"
+)
+
+type quakeML struct {
+	XMLName         xml.Name        `xml:"q:quakeml"`
+	Q               string          `xml:"xmlns:q,attr"`
+	NS              string          `xml:"xmlns,attr"`
+	EventParameters eventParameters `xml:"eventParameters"`
+}
+
+type eventParameters struct {
+	PublicID string  `xml:"publicID,attr"`
+	Events   []event `xml:"event"`
+}
+
+type event struct {
+	PublicID             string       `xml:"publicID,attr"`
+	PreferredOriginID    string       `xml:"preferredOriginID"`
+	PreferredMagnitudeID string       `xml:"preferredMagnitudeID"`
+	Type                 string       `xml:"type,omitempty"`
+	Description          *description `xml:"description,omitempty"`
+	CreationInfo         creationInfo `xml:"creationInfo"`
+	Origin               origin       `xml:"origin"`
+	Magnitude            magnitude    `xml:"magnitude"`
+}
+
+type description struct {
+	Text string `xml:"text"`
+	Type string `xml:"type"`
+}
+
+type creationInfo struct {
+	AgencyID     string `xml:"agencyID,omitempty"`
+	CreationTime string `xml:"creationTime"`
+}
+
+type realQuantity struct {
+	Value       float64  `xml:"value"`
+	Uncertainty *float64 `xml:"uncertainty,omitempty"`
+}
+
+type timeQuantity struct {
+	Value string `xml:"value"`
+}
+
+type originQuality struct {
+	UsedPhaseCount   int     `xml:"usedPhaseCount"`
+	UsedStationCount int     `xml:"usedStationCount"`
+	StandardError    float64 `xml:"standardError"`
+	AzimuthalGap     float64 `xml:"azimuthalGap"`
+	MinimumDistance  float64 `xml:"minimumDistance"`
+}
+
+type origin struct {
+	PublicID         string        `xml:"publicID,attr"`
+	Time             timeQuantity  `xml:"time"`
+	Latitude         realQuantity  `xml:"latitude"`
+	Longitude        realQuantity  `xml:"longitude"`
+	Depth            realQuantity  `xml:"depth"`
+	DepthType        string        `xml:"depthType,omitempty"`
+	MethodID         string        `xml:"methodID,omitempty"`
+	EarthModelID     string        `xml:"earthModelID,omitempty"`
+	Quality          originQuality `xml:"quality"`
+	EvaluationMode   string        `xml:"evaluationMode,omitempty"`
+	EvaluationStatus string        `xml:"evaluationStatus,omitempty"`
+	CreationInfo     creationInfo  `xml:"creationInfo"`
+}
+
+type magnitude struct {
+	PublicID     string       `xml:"publicID,attr"`
+	Mag          realQuantity `xml:"mag"`
+	Type         string       `xml:"type,omitempty"`
+	OriginID     string       `xml:"originID"`
+	StationCount int          `xml:"stationCount"`
+	CreationInfo creationInfo `xml:"creationInfo"`
+}
+
+// eventTypes maps SeisComP event types to QuakeML.  duplicate is not a QuakeML type.
+var eventTypes = map[string]string{
+	"duplicate": "",
+}
+
+// Write writes the quakes q to w as a QuakeML document.
+func Write(w io.Writer, q []msg.Quake) error {
+	d := quakeML{
+		Q:  qNS,
+		NS: namespace,
+		EventParameters: eventParameters{
+			PublicID: Prefix + "eventParameters",
+			Events:   make([]event, len(q)),
+		},
+	}
+
+	for i := range q {
+		d.EventParameters.Events[i] = newEvent(q[i])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+
+	if err := e.Encode(d); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func newEvent(q msg.Quake) event {
+	id := Prefix + q.PublicID
+	originID := id + "/origin"
+	magnitudeID := id + "/magnitude"
+
+	c := creationInfo{AgencyID: Agency(q.AgencyID), CreationTime: Time(q.ModificationTime)}
+
+	e := event{
+		PublicID:             id,
+		PreferredOriginID:    originID,
+		PreferredMagnitudeID: magnitudeID,
+		Type:                 q.Type,
+		CreationInfo:         c,
+		Origin: origin{
+			PublicID:         originID,
+			Time:             timeQuantity{Value: Time(q.Time)},
+			Latitude:         realQuantity{Value: q.Latitude},
+			Longitude:        realQuantity{Value: q.Longitude},
+			Depth:            realQuantity{Value: q.Depth * 1000.0},
+			DepthType:        q.DepthType,
+			MethodID:         resource(q.MethodID),
+			EarthModelID:     resource(q.EarthModelID),
+			EvaluationMode:   q.EvaluationMode,
+			EvaluationStatus: q.EvaluationStatus,
+			CreationInfo:     c,
+			Quality: originQuality{
+				UsedPhaseCount:   q.UsedPhaseCount,
+				UsedStationCount: q.UsedStationCount,
+				StandardError:    q.StandardError,
+				AzimuthalGap:     q.AzimuthalGap,
+				MinimumDistance:  q.MinimumDistance,
+			},
+		},
+		Magnitude: magnitude{
+			PublicID:     magnitudeID,
+			Mag:          realQuantity{Value: q.Magnitude},
+			Type:         q.MagnitudeType,
+			OriginID:     originID,
+			StationCount: q.MagnitudeStationCount,
+			CreationInfo: c,
+		},
+	}
+
+	if t, ok := eventTypes[q.Type]; ok {
+		e.Type = t
+	}
+
+	if q.MagnitudeUncertainty > 0 {
+		u := q.MagnitudeUncertainty
+		e.Magnitude.Mag.Uncertainty = &u
+	}
+
+	if l, err := q.Closest(); err == nil {
+		e.Description = &description{Text: l.Location(), Type: "nearest cities"}
+	}
+
+	return e
+}
+
+// Time formats t for QuakeML and FDSN text.
+func Time(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000Z")
+}
+
+// Agency returns the agency from a SeisComP agency e.g., WEL from WEL(GNS_Primary).
+func Agency(a string) string {
+	if i := strings.Index(a, "("); i > 0 {
+		return a[:i]
+	}
+	return a
+}
+
+func resource(id string) string {
+	if id == "" {
+		return ""
+	}
+	return Prefix + strings.Replace(id, " ", "_", -1)
+}
@@ -0,0 +1,79 @@
+package quakeml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/GeoNet/haz/msg"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	q := msg.Quake{
+		PublicID:              "2016p408314",
+		Type:                  "earthquake",
+		AgencyID:              "WEL(GNS_Primary)",
+		Time:                  time.Date(2016, 6, 1, 4, 31, 27, 608300000, time.UTC),
+		ModificationTime:      time.Date(2016, 6, 1, 4, 40, 0, 0, time.UTC),
+		Latitude:              -41.5,
+		Longitude:             174.0,
+		Depth:                 12.5,
+		MethodID:              "LOCSAT",
+		EvaluationMode:        "manual",
+		Magnitude:             4.2,
+		MagnitudeUncertainty:  0.1,
+		MagnitudeType:         "M",
+		MagnitudeStationCount: 12,
+	}
+
+	d := q
+	d.PublicID = "2016p408315"
+	d.Type = "duplicate"
+
+	var b bytes.Buffer
+
+	if err := Write(&b, []msg.Quake{q, d}); err != nil {
+		t.Fatal(err)
+	}
+
+	var r struct {
+		EventParameters eventParameters `xml:"eventParameters"`
+	}
+	if err := xml.Unmarshal(b.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.EventParameters.Events) != 2 {
+		t.Fatalf("expected 2 events got %d", len(r.EventParameters.Events))
+	}
+
+	e := r.EventParameters.Events[0]
+
+	switch {
+	case e.PublicID != "smi:nz.org.geonet/2016p408314":
+		t.Errorf("unexpected publicID %s", e.PublicID)
+	case e.PreferredOriginID != e.Origin.PublicID || e.Magnitude.OriginID != e.Origin.PublicID:
+		t.Error("origin IDs don't match")
+	case e.Origin.Time.Value != "2016-06-01T04:31:27.608300Z":
+		t.Errorf("unexpected time %s", e.Origin.Time.Value)
+	case e.Origin.Depth.Value != 12500:
+		t.Errorf("expected depth in m got %f", e.Origin.Depth.Value)
+	case e.CreationInfo.AgencyID != "WEL":
+		t.Errorf("unexpected agency %s", e.CreationInfo.AgencyID)
+	case e.Origin.MethodID != "smi:nz.org.geonet/LOCSAT":
+		t.Errorf("unexpected method %s", e.Origin.MethodID)
+	case e.Magnitude.Mag.Uncertainty == nil || *e.Magnitude.Mag.Uncertainty != 0.1:
+		t.Error("expected magnitude uncertainty")
+	case e.Description == nil || e.Description.Text == "":
+		t.Error("expected a description")
+	}
+
+	if r.EventParameters.Events[1].Type != "" {
+		t.Errorf("duplicate is not a QuakeML event type got %s", r.EventParameters.Events[1].Type)
+	}
+
+	if !strings.Contains(b.String(), `<q:quakeml xmlns:q="http://quakeml.org/xmlns/quakeml/1.2" xmlns="http://quakeml.org/xmlns/bed/1.2">`) {
+		t.Error("missing QuakeML namespaces")
+	}
+}
@@ -167,7 +167,7 @@ var qsInteractive = {
         "fiordland": [[-46.083,164.218 ], [-47.212, 163.787 ], [-47.827, 165.247 ], [-44.668, 169.168 ], [-43.711, 167.399 ], [-46.083, 164.218 ]],
         "otagosouthland": [[-47.827, 165.247 ], [-48.410, 169.148 ], [-45.412, 172.312 ], [-44.341, 169.564 ], [-44.668, 169.168 ], [-47.827, 165.247 ]]
     },
-    outputOptions : ["GeoJSON", "GML", "KML", "CSV"], //, "Image"
+    outputOptions : ["GeoJSON", "GML", "KML", "CSV", "QuakeML"], //, "Image"
     imageSizes : ["512", "1024", "2048"],
     maxNumQuakes : ["100", "500", "1000", "1500", "2000"],
     locationByMap:true, //if true, the map extent will be populated in the location field
@@ -6,6 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/quakecsv"
+	"github.com/GeoNet/haz/quakeml"
 	"github.com/GeoNet/weft"
 	"log"
 	"net/http"
@@ -37,7 +41,7 @@ const (
 
 	CONTENT_TYPE_GeoJSON = "application/vnd.geo+json"
 	CONTENT_TYPE_JSON    = "application/json"
-	CONTENT_TYPE_CSV     = "text/csv"
+	CONTENT_TYPE_CSV     = quakecsv.ContentType
 )
 
 var (
@@ -562,57 +566,92 @@ func getQuakesGml(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result
 	return &weft.StatusOK
 }
 
+/*
+csvHeader is the header row for the CSV.  It has the same columns as quakecsv.Header but
+keeps the spaces from the original quakesearch header so existing parsers don't break.
+*/
+const csvHeader = "publicid,eventtype,origintime,modificationtime,longitude, latitude, magnitude, depth,magnitudetype,depthtype," +
+	"evaluationmethod,evaluationstatus,evaluationmode,earthmodel,usedphasecount,usedstationcount,magnitudestationcount,minimumdistance," +
+	"azimuthalgap,originerror,magnitudeuncertainty"
+
 func getQuakesCsv(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
-	//1. check query parameters
-	if res := weft.CheckQuery(r, []string{}, optionalParams); !res.Ok {
+	q, res := getQuakes(r)
+	if !res.Ok {
 		return res
 	}
 
-	v := r.URL.Query()
-	//21  fields
-	sqlString := `select format('%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s',
-               publicid,eventtype,to_char(origintime, 'YYYY-MM-DD"T"HH24:MI:SS.MS"Z"'),
-               to_char(modificationtime, 'YYYY-MM-DD"T"HH24:MI:SS.MS"Z"'),longitude, latitude, magnitude,
-               depth,magnitudetype, depthtype, evaluationmethod, evaluationstatus, evaluationmode, earthmodel, usedphasecount,
-               usedstationcount,magnitudestationcount, minimumdistance,
-               azimuthalgap,originerror,magnitudeuncertainty) as csv from haz.quake_search_v1`
+	if err := writeCSV(b, q); err != nil {
+		return weft.InternalServerError(err)
+	}
 
-	params, err := getQueryParams(v)
+	// send result response
+	h.Set("Content-Disposition", `attachment; filename="earthquakes.csv"`)
+	h.Set("Content-Type", CONTENT_TYPE_CSV)
+	return &weft.StatusOK
+}
+
+// writeCSV writes csvHeader and the quakes q to b.
+func writeCSV(b *bytes.Buffer, q []msg.Quake) error {
+	b.WriteString(csvHeader)
+	b.WriteString("\n")
+
+	return quakecsv.WriteRecords(b, q)
+}
+
+func getQuakesQuakeML(r *http.Request, h http.Header, b *bytes.Buffer) *weft.Result {
+	q, res := getQuakes(r)
+	if !res.Ok {
+		return res
+	}
+
+	if err := quakeml.Write(b, q); err != nil {
+		return weft.InternalServerError(err)
+	}
+
+	h.Set("Content-Disposition", `attachment; filename="earthquakes.xml"`)
+	h.Set("Content-Type", CONTENT_TYPE_XML)
+	return &weft.StatusOK
+}
+
+/*
+getQuakes returns the quakes for the search in r, newest first.  The search is on haz.quake_search_v1
+and the quakes are read from haz.quake so the CSV and QuakeML are the same as from geonet-rest.
+*/
+func getQuakes(r *http.Request) ([]msg.Quake, *weft.Result) {
+	//1. check query parameters
+	if res := weft.CheckQuery(r, []string{}, optionalParams); !res.Ok {
+		return nil, res
+	}
+
+	params, err := getQueryParams(r.URL.Query())
 	if err != nil {
-		return weft.BadRequest(err.Error())
+		return nil, weft.BadRequest(err.Error())
 	}
 
-	sqlString, args := getSqlQuery(sqlString, params)
+	sqlString, args := getSqlQuery(`select publicid from haz.quake_search_v1`, params)
+	sqlString = `SELECT ` + database.QuakeColumns + ` FROM haz.quake WHERE publicid IN (` + sqlString + `) ORDER BY time DESC`
 
 	rows, err := db.Query(sqlString, args...)
-
 	if err != nil {
-		return weft.InternalServerError(err)
+		return nil, weft.InternalServerError(err)
 	}
 	defer rows.Close()
 
-	var (
-		// b bytes.Buffer
-		d string
-	)
+	var quakes []msg.Quake
 
-	b.WriteString("publicid,eventtype,origintime,modificationtime,longitude, latitude, magnitude, depth,magnitudetype,depthtype," +
-		"evaluationmethod,evaluationstatus,evaluationmode,earthmodel,usedphasecount,usedstationcount,magnitudestationcount,minimumdistance," +
-		"azimuthalgap,originerror,magnitudeuncertainty")
-	b.WriteString("\n")
 	for rows.Next() {
-		err := rows.Scan(&d)
+		q, err := database.ScanQuake(rows)
 		if err != nil {
-			return weft.InternalServerError(err)
+			return nil, weft.InternalServerError(err)
 		}
-		b.WriteString(d)
-		b.WriteString("\n")
+		quakes = append(quakes, q)
 	}
 
-	// send result response
-	h.Set("Content-Disposition", `attachment; filename="earthquakes.csv"`)
-	h.Set("Content-Type", CONTENT_TYPE_CSV)
-	return &weft.StatusOK
+	if err = rows.Err(); err != nil {
+		return nil, weft.InternalServerError(err)
+	}
+
+	return quakes, &weft.StatusOK
 }
 
 //http://hutl14681.gns.cri.nz:8081/geojson?limit=100&bbox=163.60840,-49.18170,182.98828,-32.28713&startdate=2015-6-27T22:00:00&enddate=2015-7-27T23:00:00
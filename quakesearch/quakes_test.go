@@ -2,11 +2,15 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/quakecsv"
 	wt "github.com/GeoNet/weft/wefttest"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 )
@@ -65,6 +69,10 @@ var routes = wt.Requests{
 	{ID: wt.L(), URL: "/gml?limit=100&bbox=163.60840,-49.18170,182.98828,-32.28713&mindepth=10&maxdepth=200", Content: CONTENT_TYPE_XML, Accept: CONTENT_TYPE_XML},
 	{ID: wt.L(), URL: "/gml?limit=100&region=canterbury&minmag=3&maxmag=7&mindepth=1&maxdepth=200", Content: CONTENT_TYPE_XML, Accept: CONTENT_TYPE_XML},
 
+	//QuakeML routes
+	{ID: wt.L(), URL: "/quakeml?limit=100&bbox=163.60840,-49.18170,182.98828,-32.28713", Content: CONTENT_TYPE_XML, Accept: CONTENT_TYPE_XML},
+	{ID: wt.L(), URL: "/quakeml?limit=100&region=canterbury&minmag=3&maxmag=7&mindepth=1&maxdepth=200", Content: CONTENT_TYPE_XML, Accept: CONTENT_TYPE_XML},
+
 	//kml routes
 	{ID: wt.L(), URL: "/kml?limit=100&bbox=163.60840,-49.18170,182.98828,-32.28713", Content: CONTENT_TYPE_KML, Accept: CONTENT_TYPE_KML},
 	{ID: wt.L(), URL: "/kml?limit=100&bbox=163.60840,-49.18170,182.98828,-32.28713&startdate=2010-1-1T00:00:00&enddate=2015-1-1T00:00:00", Content: CONTENT_TYPE_KML, Accept: CONTENT_TYPE_KML},
@@ -242,3 +250,35 @@ type QuakesCount struct {
 	Count int      `json:"count"`
 	Dates []string `json:"dates"`
 }
+
+// TestCSVHeader pins the CSV header so changes to it are deliberate.
+func TestCSVHeader(t *testing.T) {
+	var b bytes.Buffer
+
+	if err := writeCSV(&b, []msg.Quake{{PublicID: "2016p408314", EarthModelID: "iasp91, nz", Depth: 5}}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(b.String(), "\n")
+
+	expected := "publicid,eventtype,origintime,modificationtime,longitude, latitude, magnitude, depth,magnitudetype,depthtype," +
+		"evaluationmethod,evaluationstatus,evaluationmode,earthmodel,usedphasecount,usedstationcount,magnitudestationcount,minimumdistance," +
+		"azimuthalgap,originerror,magnitudeuncertainty"
+
+	if lines[0] != expected {
+		t.Errorf("expected header %s got %s", expected, lines[0])
+	}
+
+	r, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r) != 2 || len(r[0]) != len(quakecsv.Header) || len(r[1]) != len(quakecsv.Header) {
+		t.Fatalf("expected a header and 1 record with %d columns got %v", len(quakecsv.Header), r)
+	}
+
+	if r[1][0] != "2016p408314" || r[1][13] != "iasp91, nz" || r[1][7] != "5" {
+		t.Errorf("unexpected record %v", r[1])
+	}
+}
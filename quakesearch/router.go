@@ -24,6 +24,7 @@ func init() {
 	serveMux.HandleFunc("/count", weft.MakeHandlerAPI(getQuakesCount))
 	serveMux.HandleFunc("/csv", weft.MakeHandlerAPI(getQuakesCsv))
 	serveMux.HandleFunc("/gml", weft.MakeHandlerAPI(getQuakesGml))
+	serveMux.HandleFunc("/quakeml", weft.MakeHandlerAPI(getQuakesQuakeML))
 	serveMux.HandleFunc("/kml", weft.MakeHandlerAPI(getQuakesKml))
 	serveMux.HandleFunc("/", weft.MakeHandlerPage(indexPage))
 
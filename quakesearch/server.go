@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/GeoNet/haz/database"
+	"github.com/GeoNet/haz/msg"
 	"github.com/GeoNet/weft"
 	"log"
 	"net/http"
@@ -12,6 +13,10 @@ var db database.DB
 
 // main connects to the database, sets up request routing, and starts the http server.
 func main() {
+	if err := msg.InitIntensityRules(); err != nil {
+		log.Fatalf("ERROR: problem with INTENSITY_RULES: %s", err)
+	}
+
 	var err error
 	db, err = database.InitPG()
 	if err != nil {
@@ -20,6 +25,10 @@ func main() {
 	}
 	defer db.Close()
 
+	if err = db.InitLocalities(); err != nil {
+		log.Fatalf("ERROR: problem with localities: %s", err)
+	}
+
 	if err = db.Ping(); err != nil {
 		log.Println("ERROR: problem pinging DB - is it up and contactable? 500s will be served")
 	}
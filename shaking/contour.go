@@ -0,0 +1,279 @@
+package shaking
+
+import (
+	"encoding/json"
+	"github.com/GeoNet/haz/msg"
+	"math"
+)
+
+// FeatureCollection is GeoJSON contour polygons.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string       `json:"type"`
+	Geometry   MultiPolygon `json:"geometry"`
+	Properties Properties   `json:"properties"`
+}
+
+type MultiPolygon struct {
+	Type        string          `json:"type"`
+	Coordinates [][][][]float64 `json:"coordinates"`
+}
+
+type Properties struct {
+	MMI       int    `json:"mmi"`
+	Intensity string `json:"intensity"`
+}
+
+/*
+Contours returns polygons for the area of the grid with MMI >= each integer MMI from
+min to the maximum in the grid.  Polygons follow the cell edges.  Outer rings are
+counter clockwise and holes clockwise.
+*/
+func (g *Grid) Contours(min int) FeatureCollection {
+	f := FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+
+	for l := min; l <= int(math.Floor(g.Max())); l++ {
+		p := g.polygons(float64(l))
+		if len(p) == 0 {
+			continue
+		}
+
+		f.Features = append(f.Features, Feature{
+			Type:       "Feature",
+			Geometry:   MultiPolygon{Type: "MultiPolygon", Coordinates: p},
+			Properties: Properties{MMI: l, Intensity: msg.MMIIntensity(float64(l))},
+		})
+	}
+
+	return f
+}
+
+// GeoJSON returns the contours as GeoJSON.
+func (g *Grid) GeoJSON(min int) ([]byte, error) {
+	return json.Marshal(g.Contours(min))
+}
+
+// vertex is a cell corner.  Corner i, j is the south west corner of cell i, j.
+type vertex struct {
+	i, j int
+}
+
+type edge struct {
+	from, to vertex
+}
+
+func (e edge) dir() vertex {
+	return vertex{i: e.to.i - e.from.i, j: e.to.j - e.from.j}
+}
+
+func (g *Grid) in(i, j int, level float64) bool {
+	if i < 0 || i >= g.Cols || j < 0 || j >= g.Rows {
+		return false
+	}
+
+	return g.MMI[j*g.Cols+i] >= level
+}
+
+// polygons traces the outline of the cells >= level and returns them as polygons.
+func (g *Grid) polygons(level float64) [][][][]float64 {
+	// directed boundary edges with the cells >= level on the left.
+	edges := make(map[vertex][]edge)
+
+	add := func(from, to vertex) {
+		edges[from] = append(edges[from], edge{from: from, to: to})
+	}
+
+	for j := 0; j < g.Rows; j++ {
+		for i := 0; i < g.Cols; i++ {
+			if !g.in(i, j, level) {
+				continue
+			}
+			if !g.in(i, j-1, level) {
+				add(vertex{i, j}, vertex{i + 1, j})
+			}
+			if !g.in(i+1, j, level) {
+				add(vertex{i + 1, j}, vertex{i + 1, j + 1})
+			}
+			if !g.in(i, j+1, level) {
+				add(vertex{i + 1, j + 1}, vertex{i, j + 1})
+			}
+			if !g.in(i-1, j, level) {
+				add(vertex{i, j + 1}, vertex{i, j})
+			}
+		}
+	}
+
+	var outer, holes [][]vertex
+
+	for len(edges) > 0 {
+		// start from the south west most vertex so output is repeatable.
+		first := true
+		var start vertex
+		for k := range edges {
+			if first || k.j < start.j || (k.j == start.j && k.i < start.i) {
+				start = k
+				first = false
+			}
+		}
+
+		r := trace(edges, start)
+
+		if area(r) > 0 {
+			outer = append(outer, r)
+		} else {
+			holes = append(holes, r)
+		}
+	}
+
+	// assign each hole to the smallest outer ring that contains it.
+	h := make([][][]vertex, len(outer))
+
+	for _, v := range holes {
+		// the cell on the left of the first hole edge is >= level so it's in the enclosing outer ring.
+		d := sign(vertex{i: v[1].i - v[0].i, j: v[1].j - v[0].j})
+		x := float64(v[0].i) + 0.5*float64(d.i) - 0.5*float64(d.j)
+		y := float64(v[0].j) + 0.5*float64(d.j) + 0.5*float64(d.i)
+
+		best := -1
+		for k, o := range outer {
+			if contains(o, x, y) && (best == -1 || area(o) < area(outer[best])) {
+				best = k
+			}
+		}
+
+		if best >= 0 {
+			h[best] = append(h[best], v)
+		}
+	}
+
+	var p [][][][]float64
+
+	for k, o := range outer {
+		poly := [][][]float64{g.coords(o)}
+		for _, v := range h[k] {
+			poly = append(poly, g.coords(v))
+		}
+		p = append(p, poly)
+	}
+
+	return p
+}
+
+// trace follows boundary edges from start until the ring is closed.  Used edges are
+// removed from edges.  Where two rings touch at a corner the left turn is taken.
+func trace(edges map[vertex][]edge, start vertex) []vertex {
+	var r []vertex
+	var last vertex
+
+	v := start
+
+	for {
+		out := edges[v]
+		if len(out) == 0 {
+			break
+		}
+
+		k := 0
+		if len(out) > 1 && len(r) > 0 {
+			for n, e := range out {
+				d := e.dir()
+				// left turn of last.
+				if d.i == -last.j && d.j == last.i {
+					k = n
+				}
+			}
+		}
+
+		e := out[k]
+		out = append(out[:k], out[k+1:]...)
+		if len(out) == 0 {
+			delete(edges, v)
+		} else {
+			edges[v] = out
+		}
+
+		// drop collinear vertices.
+		if len(r) == 0 || e.dir() != last {
+			r = append(r, v)
+		}
+
+		last = e.dir()
+		v = e.to
+
+		if v == start {
+			break
+		}
+	}
+
+	// close the ring.  The start may be collinear with the last edge.
+	if len(r) > 1 {
+		d := vertex{i: r[1].i - r[0].i, j: r[1].j - r[0].j}
+		if sign(d) == sign(last) {
+			r = r[1:]
+		}
+	}
+
+	return append(r, r[0])
+}
+
+func sign(v vertex) vertex {
+	s := func(x int) int {
+		switch {
+		case x > 0:
+			return 1
+		case x < 0:
+			return -1
+		}
+		return 0
+	}
+
+	return vertex{i: s(v.i), j: s(v.j)}
+}
+
+// area is the signed area of the closed ring r.  Positive for counter clockwise.
+func area(r []vertex) float64 {
+	var a int
+	for k := 0; k < len(r)-1; k++ {
+		a += r[k].i*r[k+1].j - r[k+1].i*r[k].j
+	}
+
+	return float64(a) / 2.0
+}
+
+// contains returns true if x, y is inside the closed ring r.
+func contains(r []vertex, x, y float64) bool {
+	c := false
+
+	for k := 0; k < len(r)-1; k++ {
+		xi, yi := float64(r[k].i), float64(r[k].j)
+		xj, yj := float64(r[k+1].i), float64(r[k+1].j)
+
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			c = !c
+		}
+	}
+
+	return c
+}
+
+// coords converts corners to longitude, latitude.
+func (g *Grid) coords(r []vertex) [][]float64 {
+	c := make([][]float64, len(r))
+
+	for k, v := range r {
+		c[k] = []float64{
+			round(g.West + (float64(v.i)-0.5)*g.Cell),
+			round(g.South + (float64(v.j)-0.5)*g.Cell),
+		}
+	}
+
+	return c
+}
+
+func round(f float64) float64 {
+	return math.Floor(f*1e5+0.5) / 1e5
+}
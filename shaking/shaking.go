@@ -0,0 +1,235 @@
+/*
+Package shaking calculates a regular grid of shaking intensity (MMI) for a quake, similar to a ShakeMap.
+The grid is calculated with the intensity model for the quake and can be biased with measured intensities.
+It can be output as GeoJSON contour polygons or an ESRI ASCII grid.
+*/
+package shaking
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/GeoNet/Golang-Ellipsoid/ellipsoid"
+	"github.com/GeoNet/haz/msg"
+	"io"
+	"math"
+)
+
+var geo = ellipsoid.Init("WGS84", ellipsoid.Degrees, ellipsoid.Kilometer, ellipsoid.LongitudeIsSymmetric, ellipsoid.BearingNotSymmetric)
+
+const kmPerDegree = 111.2
+
+// noData is used in the ESRI ASCII grid.  All cells have a value.
+const noData = -9999
+
+// Options for calculating a Grid.  Zero values use the defaults.
+type Options struct {
+	Cell      float64 // cell size in degrees.  Default 0.05
+	MinMMI    float64 // the grid covers the area where the modelled MMI is >= MinMMI.  Default 3.0
+	MaxRadius float64 // the maximum radius of the grid in km.  Default 500.0
+	MinRadius float64 // the minimum radius of the grid in km.  Default 20.0
+}
+
+// Observation is a measured MMI.
+type Observation struct {
+	Latitude, Longitude float64
+	MMI                 float64
+}
+
+/*
+Grid is a regular grid of MMI values.  Values are stored by row starting
+from the south west cell.
+*/
+type Grid struct {
+	West, South float64 // the centre of the south west cell (degrees).
+	Cell        float64 // cell size (degrees).
+	Cols, Rows  int
+	Radius      float64 // the distance (km) from the epicentre to the edge of the grid.
+	MMI         []float64
+}
+
+/*
+New returns a Grid of MMI calculated for q using the quake's intensity model.
+The grid is centred on the epicentre.
+*/
+func New(q *msg.Quake, o Options) (*Grid, error) {
+	if q.Err() != nil {
+		return nil, q.Err()
+	}
+
+	if o.Cell <= 0 {
+		o.Cell = 0.05
+	}
+	if o.MinMMI <= 0 {
+		o.MinMMI = 3.0
+	}
+	if o.MaxRadius <= 0 {
+		o.MaxRadius = 500.0
+	}
+	if o.MinRadius <= 0 {
+		o.MinRadius = 20.0
+	}
+
+	r := o.MinRadius
+	for r < o.MaxRadius && q.MMIAtDistance(r) >= o.MinMMI {
+		r += 10.0
+	}
+	r = math.Min(r, o.MaxRadius)
+
+	dLat := r / kmPerDegree
+	dLon := r / (kmPerDegree * math.Max(math.Cos(q.Latitude*math.Pi/180.0), 0.1))
+
+	nLat := int(math.Ceil(dLat / o.Cell))
+	nLon := int(math.Ceil(dLon / o.Cell))
+
+	g := &Grid{
+		West:   q.Longitude - float64(nLon)*o.Cell,
+		South:  q.Latitude - float64(nLat)*o.Cell,
+		Cell:   o.Cell,
+		Cols:   2*nLon + 1,
+		Rows:   2*nLat + 1,
+		Radius: r,
+	}
+
+	g.MMI = make([]float64, g.Cols*g.Rows)
+
+	for j := 0; j < g.Rows; j++ {
+		for i := 0; i < g.Cols; i++ {
+			lat, lon := g.Centre(i, j)
+			d, _ := geo.To(lat, lon, q.Latitude, q.Longitude)
+			g.MMI[j*g.Cols+i] = q.MMIAtDistance(d)
+		}
+	}
+
+	return g, nil
+}
+
+// Centre returns the latitude and longitude of the centre of cell i, j.
+func (g *Grid) Centre(i, j int) (latitude, longitude float64) {
+	return g.South + float64(j)*g.Cell, g.West + float64(i)*g.Cell
+}
+
+// At returns the MMI for the cell containing latitude, longitude.  ok is false if the
+// location is outside the grid.
+func (g *Grid) At(latitude, longitude float64) (mmi float64, ok bool) {
+	i := int(math.Floor((longitude-g.West)/g.Cell + 0.5))
+	j := int(math.Floor((latitude-g.South)/g.Cell + 0.5))
+
+	if i < 0 || i >= g.Cols || j < 0 || j >= g.Rows {
+		return 0, false
+	}
+
+	return g.MMI[j*g.Cols+i], true
+}
+
+// Max returns the maximum MMI in the grid.
+func (g *Grid) Max() float64 {
+	m := -1.0
+	for _, v := range g.MMI {
+		m = math.Max(m, v)
+	}
+
+	return m
+}
+
+/*
+Bias adjusts the grid to agree with the observations obs for quake q.
+
+The mean difference between the observations in the grid and the modelled MMI is applied to the
+whole grid (the event bias).  Remaining differences are then spread to nearby cells with a weight that
+decays exponentially over radius (km).  Close to an observation the grid tends towards the observed value
+while far from observations the grid stays at the biased model value.
+
+Returns the event bias.
+*/
+func (g *Grid) Bias(q *msg.Quake, obs []Observation, radius float64) float64 {
+	type residual struct {
+		Observation
+		r float64
+	}
+
+	var res []residual
+	var sum float64
+
+	for _, o := range obs {
+		if _, ok := g.At(o.Latitude, o.Longitude); !ok {
+			continue
+		}
+
+		d, _ := geo.To(o.Latitude, o.Longitude, q.Latitude, q.Longitude)
+		r := o.MMI - q.MMIAtDistance(d)
+
+		res = append(res, residual{Observation: o, r: r})
+		sum += r
+	}
+
+	if len(res) == 0 {
+		return 0.0
+	}
+
+	bias := sum / float64(len(res))
+
+	for i := range res {
+		res[i].r -= bias
+	}
+
+	if radius <= 0 {
+		radius = 10.0
+	}
+
+	max := 3.0 * radius
+
+	for j := 0; j < g.Rows; j++ {
+		for i := 0; i < g.Cols; i++ {
+			lat, lon := g.Centre(i, j)
+
+			// the model has weight 1 so that a single observation
+			// can't completely override it.
+			w, wr := 1.0, 0.0
+
+			for _, o := range res {
+				// quick reject before the more expensive distance calc.
+				if math.Abs(o.Latitude-lat)*kmPerDegree > max {
+					continue
+				}
+
+				d, _ := geo.To(lat, lon, o.Latitude, o.Longitude)
+				if d > max {
+					continue
+				}
+
+				e := math.Exp(-d / radius)
+				w += e
+				wr += e * o.r
+			}
+
+			k := j*g.Cols + i
+			g.MMI[k] = math.Min(math.Max(g.MMI[k]+bias+wr/w, -1.0), 12.0)
+		}
+	}
+
+	return bias
+}
+
+// WriteASCII writes g as an ESRI ASCII grid.  Rows are written from the north.
+func (g *Grid) WriteASCII(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "ncols %d\n", g.Cols)
+	fmt.Fprintf(b, "nrows %d\n", g.Rows)
+	fmt.Fprintf(b, "xllcenter %.4f\n", g.West)
+	fmt.Fprintf(b, "yllcenter %.4f\n", g.South)
+	fmt.Fprintf(b, "cellsize %.4f\n", g.Cell)
+	fmt.Fprintf(b, "NODATA_value %d\n", noData)
+
+	for j := g.Rows - 1; j >= 0; j-- {
+		for i := 0; i < g.Cols; i++ {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(b, "%.2f", g.MMI[j*g.Cols+i])
+		}
+		b.WriteByte('\n')
+	}
+
+	return b.Flush()
+}
@@ -0,0 +1,258 @@
+package shaking
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/GeoNet/haz/msg"
+	"math"
+	"testing"
+)
+
+func testQuake() *msg.Quake {
+	return &msg.Quake{
+		PublicID:  "2013p407387",
+		Latitude:  -41.6,
+		Longitude: 174.3,
+		Depth:     10.0,
+		Magnitude: 6.5,
+	}
+}
+
+func TestNew(t *testing.T) {
+	q := testQuake()
+
+	g, err := New(q, Options{Cell: 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Cols%2 != 1 || g.Rows%2 != 1 {
+		t.Errorf("expected odd rows and cols got %d %d", g.Rows, g.Cols)
+	}
+
+	m, ok := g.At(q.Latitude, q.Longitude)
+	if !ok {
+		t.Fatal("expected epicentre in grid")
+	}
+
+	if math.Abs(m-q.MMI()) > 0.001 {
+		t.Errorf("expected MMI %.2f at the epicentre got %.2f", q.MMI(), m)
+	}
+
+	if m != g.Max() {
+		t.Errorf("expected the max MMI at the epicentre got %.2f not %.2f", m, g.Max())
+	}
+
+	// the edge of the grid should be close to the min MMI.
+	e := g.MMI[(g.Rows/2)*g.Cols]
+	if e > 3.5 {
+		t.Errorf("expected MMI near 3 at the grid edge got %.2f", e)
+	}
+
+	// the grid reaches at least Radius from the epicentre in every direction.
+	if _, ok = g.At(q.Latitude+(g.Radius-1.0)/kmPerDegree, q.Longitude); !ok {
+		t.Errorf("expected location %.0f km north of the epicentre in the grid", g.Radius-1.0)
+	}
+
+	if _, ok = g.At(q.Latitude+20.0, q.Longitude); ok {
+		t.Error("expected location outside the grid")
+	}
+
+	q.SetErr(fmt.Errorf("errored quake"))
+	if _, err = New(q, Options{}); err == nil {
+		t.Error("expected error for errored quake")
+	}
+}
+
+func TestBias(t *testing.T) {
+	q := testQuake()
+
+	g, err := New(q, Options{Cell: 0.05})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wellington
+	lat, lon := -41.29, 174.78
+
+	before, _ := g.At(lat, lon)
+	far, _ := g.At(q.Latitude-2.0, q.Longitude)
+
+	b := g.Bias(q, []Observation{{Latitude: lat, Longitude: lon, MMI: before + 2.0}}, 10.0)
+
+	if math.Abs(b-2.0) > 0.1 {
+		t.Errorf("expected event bias of 2 got %.2f", b)
+	}
+
+	after, _ := g.At(lat, lon)
+	if math.Abs(after-(before+2.0)) > 0.1 {
+		t.Errorf("expected MMI %.2f at observation got %.2f", before+2.0, after)
+	}
+
+	farAfter, _ := g.At(q.Latitude-2.0, q.Longitude)
+	if math.Abs(farAfter-(far+b)) > 0.01 {
+		t.Errorf("expected far cell to only have the event bias %.2f got %.2f", far+b, farAfter)
+	}
+
+	// two observations that disagree in opposite directions cancel the event bias
+	// but move the grid locally.
+	g, _ = New(q, Options{Cell: 0.05})
+
+	nLat, nLon := -40.9, 175.0 // north east
+	sLat, sLon := -42.4, 173.7 // south west
+
+	n, _ := g.At(nLat, nLon)
+	s, _ := g.At(sLat, sLon)
+
+	b = g.Bias(q, []Observation{{Latitude: nLat, Longitude: nLon, MMI: n + 1.0}, {Latitude: sLat, Longitude: sLon, MMI: s - 1.0}}, 10.0)
+	if math.Abs(b) > 0.1 {
+		t.Errorf("expected no event bias got %.2f", b)
+	}
+
+	nAfter, _ := g.At(nLat, nLon)
+	sAfter, _ := g.At(sLat, sLon)
+
+	if nAfter < n+0.4 {
+		t.Errorf("expected MMI to increase near the north observation %.2f %.2f", n, nAfter)
+	}
+	if sAfter > s-0.4 {
+		t.Errorf("expected MMI to decrease near the south observation %.2f %.2f", s, sAfter)
+	}
+
+	// observations outside the grid are ignored.
+	if b = g.Bias(q, []Observation{{Latitude: 0, Longitude: 0, MMI: 12}}, 10.0); b != 0.0 {
+		t.Errorf("expected no bias for observation outside the grid got %.2f", b)
+	}
+}
+
+func TestContours(t *testing.T) {
+	q := testQuake()
+
+	g, err := New(q, Options{Cell: 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := g.Contours(3)
+
+	if len(c.Features) != int(g.Max())-2 {
+		t.Fatalf("expected %d features got %d", int(g.Max())-2, len(c.Features))
+	}
+
+	var last float64
+
+	for i, f := range c.Features {
+		if f.Properties.MMI != i+3 {
+			t.Errorf("expected MMI %d got %d", i+3, f.Properties.MMI)
+		}
+
+		if len(f.Geometry.Coordinates) != 1 {
+			t.Errorf("MMI %d expected 1 polygon for a single quake got %d", f.Properties.MMI, len(f.Geometry.Coordinates))
+			continue
+		}
+
+		r := f.Geometry.Coordinates[0][0]
+
+		if r[0][0] != r[len(r)-1][0] || r[0][1] != r[len(r)-1][1] {
+			t.Errorf("MMI %d ring not closed", f.Properties.MMI)
+		}
+
+		a := ringArea(r)
+		if a <= 0 {
+			t.Errorf("MMI %d expected counter clockwise outer ring", f.Properties.MMI)
+		}
+
+		if i > 0 && a >= last {
+			t.Errorf("MMI %d expected smaller area than the lower MMI", f.Properties.MMI)
+		}
+		last = a
+	}
+
+	b, err := g.GeoJSON(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fc FeatureCollection
+	if err = json.Unmarshal(b, &fc); err != nil {
+		t.Error(err)
+	}
+}
+
+// A ring of high cells around a low cell should give a polygon with a hole.
+func TestContoursHole(t *testing.T) {
+	g := &Grid{West: 170.0, South: -42.0, Cell: 1.0, Cols: 5, Rows: 5}
+	g.MMI = []float64{
+		1, 1, 1, 1, 1,
+		1, 5, 5, 5, 1,
+		1, 5, 1, 5, 1,
+		1, 5, 5, 5, 1,
+		1, 1, 1, 5, 1,
+	}
+
+	c := g.Contours(5)
+	if len(c.Features) != 1 {
+		t.Fatalf("expected 1 feature got %d", len(c.Features))
+	}
+
+	p := c.Features[0].Geometry.Coordinates
+	if len(p) != 1 {
+		t.Fatalf("expected 1 polygon got %d", len(p))
+	}
+
+	if len(p[0]) != 2 {
+		t.Fatalf("expected outer ring and hole got %d rings", len(p[0]))
+	}
+
+	if ringArea(p[0][0]) != 10.0 {
+		t.Errorf("expected outer area 10 got %f", ringArea(p[0][0]))
+	}
+
+	if ringArea(p[0][1]) != -1.0 {
+		t.Errorf("expected clockwise hole area -1 got %f", ringArea(p[0][1]))
+	}
+
+	// hole is cell 2,2
+	expected := [][]float64{{171.5, -40.5}, {171.5, -39.5}, {172.5, -39.5}, {172.5, -40.5}, {171.5, -40.5}}
+	if len(p[0][1]) != len(expected) {
+		t.Fatalf("expected hole %v got %v", expected, p[0][1])
+	}
+	for i := range expected {
+		if p[0][1][i][0] != expected[i][0] || p[0][1][i][1] != expected[i][1] {
+			t.Errorf("expected hole %v got %v", expected, p[0][1])
+			break
+		}
+	}
+}
+
+func TestWriteASCII(t *testing.T) {
+	g := &Grid{West: 170.0, South: -42.0, Cell: 0.5, Cols: 3, Rows: 2, MMI: []float64{1, 2, 3, 4, 5, 6}}
+
+	var b bytes.Buffer
+	if err := g.WriteASCII(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `ncols 3
+nrows 2
+xllcenter 170.0000
+yllcenter -42.0000
+cellsize 0.5000
+NODATA_value -9999
+4.00 5.00 6.00
+1.00 2.00 3.00
+`
+
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func ringArea(r [][]float64) float64 {
+	var a float64
+	for k := 0; k < len(r)-1; k++ {
+		a += r[k][0]*r[k+1][1] - r[k+1][0]*r[k][1]
+	}
+	return a / 2.0
+}
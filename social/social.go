@@ -0,0 +1,88 @@
+// Package social provides an abstraction for posting quake messages to social media accounts.
+package social
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GeoNet/haz/mastodon"
+	"github.com/GeoNet/haz/msg"
+	"github.com/GeoNet/haz/twitter"
+	"io/ioutil"
+)
+
+// Poster posts messages to a social media account.
+type Poster interface {
+	// Post posts message.  publicID identifies the quake the message is for and
+	// longitude and latitude are its location, implementations may ignore them.
+	Post(publicID, message string, longitude, latitude float64) error
+	// Limits returns the message length rules for the account.
+	Limits() msg.SocialLimits
+}
+
+/*
+Account is the config for a social media account e.g.,
+
+	{"Name": "geonet", "Type": "twitter", "Threshold": 3, "Token": "...", "Secret": "...", "Geo": true}
+	{"Name": "geonet_mi", "Type": "twitter", "Threshold": 3, "Token": "...", "Secret": "...", "Language": "mi"}
+	{"Name": "geonet@mastodon.nz", "Type": "mastodon", "Threshold": 4, "Server": "https://mastodon.nz",
+	  "Token": "...", "Visibility": "public", "Language": "en", "MaxChars": 500}
+*/
+type Account struct {
+	Name      string
+	Type      string  // twitter or mastodon.
+	Threshold float64 // the minimum magnitude to post.
+	Token     string  // OAuth token (twitter) or access token (mastodon).
+	Secret    string  // OAuth token secret (twitter only).
+	// Language is the language for the post text e.g., en or mi.  Empty for English.
+	// It is also set as the post language for Mastodon.
+	Language string
+	// Twitter only.
+	Geo bool
+	// Mastodon only.
+	Server     string
+	Visibility string
+	MaxChars   int
+}
+
+// Poster returns the Poster for a.
+func (a Account) Poster() (Poster, error) {
+	switch a.Type {
+	case "twitter":
+		t, err := twitter.New(a.Token, a.Secret)
+		if err != nil {
+			return nil, err
+		}
+		t.Geo = a.Geo
+		return &t, nil
+	case "mastodon":
+		m, err := mastodon.New(a.Server, a.Token, a.Visibility)
+		if err != nil {
+			return nil, err
+		}
+		m.Language = a.Language
+		m.MaxChars = a.MaxChars
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown account type %s for %s", a.Type, a.Name)
+	}
+}
+
+// Load reads a JSON array of Account from the file f.
+func Load(f string) ([]Account, error) {
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var a []Account
+
+	if err = json.Unmarshal(b, &a); err != nil {
+		return nil, err
+	}
+
+	if len(a) == 0 {
+		return nil, fmt.Errorf("no accounts in %s", f)
+	}
+
+	return a, nil
+}
@@ -37,6 +37,12 @@ var (
 //
 // The chans block for slow consumers.
 func InitRx() (<-chan msg.Raw, chan<- string, error) {
+	return InitRxQueue(queueName)
+}
+
+// InitRxQueue is the same as InitRx for the queue with name.  Use it to receive from
+// more than one queue.
+func InitRxQueue(name string) (<-chan msg.Raw, chan<- string, error) {
 	var rx = make(chan msg.Raw)
 	var dx = make(chan string)
 
@@ -55,7 +61,7 @@ func InitRx() (<-chan msg.Raw, chan<- string, error) {
 
 	for {
 		params := &sqs.GetQueueUrlInput{
-			QueueName: aws.String(queueName),
+			QueueName: aws.String(name),
 		}
 		q, err = svc.GetQueueUrl(params)
 		if err != nil {
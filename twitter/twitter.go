@@ -3,6 +3,7 @@ package twitter
 import (
 	"fmt"
 	"github.com/ChimeraCoder/anaconda"
+	"github.com/GeoNet/haz/msg"
 	"net/url"
 	"os"
 	"strconv"
@@ -10,6 +11,8 @@ import (
 
 type Twitter struct {
 	api *anaconda.TwitterApi
+	// Geo adds the longitude and latitude to posts.
+	Geo bool
 }
 
 var (
@@ -20,10 +23,20 @@ var (
 	minMagnitude   = os.Getenv("TWITTER_THRESHOLD")
 )
 
+// Init returns a Twitter for the account in the env var config.
 func Init() (Twitter, error) {
+	t, err := New(oauthToken, oauthSecret)
+	t.Geo = true
+	return t, err
+}
+
+// New returns a Twitter for the account with the OAuth token and secret.
+// The consumer (application) key and secret are global in anaconda so all accounts
+// must use the same application; they are read from the env var config.
+func New(token, secret string) (Twitter, error) {
 	anaconda.SetConsumerKey(consumerKey)
 	anaconda.SetConsumerSecret(consumerSecret)
-	api := anaconda.NewTwitterApi(oauthToken, oauthSecret)
+	api := anaconda.NewTwitterApi(token, secret)
 
 	var err error
 
@@ -54,3 +67,24 @@ func (a *Twitter) PostTweet(message string, longitude float64, latitude float64)
 
 	return err
 }
+
+// Post posts message.  The location is only added if a.Geo is true.
+// publicID is not used by Twitter.
+func (a *Twitter) Post(publicID, message string, longitude, latitude float64) error {
+	if a.Geo {
+		return a.PostTweet(message, longitude, latitude)
+	}
+
+	if a.api.Credentials == nil {
+		return fmt.Errorf("Credentials are invalid, cannot post.")
+	}
+
+	_, err := a.api.PostTweet(message, url.Values{})
+
+	return err
+}
+
+// Limits returns the message length rules for Twitter.
+func (a *Twitter) Limits() msg.SocialLimits {
+	return msg.TwitterLimits
+}
@@ -7,21 +7,30 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 )
 
-const api = "https://go.urbanairship.com/api/push"
+var api = "https://go.urbanairship.com/api/push"
 
 // UA's push object JSON
 type pushData struct {
 	Audience     audience     `json:"audience,omitempty"`
 	Notification notification `json:"notification,omitempty"`
 	DeviceTypes  []string     `json:"device_types,omitempty"`
+	Options      *options     `json:"options,omitempty"`
+}
+
+type options struct {
+	Expiry int64 `json:"expiry,omitempty"` // seconds
 }
 
 type audience struct {
-	DeviceToken []string `json:"device_token,omitempty"`
-	Tag         []string `json:"tag,omitempty"`
-	ApID        []string `json:"apid,omitempty"`
+	DeviceToken    []string   `json:"device_token,omitempty"`
+	Tag            []string   `json:"tag,omitempty"`
+	ApID           []string   `json:"apid,omitempty"`
+	AndroidChannel []string   `json:"android_channel,omitempty"`
+	Or             []audience `json:"OR,omitempty"`
 }
 
 type notification struct {
@@ -30,20 +39,20 @@ type notification struct {
 }
 
 type ios struct {
-	Badge            string `json:"badge,omitempty"`
-	ContentAvailable bool   `json:"content-available,omitempty"`
-	Extra            extra  `json:"extra"`
-	Alert            string `json:"alert"`
-	Sound            string `json:"sound,omitempty"`
+	Badge            string            `json:"badge,omitempty"`
+	ContentAvailable bool              `json:"content-available,omitempty"`
+	Extra            map[string]string `json:"extra"`
+	Alert            string            `json:"alert"`
+	Sound            string            `json:"sound,omitempty"`
+	Title            string            `json:"title,omitempty"`
+	CollapseID       string            `json:"collapse_id,omitempty"`
 }
 
 type android struct {
-	Alert string `json:"alert"`
-	Extra extra  `json:"extra"`
-}
-
-type extra struct {
-	PublicID string `json:"publicid"`
+	Alert       string            `json:"alert"`
+	Extra       map[string]string `json:"extra"`
+	Title       string            `json:"title,omitempty"`
+	CollapseKey string            `json:"collapse_key,omitempty"`
 }
 
 // end UA's push object JSON
@@ -66,33 +75,121 @@ func Init() *Client {
 }
 
 func (a *Client) Push(publicID string, message string, tags []string) (err error) {
-	e := extra{
-		PublicID: publicID,
+	au := audience{
+		Tag: tags,
 	}
+
+	return a.send(pushData{
+		Audience:     au,
+		Notification: notify(publicID, message),
+		DeviceTypes:  []string{"ios", "android"},
+	})
+}
+
+// Devices is a push to individual devices.
+type Devices struct {
+	IOS     []string // iOS device tokens.
+	Android []string // Android channel IDs.
+
+	PublicID     string
+	IOSAlert     string
+	AndroidAlert string
+	IOSTitle     string
+	AndroidTitle string
+	Sound        string // iOS sound.  Defaults to "default".
+	Badge        int    // iOS badge.  0 increments the badge.
+	// CollapseKey replaces earlier notifications with the same key.
+	CollapseKey string
+	// TTL is the push expiry.  0 for the UA default.
+	TTL time.Duration
+	// Extra is added to the extra for both platforms.
+	Extra map[string]string
+}
+
+// PushDevices pushes to individual devices.
+func (a *Client) PushDevices(d Devices) (err error) {
+	var au audience
+
+	switch {
+	case len(d.IOS) > 0 && len(d.Android) > 0:
+		au.Or = []audience{{DeviceToken: d.IOS}, {AndroidChannel: d.Android}}
+	case len(d.IOS) > 0:
+		au.DeviceToken = d.IOS
+	case len(d.Android) > 0:
+		au.AndroidChannel = d.Android
+	default:
+		return
+	}
+
+	e := map[string]string{"publicid": d.PublicID}
+	for k, v := range d.Extra {
+		e[k] = v
+	}
+
 	i := ios{
 		Badge:            "+1",
 		ContentAvailable: true,
 		Extra:            e,
-		Alert:            message,
+		Alert:            d.IOSAlert,
 		Sound:            "default",
+		Title:            d.IOSTitle,
+		CollapseID:       d.CollapseKey,
 	}
-	an := android{
-		Alert: message,
-		Extra: e,
+
+	if d.Badge > 0 {
+		i.Badge = strconv.Itoa(d.Badge)
 	}
-	n := notification{
-		Ios:     i,
-		Android: an,
+
+	if d.Sound != "" {
+		i.Sound = d.Sound
 	}
-	au := audience{
-		Tag: tags,
+
+	n := notification{
+		Ios: i,
+		Android: android{
+			Alert:       d.AndroidAlert,
+			Extra:       e,
+			Title:       d.AndroidTitle,
+			CollapseKey: d.CollapseKey,
+		},
 	}
+
 	data := pushData{
 		Audience:     au,
 		Notification: n,
 		DeviceTypes:  []string{"ios", "android"},
 	}
 
+	if d.TTL > 0 {
+		data.Options = &options{Expiry: int64(d.TTL.Seconds())}
+	}
+
+	return a.send(data)
+}
+
+func notify(publicID, message string) notification {
+	e := map[string]string{
+		"publicid": publicID,
+	}
+	i := ios{
+		Badge:            "+1",
+		ContentAvailable: true,
+		Extra:            e,
+		Alert:            message,
+		Sound:            "default",
+	}
+	an := android{
+		Alert: message,
+		Extra: e,
+	}
+
+	return notification{
+		Ios:     i,
+		Android: an,
+	}
+}
+
+func (a *Client) send(data pushData) (err error) {
 	b, err := json.Marshal(data)
 	if err != nil {
 		return
@@ -0,0 +1,62 @@
+package ua
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPushDevices(t *testing.T) {
+	var p pushData
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p = pushData{}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	api = ts.URL
+
+	c := Init()
+
+	if err := c.PushDevices(Devices{
+		IOS:          []string{"ios1"},
+		Android:      []string{"android1", "android2"},
+		PublicID:     "2015p278423",
+		IOSAlert:     "M6.0 quake",
+		AndroidAlert: "M6.0 quake",
+		CollapseKey:  "2015p278423",
+		TTL:          time.Hour,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.Audience.Or) != 2 || p.Audience.Or[0].DeviceToken[0] != "ios1" || len(p.Audience.Or[1].AndroidChannel) != 2 {
+		t.Errorf("unexpected audience %+v", p.Audience)
+	}
+
+	if p.Notification.Ios.Extra["publicid"] != "2015p278423" || p.Notification.Android.Alert != "M6.0 quake" {
+		t.Errorf("unexpected notification %+v", p.Notification)
+	}
+
+	if p.Notification.Ios.CollapseID != "2015p278423" || p.Notification.Android.CollapseKey != "2015p278423" {
+		t.Errorf("unexpected collapse keys %+v", p.Notification)
+	}
+
+	if p.Options == nil || p.Options.Expiry != 3600 {
+		t.Errorf("expected expiry 3600 got %+v", p.Options)
+	}
+
+	if err := c.PushDevices(Devices{Android: []string{"android1"}, PublicID: "2015p278423"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.Audience.Or) != 0 || p.Audience.AndroidChannel[0] != "android1" {
+		t.Errorf("unexpected audience %+v", p.Audience)
+	}
+}